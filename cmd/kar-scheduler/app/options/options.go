@@ -17,6 +17,9 @@ limitations under the License.
 package options
 
 import (
+	"time"
+
+	"github.com/golang/glog"
 	"github.com/spf13/pflag"
 )
 
@@ -26,6 +29,24 @@ type ServerOption struct {
 	Kubeconfig    string
 	SchedulerName string
 	Actions       []string
+	Plugins       []string
+	PluginArgs    []string
+	ListenAddress string
+
+	PercentageOfNodesToScore int32
+	MaxPreemptees            int
+	MaxPreemptions           int
+	MaxPreemptionRounds      int
+	MaxVictimsPerPreemptor   int
+	MaxVictimsPerJob         int
+	APIQPS                   float32
+	APIBurst                 int
+	PodStartSLO              time.Duration
+	DefaultRequests          []string
+	NominationTimeout        time.Duration
+	SessionTimeout           time.Duration
+	ValidateSession          bool
+	DebugSession             bool
 }
 
 // NewServerOption creates a new CMServer with a default config.
@@ -40,9 +61,47 @@ func (s *ServerOption) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.Kubeconfig, "kubeconfig", s.Kubeconfig, "Path to kubeconfig file with authorization and master location information.")
 	// kube-arbitrator will ignore pods with scheduler names other than specified with the option
 	fs.StringVar(&s.SchedulerName, "scheduler-name", "kar-scheduler", "kube-arbitrator will handle pods with the scheduler-name")
-	fs.StringArrayVar(&s.Actions, "action", []string{"decorate", "allocate"}, "The actions that executed by scheduler")
+	fs.StringArrayVar(&s.Actions, "action", []string{"decorate", "drain", "allocate"}, "The actions that executed by scheduler")
+	fs.StringArrayVar(&s.Plugins, "plugin", []string{"priority", "gang", "drf", "predicates"}, "The plugins that enabled by scheduler")
+	fs.StringArrayVar(&s.PluginArgs, "plugin-arg", []string{}, "The arguments of plugins, in the format of <plugin>.<key>=<value>")
+	fs.StringVar(&s.ListenAddress, "listen-address", "", "The address to listen on for HTTP requests, e.g. metrics at \"/debug/vars\", such as \":8080\"; no HTTP server if empty")
+	fs.Int32Var(&s.PercentageOfNodesToScore, "percentage-of-nodes-to-score", 100, "The percentage of nodes to find feasible for a task before scoring them; the scheduler scores at least 100 nodes if there are")
+	fs.IntVar(&s.MaxPreemptees, "max-preemptees", 0, "The max number of victims examined for a preemptor in a session; 0 means no limit")
+	fs.IntVar(&s.MaxPreemptions, "max-preemptions", 0, "The max number of tasks evicted by preemption in a session; 0 means no limit")
+	fs.IntVar(&s.MaxPreemptionRounds, "max-preemption-rounds", 0, "The max number of preemptor jobs that evict tasks in a session, to bound cascading preemptions; 0 means no limit")
+	fs.IntVar(&s.MaxVictimsPerPreemptor, "max-victims-per-preemptor", 0, "The max number of victims evicted for a preemptor in a session; 0 means no limit")
+	fs.IntVar(&s.MaxVictimsPerJob, "max-victims-per-job", 0, "The max number of victims evicted for the preemptors of a job in a session, unless overridden by the job's preemption-budget annotation; 0 means no limit")
+	fs.Float32Var(&s.APIQPS, "api-qps", 0, "The max number of binds and evictions sent to the API server per second; 0 means no limit")
+	fs.IntVar(&s.APIBurst, "api-burst", 10, "The max burst of binds and evictions sent to the API server, with api-qps")
+	fs.DurationVar(&s.PodStartSLO, "pod-start-slo", 0, "The max time that a pod waits from pending to bound; the pods waiting longer are flagged by an event, and 0 means no SLO")
+	fs.StringArrayVar(&s.DefaultRequests, "default-request", []string{}, "The request of the pods that request nothing, for scheduling only, in the format of [<namespace>:]<resource>=<quantity>,...; the one without namespace is for all other namespaces")
+	fs.DurationVar(&s.NominationTimeout, "nomination-timeout", 0, "The max time that a preemptor keeps the resource released on its nominated node until it's bound; 0 means no timeout")
+	fs.DurationVar(&s.SessionTimeout, "session-timeout", 0, "The max time of a scheduling session; the actions stop when it's passed and the decisions made so far are committed, and 0 means no timeout")
+	fs.BoolVar(&s.ValidateSession, "validate-session", false, "Validate the resource accounting of session after each action, for debugging")
+	fs.BoolVar(&s.DebugSession, "debug-session", false, "Serve the state of the latest session as JSON at \"/debug/session\" of the HTTP server; it requires listen-address")
 }
 
 func (s *ServerOption) CheckOptionOrDie() {
-
+	if s.PercentageOfNodesToScore < 0 || s.PercentageOfNodesToScore > 100 {
+		glog.Fatalf("percentage-of-nodes-to-score must be in [0, 100], got %d", s.PercentageOfNodesToScore)
+	}
+	if s.MaxPreemptees < 0 || s.MaxPreemptions < 0 {
+		glog.Fatalf("max-preemptees and max-preemptions must not be negative, got %d and %d",
+			s.MaxPreemptees, s.MaxPreemptions)
+	}
+	if s.MaxPreemptionRounds < 0 || s.MaxVictimsPerPreemptor < 0 || s.MaxVictimsPerJob < 0 {
+		glog.Fatalf("max-preemption-rounds, max-victims-per-preemptor and max-victims-per-job must not be negative, got %d, %d and %d",
+			s.MaxPreemptionRounds, s.MaxVictimsPerPreemptor, s.MaxVictimsPerJob)
+	}
+	if s.APIQPS < 0 || (s.APIQPS > 0 && s.APIBurst < 1) {
+		glog.Fatalf("api-qps must not be negative and api-burst must be positive, got %v and %d",
+			s.APIQPS, s.APIBurst)
+	}
+	if s.PodStartSLO < 0 || s.NominationTimeout < 0 || s.SessionTimeout < 0 {
+		glog.Fatalf("pod-start-slo, nomination-timeout and session-timeout must not be negative, got %v, %v and %v",
+			s.PodStartSLO, s.NominationTimeout, s.SessionTimeout)
+	}
+	if s.DebugSession && len(s.ListenAddress) == 0 {
+		glog.Fatalf("debug-session requires listen-address for the HTTP server")
+	}
 }
@@ -17,12 +17,23 @@ limitations under the License.
 package app
 
 import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/golang/glog"
+
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/kubernetes-incubator/kube-arbitrator/cmd/kar-scheduler/app/options"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler"
 
+	// Register the metrics of scheduler to "/debug/vars".
+	_ "github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
+
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 )
 
@@ -39,17 +50,52 @@ func Run(opt *options.ServerOption) error {
 		return err
 	}
 
-	neverStop := make(chan struct{})
-
 	// Start policy controller to allocate resources.
-	sched, err := scheduler.NewScheduler(config, opt.SchedulerName, opt.Actions)
+	sched, err := scheduler.NewScheduler(config, &scheduler.Options{
+		SchedulerName:            opt.SchedulerName,
+		Actions:                  opt.Actions,
+		Plugins:                  opt.Plugins,
+		PluginArgs:               opt.PluginArgs,
+		PercentageOfNodesToScore: opt.PercentageOfNodesToScore,
+		MaxPreemptees:            opt.MaxPreemptees,
+		MaxPreemptions:           opt.MaxPreemptions,
+		MaxPreemptionRounds:      opt.MaxPreemptionRounds,
+		MaxVictimsPerPreemptor:   opt.MaxVictimsPerPreemptor,
+		MaxVictimsPerJob:         opt.MaxVictimsPerJob,
+		APIQPS:                   opt.APIQPS,
+		APIBurst:                 opt.APIBurst,
+		PodStartSLO:              opt.PodStartSLO,
+		DefaultRequests:          opt.DefaultRequests,
+		NominationTimeout:        opt.NominationTimeout,
+		SessionTimeout:           opt.SessionTimeout,
+		ValidateSession:          opt.ValidateSession,
+		DebugSession:             opt.DebugSession,
+	})
 	if err != nil {
 		panic(err)
 	}
 
-	sched.Run(neverStop)
+	if opt.DebugSession {
+		http.Handle(scheduler.SessionPath, sched)
+	}
+
+	if len(opt.ListenAddress) != 0 {
+		go func() {
+			glog.Fatalf("HTTP server failed: %v", http.ListenAndServe(opt.ListenAddress, nil))
+		}()
+	}
+
+	// Shut down the scheduler gracefully on SIGTERM or SIGINT.
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		sig := <-signals
+		glog.Infof("Received signal %v, shutting down", sig)
+		cancel()
+	}()
 
-	<-neverStop
+	sched.Run(ctx)
 
 	return nil
 }
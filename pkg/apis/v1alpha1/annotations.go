@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// The annotations of SchedulingSpec to tune the scheduling of its job.
+const (
+	// GangTimeoutAnnotationKey is the duration, e.g. "10m", that the job waits for
+	// its MinAvailable tasks before gang scheduling times out.
+	GangTimeoutAnnotationKey = GroupName + "/gang-timeout"
+
+	// PreemptableAnnotationKey is whether the tasks of the job can be preempted;
+	// "false" protects them from preemption, the default is "true".
+	PreemptableAnnotationKey = GroupName + "/preemptable"
+
+	// SpreadReplicasAnnotationKey is how the tasks of the job are spread across
+	// nodes: "required" forbids two tasks of the job on one node, "preferred"
+	// prefers the nodes with fewer tasks of the job.
+	SpreadReplicasAnnotationKey = GroupName + "/spread-replicas"
+
+	// SameTopologyAnnotationKey is the node label, e.g. "topology.kubernetes.io/zone",
+	// whose value all tasks of the job share: once a task of the job is placed,
+	// the rest are placed only on the nodes of the same label value.
+	SameTopologyAnnotationKey = GroupName + "/same-topology"
+
+	// ExclusiveAnnotationKey is whether the tasks of the job take whole nodes;
+	// "true" places them only on the nodes without tasks of other jobs, and
+	// keeps other jobs off those nodes.
+	ExclusiveAnnotationKey = GroupName + "/exclusive"
+
+	// AntiAffinityAnnotationKey is the jobs that the tasks of the job do not
+	// share nodes with, separated by ";": each is either "job:<namespace>/<name>"
+	// of a job, or a label selector of jobs, e.g. "team=ads,tier!=web". It
+	// keeps the jobs apart both ways, whichever of them is placed first.
+	AntiAffinityAnnotationKey = GroupName + "/anti-affinity"
+
+	// WeightAnnotationKey is the weight of the job in fair sharing, e.g. "2"
+	// for twice the dominant share of the jobs of weight 1; the default is 1.
+	WeightAnnotationKey = GroupName + "/weight"
+
+	// MinTasksPerNodeAnnotationKey is the least number of tasks of the job on
+	// a node, e.g. "2" for the shards working in pairs: if any task of the job
+	// is placed on a node, at least that many are; the default is 1.
+	MinTasksPerNodeAnnotationKey = GroupName + "/min-tasks-per-node"
+
+	// PausedAnnotationKey is whether the job is paused; "true" keeps its
+	// pending tasks from being scheduled until it's resumed.
+	PausedAnnotationKey = GroupName + "/paused"
+
+	// GPUModelAnnotationKey is the GPU model that job's tasks prefer, e.g.
+	// "a100"; the tasks still run on the nodes of other models if the
+	// preferred ones are full.
+	GPUModelAnnotationKey = GroupName + "/gpu-model"
+
+	// DeadlineAnnotationKey is the time that job is due in RFC3339, e.g.
+	// "2018-06-01T12:00:00Z".
+	DeadlineAnnotationKey = GroupName + "/deadline"
+
+	// PreemptionBudgetAnnotationKey is the max number of victims, e.g. "4",
+	// that are evicted for the tasks of the job in a session; it overrides
+	// the default of scheduler, and "0" means no limit.
+	PreemptionBudgetAnnotationKey = GroupName + "/preemption-budget"
+)
+
+// The annotations of Node.
+const (
+	// SystemReservedAnnotationKey is the fraction, e.g. "0.1", of node's allocatable
+	// resource that is reserved for system daemons and not used by the scheduler.
+	SystemReservedAnnotationKey = GroupName + "/system-reserved"
+
+	// GPUTopologyAnnotationKey is the NVLink domains of node's GPUs, as groups
+	// of GPU indexes separated by ";", e.g. "0,1;2,3" for two pairs.
+	GPUTopologyAnnotationKey = GroupName + "/gpu-topology"
+
+	// ReservedAnnotationPrefix is the prefix of the annotations that reserve
+	// node's resource for out-of-band use, followed by the resource name, e.g.
+	// "<prefix>cpu: 2" or "<prefix>memory: 4Gi"; the reserved resource is not
+	// used by the scheduler.
+	ReservedAnnotationPrefix = GroupName + "/reserved-"
+
+	// DrainAnnotationKey is whether the node is drained; "true" stops placing
+	// tasks onto the node and evicts its tasks, except the protected ones and
+	// the ones that their jobs need for minAvailable. The node is schedulable
+	// again once the annotation is removed.
+	DrainAnnotationKey = GroupName + "/drain"
+)
+
+// The annotations of Pod.
+const (
+	// GPUIndexesAnnotationKey is the indexes of the GPUs assigned to the pod on
+	// its node, e.g. "0,1"; it's set by the GPU device plugin.
+	GPUIndexesAnnotationKey = GroupName + "/gpu-indexes"
+
+	// SchedulingGatesAnnotationKey is the gates of the pod not ready to be
+	// scheduled, separated by ",", e.g. "quota-check"; the pending pod is
+	// not scheduled until the annotation is removed or empty.
+	SchedulingGatesAnnotationKey = GroupName + "/scheduling-gates"
+)
@@ -43,6 +43,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&SchedulingSpec{},
 		&SchedulingSpecList{},
+		&Reservation{},
+		&ReservationList{},
 		&QueueJob{},
 		&QueueJobList{},
 		&XQueueJob{},
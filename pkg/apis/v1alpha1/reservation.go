@@ -0,0 +1,53 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReservationPlural is the plural of Reservation
+const ReservationPlural = "reservations"
+
+// Reservation reserves a slice of a node for the upcoming tasks of a job;
+// the job is the controller of the Reservation, the same as SchedulingSpec.
+// The other jobs can not use the reserved resource, while the tasks of the
+// job take it without preemption. The reservation is taken up by the tasks
+// of the job on the node, and it's released once the Reservation is deleted.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type Reservation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec ReservationSpec `json:"spec"`
+}
+
+type ReservationSpec struct {
+	// NodeName is the node that the resource is reserved on.
+	NodeName string `json:"nodeName" protobuf:"bytes,1,opt,name=nodeName"`
+	// Resources is the reserved resource.
+	Resources v1.ResourceList `json:"resources,omitempty" protobuf:"bytes,2,rep,name=resources,casttype=k8s.io/api/core/v1.ResourceList,castkey=k8s.io/api/core/v1.ResourceName"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ReservationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []Reservation `json:"items"`
+}
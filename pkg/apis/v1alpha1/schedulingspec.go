@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -29,11 +30,62 @@ type SchedulingSpec struct {
 	metav1.ObjectMeta `json:"metadata"`
 
 	Spec SchedulingSpecTemplate `json:"spec"`
+
+	// Status is the scheduling status of the job, updated by the scheduler
+	// at the end of each session.
+	Status SchedulingSpecStatus `json:"status,omitempty"`
 }
 
 type SchedulingSpecTemplate struct {
 	NodeSelector map[string]string `json:"nodeSelector,omitempty" protobuf:"bytes,1,rep,name=nodeSelector"`
 	MinAvailable int               `json:"minAvailable,omitempty" protobuf:"bytes,2,rep,name=minAvailable"`
+	// MinResources is the least resource the job needs to start, so it's only
+	// admitted when the cluster has it, even before all its pods are created.
+	MinResources v1.ResourceList `json:"minResources,omitempty" protobuf:"bytes,3,rep,name=minResources,casttype=k8s.io/api/core/v1.ResourceList,castkey=k8s.io/api/core/v1.ResourceName"`
+}
+
+// SchedulingSpecConditionType is the type of the condition of SchedulingSpec.
+type SchedulingSpecConditionType string
+
+const (
+	// SchedulingSpecScheduled is true when at least MinAvailable tasks of the
+	// job are scheduled, i.e. the gang is satisfied.
+	SchedulingSpecScheduled SchedulingSpecConditionType = "Scheduled"
+
+	// SchedulingSpecUnschedulable is true when the gang is not satisfied; its
+	// reason and message tell why.
+	SchedulingSpecUnschedulable SchedulingSpecConditionType = "Unschedulable"
+)
+
+// SchedulingSpecCondition is the state of the job at a point.
+type SchedulingSpecCondition struct {
+	Type   SchedulingSpecConditionType `json:"type" protobuf:"bytes,1,opt,name=type,casttype=SchedulingSpecConditionType"`
+	Status v1.ConditionStatus          `json:"status" protobuf:"bytes,2,opt,name=status,casttype=k8s.io/api/core/v1.ConditionStatus"`
+	// The last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,3,opt,name=lastTransitionTime"`
+	// The reason of the condition, e.g. "NotEnoughResources".
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,4,opt,name=reason"`
+	// The human readable message of the condition.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,5,opt,name=message"`
+}
+
+// SchedulingSpecStatus is the scheduling status of the job of SchedulingSpec.
+type SchedulingSpecStatus struct {
+	// The number of the scheduled tasks, including the running and the
+	// succeeded ones.
+	// +optional
+	Running int32 `json:"running,omitempty" protobuf:"bytes,1,opt,name=running"`
+
+	// The number of the pending tasks.
+	// +optional
+	Pending int32 `json:"pending,omitempty" protobuf:"bytes,2,opt,name=pending"`
+
+	// The conditions of the job, e.g. Scheduled.
+	// +optional
+	Conditions []SchedulingSpecCondition `json:"conditions,omitempty" protobuf:"bytes,3,rep,name=conditions"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -28,6 +28,7 @@ type ArbV1Interface interface {
 	RESTClient() rest.Interface
 	SchedulingSpecGetter
 	QueueJobGetter
+	ReservationGetter
 }
 
 // ArbV1Client is used to interact with features provided by the  group.
@@ -43,6 +44,10 @@ func (c *ArbV1Client) QueueJobs(namespace string) QueueJobInterface {
 	return newQueueJobs(c, namespace)
 }
 
+func (c *ArbV1Client) Reservations(namespace string) ReservationInterface {
+	return newReservations(c, namespace)
+}
+
 // NewForConfig creates a new ArbV1Client for the given config.
 func NewForConfig(c *rest.Config) (*ArbV1Client, error) {
 	config := *c
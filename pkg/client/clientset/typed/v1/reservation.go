@@ -0,0 +1,112 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	v1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/client/clientset/scheme"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+type ReservationGetter interface {
+	Reservations(namespaces string) ReservationInterface
+}
+
+type ReservationInterface interface {
+	Create(*v1.Reservation) (*v1.Reservation, error)
+	Update(*v1.Reservation) (*v1.Reservation, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v1.Reservation, error)
+	List(opts meta_v1.ListOptions) (*v1.ReservationList, error)
+}
+
+// reservations implements ReservationInterface
+type reservations struct {
+	client rest.Interface
+	ns     string
+}
+
+// newReservations returns a Reservations
+func newReservations(c *ArbV1Client, namespace string) *reservations {
+	return &reservations{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Create takes the representation of a reservation and creates it.  Returns the server's representation of the reservation, and an error, if there is any.
+func (c *reservations) Create(reservation *v1.Reservation) (result *v1.Reservation, err error) {
+	result = &v1.Reservation{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource(v1.ReservationPlural).
+		Body(reservation).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a reservation and updates it. Returns the server's representation of the reservation, and an error, if there is any.
+func (c *reservations) Update(reservation *v1.Reservation) (result *v1.Reservation, err error) {
+	result = &v1.Reservation{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource(v1.ReservationPlural).
+		Name(reservation.Name).
+		Body(reservation).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the reservation and deletes it. Returns an error if one occurs.
+func (c *reservations) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource(v1.ReservationPlural).
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Get takes name of the reservation, and returns the corresponding reservation object, and an error if there is any.
+func (c *reservations) Get(name string, options meta_v1.GetOptions) (result *v1.Reservation, err error) {
+	result = &v1.Reservation{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource(v1.ReservationPlural).
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of Reservations that match those selectors.
+func (c *reservations) List(opts meta_v1.ListOptions) (result *v1.ReservationList, err error) {
+	result = &v1.ReservationList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource(v1.ReservationPlural).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
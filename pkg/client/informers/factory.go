@@ -113,6 +113,8 @@ type SharedInformerFactory interface {
 	SchedulingSpec() arbclient.Interface
 
 	QueueJob() arbclient.Interface
+
+	Reservation() arbclient.Interface
 }
 
 func (f *sharedInformerFactory) SchedulingSpec() arbclient.Interface {
@@ -122,3 +124,7 @@ func (f *sharedInformerFactory) SchedulingSpec() arbclient.Interface {
 func (f *sharedInformerFactory) QueueJob() arbclient.Interface {
 	return arbclient.New(f)
 }
+
+func (f *sharedInformerFactory) Reservation() arbclient.Interface {
+	return arbclient.New(f)
+}
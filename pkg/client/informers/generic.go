@@ -63,6 +63,11 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 			resource: resource.GroupResource(),
 			informer: f.QueueJob().QueueJobs().Informer(),
 		}, nil
+	case arbv1.SchemeGroupVersion.WithResource("reservations"):
+		return &genericInformer{
+			resource: resource.GroupResource(),
+			informer: f.Reservation().Reservations().Informer(),
+		}, nil
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
@@ -26,6 +26,8 @@ type Interface interface {
 	SchedulingSpecs() SchedulingSpecInformer
 	// QueueJobs returns a QueueJobInformer.
 	QueueJobs() QueueJobInformer
+	// Reservations returns a ReservationInformer.
+	Reservations() ReservationInformer
 }
 
 type version struct {
@@ -46,3 +48,8 @@ func (v *version) SchedulingSpecs() SchedulingSpecInformer {
 func (v *version) QueueJobs() QueueJobInformer {
 	return &queueJobInformer{factory: v.SharedInformerFactory}
 }
+
+// Reservations returns a ReservationInformer.
+func (v *version) Reservations() ReservationInformer {
+	return &reservationInformer{factory: v.SharedInformerFactory}
+}
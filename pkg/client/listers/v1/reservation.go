@@ -0,0 +1,91 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ReservationLister helps list Reservations.
+type ReservationLister interface {
+	// List lists all Reservations in the indexer.
+	List(selector labels.Selector) (ret []*arbv1.Reservation, err error)
+	// Reservations returns an object that can list and get Reservations.
+	Reservations(namespace string) ReservationNamespaceLister
+}
+
+// reservationLister implements the ReservationLister interface.
+type reservationLister struct {
+	indexer cache.Indexer
+}
+
+// NewReservationLister returns a new ReservationLister.
+func NewReservationLister(indexer cache.Indexer) ReservationLister {
+	return &reservationLister{indexer: indexer}
+}
+
+// List lists all Reservations in the indexer.
+func (s *reservationLister) List(selector labels.Selector) (ret []*arbv1.Reservation, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*arbv1.Reservation))
+	})
+	return ret, err
+}
+
+// Reservations returns an object that can list and get Reservations.
+func (s *reservationLister) Reservations(namespace string) ReservationNamespaceLister {
+	return reservationNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// ReservationNamespaceLister helps list and get Reservations.
+type ReservationNamespaceLister interface {
+	// List lists all Reservations in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*arbv1.Reservation, err error)
+	// Get retrieves the Reservation from the indexer for a given namespace and name.
+	Get(name string) (*arbv1.Reservation, error)
+}
+
+// reservationNamespaceLister implements the ReservationNamespaceLister
+// interface.
+type reservationNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all Reservations in the indexer for a given namespace.
+func (s reservationNamespaceLister) List(selector labels.Selector) (ret []*arbv1.Reservation, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*arbv1.Reservation))
+	})
+	return ret, err
+}
+
+// Get retrieves the Reservation from the indexer for a given namespace and name.
+func (s reservationNamespaceLister) Get(name string) (*arbv1.Reservation, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(arbv1.Resource("reservations"), name)
+	}
+	return obj.(*arbv1.Reservation), nil
+}
@@ -46,6 +46,9 @@ func CreateSchedulingSpecKind(clientset apiextensionsclient.Interface) (*apiexte
 				Plural: arbv1.SchedulingSpecPlural,
 				Kind:   reflect.TypeOf(arbv1.SchedulingSpec{}).Name(),
 			},
+			Subresources: &apiextensionsv1beta1.CustomResourceSubresources{
+				Status: &apiextensionsv1beta1.CustomResourceSubresourceStatus{},
+			},
 		},
 	}
 	_, err := clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Create(crd)
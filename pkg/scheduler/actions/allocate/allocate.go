@@ -24,8 +24,16 @@ import (
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util"
 )
 
+// minFeasibleNodesToFind is the least number of feasible nodes to find for
+// a task, whatever the percentage of nodes to score is.
+const minFeasibleNodesToFind = 100
+
 type allocateAction struct {
 	ssn *framework.Session
+
+	// nextStartNodeIndex is where to look for feasible nodes of next task,
+	// so the sampled nodes rotate among tasks.
+	nextStartNodeIndex int
 }
 
 func New() *allocateAction {
@@ -42,26 +50,72 @@ func (alloc *allocateAction) Execute(ssn *framework.Session) {
 	glog.V(3).Infof("Enter Allocate ...")
 	defer glog.V(3).Infof("Leaving Allocate ...")
 
-	jobs := util.NewPriorityQueue(ssn.JobOrderFn)
-
+	var jobs []*api.JobInfo
 	for _, job := range ssn.Jobs {
-		jobs.Push(job)
+		if !ssn.JobValid(job) {
+			glog.V(3).Infof("Job <%v:%v/%v> is not valid, skip it.",
+				job.UID, job.Namespace, job.Name)
+			continue
+		}
+		jobs = append(jobs, job)
 	}
 
-	glog.V(3).Infof("Try to allocate resource to %d Jobs", jobs.Len())
+	allocateNominated(ssn)
+
+	glog.V(3).Infof("Try to allocate resource to %d Jobs", len(jobs))
 
 	pendingTasks := map[api.JobID]*util.PriorityQueue{}
+	// The jobs whose task does not fit get no more tasks in this action.
+	stopped := map[api.JobID]bool{}
+
+	// Satisfy the minimum of every job first, e.g. MinAvailable of gang, so
+	// the job at head of line does not take the resource that others need
+	// to start; then the rest resource goes to the jobs for elastic growth.
+	alloc.allocateJobs(ssn, jobs, pendingTasks, stopped, true)
+	alloc.allocateJobs(ssn, jobs, pendingTasks, stopped, false)
+}
+
+// allocateJobs allocates resource to the pending tasks of jobs, one task of
+// the first job in order at a time; if minimum is true, the job gets its
+// tasks until it's pipelined, i.e. its minimum is satisfied, and then no more.
+func (alloc *allocateAction) allocateJobs(ssn *framework.Session, jobList []*api.JobInfo,
+	pendingTasks map[api.JobID]*util.PriorityQueue, stopped map[api.JobID]bool, minimum bool) {
+
+	jobs := util.NewPriorityQueue(ssn.JobOrderFn)
+	for _, job := range jobList {
+		if !stopped[job.UID] {
+			jobs.Push(job)
+		}
+	}
 
 	for {
 		if jobs.Empty() {
 			break
 		}
 
+		if ssn.Cancelled() {
+			glog.V(3).Infof("Session %v is cancelled, stop allocating", ssn.ID)
+			break
+		}
+
 		job := jobs.Pop().(*api.JobInfo)
 
+		if minimum && ssn.JobPipelined(job) {
+			continue
+		}
+
+		if ssn.Overused(job) {
+			glog.V(3).Infof("Job <%v:%v/%v> is overused, skip it.",
+				job.UID, job.Namespace, job.Name)
+			continue
+		}
+
 		if _, found := pendingTasks[job.UID]; !found {
 			tasks := util.NewPriorityQueue(ssn.TaskOrderFn)
 			for _, task := range job.TaskStatusIndex[api.Pending] {
+				if !ssn.TaskValid(task) {
+					continue
+				}
 				tasks.Push(task)
 			}
 			pendingTasks[job.UID] = tasks
@@ -86,45 +140,264 @@ func (alloc *allocateAction) Execute(ssn *framework.Session) {
 			glog.V(3).Infof("There are <%d> nodes for Job <%v:%v/%v>",
 				len(nodes), job.UID, job.Namespace, job.Name)
 
-			for _, node := range nodes {
-				glog.V(3).Infof("Considering Task <%v/%v> on node <%v>: <%v> vs. <%v>",
-					task.Job, task.UID, node.Name, task.Resreq, node.Idle)
+			if floor := job.MinTasksPerNode(); floor > 1 {
+				assigned = alloc.allocateOnFloor(ssn, job, task, tasks, nodes, floor)
+			} else if node := alloc.selectNode(ssn, task, nodes); node != nil {
 				// Allocate idle resource to the task.
-				if task.Resreq.LessEqual(node.Idle) {
+				if task.Resreq.LessEqual(node.IdleFor(task.Job)) {
 					glog.V(3).Infof("Binding Task <%v/%v> to node <%v>",
 						task.Job, task.UID, node.Name)
 					if err := ssn.Allocate(task, node.Name); err != nil {
 						glog.Errorf("Failed to bind Task %v on %v in Session %v",
 							task.UID, node.Name, ssn.ID)
-						continue
+					} else {
+						assigned = true
 					}
-					assigned = true
-					break
-				}
-
-				// Allocate releasing resource to the task if any.
-				if task.Resreq.LessEqual(node.Releasing) {
+				} else {
+					// Allocate releasing resource to the task.
 					glog.V(3).Infof("Pipelining Task <%v:%v/%v> to node <%v> for <%v> on <%v>",
 						task.UID, task.Namespace, task.Name, node.Name, task.Resreq, node.Releasing)
 					if err := ssn.Pipeline(task, node.Name); err != nil {
 						glog.Errorf("Failed to pipeline Task %v on %v in Session %v",
 							task.UID, node.Name, ssn.ID)
-						continue
+					} else {
+						assigned = true
 					}
-
-					assigned = true
-					break
 				}
 			}
 
-			if assigned {
-				jobs.Push(job)
+			if !assigned {
+				stopped[job.UID] = true
+				break
+			}
+
+			// The job is held until its minimum is satisfied, so the job
+			// order, e.g. by drf shares, does not interleave the gangs and
+			// split the resource that none of them can start with.
+			if minimum && !ssn.JobPipelined(job) {
+				continue
 			}
 
+			jobs.Push(job)
+
 			// Handle one pending task in each loop.
 			break
 		}
 	}
 }
 
+// allocateNominated places the pending tasks on the nodes they're nominated
+// to by previous preemption, before other tasks, so the released resource is
+// not taken by other jobs. The tasks are handled as usual if their nominated
+// node does not fit any more.
+func allocateNominated(ssn *framework.Session) {
+	for _, job := range ssn.Jobs {
+		if ssn.Cancelled() {
+			return
+		}
+
+		if !ssn.JobValid(job) || ssn.Overused(job) {
+			continue
+		}
+
+		for _, task := range job.TaskStatusIndex[api.Pending] {
+			if len(task.NominatedNodeName) == 0 || !ssn.TaskValid(task) {
+				continue
+			}
+
+			node, found := ssn.NodeIndex[task.NominatedNodeName]
+			if !found {
+				glog.V(3).Infof("Nominated node <%v> of Task <%v/%v> is not found",
+					task.NominatedNodeName, task.Namespace, task.Name)
+				continue
+			}
+
+			if err := ssn.PredicateFn(task, node); err != nil {
+				glog.V(3).Infof("Predicates failed for Task <%v/%v> on nominated node <%v>: %v",
+					task.Namespace, task.Name, node.Name, err)
+				continue
+			}
+
+			if task.Resreq.LessEqual(node.IdleFor(task.Job)) {
+				glog.V(3).Infof("Binding Task <%v/%v> to nominated node <%v>",
+					task.Namespace, task.Name, node.Name)
+				if err := ssn.Allocate(task, node.Name); err != nil {
+					glog.Errorf("Failed to bind Task %v on %v in Session %v",
+						task.UID, node.Name, ssn.ID)
+				}
+			} else if task.Resreq.LessEqual(node.Releasing) {
+				glog.V(3).Infof("Pipelining Task <%v/%v> to nominated node <%v>",
+					task.Namespace, task.Name, node.Name)
+				if err := ssn.Pipeline(task, node.Name); err != nil {
+					glog.Errorf("Failed to pipeline Task %v on %v in Session %v",
+						task.UID, node.Name, ssn.ID)
+				}
+			}
+		}
+	}
+}
+
+// numFeasibleNodesToFind returns how many feasible nodes to find among
+// numAllNodes nodes by percentage, like kube-scheduler.
+func numFeasibleNodesToFind(percentage int32, numAllNodes int) int {
+	if percentage <= 0 || percentage >= 100 || numAllNodes <= minFeasibleNodesToFind {
+		return numAllNodes
+	}
+
+	num := numAllNodes * int(percentage) / 100
+	if num < minFeasibleNodesToFind {
+		return minFeasibleNodesToFind
+	}
+
+	return num
+}
+
+// findFeasibleNodes returns the nodes that task fits in, by idle or
+// releasing resource, and passes predicates. It stops once enough nodes are
+// found by the percentage of nodes to score; in that case next search starts
+// from the node after the last one checked.
+func (alloc *allocateAction) findFeasibleNodes(ssn *framework.Session, task *api.TaskInfo, nodes []*api.NodeInfo) []*api.NodeInfo {
+	numToFind := numFeasibleNodesToFind(ssn.PercentageOfNodesToScore, len(nodes))
+
+	start := 0
+	if numToFind < len(nodes) {
+		start = alloc.nextStartNodeIndex % len(nodes)
+	}
+
+	var feasible []*api.NodeInfo
+	checked := 0
+	for ; checked < len(nodes) && len(feasible) < numToFind; checked++ {
+		node := nodes[(start+checked)%len(nodes)]
+
+		glog.V(3).Infof("Considering Task <%v/%v> on node <%v>: <%v> vs. <%v>",
+			task.Job, task.UID, node.Name, task.Resreq, node.Idle)
+
+		if !task.Resreq.LessEqual(node.IdleFor(task.Job)) && !task.Resreq.LessEqual(node.Releasing) {
+			continue
+		}
+
+		if err := ssn.PredicateFn(task, node); err != nil {
+			glog.V(3).Infof("Predicates failed for Task <%v/%v> on node <%v>: %v",
+				task.Job, task.UID, node.Name, err)
+			continue
+		}
+
+		feasible = append(feasible, node)
+	}
+
+	if numToFind < len(nodes) {
+		alloc.nextStartNodeIndex = (start + checked) % len(nodes)
+	}
+
+	return feasible
+}
+
+// selectNode returns the node of the highest score among the feasible nodes
+// of task; the first one wins if the scores are equal. It's nil if no such
+// node.
+func (alloc *allocateAction) selectNode(ssn *framework.Session, task *api.TaskInfo, nodes []*api.NodeInfo) *api.NodeInfo {
+	return bestNode(ssn, task, alloc.findFeasibleNodes(ssn, task, nodes))
+}
+
+// bestNode returns the node of the highest score for task among the feasible
+// nodes; the first one wins if the scores are equal.
+func bestNode(ssn *framework.Session, task *api.TaskInfo, feasibleNodes []*api.NodeInfo) *api.NodeInfo {
+	var selected *api.NodeInfo
+	var selectedScore float64
+
+	scores := ssn.NodeScores(task, feasibleNodes)
+	for _, node := range feasibleNodes {
+		score, found := scores[node.Name]
+		if !found {
+			continue
+		}
+
+		if selected == nil || score > selectedScore {
+			selected = node
+			selectedScore = score
+		}
+	}
+
+	return selected
+}
+
+// allocateOnFloor allocates task of the job that needs at least floor tasks
+// on each node it uses: on the node with fewer tasks of the job, the task is
+// allocated together with the next pending tasks to reach the floor. The
+// group only takes idle resource, as its tasks can not be pipelined apart.
+// It returns false if no node fits, and the other pending tasks are kept.
+func (alloc *allocateAction) allocateOnFloor(ssn *framework.Session, job *api.JobInfo, task *api.TaskInfo,
+	tasks *util.PriorityQueue, nodes []*api.NodeInfo, floor int) bool {
+
+	group := []*api.TaskInfo{task}
+	for len(group) < floor && !tasks.Empty() {
+		group = append(group, tasks.Pop().(*api.TaskInfo))
+	}
+
+	onNodes := map[string]int{}
+	for _, t := range job.Tasks {
+		if api.AllocatedStatus(t.Status) || t.Status == api.Pipelined {
+			onNodes[t.NodeName]++
+		}
+	}
+
+	// The number of tasks of group to allocate onto each feasible node.
+	need := map[string]int{}
+	var feasibleNodes []*api.NodeInfo
+	for _, node := range alloc.findFeasibleNodes(ssn, task, nodes) {
+		n := 1
+		if onNodes[node.Name] < floor {
+			n = floor - onNodes[node.Name]
+		}
+		if n > len(group) || !fitGroup(ssn, job, group[:n], node) {
+			continue
+		}
+		need[node.Name] = n
+		feasibleNodes = append(feasibleNodes, node)
+	}
+
+	node := bestNode(ssn, task, feasibleNodes)
+	if node == nil {
+		glog.V(3).Infof("No node fits %d tasks of Job <%v:%v/%v> for its floor %d per node",
+			len(group), job.UID, job.Namespace, job.Name, floor)
+		for _, t := range group[1:] {
+			tasks.Push(t)
+		}
+		return false
+	}
+
+	n := need[node.Name]
+	for _, t := range group[n:] {
+		tasks.Push(t)
+	}
+
+	assigned := false
+	for _, t := range group[:n] {
+		glog.V(3).Infof("Binding Task <%v/%v> to node <%v> for floor %d per node",
+			t.Job, t.UID, node.Name, floor)
+		if err := ssn.Allocate(t, node.Name); err != nil {
+			glog.Errorf("Failed to bind Task %v on %v in Session %v",
+				t.UID, node.Name, ssn.ID)
+			continue
+		}
+		assigned = true
+	}
+
+	return assigned
+}
+
+// fitGroup returns true if all the tasks of job fit the idle resource of
+// node together, and pass predicates on it.
+func fitGroup(ssn *framework.Session, job *api.JobInfo, tasks []*api.TaskInfo, node *api.NodeInfo) bool {
+	req := api.EmptyResource()
+	for _, task := range tasks {
+		if err := ssn.PredicateFn(task, node); err != nil {
+			return false
+		}
+		req.Add(task.Resreq)
+	}
+
+	return req.LessEqual(node.IdleFor(job.UID))
+}
+
 func (alloc *allocateAction) UnInitialize() {}
@@ -27,13 +27,15 @@ import (
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 
 	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/drf"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/gang"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/lottery"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
 )
 
 func init() {
@@ -45,90 +47,12 @@ func init() {
 	}
 }
 
-func buildResourceList(cpu string, memory string) v1.ResourceList {
-	return v1.ResourceList{
-		v1.ResourceCPU:      resource.MustParse(cpu),
-		v1.ResourceMemory:   resource.MustParse(memory),
-		api.GPUResourceName: resource.MustParse("0"),
-	}
-}
-
-func buildResourceListWithGPU(cpu string, memory string, GPU string) v1.ResourceList {
-	return v1.ResourceList{
-		v1.ResourceCPU:      resource.MustParse(cpu),
-		v1.ResourceMemory:   resource.MustParse(memory),
-		api.GPUResourceName: resource.MustParse(GPU),
-	}
-}
-
-func buildNode(name string, alloc v1.ResourceList, labels map[string]string) *v1.Node {
-	return &v1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:   name,
-			Labels: labels,
-		},
-		Status: v1.NodeStatus{
-			Capacity:    alloc,
-			Allocatable: alloc,
-		},
-	}
-}
-
-func buildPod(ns, n, nn string, p v1.PodPhase, req v1.ResourceList, owner []metav1.OwnerReference, labels map[string]string, selector map[string]string) *v1.Pod {
-	return &v1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			UID:             types.UID(fmt.Sprintf("%v-%v", ns, n)),
-			Name:            n,
-			Namespace:       ns,
-			OwnerReferences: owner,
-			Labels:          labels,
-		},
-		Status: v1.PodStatus{
-			Phase: p,
-		},
-		Spec: v1.PodSpec{
-			NodeName:     nn,
-			NodeSelector: selector,
-			Containers: []v1.Container{
-				{
-					Resources: v1.ResourceRequirements{
-						Requests: req,
-					},
-				},
-			},
-		},
-	}
-}
-
-func buildOwnerReference(owner string) metav1.OwnerReference {
-	controller := true
-	return metav1.OwnerReference{
-		Controller: &controller,
-		UID:        types.UID(owner),
-	}
-}
-
-type fakeBinder struct {
-	binds map[string]string
-	c     chan string
-}
-
-func (fb *fakeBinder) Bind(p *v1.Pod, hostname string) error {
-	key := fmt.Sprintf("%v/%v", p.Namespace, p.Name)
-
-	fb.binds[key] = hostname
-
-	fb.c <- key
-
-	return nil
-}
-
 func TestAllocate(t *testing.T) {
-	framework.RegisterPluginBuilder(drf.New)
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
 	defer framework.CleanupPluginBuilders()
 
-	owner1 := buildOwnerReference("owner1")
-	owner2 := buildOwnerReference("owner2")
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
 
 	tests := []struct {
 		name       string
@@ -148,13 +72,13 @@ func TestAllocate(t *testing.T) {
 			},
 			pods: []*v1.Pod{
 				// pending pod with owner, under c1
-				buildPod("c1", "p1", "", v1.PodPending, buildResourceList("1", "1G"), []metav1.OwnerReference{owner1}, make(map[string]string), make(map[string]string)),
+				testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner1),
 
 				// pending pod with owner, under c1
-				buildPod("c1", "p2", "", v1.PodPending, buildResourceList("1", "1G"), []metav1.OwnerReference{owner1}, make(map[string]string), make(map[string]string)),
+				testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner1),
 			},
 			nodes: []*v1.Node{
-				buildNode("n1", buildResourceList("2", "4Gi"), make(map[string]string)),
+				testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")),
 			},
 			expected: map[string]string{
 				"c1/p1": "n1",
@@ -168,29 +92,35 @@ func TestAllocate(t *testing.T) {
 					ObjectMeta: metav1.ObjectMeta{
 						OwnerReferences: []metav1.OwnerReference{owner1},
 					},
+					Spec: arbv1.SchedulingSpecTemplate{
+						MinAvailable: 1,
+					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
 						OwnerReferences: []metav1.OwnerReference{owner2},
 					},
+					Spec: arbv1.SchedulingSpecTemplate{
+						MinAvailable: 1,
+					},
 				},
 			},
 
 			pods: []*v1.Pod{
 				// pending pod with owner1, under c1
-				buildPod("c1", "p1", "", v1.PodPending, buildResourceList("1", "1G"), []metav1.OwnerReference{owner1}, make(map[string]string), make(map[string]string)),
+				testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner1),
 
 				// pending pod with owner1, under c1
-				buildPod("c1", "p2", "", v1.PodPending, buildResourceList("1", "1G"), []metav1.OwnerReference{owner1}, make(map[string]string), make(map[string]string)),
+				testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner1),
 
 				// pending pod with owner2, under c2
-				buildPod("c2", "p1", "", v1.PodPending, buildResourceList("1", "1G"), []metav1.OwnerReference{owner2}, make(map[string]string), make(map[string]string)),
+				testutil.BuildPod("c2", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner2),
 
 				// pending pod with owner, under c2
-				buildPod("c2", "p2", "", v1.PodPending, buildResourceList("1", "1G"), []metav1.OwnerReference{owner2}, make(map[string]string), make(map[string]string)),
+				testutil.BuildPod("c2", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner2),
 			},
 			nodes: []*v1.Node{
-				buildNode("n1", buildResourceList("2", "4G"), make(map[string]string)),
+				testutil.BuildNode("n1", testutil.BuildResourceList("2", "4G")),
 			},
 			expected: map[string]string{
 				"c2/p1": "n1",
@@ -202,10 +132,7 @@ func TestAllocate(t *testing.T) {
 	allocate := New()
 
 	for i, test := range tests {
-		binder := &fakeBinder{
-			binds: map[string]string{},
-			c:     make(chan string),
-		}
+		binder := testutil.NewFakeBinder()
 		schedulerCache := &cache.SchedulerCache{
 			Nodes:  make(map[string]*api.NodeInfo),
 			Jobs:   make(map[api.JobID]*api.JobInfo),
@@ -222,21 +149,907 @@ func TestAllocate(t *testing.T) {
 			schedulerCache.AddSchedulingSpec(ss)
 		}
 
-		ssn := framework.OpenSession(schedulerCache)
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
 		defer framework.CloseSession(ssn)
 
 		allocate.Execute(ssn)
 
-		for i := 0; i < len(test.expected); i++ {
-			select {
-			case <-binder.c:
-			case <-time.After(3 * time.Second):
-				t.Errorf("Failed to get binding request.")
+		if !schedulerCache.WaitForInflight(3 * time.Second) {
+			t.Errorf("Failed to get binding request.")
+		}
+
+		if !reflect.DeepEqual(test.expected, binder.Binds) {
+			t.Errorf("case %d (%s): expected: %v, got %v ", i, test.name, test.expected, binder.Binds)
+		}
+	}
+}
+
+// fakeCache records the unschedulable tasks instead of updating pods.
+type fakeCache struct {
+	*cache.SchedulerCache
+
+	unschedulable map[string]string
+}
+
+func (fc *fakeCache) TaskUnschedulable(task *api.TaskInfo, message string) error {
+	fc.unschedulable[fmt.Sprintf("%v/%v", task.Namespace, task.Name)] = message
+	return nil
+}
+
+func TestAllocateImpossibleTask(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+
+	binder := testutil.NewFakeBinder()
+	schedulerCache := &fakeCache{
+		SchedulerCache: &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: binder,
+		},
+		unschedulable: map[string]string{},
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("4", "4Gi")))
+
+	// p1 requests more cpu than any node, it should not block p2.
+	for _, pod := range []*v1.Pod{
+		testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("8", "1G"), owner1),
+		testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner1),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			OwnerReferences: []metav1.OwnerReference{owner1},
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Errorf("Failed to get binding request.")
+	}
+
+	if _, found := binder.Binds["c1/p2"]; !found || len(binder.Binds) != 1 {
+		t.Errorf("expected only c1/p2 to be bound, got %v", binder.Binds)
+	}
+
+	expected := map[string]string{"c1/p1": "requests 8 CPU; largest node has 4"}
+	if !reflect.DeepEqual(expected, schedulerCache.unschedulable) {
+		t.Errorf("expected unschedulable tasks %v, got %v", expected, schedulerCache.unschedulable)
+	}
+}
+
+func TestAllocateEphemeralStorage(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+
+	binder := testutil.NewFakeBinder()
+	schedulerCache := &fakeCache{
+		SchedulerCache: &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: binder,
+		},
+		unschedulable: map[string]string{},
+	}
+
+	withStorage := func(rl v1.ResourceList, storage string) v1.ResourceList {
+		rl[v1.ResourceEphemeralStorage] = resource.MustParse(storage)
+		return rl
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", withStorage(testutil.BuildResourceList("4", "8Gi"), "10Gi")))
+
+	// p1 requests more disk than the node has, while p2 fits.
+	for _, pod := range []*v1.Pod{
+		testutil.BuildPod("c1", "p1", "", v1.PodPending, withStorage(testutil.BuildResourceList("1", "1G"), "20Gi"), owner1),
+		testutil.BuildPod("c1", "p2", "", v1.PodPending, withStorage(testutil.BuildResourceList("1", "1G"), "5Gi"), owner1),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			OwnerReferences: []metav1.OwnerReference{owner1},
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Errorf("Failed to get binding request.")
+	}
+
+	if _, found := binder.Binds["c1/p2"]; !found || len(binder.Binds) != 1 {
+		t.Errorf("expected only c1/p2 to be bound, got %v", binder.Binds)
+	}
+
+	expected := map[string]string{"c1/p1": "requests 20Gi ephemeral-storage; largest node has 10Gi"}
+	if !reflect.DeepEqual(expected, schedulerCache.unschedulable) {
+		t.Errorf("expected unschedulable tasks %v, got %v", expected, schedulerCache.unschedulable)
+	}
+}
+
+func TestAllocateDefaultRequest(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name     string
+		requests map[string]*api.Resource
+		bound    int
+	}{
+		{
+			name:  "no default request, all pods fit",
+			bound: 3,
+		},
+		{
+			name: "the pods request the default of 1 cpu, only two of them fit",
+			requests: map[string]*api.Resource{
+				"": api.NewResource(v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}),
+			},
+			bound: 2,
+		},
+		{
+			name: "the default of namespace overrides the global one",
+			requests: map[string]*api.Resource{
+				"":   api.NewResource(v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}),
+				"c1": api.NewResource(v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}),
+			},
+			bound: 1,
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+
+		binder := testutil.NewFakeBinder()
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:           make(map[string]*api.NodeInfo),
+			Jobs:            make(map[api.JobID]*api.JobInfo),
+			Binder:          binder,
+			DefaultRequests: test.requests,
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+		for i := 0; i < 3; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, v1.ResourceList{}, owner1))
+		}
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner1},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 1,
+			},
+		})
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+		New().Execute(ssn)
+		framework.CloseSession(ssn)
+
+		schedulerCache.WaitForInflight(3 * time.Second)
+		if len(binder.Binds) != test.bound {
+			t.Errorf("case %s: expected %d tasks bound, got %v", test.name, test.bound, binder.Binds)
+		}
+
+		// The pods are unchanged, only the scheduler counts them as requesting.
+		for _, job := range schedulerCache.Jobs {
+			for _, task := range job.Tasks {
+				if len(task.Pod.Spec.Containers[0].Resources.Requests) != 0 {
+					t.Errorf("case %s: expected pod %s unchanged, got requests %v",
+						test.name, task.Name, task.Pod.Spec.Containers[0].Resources.Requests)
+				}
 			}
 		}
+	}
+}
+
+func TestAllocateFIFO(t *testing.T) {
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	binder := testutil.NewFakeBinder()
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: binder,
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("1", "4Gi")))
+
+	// Only one task fits; the job of owner2 is older, so it goes first
+	// although its UID is larger.
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner1))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner2))
+
+	now := time.Now()
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "j1",
+			CreationTimestamp: metav1.NewTime(now),
+			OwnerReferences:   []metav1.OwnerReference{owner1},
+		},
+	})
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "j2",
+			CreationTimestamp: metav1.NewTime(now.Add(-time.Minute)),
+			OwnerReferences:   []metav1.OwnerReference{owner2},
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, nil)
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Errorf("Failed to get binding request.")
+	}
+
+	expected := map[string]string{"c1/p2": "n1"}
+	if !reflect.DeepEqual(expected, binder.Binds) {
+		t.Errorf("expected binds %v, got %v", expected, binder.Binds)
+	}
+}
+
+func TestAllocateNominated(t *testing.T) {
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
 
-		if !reflect.DeepEqual(test.expected, binder.binds) {
-			t.Errorf("case %d (%s): expected: %v, got %v ", i, test.name, test.expected, binder.binds)
+	binder := testutil.NewFakeBinder()
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: binder,
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("1", "4Gi")))
+	schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("1", "4Gi")))
+
+	// The job of owner1 is older, but the task of owner2 was nominated to
+	// n2 by preemption, so it's not taken by owner1.
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner1))
+	nominated := testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner2)
+	nominated.Status.NominatedNodeName = "n2"
+	schedulerCache.AddPod(nominated)
+
+	now := time.Now()
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "j1",
+			CreationTimestamp: metav1.NewTime(now.Add(-time.Minute)),
+			OwnerReferences:   []metav1.OwnerReference{owner1},
+		},
+	})
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "j2",
+			CreationTimestamp: metav1.NewTime(now),
+			OwnerReferences:   []metav1.OwnerReference{owner2},
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, nil)
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Errorf("Failed to get binding request.")
+	}
+
+	expected := map[string]string{"c1/p1": "n1", "c1/p2": "n2"}
+	if !reflect.DeepEqual(expected, binder.Binds) {
+		t.Errorf("expected binds %v, got %v", expected, binder.Binds)
+	}
+}
+
+// holdPlugin makes the job invalid while it's held.
+type holdPlugin struct {
+	job  api.JobID
+	held bool
+}
+
+func (hp *holdPlugin) Name() string {
+	return "hold"
+}
+
+func (hp *holdPlugin) OnSessionOpen(ssn *framework.Session) {
+	ssn.AddJobValidFn(func(obj interface{}) bool {
+		return !hp.held || obj.(*api.JobInfo).UID != hp.job
+	})
+}
+
+func (hp *holdPlugin) OnSessionClose(ssn *framework.Session) {}
+
+func TestAllocateNominationReserved(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		elapsed time.Duration
+		// The binds while the nominated job is held, and after it's not.
+		held     map[string]string
+		released map[string]string
+	}{
+		{
+			name:     "the released resource is kept for the held preemptor",
+			held:     map[string]string{},
+			released: map[string]string{"c1/p2": "n1"},
+		},
+		{
+			name:     "the released resource is kept within the timeout",
+			timeout:  time.Minute,
+			elapsed:  30 * time.Second,
+			held:     map[string]string{},
+			released: map[string]string{"c1/p2": "n1"},
+		},
+		{
+			name:     "the released resource goes to others after the timeout",
+			timeout:  time.Minute,
+			elapsed:  2 * time.Minute,
+			held:     map[string]string{"c1/p1": "n1"},
+			released: map[string]string{"c1/p1": "n1"},
+		},
+	}
+
+	for _, test := range tests {
+		hp := &holdPlugin{job: "owner2", held: true}
+		framework.RegisterPluginBuilder(hp.Name(), func(framework.Arguments) framework.Plugin {
+			return hp
+		})
+
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		binder := testutil.NewFakeBinder()
+		clock := &testutil.FakeClock{Time: time.Now()}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:             make(map[string]*api.NodeInfo),
+			Jobs:              make(map[api.JobID]*api.JobInfo),
+			Binder:            binder,
+			Clock:             clock,
+			NominationTimeout: test.timeout,
 		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("1", "4Gi")))
+
+		// The victims of p2 are gone from n1, but p2 is not scheduled while
+		// its job is held; p1 of the older job fits the released resource.
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner1))
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner2))
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "j1",
+				CreationTimestamp: metav1.NewTime(clock.Time.Add(-time.Minute)),
+				OwnerReferences:   []metav1.OwnerReference{owner1},
+			},
+		})
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "j2",
+				CreationTimestamp: metav1.NewTime(clock.Time),
+				OwnerReferences:   []metav1.OwnerReference{owner2},
+			},
+		})
+
+		if err := schedulerCache.NominateTask(schedulerCache.Jobs["owner2"].Tasks["c1-p2"], "n1"); err != nil {
+			t.Fatalf("case %s: failed to nominate task: %v", test.name, err)
+		}
+		clock.Time = clock.Time.Add(test.elapsed)
+
+		for _, held := range []bool{true, false} {
+			hp.held = held
+
+			ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: hp.Name()}})
+			New().Execute(ssn)
+			framework.CloseSession(ssn)
+
+			if !schedulerCache.WaitForInflight(3 * time.Second) {
+				t.Fatalf("case %s: binds are not completed in time", test.name)
+			}
+
+			expected := test.released
+			if held {
+				expected = test.held
+			}
+			if !reflect.DeepEqual(expected, binder.Binds) {
+				t.Errorf("case %s (held %v): expected binds %v, got %v", test.name, held, expected, binder.Binds)
+			}
+		}
+
+		framework.CleanupPluginBuilders()
+	}
+}
+
+func TestAllocatePercentageOfNodesToScore(t *testing.T) {
+	owner := testutil.BuildOwnerReference("owner1")
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	for i := 0; i < 400; i++ {
+		schedulerCache.AddNode(testutil.BuildNode(fmt.Sprintf("n%d", i), testutil.BuildResourceList("1", "4Gi")))
+	}
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner))
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, nil)
+	defer framework.CloseSession(ssn)
+
+	ssn.PercentageOfNodesToScore = 50
+
+	scored := map[string]int{}
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		scored[node.Name]++
+		return 0, nil
+	})
+
+	task := ssn.JobIndex["owner1"].TaskStatusIndex[api.Pending]["c1-p1"]
+	if task == nil {
+		t.Fatalf("failed to find pending task")
+	}
+
+	alloc := New()
+	for i := 0; i < 2; i++ {
+		if node := alloc.selectNode(ssn, task, ssn.Nodes); node == nil {
+			t.Errorf("round %d: expected a feasible node, got nil", i)
+		}
+		if got := len(scored); got != 200*(i+1) {
+			t.Errorf("round %d: expected %d nodes scored, got %d", i, 200*(i+1), got)
+		}
+	}
+
+	// The second round starts from where the first stopped, so no node is
+	// scored twice.
+	for name, count := range scored {
+		if count != 1 {
+			t.Errorf("expected node %s scored once, got %d", name, count)
+		}
+	}
+}
+
+func TestAllocateDrainingNode(t *testing.T) {
+	owner := testutil.BuildOwnerReference("owner1")
+
+	binder := testutil.NewFakeBinder()
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: binder,
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("2", "4Gi")))
+	if err := schedulerCache.DrainNode("n1"); err != nil {
+		t.Fatalf("failed to drain n1: %v", err)
+	}
+	for _, name := range []string{"p1", "p2", "p3"} {
+		schedulerCache.AddPod(testutil.BuildPod("c1", name, "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner))
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec: arbv1.SchedulingSpecTemplate{
+			MinAvailable: 2,
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, nil)
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	// n2 holds two of the tasks, and the last one is left pending.
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Errorf("Failed to get binding request.")
+	}
+
+	for task, host := range binder.Binds {
+		if host != "n2" {
+			t.Errorf("expected task %v on n2, got %v", task, host)
+		}
+	}
+	if len(binder.Binds) != 2 {
+		t.Errorf("expected 2 binds, got %v", binder.Binds)
+	}
+}
+
+func TestAllocateMinAvailableFirst(t *testing.T) {
+	framework.RegisterPluginBuilder(lottery.PluginName, lottery.New)
+	framework.RegisterPluginBuilder(gang.PluginName, gang.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	binder := testutil.NewFakeBinder()
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: binder,
+	}
+
+	// Each job wants the whole node, but needs only half of it to start.
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+	for i := 0; i < 4; i++ {
+		schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner1))
+		schedulerCache.AddPod(testutil.BuildPod("c2", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner2))
+	}
+
+	now := time.Now()
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "j1",
+			Namespace:         "c1",
+			CreationTimestamp: metav1.NewTime(now.Add(-time.Minute)),
+			OwnerReferences:   []metav1.OwnerReference{owner1},
+		},
+		Spec: arbv1.SchedulingSpecTemplate{
+			MinAvailable: 2,
+		},
+	})
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "j2",
+			Namespace:         "c2",
+			CreationTimestamp: metav1.NewTime(now),
+			OwnerReferences:   []metav1.OwnerReference{owner2},
+		},
+		Spec: arbv1.SchedulingSpecTemplate{
+			MinAvailable: 2,
+		},
+	})
+
+	// The lottery draws one job ahead of the other for the whole session,
+	// which takes all the node if it's allocated first.
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+		{Name: lottery.PluginName, Arguments: framework.Arguments{lottery.Seed: "1"}},
+		{Name: gang.PluginName},
+	})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Errorf("Failed to get binding request.")
+	}
+
+	bound := map[string]int{}
+	for task := range binder.Binds {
+		bound[task[:2]]++
+	}
+	expected := map[string]int{"c1": 2, "c2": 2}
+	if !reflect.DeepEqual(expected, bound) {
+		t.Errorf("expected bound tasks by namespace %v, got %v (%v)", expected, bound, binder.Binds)
+	}
+}
+
+func TestAllocateMinTasksPerNode(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks int
+		bound int
+	}{
+		{
+			name:  "the odd task is left pending instead of alone on a node",
+			tasks: 3,
+			bound: 2,
+		},
+		{
+			name:  "pairs of tasks on both nodes",
+			tasks: 4,
+			bound: 4,
+		},
+	}
+
+	for _, test := range tests {
+		owner := testutil.BuildOwnerReference("owner1")
+
+		binder := testutil.NewFakeBinder()
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: binder,
+		}
+
+		// Each node holds two tasks.
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+		schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("2", "4Gi")))
+		for i := 0; i < test.tasks; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner))
+		}
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+				Annotations:     map[string]string{arbv1.MinTasksPerNodeAnnotationKey: "2"},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 2,
+			},
+		})
+
+		ssn := framework.OpenSession(schedulerCache, nil)
+
+		New().Execute(ssn)
+
+		if !schedulerCache.WaitForInflight(3 * time.Second) {
+			t.Errorf("case %s: failed to get binding request.", test.name)
+		}
+
+		perNode := map[string]int{}
+		for _, host := range binder.Binds {
+			perNode[host]++
+		}
+		for host, n := range perNode {
+			if n < 2 {
+				t.Errorf("case %s: expected at least 2 tasks on %v, got %d", test.name, host, n)
+			}
+		}
+		if len(binder.Binds) != test.bound {
+			t.Errorf("case %s: expected %d tasks bound, got %v", test.name, test.bound, binder.Binds)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+// countingPlugin rejects the nodes of label "reject", and counts the
+// predicate evaluations of each node.
+type countingPlugin struct {
+	evaluations map[string]int
+}
+
+func (cp *countingPlugin) Name() string {
+	return "counting"
+}
+
+func (cp *countingPlugin) OnSessionOpen(ssn *framework.Session) {
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		cp.evaluations[node.Name]++
+		if _, found := node.Node.Labels["reject"]; found {
+			return fmt.Errorf("node <%v> rejects all tasks", node.Name)
+		}
+		return nil
+	})
+}
+
+func (cp *countingPlugin) OnSessionClose(ssn *framework.Session) {}
+
+func TestAllocateEquivalenceClass(t *testing.T) {
+	cp := &countingPlugin{evaluations: map[string]int{}}
+	framework.RegisterPluginBuilder(cp.Name(), func(framework.Arguments) framework.Plugin {
+		return cp
+	})
+	defer framework.CleanupPluginBuilders()
+
+	owner := testutil.BuildOwnerReference("owner1")
+
+	binder := testutil.NewFakeBinder()
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: binder,
+	}
+
+	// Only n4 accepts the tasks, and it holds all of them.
+	for _, name := range []string{"n1", "n2", "n3"} {
+		schedulerCache.AddNode(testutil.BuildNodeWithLabels(name, testutil.BuildResourceList("50", "50Gi"), map[string]string{"reject": "true"}))
+	}
+	schedulerCache.AddNode(testutil.BuildNode("n4", testutil.BuildResourceList("50", "50Gi")))
+
+	replicas := 50
+	for i := 0; i < replicas; i++ {
+		schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner))
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: cp.Name()}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Fatalf("failed to get binding request.")
+	}
+
+	// The identical replicas are checked once on the nodes rejecting them.
+	for _, name := range []string{"n1", "n2", "n3"} {
+		if got := cp.evaluations[name]; got != 1 {
+			t.Errorf("expected 1 predicate evaluation on %v, got %d", name, got)
+		}
+	}
+}
+
+func TestAllocateReservation(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	binder := testutil.NewFakeBinder()
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: binder,
+	}
+
+	// Half of n1 is reserved for the upcoming tasks of owner2.
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+	schedulerCache.AddReservation(&arbv1.Reservation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "r1",
+			Namespace:       "c2",
+			OwnerReferences: []metav1.OwnerReference{owner2},
+		},
+		Spec: arbv1.ReservationSpec{
+			NodeName:  "n1",
+			Resources: testutil.BuildResourceList("2", "2Gi"),
+		},
+	})
+
+	for i := 0; i < 4; i++ {
+		schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner1))
+	}
+	for i := 0; i < 2; i++ {
+		schedulerCache.AddPod(testutil.BuildPod("c2", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2))
+	}
+	for _, spec := range []struct {
+		ns    string
+		owner metav1.OwnerReference
+	}{{"c1", owner1}, {"c2", owner2}} {
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       spec.ns,
+				OwnerReferences: []metav1.OwnerReference{spec.owner},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 1,
+			},
+		})
+	}
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Fatalf("failed to get binding request.")
+	}
+
+	bound := map[string]int{}
+	for key := range binder.Binds {
+		bound[key[:2]]++
+	}
+	// The job of owner1 only gets the resource not reserved, while the job
+	// of owner2 takes its reservation.
+	if expected := map[string]int{"c1": 2, "c2": 2}; !reflect.DeepEqual(bound, expected) {
+		t.Errorf("expected tasks bound by namespace %v, got %v", expected, bound)
+	}
+}
+
+// freezePlugin vetoes the session while the cluster is frozen.
+type freezePlugin struct {
+	frozen bool
+}
+
+func (fp *freezePlugin) Name() string {
+	return "freeze"
+}
+
+func (fp *freezePlugin) OnSessionOpen(ssn *framework.Session) {
+	ssn.AddSessionGateFn(func() error {
+		if fp.frozen {
+			return fmt.Errorf("cluster is frozen")
+		}
+		return nil
+	})
+}
+
+func (fp *freezePlugin) OnSessionClose(ssn *framework.Session) {}
+
+func TestAllocateSessionGate(t *testing.T) {
+	tests := []struct {
+		name   string
+		frozen bool
+		binds  int
+	}{
+		{
+			name:   "the tasks are bound if not frozen",
+			frozen: false,
+			binds:  2,
+		},
+		{
+			name:   "the tasks are placed but not bound if frozen",
+			frozen: true,
+			binds:  0,
+		},
+	}
+
+	for _, test := range tests {
+		fp := &freezePlugin{frozen: test.frozen}
+		framework.RegisterPluginBuilder(fp.Name(), func(framework.Arguments) framework.Plugin {
+			return fp
+		})
+
+		owner := testutil.BuildOwnerReference("owner1")
+
+		binder := testutil.NewFakeBinder()
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: binder,
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+		for _, name := range []string{"p1", "p2"} {
+			schedulerCache.AddPod(testutil.BuildPod("c1", name, "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner))
+		}
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+		})
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: fp.Name()}})
+		if ssn.DryRun != test.frozen {
+			t.Errorf("case %s: expected dry run %v, got %v", test.name, test.frozen, ssn.DryRun)
+		}
+
+		New().Execute(ssn)
+
+		// The placements are made in session whether frozen or not.
+		if got := len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Binding]); got != 2 {
+			t.Errorf("case %s: expected 2 tasks placed in session, got %d", test.name, got)
+		}
+		framework.CloseSession(ssn)
+
+		if !schedulerCache.WaitForInflight(3 * time.Second) {
+			t.Fatalf("case %s: binds are not completed in time", test.name)
+		}
+		if got := len(binder.Binds); got != test.binds {
+			t.Errorf("case %s: expected %d binds, got %v", test.name, test.binds, binder.Binds)
+		}
+		if got := len(schedulerCache.Jobs["owner1"].TaskStatusIndex[api.Pending]); got != 2-test.binds {
+			t.Errorf("case %s: expected %d tasks pending in cache, got %d", test.name, 2-test.binds, got)
+		}
+
+		framework.CleanupPluginBuilders()
 	}
 }
@@ -24,14 +24,13 @@ import (
 	"testing"
 
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 
 	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
 )
 
 func init() {
@@ -43,35 +42,6 @@ func init() {
 	}
 }
 
-func buildResourceList(cpu string, memory string) v1.ResourceList {
-	return v1.ResourceList{
-		v1.ResourceCPU:      resource.MustParse(cpu),
-		v1.ResourceMemory:   resource.MustParse(memory),
-		api.GPUResourceName: resource.MustParse("0"),
-	}
-}
-
-func buildNode(name string, alloc v1.ResourceList, labels map[string]string) *v1.Node {
-	return &v1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:   name,
-			Labels: labels,
-		},
-		Status: v1.NodeStatus{
-			Capacity:    alloc,
-			Allocatable: alloc,
-		},
-	}
-}
-
-func buildOwnerReference(owner string) metav1.OwnerReference {
-	controller := true
-	return metav1.OwnerReference{
-		Controller: &controller,
-		UID:        types.UID(owner),
-	}
-}
-
 func nodesEqual(l, r []string) bool {
 	if len(l) != len(r) {
 		return false
@@ -95,7 +65,7 @@ func TestExecute(t *testing.T) {
 			schedSpec: &arbv1.SchedulingSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					OwnerReferences: []metav1.OwnerReference{
-						buildOwnerReference("j1"),
+						testutil.BuildOwnerReference("j1"),
 					},
 				},
 				Spec: arbv1.SchedulingSpecTemplate{
@@ -105,9 +75,9 @@ func TestExecute(t *testing.T) {
 				},
 			},
 			nodes: []*v1.Node{
-				buildNode("n1", buildResourceList("2", "4Gi"), map[string]string{"label_1": "val_1"}),
-				buildNode("n2", buildResourceList("2", "4Gi"), map[string]string{"label_2": "val_2"}),
-				buildNode("n3", buildResourceList("2", "4Gi"), map[string]string{"label_3": "val_3"}),
+				testutil.BuildNodeWithLabels("n1", testutil.BuildResourceList("2", "4Gi"), map[string]string{"label_1": "val_1"}),
+				testutil.BuildNodeWithLabels("n2", testutil.BuildResourceList("2", "4Gi"), map[string]string{"label_2": "val_2"}),
+				testutil.BuildNodeWithLabels("n3", testutil.BuildResourceList("2", "4Gi"), map[string]string{"label_3": "val_3"}),
 			},
 			expected: []string{"n1"},
 		},
@@ -126,7 +96,7 @@ func TestExecute(t *testing.T) {
 
 		schedulerCache.AddSchedulingSpec(test.schedSpec)
 
-		ssn := framework.OpenSession(schedulerCache)
+		ssn := framework.OpenSession(schedulerCache, nil)
 		defer framework.CloseSession(ssn)
 
 		decorate.Execute(ssn)
@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+const (
+	// criticalPodAnnotationKey marks the pods in kube-system critical.
+	criticalPodAnnotationKey = "scheduler.alpha.kubernetes.io/critical-pod"
+
+	// systemCriticalPriority is the lowest priority of system critical pods.
+	systemCriticalPriority = int32(2000000000)
+)
+
+type drainAction struct {
+	ssn *framework.Session
+}
+
+func New() *drainAction {
+	return &drainAction{}
+}
+
+func (da *drainAction) Name() string {
+	return "drain"
+}
+
+func (da *drainAction) Initialize() {}
+
+// Execute evicts the tasks of the draining nodes by a statement of each node;
+// the protected tasks are kept, and so are the tasks whose eviction would make
+// the job under its minAvailable, as its PDB disallows. The evictions count in
+// MaxPreemptions of session, the rest are evicted in following sessions, as
+// are the tasks that come to the node later.
+func (da *drainAction) Execute(ssn *framework.Session) {
+	glog.V(3).Infof("Enter Drain ...")
+	defer glog.V(3).Infof("Leaving Drain ...")
+
+	// The number of tasks evicted in session, and the number of tasks that
+	// each job can lose by eviction.
+	evicted := 0
+	budget := map[api.JobID]int{}
+
+	for _, node := range ssn.Nodes {
+		if !node.Draining {
+			continue
+		}
+
+		if ssn.Cancelled() {
+			glog.V(3).Infof("Session %v is cancelled, stop draining", ssn.ID)
+			return
+		}
+
+		stmt := ssn.Statement()
+		for _, task := range nodeTasks(node) {
+			if ssn.MaxPreemptions > 0 && evicted >= ssn.MaxPreemptions {
+				glog.V(3).Infof("Evicted %d tasks for draining, reached the limit of session", evicted)
+				break
+			}
+
+			if !api.AllocatedStatus(task.Status) || protectedTask(task) {
+				continue
+			}
+
+			// The tasks of the jobs not in session are not scheduled by us.
+			job, found := ssn.JobIndex[task.Job]
+			if !found {
+				continue
+			}
+
+			if _, found := budget[job.UID]; !found {
+				budget[job.UID] = readyTaskNum(job) - job.MinAvailable
+			}
+
+			if budget[job.UID] <= 0 {
+				glog.V(3).Infof("Keep Task <%v/%v> on draining node <%v>, Job <%v> would be under minAvailable %d",
+					task.Namespace, task.Name, node.Name, job.UID, job.MinAvailable)
+				continue
+			}
+
+			if err := stmt.Evict(task, fmt.Sprintf("Node <%v> is drained", node.Name)); err != nil {
+				glog.Errorf("Failed to evict Task <%v/%v> on draining node <%v>: %v",
+					task.Namespace, task.Name, node.Name, err)
+				continue
+			}
+			budget[job.UID]--
+			evicted++
+		}
+		stmt.Commit()
+	}
+}
+
+func (da *drainAction) UnInitialize() {}
+
+func readyTaskNum(job *api.JobInfo) int {
+	occupied := 0
+	for status, tasks := range job.TaskStatusIndex {
+		if api.AllocatedStatus(status) {
+			occupied += len(tasks)
+		}
+	}
+	return occupied
+}
+
+// nodeTasks returns the tasks of node by UID, so the evictions are stable
+// across sessions.
+func nodeTasks(node *api.NodeInfo) []*api.TaskInfo {
+	tasks := make([]*api.TaskInfo, 0, len(node.Tasks))
+	for _, task := range node.Tasks {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].UID < tasks[j].UID
+	})
+	return tasks
+}
+
+// protectedTask returns true if the pod of task should not be evicted by
+// draining, i.e. the critical pods and the pods of DaemonSet, which will
+// be placed onto the node again anyway.
+func protectedTask(task *api.TaskInfo) bool {
+	pod := task.Pod
+	if pod.Namespace == metav1.NamespaceSystem {
+		if _, found := pod.Annotations[criticalPodAnnotationKey]; found {
+			return true
+		}
+	}
+
+	if pod.Spec.Priority != nil && *pod.Spec.Priority >= systemCriticalPriority {
+		return true
+	}
+
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+		return true
+	}
+
+	return false
+}
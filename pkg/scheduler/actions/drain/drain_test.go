@@ -0,0 +1,130 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name           string
+		dryRun         bool
+		maxPreemptions int
+		expected       []string
+	}{
+		{
+			name: "the tasks are evicted but protected ones and minAvailable",
+			// j2 has minAvailable 2, so only one of its tasks is evicted.
+			expected: []string{"c1/p1", "c1/p2", "c1/p3"},
+		},
+		{
+			name:     "nothing is evicted in dry run",
+			dryRun:   true,
+			expected: []string{},
+		},
+		{
+			name:           "the evictions are limited by MaxPreemptions",
+			maxPreemptions: 2,
+			expected:       []string{"c1/p1", "c1/p2"},
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("j1")
+		owner2 := testutil.BuildOwnerReference("j2")
+		owner3 := testutil.BuildOwnerReference("j3")
+		daemonSet := testutil.BuildOwnerReference("ds1")
+		daemonSet.Kind = "DaemonSet"
+
+		evictor := &testutil.FakeEvictor{}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: evictor,
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("8", "16G")))
+		schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("8", "16G")))
+
+		critical := testutil.BuildPod(metav1.NamespaceSystem, "p7", "n1", v1.PodRunning,
+			testutil.BuildResourceList("1", "1G"), owner3)
+		critical.Annotations = map[string]string{criticalPodAnnotationKey: ""}
+
+		for _, pod := range []*v1.Pod{
+			testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1G"), owner1),
+			testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1G"), owner1),
+			testutil.BuildPod("c1", "p3", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1G"), owner2),
+			testutil.BuildPod("c1", "p4", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1G"), owner2),
+			testutil.BuildPod("c1", "p5", "n2", v1.PodRunning, testutil.BuildResourceList("1", "1G"), owner2),
+			// The pods of DaemonSet and the critical pods are protected.
+			testutil.BuildPod("c1", "p6", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1G"), daemonSet),
+			critical,
+		} {
+			schedulerCache.AddPod(pod)
+		}
+
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "j1", owner1))
+		spec := testutil.BuildSchedulingSpec("c1", "j2", owner2)
+		spec.Spec.MinAvailable = 2
+		schedulerCache.AddSchedulingSpec(spec)
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "ds1", daemonSet))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec(metav1.NamespaceSystem, "j3", owner3))
+
+		if err := schedulerCache.DrainNode("n1"); err != nil {
+			t.Fatalf("case %s: failed to drain n1: %v", test.name, err)
+		}
+
+		ssn := framework.OpenSession(schedulerCache, nil)
+		ssn.DryRun = test.dryRun
+		ssn.MaxPreemptions = test.maxPreemptions
+
+		New().Execute(ssn)
+		framework.CloseSession(ssn)
+
+		if !schedulerCache.WaitForInflight(3 * time.Second) {
+			t.Fatalf("case %s: evictions in flight are not completed", test.name)
+		}
+
+		if got := evictor.Evicted(); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("case %s: expected evicted %v, got %v", test.name, test.expected, got)
+		}
+
+		// Nothing more is evicted in next session, as j2 can not lose more
+		// tasks.
+		if test.maxPreemptions == 0 && !test.dryRun {
+			ssn = framework.OpenSession(schedulerCache, nil)
+			New().Execute(ssn)
+			framework.CloseSession(ssn)
+			schedulerCache.WaitForInflight(3 * time.Second)
+
+			if got := evictor.Evicted(); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("case %s: expected no more evictions than %v, got %v", test.name, test.expected, got)
+			}
+		}
+	}
+}
@@ -28,6 +28,12 @@ type preemptAction struct {
 	ssn *framework.Session
 }
 
+// promise is the releasing resource on node promised to a nominated task.
+type promise struct {
+	node     *api.NodeInfo
+	resource *api.Resource
+}
+
 func New() *preemptAction {
 	return &preemptAction{}
 }
@@ -42,92 +48,305 @@ func (alloc *preemptAction) Execute(ssn *framework.Session) {
 	glog.V(3).Infof("Enter Preempt ...")
 	defer glog.V(3).Infof("Leaving Preempt ...")
 
-	jobRevOrderFn := func(l, r interface{}) bool {
-		return !ssn.JobOrderFn(l, r)
-	}
-
-	taskRevOrderFn := func(l, r interface{}) bool {
-		return !ssn.TaskOrderFn(l, r)
-	}
-
 	preemptors := util.NewPriorityQueue(ssn.JobOrderFn)
-	preemptees := util.NewPriorityQueue(jobRevOrderFn)
-
 	preemptorTasks := map[api.JobID]*util.PriorityQueue{}
-	preempteeTasks := map[api.JobID]*util.PriorityQueue{}
+
+	victims := newVictimSelector(ssn)
+
+	promises := promiseNominated(ssn)
+	// The promises left are of the tasks not handled as preemptors, e.g. of
+	// invalid jobs; they're given back for the following actions.
+	defer func() {
+		for id := range promises {
+			unpromise(promises, id)
+		}
+	}()
 
 	for _, job := range ssn.Jobs {
-		preemptors.Push(job)
 		preemptorTasks[job.UID] = util.NewPriorityQueue(ssn.TaskOrderFn)
-		for _, task := range job.TaskStatusIndex[api.Pending] {
-			preemptorTasks[job.UID].Push(task)
+		// Invalid job can not preempt others, but its tasks are still preemptable.
+		if ssn.JobValid(job) {
+			preemptors.Push(job)
+			for _, task := range job.TaskStatusIndex[api.Pending] {
+				if !ssn.TaskValid(task) {
+					continue
+				}
+				preemptorTasks[job.UID].Push(task)
+			}
 		}
 
-		// If no running tasks in job, skip it as preemptee.
-		if len(job.TaskStatusIndex[api.Running]) != 0 {
-			preemptees.Push(job)
-			preempteeTasks[job.UID] = util.NewPriorityQueue(taskRevOrderFn)
+		// If no running tasks in job or it's protected, skip it as preemptee.
+		if len(job.TaskStatusIndex[api.Running]) != 0 && job.Preemptable() {
 			// TODO (k82cn): it's better to also includes Binding/Bound tasks.
 			for _, task := range job.TaskStatusIndex[api.Running] {
-				preempteeTasks[job.UID].Push(task)
+				victims.candidates = append(victims.candidates, task)
 			}
 		}
 	}
 
+	// The number of tasks evicted by the committed statements, and the
+	// number of the committed statements with evictions.
+	evicted, rounds := 0, 0
+	// The victims evicted for each preemptor job, bounded by its budget.
+	jobEvicted := map[api.JobID]int{}
+
 	for {
 		// If no preemptors nor preemptees, no preemption.
-		if preemptors.Empty() || preemptees.Empty() {
+		if preemptors.Empty() || len(victims.candidates) == 0 {
 			break
 		}
 
-		preemptorJob := preemptors.Pop().(*api.JobInfo)
-
-		// If not preemptor tasks, next job.
-		if preemptorTasks[preemptorJob.UID].Empty() {
-			continue
+		if ssn.MaxPreemptions > 0 && evicted >= ssn.MaxPreemptions {
+			glog.V(3).Infof("Evicted %d tasks for preemption, reached the limit of session", evicted)
+			break
 		}
 
-		preempteeJob := preemptees.Pop().(*api.JobInfo)
-		for preempteeTasks[preempteeJob.UID].Empty() && preemptorJob.UID != preempteeJob.UID {
-			preempteeJob = preemptees.Pop().(*api.JobInfo)
+		if ssn.MaxPreemptionRounds > 0 && rounds >= ssn.MaxPreemptionRounds {
+			glog.V(3).Infof("Committed %d rounds of preemption, reached the limit of session", rounds)
+			break
 		}
 
-		// The most underused job can not preempt any resource, break the loop.
-		if preemptorJob.UID == preempteeJob.UID {
+		if ssn.Cancelled() {
+			glog.V(3).Infof("Session %v is cancelled, stop preempting", ssn.ID)
 			break
 		}
 
-		glog.V(3).Infof("The preemptor is %v:%v/%v, the preemptee is %v:%v/%v",
-			preemptorJob.UID, preemptorJob.Namespace, preemptorJob.Name,
-			preempteeJob.UID, preempteeJob.Namespace, preempteeJob.Name)
+		preemptorJob := preemptors.Pop().(*api.JobInfo)
+
+		// The evictions for preemptor job are committed only if the job
+		// can start with them, e.g. all MinAvailable tasks of gang job are
+		// pipelined; otherwise evicting victims is wasted.
+		stmt := ssn.Statement()
+		assigned := false
+		stmtEvicted := 0
 
-		preemptor := preemptorTasks[preemptorJob.UID].Pop().(*api.TaskInfo)
-		preemptee := preempteeTasks[preempteeJob.UID].Pop().(*api.TaskInfo)
+		for !preemptorTasks[preemptorJob.UID].Empty() {
+			preemptor := preemptorTasks[preemptorJob.UID].Pop().(*api.TaskInfo)
 
-		preempted := false
+			// The resource promised to preemptor is its own again.
+			unpromise(promises, preemptor.UID)
 
-		if ssn.Preemptable(preemptor, preemptee) {
-			if err := ssn.Preempt(preemptor, preemptee); err != nil {
-				glog.Errorf("Failed to evict task %v for task %v: %v", nil, nil, err)
+			// Only preempt if allocate really failed: if any node has enough
+			// idle resource, the preemptor is skipped by preempt and left
+			// for the next allocate to place it.
+			if node := idleNode(ssn, preemptor); node != nil {
+				glog.V(3).Infof("Task <%v:%v/%v> fits idle resource of node <%v>, no preemption for it",
+					preemptor.UID, preemptor.Namespace, preemptor.Name, node.Name)
+				continue
+			}
+
+			// If the releasing resource that is not promised to others is enough,
+			// pipeline preemptor instead of preempting more.
+			if node := releasingNode(ssn, preemptor); node != nil {
+				glog.V(3).Infof("Pipelining Task <%v:%v/%v> to node <%v> for <%v> on <%v>",
+					preemptor.UID, preemptor.Namespace, preemptor.Name, node.Name, preemptor.Resreq, node.Releasing)
+				if err := stmt.Pipeline(preemptor, node.Name); err != nil {
+					glog.Errorf("Failed to pipeline Task %v on %v in Session %v",
+						preemptor.UID, node.Name, ssn.ID)
+					break
+				}
 			} else {
-				preempted = true
+				// The evictions left in the limit of session, negative if no limit.
+				left := -1
+				if ssn.MaxPreemptions > 0 {
+					left = ssn.MaxPreemptions - evicted - stmtEvicted
+				}
+				if budget := preemptorJob.PreemptionBudget(ssn.MaxVictimsPerJob); budget > 0 {
+					if jobLeft := budget - jobEvicted[preemptorJob.UID] - stmtEvicted; left < 0 || jobLeft < left {
+						left = jobLeft
+					}
+				}
+				stmtEvicted += preempt(ssn, stmt, preemptor, victims, left)
+
+				if preemptor.Status != api.Pipelined {
+					glog.V(3).Infof("Failed to preempt enough resource for Task <%v:%v/%v>",
+						preemptor.UID, preemptor.Namespace, preemptor.Name)
+					break
+				}
 			}
+
+			if ssn.JobPipelined(preemptorJob) {
+				assigned = true
+				break
+			}
+		}
+
+		if assigned {
+			stmt.Commit()
+			evicted += stmtEvicted
+			jobEvicted[preemptorJob.UID] += stmtEvicted
+			if stmtEvicted > 0 {
+				rounds++
+			}
+			// Put it back to the queue for its other tasks.
+			preemptors.Push(preemptorJob)
 		} else {
+			// The victims are running again after discarding, so others
+			// can still preempt them.
+			glog.V(3).Infof("Discard the preemption for Job <%v:%v/%v>",
+				preemptorJob.UID, preemptorJob.Namespace, preemptorJob.Name)
+			stmt.Discard()
+		}
+	}
+}
+
+// preempt evicts victims in statement until preemptor is pipelined, or no
+// more victim can be preempted by preemptor; it stops after examining
+// ssn.MaxPreemptees victims, evicting ssn.MaxVictimsPerPreemptor victims,
+// or evicting maxEvictions victims unless it's negative. It returns the
+// number of evicted victims.
+func preempt(ssn *framework.Session, stmt *framework.Statement, preemptor *api.TaskInfo,
+	victims *victimSelector, maxEvictions int) int {
+	// The tasks that can not be preempted by preemptor.
+	skipped := map[api.TaskID]bool{}
+	examined, evicted := 0, 0
+
+	for preemptor.Status != api.Pipelined {
+		if ssn.MaxPreemptees > 0 && examined >= ssn.MaxPreemptees {
+			glog.V(3).Infof("Examined %d victims for Task <%v:%v/%v>, reached the limit",
+				examined, preemptor.UID, preemptor.Namespace, preemptor.Name)
+			break
+		}
+
+		if ssn.MaxVictimsPerPreemptor > 0 && evicted >= ssn.MaxVictimsPerPreemptor {
+			glog.V(3).Infof("Evicted %d victims for Task <%v:%v/%v>, reached the limit",
+				evicted, preemptor.UID, preemptor.Namespace, preemptor.Name)
+			break
+		}
+
+		if maxEvictions >= 0 && evicted >= maxEvictions {
+			glog.V(3).Infof("No evictions left in session or budget of job for Task <%v:%v/%v>",
+				preemptor.UID, preemptor.Namespace, preemptor.Name)
+			break
+		}
+
+		preemptee := victims.selectVictim(preemptor, skipped)
+		if preemptee == nil {
+			break
+		}
+		examined++
+
+		if !ssn.Preemptable(preemptor, preemptee) {
 			glog.V(3).Infof("Can not preempt task <%v:%v/%v> for task <%v:%v/%v>",
 				preemptee.UID, preemptee.Namespace, preemptee.Name,
 				preemptor.UID, preemptor.Namespace, preemptor.Name)
+			skipped[preemptee.UID] = true
+			continue
 		}
 
-		// If preempted resource, put it back to the queue.
-		if preempted {
-			preemptors.Push(preemptorJob)
-		} else {
-			// If the preemptee is not preempted, push it back for other to preempt.
-			preempteeTasks[preempteeJob.UID].Push(preemptee)
+		// The evicted victim is recreated by its controller onto the node
+		// that can host it; if preemptor also fits there once the node's
+		// releasing resource is freed, it's placed there later instead of
+		// killing the victim only to have it take that space.
+		home := victims.home(preemptee)
+		if home != nil && victims.hosts(home, preemptor) {
+			glog.V(3).Infof("Task <%v:%v/%v> can be placed on node <%v> where task <%v:%v/%v> would move to, skip evicting it",
+				preemptor.UID, preemptor.Namespace, preemptor.Name, home.Name,
+				preemptee.UID, preemptee.Namespace, preemptee.Name)
+			skipped[preemptee.UID] = true
+			continue
+		}
+
+		glog.V(3).Infof("The preemptor is %v:%v/%v, the preemptee is %v:%v/%v",
+			preemptor.UID, preemptor.Namespace, preemptor.Name,
+			preemptee.UID, preemptee.Namespace, preemptee.Name)
+
+		if err := stmt.Preempt(preemptor, preemptee); err != nil {
+			glog.Errorf("Failed to preempt task <%v/%v> for task <%v/%v>: %v",
+				preemptee.Namespace, preemptee.Name, preemptor.Namespace, preemptor.Name, err)
+			skipped[preemptee.UID] = true
+			continue
+		}
+		evicted++
+
+		if home != nil {
+			glog.V(3).Infof("Task <%v:%v/%v> is expected to be re-homed to node <%v>",
+				preemptee.UID, preemptee.Namespace, preemptee.Name, home.Name)
+			victims.rehome(preemptee, home)
+		}
+	}
+
+	return evicted
+}
+
+// promiseNominated takes the releasing resource on the nominated nodes of
+// the pending tasks out of Releasing, e.g. of the victims evicted for them
+// in previous sessions that are still terminating; so other preemptors do
+// not pipeline onto the same capacity, or preempt others as if it's free,
+// which breaks the plan of the nominated ones. It returns the promises by
+// task, which are given back by unpromise.
+func promiseNominated(ssn *framework.Session) map[api.TaskID]*promise {
+	promises := map[api.TaskID]*promise{}
+
+	for _, job := range ssn.Jobs {
+		for _, task := range job.TaskStatusIndex[api.Pending] {
+			if len(task.NominatedNodeName) == 0 {
+				continue
+			}
+
+			node, found := ssn.NodeIndex[task.NominatedNodeName]
+			if !found || node.Releasing.IsEmpty() || task.Resreq.LessEqual(node.IdleFor(task.Job)) {
+				continue
+			}
+
+			res := api.Min(task.Resreq, node.Releasing)
+			if res.IsEmpty() {
+				continue
+			}
+
+			glog.V(4).Infof("Promise releasing <%v> on node <%v> to nominated Task <%v/%v>",
+				res, node.Name, task.Namespace, task.Name)
+			node.Releasing.Sub(res)
+			promises[task.UID] = &promise{node: node, resource: res}
+		}
+	}
+
+	return promises
+}
+
+// unpromise gives the releasing resource promised to task back to its node.
+func unpromise(promises map[api.TaskID]*promise, task api.TaskID) {
+	if p, found := promises[task]; found {
+		p.node.Releasing.Add(p.resource)
+		delete(promises, task)
+	}
+}
+
+// idleNode returns the node whose idle resource is enough for task.
+func idleNode(ssn *framework.Session, task *api.TaskInfo) *api.NodeInfo {
+	for _, node := range ssn.Nodes {
+		if !task.Resreq.LessEqual(node.IdleFor(task.Job)) {
+			continue
+		}
+
+		if err := ssn.PredicateFn(task, node); err != nil {
+			continue
+		}
+
+		return node
+	}
+
+	return nil
+}
+
+// releasingNode returns the node whose releasing resource, excluding the part
+// already pipelined to other tasks, is enough for task.
+func releasingNode(ssn *framework.Session, task *api.TaskInfo) *api.NodeInfo {
+	for _, node := range ssn.Nodes {
+		if node.Releasing.IsEmpty() || !task.Resreq.LessEqual(node.Releasing) {
+			continue
+		}
+
+		if err := ssn.PredicateFn(task, node); err != nil {
+			glog.V(3).Infof("Predicates failed for Task <%v/%v> on node <%v>: %v",
+				task.Job, task.UID, node.Name, err)
+			continue
 		}
 
-		preemptees.Push(preempteeJob)
+		return node
 	}
+
+	return nil
 }
 
 func (alloc *preemptAction) UnInitialize() {}
@@ -17,16 +17,1054 @@ limitations under the License.
 package preempt
 
 import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/benefit"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/drf"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/gang"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
 )
 
+type fakeEvictor struct {
+	sync.Mutex
+	evicts map[string]string
+	c      chan string
+}
+
+func (fe *fakeEvictor) Evict(p *v1.Pod, reason string) error {
+	key := fmt.Sprintf("%v/%v", p.Namespace, p.Name)
+
+	fe.Lock()
+	fe.evicts[key] = reason
+	fe.Unlock()
+
+	fe.c <- key
+
+	return nil
+}
+
 func TestPreempt(t *testing.T) {
-	framework.RegisterPluginBuilder(drf.New)
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	evictor := &fakeEvictor{
+		evicts: map[string]string{},
+		c:      make(chan string),
+	}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:   make(map[string]*api.NodeInfo),
+		Jobs:    make(map[api.JobID]*api.JobInfo),
+		Evictor: evictor,
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+	for _, pod := range []*v1.Pod{
+		testutil.BuildPod("c1", "preemptee1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c1", "preemptee2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c2", "preemptor1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner2))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	// The releasing resource of preemptee is promised to preemptor.
+	if got := len(ssn.JobIndex["owner2"].TaskStatusIndex[api.Pipelined]); got != 1 {
+		t.Errorf("expected preemptor to be pipelined, got %d pipelined tasks", got)
+	}
+
+	// The preemptor is nominated to the node, so it's placed there first
+	// in next sessions.
+	schedulerCache.Mutex.Lock()
+	for _, task := range schedulerCache.Jobs["owner2"].Tasks {
+		if task.NominatedNodeName != "n1" {
+			t.Errorf("expected preemptor nominated to n1, got %q", task.NominatedNodeName)
+		}
+	}
+	schedulerCache.Mutex.Unlock()
+
+	select {
+	case <-evictor.c:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Failed to get evicting request.")
+	}
+
+	evictor.Lock()
+	defer evictor.Unlock()
+
+	if len(evictor.evicts) != 1 {
+		t.Fatalf("expected 1 evicted pod, got %v", evictor.evicts)
+	}
+
+	for key, reason := range evictor.evicts {
+		if !strings.HasPrefix(key, "c1/preemptee") {
+			t.Errorf("unexpected evicted pod %v", key)
+		}
+		if !strings.Contains(reason, "Preempted") || !strings.Contains(reason, "c2/preemptor1") {
+			t.Errorf("expected evict reason names the preemptor, got %q", reason)
+		}
+	}
+}
+
+func TestPreemptWithReleasing(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+	owner3 := testutil.BuildOwnerReference("owner3")
+
+	evictor := &fakeEvictor{
+		evicts: map[string]string{},
+		c:      make(chan string, 10),
+	}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:   make(map[string]*api.NodeInfo),
+		Jobs:    make(map[api.JobID]*api.JobInfo),
+		Evictor: evictor,
+	}
+
+	releasing := testutil.BuildPod("c3", "releasing1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner3)
+	now := metav1.Now()
+	releasing.DeletionTimestamp = &now
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("3", "6Gi")))
+	for _, pod := range []*v1.Pod{
+		testutil.BuildPod("c1", "preemptee1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c1", "preemptee2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+		releasing,
+		testutil.BuildPod("c2", "preemptor1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner2))
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner3))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	// The preemptor waits for the releasing resource instead of preempting others.
+	if got := len(ssn.JobIndex["owner2"].TaskStatusIndex[api.Pipelined]); got != 1 {
+		t.Errorf("expected preemptor to be pipelined, got %d pipelined tasks", got)
+	}
+
+	if got := len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Running]); got != 2 {
+		t.Errorf("expected 2 running preemptees, got %d", got)
+	}
+
+	evictor.Lock()
+	defer evictor.Unlock()
+
+	if len(evictor.evicts) != 0 {
+		t.Errorf("expected no evicted pod, got %v", evictor.evicts)
+	}
+}
+
+func TestPreemptProtectedJob(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+	owner3 := testutil.BuildOwnerReference("owner3")
+
+	evictor := &fakeEvictor{
+		evicts: map[string]string{},
+		c:      make(chan string, 10),
+	}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:   make(map[string]*api.NodeInfo),
+		Jobs:    make(map[api.JobID]*api.JobInfo),
+		Evictor: evictor,
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+	for _, pod := range []*v1.Pod{
+		testutil.BuildPod("c1", "preemptee1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c1", "preemptee2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c3", "preemptee1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner3),
+		testutil.BuildPod("c3", "preemptee2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner3),
+		testutil.BuildPod("c2", "preemptor1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+
+	protected := testutil.BuildSchedulingSpec("", "", owner1)
+	protected.Annotations = map[string]string{arbv1.PreemptableAnnotationKey: "false"}
+	schedulerCache.AddSchedulingSpec(protected)
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner2))
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner3))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if got := len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Running]); got != 2 {
+		t.Errorf("expected 2 running tasks of protected job, got %d", got)
+	}
+
+	select {
+	case <-evictor.c:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Failed to get evicting request.")
+	}
+
+	evictor.Lock()
+	defer evictor.Unlock()
+
+	for key := range evictor.evicts {
+		if !strings.HasPrefix(key, "c3/") {
+			t.Errorf("unexpected evicted pod %v", key)
+		}
+	}
+}
+
+func TestPreemptGang(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	framework.RegisterPluginBuilder(gang.PluginName, gang.New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name         string
+		minAvailable int
+		preemptorReq v1.ResourceList
+		evicted      int
+		pipelined    int
+	}{
+		{
+			name:         "enough resource for gang",
+			minAvailable: 2,
+			preemptorReq: testutil.BuildResourceList("1", "1Gi"),
+			evicted:      2,
+			pipelined:    2,
+		},
+		{
+			// Only one task of the gang can be pipelined, none is preempted.
+			name:         "not enough resource for gang",
+			minAvailable: 2,
+			preemptorReq: testutil.BuildResourceList("2", "1Gi"),
+			evicted:      0,
+			pipelined:    0,
+		},
+	}
+
+	for i, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		evictor := &fakeEvictor{
+			evicts: map[string]string{},
+			c:      make(chan string, 10),
+		}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: evictor,
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+		for j := 0; j < 4; j++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("preemptee%d", j), "n1", v1.PodRunning,
+				testutil.BuildResourceList("1", "1Gi"), owner1))
+		}
+		for j := 0; j < 2; j++ {
+			schedulerCache.AddPod(testutil.BuildPod("c2", fmt.Sprintf("preemptor%d", j), "", v1.PodPending,
+				test.preemptorReq, owner2))
+		}
+
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+		gangSpec := testutil.BuildSchedulingSpec("", "", owner2)
+		gangSpec.Spec.MinAvailable = test.minAvailable
+		schedulerCache.AddSchedulingSpec(gangSpec)
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{Name: drf.PluginName},
+			{Name: gang.PluginName},
+		})
+
+		New().Execute(ssn)
+
+		if got := len(ssn.JobIndex["owner2"].TaskStatusIndex[api.Pipelined]); got != test.pipelined {
+			t.Errorf("case %d (%s): expected %d pipelined tasks, got %d", i, test.name, test.pipelined, got)
+		}
+
+		if got := len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Running]); got != 4-test.evicted {
+			t.Errorf("case %d (%s): expected %d running preemptees, got %d", i, test.name, 4-test.evicted, got)
+		}
+
+		// The discarded preemption does not change the node.
+		if test.evicted == 0 && !ssn.NodeIndex["n1"].Releasing.IsEmpty() {
+			t.Errorf("case %d (%s): expected no releasing resource, got %v", i, test.name, ssn.NodeIndex["n1"].Releasing)
+		}
+
+		for j := 0; j < test.evicted; j++ {
+			select {
+			case <-evictor.c:
+			case <-time.After(3 * time.Second):
+				t.Fatalf("case %d (%s): failed to get evicting request", i, test.name)
+			}
+		}
+
+		evictor.Lock()
+		if len(evictor.evicts) != test.evicted {
+			t.Errorf("case %d (%s): expected %d evicted pods, got %v", i, test.name, test.evicted, evictor.evicts)
+		}
+		evictor.Unlock()
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestPreemptVictimOrder(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
 	defer framework.CleanupPluginBuilders()
 
-	// TODO (k82cn): Add UT cases here.
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+	owner3 := testutil.BuildOwnerReference("owner3")
+	owner4 := testutil.BuildOwnerReference("owner4")
+
+	evictor := &fakeEvictor{
+		evicts: map[string]string{},
+		c:      make(chan string, 10),
+	}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:   make(map[string]*api.NodeInfo),
+		Jobs:    make(map[api.JobID]*api.JobInfo),
+		Evictor: evictor,
+	}
+
+	withPriority := func(pod *v1.Pod, priority int32) *v1.Pod {
+		pod.Spec.Priority = &priority
+		return pod
+	}
+
+	// owner1 is the most over its share, p2 is its lowest priority task;
+	// owner2's tasks are of the same priority as p2, but it's less over its
+	// share.
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("6", "12Gi")))
+	for _, pod := range []*v1.Pod{
+		withPriority(testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1), 10),
+		withPriority(testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1), 1),
+		withPriority(testutil.BuildPod("c1", "p3", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1), 5),
+		withPriority(testutil.BuildPod("c2", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner2), 1),
+		withPriority(testutil.BuildPod("c2", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner2), 1),
+		withPriority(testutil.BuildPod("c3", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner3), 1),
+		testutil.BuildPod("c4", "preemptor1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner4),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	for _, owner := range []metav1.OwnerReference{owner1, owner2, owner3, owner4} {
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+	}
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	select {
+	case <-evictor.c:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Failed to get evicting request.")
+	}
+
+	evictor.Lock()
+	defer evictor.Unlock()
+
+	if _, found := evictor.evicts["c1/p2"]; !found || len(evictor.evicts) != 1 {
+		t.Errorf("expected only c1/p2 to be evicted, got %v", evictor.evicts)
+	}
+}
+
+func TestPreemptWithIdleNode(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	evictor := &fakeEvictor{
+		evicts: map[string]string{},
+		c:      make(chan string, 1),
+	}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:   make(map[string]*api.NodeInfo),
+		Jobs:    make(map[api.JobID]*api.JobInfo),
+		Evictor: evictor,
+	}
+
+	// The job of owner1 is over its share on n1, but n2 has idle resource
+	// for the preemptor.
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("1", "4Gi")))
+	for _, pod := range []*v1.Pod{
+		testutil.BuildPod("c1", "preemptee1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c1", "preemptee2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c2", "preemptor1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner2))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if got := len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Releasing]); got != 0 {
+		t.Errorf("expected no task preempted, got %d", got)
+	}
+	if got := len(ssn.JobIndex["owner2"].TaskStatusIndex[api.Pending]); got != 1 {
+		t.Errorf("expected preemptor left pending for allocate, got %d pending tasks", got)
+	}
+
+	evictor.Lock()
+	defer evictor.Unlock()
+	if len(evictor.evicts) != 0 {
+		t.Errorf("expected no eviction, got %v", evictor.evicts)
+	}
+}
+
+func TestPreemptQoSOrder(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	evictor := &fakeEvictor{
+		evicts: map[string]string{},
+		c:      make(chan string, 3),
+	}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:   make(map[string]*api.NodeInfo),
+		Jobs:    make(map[api.JobID]*api.JobInfo),
+		Evictor: evictor,
+	}
+
+	// The victims are of the same job and priority; without QoS class, the
+	// guaranteed one would be evicted first as it goes last in job.
+	bestEffort := testutil.BuildPod("c1", "victim-a", "n1", v1.PodRunning, v1.ResourceList{}, owner1)
+	burstable := testutil.BuildPod("c1", "victim-b", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1)
+	guaranteed := testutil.BuildPod("c1", "victim-c", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1)
+	guaranteed.Spec.Containers[0].Resources.Limits = testutil.BuildResourceList("1", "1Gi")
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+	for _, pod := range []*v1.Pod{
+		bestEffort,
+		burstable,
+		guaranteed,
+		testutil.BuildPod("c2", "preemptor1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner2))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	// The BestEffort victim releases nothing, so it's skipped and only the
+	// Burstable one is evicted for preemptor.
+	var releasing []string
+	for _, task := range ssn.JobIndex["owner1"].TaskStatusIndex[api.Releasing] {
+		releasing = append(releasing, task.Name)
+	}
+	sort.Strings(releasing)
+
+	if expected := []string{"victim-b"}; !reflect.DeepEqual(expected, releasing) {
+		t.Errorf("expected victims %v, got %v", expected, releasing)
+	}
+	if got := len(ssn.JobIndex["owner2"].TaskStatusIndex[api.Pipelined]); got != 1 {
+		t.Errorf("expected preemptor to be pipelined, got %d pipelined tasks", got)
+	}
+}
+
+func TestPreemptScarceResource(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+	owner3 := testutil.BuildOwnerReference("owner3")
+
+	evictor := &fakeEvictor{
+		evicts: map[string]string{},
+		c:      make(chan string, 3),
+	}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:   make(map[string]*api.NodeInfo),
+		Jobs:    make(map[api.JobID]*api.JobInfo),
+		Evictor: evictor,
+	}
+
+	withGPU := func(rl v1.ResourceList, gpu string) v1.ResourceList {
+		rl[api.GPUResourceName] = resource.MustParse(gpu)
+		return rl
+	}
+
+	// The CPU-only victims are of the job most over its share, but they free
+	// no GPU that preemptor is short of; n2 has idle GPUs but no idle CPU.
+	schedulerCache.AddNode(testutil.BuildNode("n1", withGPU(testutil.BuildResourceList("16", "8Gi"), "0")))
+	schedulerCache.AddNode(testutil.BuildNode("n2", withGPU(testutil.BuildResourceList("2", "8Gi"), "8")))
+	for _, pod := range []*v1.Pod{
+		testutil.BuildPod("c1", "cpu-victim1", "n1", v1.PodRunning, testutil.BuildResourceList("8", "1Gi"), owner1),
+		testutil.BuildPod("c1", "cpu-victim2", "n1", v1.PodRunning, testutil.BuildResourceList("8", "1Gi"), owner1),
+		testutil.BuildPod("c2", "gpu-victim1", "n2", v1.PodRunning, withGPU(testutil.BuildResourceList("1", "1Gi"), "2"), owner2),
+		testutil.BuildPod("c2", "gpu-victim2", "n2", v1.PodRunning, withGPU(testutil.BuildResourceList("1", "1Gi"), "2"), owner2),
+		testutil.BuildPod("c3", "preemptor", "", v1.PodPending, withGPU(testutil.BuildResourceList("1", "1Gi"), "1"), owner3),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	for _, owner := range []metav1.OwnerReference{owner1, owner2, owner3} {
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+	}
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	if got := len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Releasing]); got != 0 {
+		t.Errorf("expected CPU-only victims kept, got %d releasing tasks", got)
+	}
+	if got := len(ssn.JobIndex["owner2"].TaskStatusIndex[api.Releasing]); got != 1 {
+		t.Errorf("expected one GPU victim evicted, got %d releasing tasks", got)
+	}
+	if task := ssn.JobIndex["owner3"].Tasks["c3-preemptor"]; task.Status != api.Pipelined || task.NodeName != "n2" {
+		t.Errorf("expected preemptor pipelined to n2, got %v on <%v>", task.Status, task.NodeName)
+	}
+}
+
+func TestPreemptLimits(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name           string
+		preemptors     int
+		cpu            string
+		maxPreemptees  int
+		maxPreemptions int
+		maxRounds      int
+		maxVictims     int
+		maxJobVictims  int
+		budget         string
+		evicted        int
+		pipelined      int
+	}{
+		{
+			name:       "no limits",
+			preemptors: 3,
+			cpu:        "1",
+			evicted:    3,
+			pipelined:  3,
+		},
+		{
+			name:           "evictions stop at the limit of session",
+			preemptors:     3,
+			cpu:            "1",
+			maxPreemptions: 2,
+			evicted:        2,
+			pipelined:      2,
+		},
+		{
+			name:          "the preemptor needs more victims than the limit",
+			preemptors:    1,
+			cpu:           "2",
+			maxPreemptees: 1,
+			evicted:       0,
+			pipelined:     0,
+		},
+		{
+			name:          "the preemptor needs as many victims as the limit",
+			preemptors:    1,
+			cpu:           "2",
+			maxPreemptees: 2,
+			evicted:       2,
+			pipelined:     1,
+		},
+		{
+			name:       "preemptions stop after the rounds of session",
+			preemptors: 3,
+			cpu:        "1",
+			maxRounds:  2,
+			evicted:    2,
+			pipelined:  2,
+		},
+		{
+			name:       "the preemptor needs more evictions than the limit",
+			preemptors: 1,
+			cpu:        "2",
+			maxVictims: 1,
+			evicted:    0,
+			pipelined:  0,
+		},
+		{
+			name:       "the preemptor needs as many evictions as the limit",
+			preemptors: 2,
+			cpu:        "2",
+			maxVictims: 2,
+			evicted:    4,
+			pipelined:  2,
+		},
+		{
+			name:          "evictions stop at the budget of preemptor job",
+			preemptors:    3,
+			cpu:           "1",
+			maxJobVictims: 2,
+			evicted:       2,
+			pipelined:     2,
+		},
+		{
+			name:          "the preemptor job overrides the budget",
+			preemptors:    3,
+			cpu:           "1",
+			maxJobVictims: 2,
+			budget:        "1",
+			evicted:       1,
+			pipelined:     1,
+		},
+		{
+			name:       "the budget of preemptor job without default",
+			preemptors: 3,
+			cpu:        "1",
+			budget:     "2",
+			evicted:    2,
+			pipelined:  2,
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		evictor := &fakeEvictor{
+			evicts: map[string]string{},
+			c:      make(chan string, 8),
+		}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: evictor,
+		}
+
+		// The victims of 1 CPU take the whole node.
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("8", "16Gi")))
+		for i := 0; i < 8; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("victim%d", i), "n1", v1.PodRunning,
+				testutil.BuildResourceList("1", "1Gi"), owner1))
+		}
+		for i := 0; i < test.preemptors; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c2", fmt.Sprintf("preemptor%d", i), "", v1.PodPending,
+				testutil.BuildResourceList(test.cpu, "1Gi"), owner2))
+		}
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+		preemptorSpec := testutil.BuildSchedulingSpec("", "", owner2)
+		if len(test.budget) != 0 {
+			preemptorSpec.Annotations = map[string]string{arbv1.PreemptionBudgetAnnotationKey: test.budget}
+		}
+		schedulerCache.AddSchedulingSpec(preemptorSpec)
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+		ssn.MaxPreemptees = test.maxPreemptees
+		ssn.MaxPreemptions = test.maxPreemptions
+		ssn.MaxPreemptionRounds = test.maxRounds
+		ssn.MaxVictimsPerPreemptor = test.maxVictims
+		ssn.MaxVictimsPerJob = test.maxJobVictims
+
+		New().Execute(ssn)
+
+		if got := len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Releasing]); got != test.evicted {
+			t.Errorf("case %s: expected %d victims evicted, got %d", test.name, test.evicted, got)
+		}
+		if got := len(ssn.JobIndex["owner2"].TaskStatusIndex[api.Pipelined]); got != test.pipelined {
+			t.Errorf("case %s: expected %d preemptors pipelined, got %d", test.name, test.pipelined, got)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestPreemptRehomedVictim(t *testing.T) {
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name      string
+		releasing bool
+		evicted   int
+		pipelined int
+	}{
+		{
+			// The victims would move to n2, where the preemptor fits once
+			// the releasing task is gone.
+			name:      "the preemptor fits where the victims would move to",
+			releasing: true,
+			evicted:   0,
+			pipelined: 0,
+		},
+		{
+			name:      "the preemptor fits nowhere else",
+			releasing: false,
+			evicted:   2,
+			pipelined: 1,
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+		owner3 := testutil.BuildOwnerReference("owner3")
+
+		evictor := &fakeEvictor{
+			evicts: map[string]string{},
+			c:      make(chan string, 8),
+		}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: evictor,
+		}
+
+		// The victims take the whole n1, and n2 has 1 idle CPU.
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+		schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("2", "4Gi")))
+		for i := 0; i < 4; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("victim%d", i), "n1", v1.PodRunning,
+				testutil.BuildResourceList("1", "1Gi"), owner1))
+		}
+
+		other := testutil.BuildPod("c3", "other1", "n2", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner3)
+		if test.releasing {
+			now := metav1.Now()
+			other.DeletionTimestamp = &now
+		}
+		schedulerCache.AddPod(other)
+
+		schedulerCache.AddPod(testutil.BuildPod("c2", "preemptor1", "", v1.PodPending,
+			testutil.BuildResourceList("2", "1Gi"), owner2))
+		for _, owner := range []metav1.OwnerReference{owner1, owner2, owner3} {
+			schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+		}
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: drf.PluginName}})
+
+		New().Execute(ssn)
+
+		if got := len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Releasing]); got != test.evicted {
+			t.Errorf("case %s: expected %d victims evicted, got %d", test.name, test.evicted, got)
+		}
+		if got := len(ssn.JobIndex["owner2"].TaskStatusIndex[api.Pipelined]); got != test.pipelined {
+			t.Errorf("case %s: expected %d preemptors pipelined, got %d", test.name, test.pipelined, got)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestPreemptMinimizePriorityInversion(t *testing.T) {
+	framework.RegisterPluginBuilder(benefit.PluginName, benefit.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+	owner3 := testutil.BuildOwnerReference("owner3")
+
+	evictor := &fakeEvictor{
+		evicts: map[string]string{},
+		c:      make(chan string, 10),
+	}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:   make(map[string]*api.NodeInfo),
+		Jobs:    make(map[api.JobID]*api.JobInfo),
+		Evictor: evictor,
+	}
+
+	withPriority := func(pod *v1.Pod, priority int32) *v1.Pod {
+		pod.Spec.Priority = &priority
+		return pod
+	}
+
+	// The preemptor fits n1 by evicting two tasks of priority 10, or n2 by
+	// evicting one of priority 100; owner2 is more over its share.
+	for _, name := range []string{"n1", "n2", "n3"} {
+		schedulerCache.AddNode(testutil.BuildNode(name, testutil.BuildResourceList("2", "4Gi")))
+	}
+	for _, pod := range []*v1.Pod{
+		withPriority(testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1), 10),
+		withPriority(testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1), 10),
+		withPriority(testutil.BuildPod("c2", "p1", "n2", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), owner2), 100),
+		withPriority(testutil.BuildPod("c2", "p2", "n3", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), owner2), 100),
+		testutil.BuildPod("c3", "preemptor1", "", v1.PodPending, testutil.BuildResourceList("2", "1Gi"), owner3),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	for _, owner := range []metav1.OwnerReference{owner1, owner2, owner3} {
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+	}
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+		{
+			Name:      benefit.PluginName,
+			Arguments: framework.Arguments{benefit.MinFraction: "0.1"},
+		},
+	})
+	defer framework.CloseSession(ssn)
+
+	New().Execute(ssn)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-evictor.c:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Failed to get evicting request.")
+		}
+	}
+
+	evictor.Lock()
+	defer evictor.Unlock()
+
+	expected := []string{"c1/p1", "c1/p2"}
+	var evicted []string
+	for key := range evictor.evicts {
+		evicted = append(evicted, key)
+	}
+	sort.Strings(evicted)
+	if !reflect.DeepEqual(expected, evicted) {
+		t.Errorf("expected %v evicted instead of the task of priority 100, got %v", expected, evicted)
+	}
+}
+
+func TestPreemptClusteredVictims(t *testing.T) {
+	framework.RegisterPluginBuilder(benefit.PluginName, benefit.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+	owner3 := testutil.BuildOwnerReference("owner3")
+
+	tests := []struct {
+		name     string
+		nodes    map[string]string
+		pods     []*v1.Pod
+		expected []string
+	}{
+		{
+			// n2 and n3 need three evictions each, as their victims of
+			// 0.5 CPU are scattered with the task of 1 CPU.
+			name:  "the victims are clustered on the node of fewest evictions",
+			nodes: map[string]string{"n1": "2", "n2": "2", "n3": "2"},
+			pods: []*v1.Pod{
+				testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+				testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+				testutil.BuildPod("c2", "p1", "n2", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner2),
+				testutil.BuildPod("c2", "p2", "n2", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner2),
+				testutil.BuildPod("c2", "p3", "n2", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner2),
+				testutil.BuildPod("c2", "p4", "n3", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner2),
+				testutil.BuildPod("c2", "p5", "n3", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner2),
+				testutil.BuildPod("c2", "p6", "n3", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner2),
+			},
+			expected: []string{"c1/p1", "c1/p2"},
+		},
+		{
+			// owner2 is more over its share, but evicting its task on n1
+			// does not save evicting the one of owner1.
+			name:  "only the victims of the plan on the node are evicted",
+			nodes: map[string]string{"n1": "3", "n2": "2"},
+			pods: []*v1.Pod{
+				testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), owner1),
+				testutil.BuildPod("c2", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner2),
+				testutil.BuildPod("c2", "p2", "n2", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner2),
+				testutil.BuildPod("c2", "p3", "n2", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner2),
+			},
+			expected: []string{"c1/p1"},
+		},
+	}
+
+	for _, test := range tests {
+		evictor := &fakeEvictor{
+			evicts: map[string]string{},
+			c:      make(chan string, 10),
+		}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: evictor,
+		}
+
+		for name, cpu := range test.nodes {
+			schedulerCache.AddNode(testutil.BuildNode(name, testutil.BuildResourceList(cpu, "8Gi")))
+		}
+		for _, pod := range test.pods {
+			schedulerCache.AddPod(pod)
+		}
+		schedulerCache.AddPod(testutil.BuildPod("c3", "preemptor1", "", v1.PodPending, testutil.BuildResourceList("2", "1Gi"), owner3))
+		for _, owner := range []metav1.OwnerReference{owner1, owner2, owner3} {
+			schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+		}
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{
+				Name:      benefit.PluginName,
+				Arguments: framework.Arguments{benefit.MinFraction: "0.1"},
+			},
+		})
+
+		New().Execute(ssn)
+
+		if got := len(ssn.JobIndex["owner3"].TaskStatusIndex[api.Pipelined]); got != 1 {
+			t.Errorf("case %s: expected the preemptor pipelined, got %d", test.name, got)
+		}
+
+		if !schedulerCache.WaitForInflight(3 * time.Second) {
+			t.Fatalf("case %s: evictions are not completed in time", test.name)
+		}
+		evictor.Lock()
+		var evicted []string
+		for key := range evictor.evicts {
+			evicted = append(evicted, key)
+		}
+		evictor.Unlock()
+		sort.Strings(evicted)
+		if !reflect.DeepEqual(test.expected, evicted) {
+			t.Errorf("case %s: expected %v evicted, got %v", test.name, test.expected, evicted)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+// holdPlugin makes the job invalid while it's held.
+type holdPlugin struct {
+	job  api.JobID
+	held bool
+}
+
+func (hp *holdPlugin) Name() string {
+	return "hold"
+}
+
+func (hp *holdPlugin) OnSessionOpen(ssn *framework.Session) {
+	ssn.AddJobValidFn(func(obj interface{}) bool {
+		return !hp.held || obj.(*api.JobInfo).UID != hp.job
+	})
+}
+
+func (hp *holdPlugin) OnSessionClose(ssn *framework.Session) {}
+
+func TestPreemptNominatedPromise(t *testing.T) {
+	tests := []struct {
+		name string
+		// Whether the nominated preemptor is held out of this session.
+		held bool
+		// The pipelined preemptors.
+		pipelined []string
+	}{
+		{
+			name:      "the nominated preemptor takes the releasing resource though the other goes first",
+			pipelined: []string{"c3/preemptor2"},
+		},
+		{
+			name:      "the releasing resource is kept for the held preemptor",
+			held:      true,
+			pipelined: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		hp := &holdPlugin{job: "owner3", held: test.held}
+		framework.RegisterPluginBuilder(hp.Name(), func(framework.Arguments) framework.Plugin {
+			return hp
+		})
+
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+		owner3 := testutil.BuildOwnerReference("owner3")
+
+		evictor := &fakeEvictor{
+			evicts: map[string]string{},
+			c:      make(chan string, 10),
+		}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: evictor,
+		}
+
+		// The victim of preemptor2 was evicted in previous session and is
+		// still terminating; no plugin allows preempting more, so the two
+		// preemptors compete for its releasing resource.
+		victim := testutil.BuildPod("c1", "victim", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1)
+		deleted := metav1.Now()
+		victim.DeletionTimestamp = &deleted
+		nominated := testutil.BuildPod("c3", "preemptor2", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner3)
+		nominated.Status.NominatedNodeName = "n1"
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+		for _, pod := range []*v1.Pod{
+			victim,
+			testutil.BuildPod("c1", "running", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+			testutil.BuildPod("c2", "preemptor1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+			nominated,
+		} {
+			schedulerCache.AddPod(pod)
+		}
+		for _, owner := range []metav1.OwnerReference{owner1, owner2, owner3} {
+			schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+		}
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: hp.Name()}})
+
+		New().Execute(ssn)
+
+		pipelined := []string{}
+		for _, job := range ssn.Jobs {
+			for _, task := range job.TaskStatusIndex[api.Pipelined] {
+				pipelined = append(pipelined, fmt.Sprintf("%v/%v", task.Namespace, task.Name))
+			}
+		}
+		if !reflect.DeepEqual(test.pipelined, pipelined) {
+			t.Errorf("case %s: expected pipelined %v, got %v", test.name, test.pipelined, pipelined)
+		}
+
+		// The promise is given back after preemption.
+		releasing := ssn.NodeIndex["n1"].Releasing.MilliCPU
+		if expected := float64(1000 - 1000*len(test.pipelined)); releasing != expected {
+			t.Errorf("case %s: expected %v releasing cpu, got %v", test.name, expected, releasing)
+		}
+
+		framework.CloseSession(ssn)
+		framework.CleanupPluginBuilders()
+	}
 }
@@ -0,0 +1,404 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preempt
+
+import (
+	"math"
+	"sort"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// victimSelector selects the victim among the running tasks of preemptable
+// jobs; the scores of victims change along with preemption, so they're
+// compared every time instead of kept in a priority queue.
+type victimSelector struct {
+	ssn *framework.Session
+
+	// The total resource of cluster and the deserved share of each job.
+	total    *api.Resource
+	deserved float64
+
+	candidates []*api.TaskInfo
+
+	// The evicted victims that are expected to be re-homed, by the node
+	// their controller's recreation lands on.
+	homed map[string][]*api.TaskInfo
+}
+
+func newVictimSelector(ssn *framework.Session) *victimSelector {
+	vs := &victimSelector{
+		ssn:   ssn,
+		total: api.EmptyResource(),
+		homed: map[string][]*api.TaskInfo{},
+	}
+
+	for _, node := range ssn.Nodes {
+		vs.total.Add(node.Allocatable)
+	}
+
+	if len(ssn.Jobs) != 0 {
+		vs.deserved = 1 / float64(len(ssn.Jobs))
+	}
+
+	return vs
+}
+
+// overShare returns how much the dominant share of job's allocated resource
+// exceeds its deserved share; it's negative if the job is under its share.
+func (vs *victimSelector) overShare(job *api.JobInfo) float64 {
+	share := 0.0
+	for _, rn := range api.ResourceNames() {
+		if vs.total.Get(rn) == 0 {
+			continue
+		}
+		if s := job.Allocated.Get(rn) / vs.total.Get(rn); s > share {
+			share = s
+		}
+	}
+
+	return share - vs.deserved
+}
+
+// better returns true if l is a better victim than r for preemptor: the job
+// of l is more over its share, or the same but l is of lower priority, or
+// lower QoS class; then the victim order of plugins, and the reverse order
+// of jobs and tasks break the tie.
+func (vs *victimSelector) better(preemptor, l, r *api.TaskInfo) bool {
+	lJob, rJob := vs.ssn.JobIndex[l.Job], vs.ssn.JobIndex[r.Job]
+
+	if lJob != rJob {
+		lOver, rOver := vs.overShare(lJob), vs.overShare(rJob)
+		if lOver != rOver {
+			return lOver > rOver
+		}
+	}
+
+	if l.Priority != r.Priority {
+		return l.Priority < r.Priority
+	}
+
+	if q := api.CompareQoS(l, r); q != 0 {
+		return q < 0
+	}
+
+	if v := vs.ssn.VictimOrderFn(preemptor, l, r); v != 0 {
+		return v < 0
+	}
+
+	if lJob != rJob {
+		return !vs.ssn.JobOrderFn(lJob, rJob)
+	}
+
+	return !vs.ssn.TaskOrderFn(l, r)
+}
+
+// releasable returns the resource that can be released on each node for
+// preemptor, i.e. the releasing resource plus the running candidates of other
+// jobs on the node.
+func (vs *victimSelector) releasable(preemptor *api.TaskInfo) map[string]*api.Resource {
+	result := map[string]*api.Resource{}
+	for _, task := range vs.candidates {
+		if task.Status != api.Running || task.Job == preemptor.Job {
+			continue
+		}
+
+		res, found := result[task.NodeName]
+		if !found {
+			node, found := vs.ssn.NodeIndex[task.NodeName]
+			if !found {
+				continue
+			}
+			res = node.Releasing.Clone()
+			result[task.NodeName] = res
+		}
+		res.Add(task.Resreq)
+	}
+
+	return result
+}
+
+// frees returns true if evicting victim helps preemptor: it releases some
+// resource that preemptor is short of on victim's node, and the node can
+// release enough in every dimension. E.g. a CPU-only victim is skipped for
+// the preemptor that lacks GPU on its node.
+func (vs *victimSelector) frees(preemptor, victim *api.TaskInfo, releasable map[string]*api.Resource) bool {
+	node, found := vs.ssn.NodeIndex[victim.NodeName]
+	if !found {
+		return false
+	}
+
+	contributes := false
+	for _, rn := range preemptor.Resreq.Insufficient(node.Releasing) {
+		if victim.Resreq.Get(rn) > 0 {
+			contributes = true
+			break
+		}
+	}
+
+	return contributes && preemptor.Resreq.LessEqual(releasable[node.Name])
+}
+
+// victimPlan is the least disruptive eviction on a node that fits preemptor:
+// the highest priority among the victims to evict, and how many of them.
+type victimPlan struct {
+	priority int32
+	count    int
+
+	// The victims to evict on the node, all of them clustered there, and
+	// the ones interchangeable with them: the victims of the same request
+	// that are not of higher priority, so the victim order still decides
+	// which of them to evict.
+	victims []*api.TaskInfo
+}
+
+// less returns true if p evicts victims of lower priority than o, or as low
+// but fewer of them; e.g. two victims of priority 10 are less than one of
+// priority 100.
+func (p *victimPlan) less(o *victimPlan) bool {
+	if p.priority != o.priority {
+		return p.priority < o.priority
+	}
+	return p.count < o.count
+}
+
+// covers returns how much of the shortage of preemptor's request in available
+// resource victim makes up, summed up over the short resource dimensions.
+func covers(preemptor, available, victim *api.Resource) float64 {
+	res := 0.0
+	for _, rn := range preemptor.Insufficient(available) {
+		short := preemptor.Get(rn) - available.Get(rn)
+		res += math.Min(victim.Get(rn), short) / short
+	}
+	return res
+}
+
+// fewest returns the victims to evict, picking the one that makes up most of
+// the shortage first, until preemptor fits in base and released resource.
+func fewest(preemptor *api.TaskInfo, base *api.Resource, victims []*api.TaskInfo) []*api.TaskInfo {
+	available := base.Clone()
+	evicted := map[int]bool{}
+	for !preemptor.Resreq.LessEqual(available) {
+		best, bestCovers := -1, 0.0
+		for i, victim := range victims {
+			if evicted[i] {
+				continue
+			}
+			if c := covers(preemptor.Resreq, available, victim.Resreq); c > bestCovers {
+				best, bestCovers = i, c
+			}
+		}
+		if best < 0 {
+			break
+		}
+		evicted[best] = true
+		available.Add(victims[best].Resreq)
+	}
+
+	var result []*api.TaskInfo
+	for i, victim := range victims {
+		if evicted[i] {
+			result = append(result, victim)
+		}
+	}
+
+	return result
+}
+
+// interchangeable returns the victims of the same request as any of the
+// planned ones, which include the planned ones.
+func interchangeable(planned, victims []*api.TaskInfo) []*api.TaskInfo {
+	var result []*api.TaskInfo
+	for _, victim := range victims {
+		for _, p := range planned {
+			if victim.Resreq.LessEqual(p.Resreq) && p.Resreq.LessEqual(victim.Resreq) {
+				result = append(result, victim)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// nodePlan returns the plan for preemptor on node with victims of node: the
+// lowest priority that fits preemptor by evicting victims up to it; it's nil
+// if evicting all victims does not fit.
+func nodePlan(preemptor *api.TaskInfo, node *api.NodeInfo, victims []*api.TaskInfo) *victimPlan {
+	sort.Slice(victims, func(i, j int) bool {
+		return victims[i].Priority < victims[j].Priority
+	})
+
+	future := node.Releasing.Clone()
+	for i := 0; i < len(victims); {
+		priority := victims[i].Priority
+		for ; i < len(victims) && victims[i].Priority == priority; i++ {
+			future.Add(victims[i].Resreq)
+		}
+
+		if preemptor.Resreq.LessEqual(future) {
+			planned := fewest(preemptor, node.Releasing, victims[:i])
+			return &victimPlan{
+				priority: priority,
+				count:    len(planned),
+				victims:  interchangeable(planned, victims[:i]),
+			}
+		}
+	}
+
+	return nil
+}
+
+// plan returns the victims of the least disruptive plans for preemptor, each
+// on a single node; so minimizing the priority inversion comes first, then
+// the number of victims. It's false if evicting all victims fits preemptor
+// on no node.
+func (vs *victimSelector) plan(preemptor *api.TaskInfo, skipped map[api.TaskID]bool) (map[api.TaskID]bool, bool) {
+	victims := map[string][]*api.TaskInfo{}
+	for _, task := range vs.candidates {
+		if task.Status != api.Running || task.Job == preemptor.Job || skipped[task.UID] {
+			continue
+		}
+		victims[task.NodeName] = append(victims[task.NodeName], task)
+	}
+
+	var best *victimPlan
+	var plans []*victimPlan
+	for name, tasks := range victims {
+		node, found := vs.ssn.NodeIndex[name]
+		if !found {
+			continue
+		}
+
+		p := nodePlan(preemptor, node, tasks)
+		if p == nil {
+			continue
+		}
+
+		if best == nil || p.less(best) {
+			best = p
+			plans = nil
+		}
+		if !best.less(p) {
+			plans = append(plans, p)
+		}
+	}
+
+	if best == nil {
+		return nil, false
+	}
+
+	planned := map[api.TaskID]bool{}
+	for _, p := range plans {
+		for _, victim := range p.victims {
+			planned[victim.UID] = true
+		}
+	}
+
+	return planned, true
+}
+
+// selectVictim returns the best running task for preemptor, except the ones
+// of preemptor's job, the skipped ones, and the ones that free nothing that
+// preemptor lacks; it's nil if none. If some node fits preemptor by evicting
+// victims, only the victims of the least disruptive plans are selected; so
+// the victims are clustered on one node instead of being scattered, as the
+// plan of the node of the first victim needs fewer evictions than others
+// once it's evicted.
+func (vs *victimSelector) selectVictim(preemptor *api.TaskInfo, skipped map[api.TaskID]bool) *api.TaskInfo {
+	var victim *api.TaskInfo
+
+	plannedVictims, planned := vs.plan(preemptor, skipped)
+
+	releasable := vs.releasable(preemptor)
+	for _, task := range vs.candidates {
+		// The candidates that are preempted in session are releasing.
+		if task.Status != api.Running || task.Job == preemptor.Job || skipped[task.UID] {
+			continue
+		}
+
+		if planned && !plannedVictims[task.UID] {
+			continue
+		}
+
+		if !vs.frees(preemptor, task, releasable) {
+			continue
+		}
+
+		if victim == nil || vs.better(preemptor, task, victim) {
+			victim = task
+		}
+	}
+
+	return victim
+}
+
+// promised returns the resource on node that the re-homed victims are
+// expected to take; the victims that are running again after discarding
+// the statement are not counted.
+func (vs *victimSelector) promised(node *api.NodeInfo) *api.Resource {
+	res := api.EmptyResource()
+	for _, task := range vs.homed[node.Name] {
+		if task.Status == api.Releasing {
+			res.Add(task.Resreq)
+		}
+	}
+	return res
+}
+
+// home returns the node other than victim's own that is able to host the
+// recreated victim by its idle resource, excluding the part promised to other
+// re-homed victims; it's nil if none.
+func (vs *victimSelector) home(victim *api.TaskInfo) *api.NodeInfo {
+	for _, node := range vs.ssn.Nodes {
+		if node.Name == victim.NodeName {
+			continue
+		}
+
+		idle := node.Idle.Clone()
+		idle.Sub(api.Min(vs.promised(node), idle))
+		if !victim.Resreq.LessEqual(idle) {
+			continue
+		}
+
+		if err := vs.ssn.PredicateFn(victim, node); err != nil {
+			continue
+		}
+
+		return node
+	}
+
+	return nil
+}
+
+// hosts returns true if preemptor fits node once its releasing resource is
+// freed, i.e. in its idle and releasing resource excluding the part promised
+// to re-homed victims.
+func (vs *victimSelector) hosts(node *api.NodeInfo, preemptor *api.TaskInfo) bool {
+	future := node.Idle.Clone().Add(node.Releasing)
+	future.Sub(api.Min(vs.promised(node), future))
+	if !preemptor.Resreq.LessEqual(future) {
+		return false
+	}
+
+	return vs.ssn.PredicateFn(preemptor, node) == nil
+}
+
+// rehome records that victim is expected to be recreated on node.
+func (vs *victimSelector) rehome(victim *api.TaskInfo, node *api.NodeInfo) {
+	vs.homed[node.Name] = append(vs.homed[node.Name], victim)
+}
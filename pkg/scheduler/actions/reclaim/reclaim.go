@@ -0,0 +1,331 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reclaim
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// maxExactVictims is the most running tasks of a job to search the best
+// combination of victims exactly; victims are selected greedily if more.
+const maxExactVictims = 16
+
+type reclaimAction struct {
+	ssn *framework.Session
+}
+
+func New() *reclaimAction {
+	return &reclaimAction{}
+}
+
+func (ra *reclaimAction) Name() string {
+	return "reclaim"
+}
+
+func (ra *reclaimAction) Initialize() {}
+
+// Execute reclaims the resource that jobs use over their deserved share for
+// the pending tasks of other jobs that allocate can not place; the victims
+// are on the node where the pending task could run, and they're the
+// combination of running tasks preemptable by it that covers what it lacks
+// there with the least over-reclamation, the victim order of plugins breaks
+// the tie. The plugins, e.g. namespace, decide the reclaimable resource of
+// jobs instead if any.
+func (ra *reclaimAction) Execute(ssn *framework.Session) {
+	glog.V(3).Infof("Enter Reclaim ...")
+	defer glog.V(3).Infof("Leaving Reclaim ...")
+
+	if len(ssn.Jobs) == 0 {
+		return
+	}
+
+	total := api.EmptyResource()
+	for _, node := range ssn.Nodes {
+		total.Add(node.Allocatable)
+	}
+
+	deserved := total.Clone().Multi(1 / float64(len(ssn.Jobs)))
+
+	// The reclaimers that victims are evicted for, so they're not reclaimed
+	// for again from other jobs.
+	reclaimed := map[api.TaskID]bool{}
+
+	for _, job := range ssn.Jobs {
+		if ssn.Cancelled() {
+			glog.V(3).Infof("Session %v is cancelled, stop reclaiming", ssn.ID)
+			break
+		}
+
+		if !job.Preemptable() {
+			continue
+		}
+
+		excess, found := ssn.Reclaimable(job)
+		if !found {
+			excess = job.Allocated.Clone().Sub(api.Min(job.Allocated, deserved))
+		}
+		if excess.IsEmpty() {
+			continue
+		}
+
+		// Keep MinAvailable tasks of job running.
+		maxVictims := readyTaskNum(job) - job.MinAvailable
+		if maxVictims <= 0 {
+			continue
+		}
+
+		reclaimer := pendingReclaimer(ssn, job, reclaimed)
+		if reclaimer == nil {
+			continue
+		}
+
+		node, victims := selectNode(ssn, job, reclaimer, excess, total, maxVictims)
+		if node == nil {
+			continue
+		}
+		reclaimed[reclaimer.UID] = true
+
+		glog.V(3).Infof("Reclaim <%v> from Job <%v:%v/%v> by %d tasks on node <%v> for Task <%v:%v/%v>",
+			excess, job.UID, job.Namespace, job.Name, len(victims), node.Name,
+			reclaimer.UID, reclaimer.Namespace, reclaimer.Name)
+
+		stmt := ssn.Statement()
+		reason := fmt.Sprintf("Reclaimed from Job <%v/%v> over its deserved share", job.Namespace, job.Name)
+		for _, victim := range victims {
+			if err := stmt.Evict(victim, reason); err != nil {
+				glog.Errorf("Failed to reclaim task <%v/%v>: %v",
+					victim.Namespace, victim.Name, err)
+			}
+		}
+		stmt.Commit()
+	}
+}
+
+// pendingReclaimer returns the first pending task in order of the other valid
+// jobs that allocate can not place, i.e. no node has enough idle resource for
+// it, nor releasing resource to pipeline it; the tasks in reclaimed are
+// skipped. It's nil if there's none, so no resource is reclaimed while the
+// free resource would fit the pending tasks.
+func pendingReclaimer(ssn *framework.Session, job *api.JobInfo, reclaimed map[api.TaskID]bool) *api.TaskInfo {
+	var pending []*api.TaskInfo
+	for _, other := range ssn.Jobs {
+		if other.UID == job.UID || !ssn.JobValid(other) {
+			continue
+		}
+		for _, task := range other.TaskStatusIndex[api.Pending] {
+			if !reclaimed[task.UID] && ssn.TaskValid(task) {
+				pending = append(pending, task)
+			}
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return ssn.TaskOrderFn(pending[i], pending[j])
+	})
+
+	for _, task := range pending {
+		if node := freeNode(ssn, task); node != nil {
+			glog.V(3).Infof("Task <%v:%v/%v> fits free resource of node <%v>, no reclaim for it",
+				task.UID, task.Namespace, task.Name, node.Name)
+			continue
+		}
+		return task
+	}
+
+	return nil
+}
+
+// freeNode returns the node whose idle and releasing resource is enough for
+// task, so it's allocated or pipelined there without reclaiming.
+func freeNode(ssn *framework.Session, task *api.TaskInfo) *api.NodeInfo {
+	for _, node := range ssn.Nodes {
+		if !task.Resreq.LessEqual(node.IdleFor(task.Job).Clone().Add(node.Releasing)) {
+			continue
+		}
+
+		if err := ssn.PredicateFn(task, node); err != nil {
+			continue
+		}
+
+		return node
+	}
+
+	return nil
+}
+
+// selectNode returns the node where reclaimer could run and the victims of
+// job there: the running tasks preemptable by reclaimer that cover what it
+// lacks on node and exceed it the least. The node is nil if no node has such
+// victims, e.g. what reclaimer lacks on every node is more than excess of job.
+func selectNode(ssn *framework.Session, job *api.JobInfo, reclaimer *api.TaskInfo,
+	excess, total *api.Resource, maxVictims int) (*api.NodeInfo, []*api.TaskInfo) {
+	var best *api.NodeInfo
+	var bestVictims []*api.TaskInfo
+	bestOver := 0.0
+
+	for _, node := range ssn.Nodes {
+		if err := ssn.PredicateFn(reclaimer, node); err != nil {
+			continue
+		}
+
+		free := node.IdleFor(reclaimer.Job).Clone().Add(node.Releasing)
+		lacking := reclaimer.Resreq.Clone()
+		lacking.Sub(api.Min(lacking, free))
+		if !lacking.LessEqual(excess) {
+			continue
+		}
+
+		var candidates []*api.TaskInfo
+		for _, task := range job.TaskStatusIndex[api.Running] {
+			if task.NodeName != node.Name {
+				continue
+			}
+			if !ssn.Preemptable(reclaimer, task) {
+				glog.V(3).Infof("Can not reclaim task <%v:%v/%v> for task <%v:%v/%v>",
+					task.UID, task.Namespace, task.Name,
+					reclaimer.UID, reclaimer.Namespace, reclaimer.Name)
+				continue
+			}
+			candidates = append(candidates, task)
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		// The better victims by plugins, e.g. the ones on spot nodes, are
+		// tried first, then the tasks that go last in job, if the same.
+		sort.Slice(candidates, func(i, j int) bool {
+			if v := ssn.VictimOrderFn(reclaimer, candidates[i], candidates[j]); v != 0 {
+				return v < 0
+			}
+			return !ssn.TaskOrderFn(candidates[i], candidates[j])
+		})
+
+		victims := selectVictims(candidates, lacking, total, maxVictims)
+		if len(victims) == 0 {
+			continue
+		}
+
+		res := api.EmptyResource()
+		for _, victim := range victims {
+			res.Add(victim.Resreq)
+		}
+		// The victims of the least over-reclamation, the fewer victims if
+		// the same, and then the better first victim by plugins.
+		over := size(res, total) - size(lacking, total)
+		if best == nil || over < bestOver ||
+			(over == bestOver && len(victims) < len(bestVictims)) ||
+			(over == bestOver && len(victims) == len(bestVictims) &&
+				ssn.VictimOrderFn(reclaimer, victims[0], bestVictims[0]) < 0) {
+			best, bestVictims, bestOver = node, victims, over
+		}
+	}
+
+	return best, bestVictims
+}
+
+func readyTaskNum(job *api.JobInfo) int {
+	occupied := 0
+	for status, tasks := range job.TaskStatusIndex {
+		if api.AllocatedStatus(status) {
+			occupied += len(tasks)
+		}
+	}
+	return occupied
+}
+
+// size returns the sum of the shares of r in total by resource, to compare
+// resources of different shapes.
+func size(r, total *api.Resource) float64 {
+	res := 0.0
+	for _, rn := range api.ResourceNames() {
+		if total.Get(rn) != 0 {
+			res += r.Get(rn) / total.Get(rn)
+		}
+	}
+	return res
+}
+
+// selectVictims returns the combination of at most maxVictims candidates
+// whose total request covers excess and exceeds it the least, fewer victims
+// if the same, and the earlier candidates if still the same; it's nil if no
+// combination covers excess.
+func selectVictims(candidates []*api.TaskInfo, excess, total *api.Resource, maxVictims int) []*api.TaskInfo {
+	if len(candidates) > maxExactVictims {
+		return selectVictimsGreedily(candidates, excess, total, maxVictims)
+	}
+
+	var best []*api.TaskInfo
+	bestOver := 0.0
+
+	for set := 1; set < 1<<uint(len(candidates)); set++ {
+		var victims []*api.TaskInfo
+		reclaimed := api.EmptyResource()
+		for i, task := range candidates {
+			if set&(1<<uint(i)) != 0 {
+				victims = append(victims, task)
+				reclaimed.Add(task.Resreq)
+			}
+		}
+
+		if len(victims) > maxVictims || !excess.LessEqual(reclaimed) {
+			continue
+		}
+
+		over := size(reclaimed, total) - size(excess, total)
+		if best == nil || over < bestOver || (over == bestOver && len(victims) < len(best)) {
+			best = victims
+			bestOver = over
+		}
+	}
+
+	return best
+}
+
+// selectVictimsGreedily adds the smallest candidates until excess is covered.
+func selectVictimsGreedily(candidates []*api.TaskInfo, excess, total *api.Resource, maxVictims int) []*api.TaskInfo {
+	remaining := append([]*api.TaskInfo{}, candidates...)
+
+	var victims []*api.TaskInfo
+	reclaimed := api.EmptyResource()
+	for len(victims) < maxVictims && len(remaining) != 0 && !excess.LessEqual(reclaimed) {
+		smallest := 0
+		for i, task := range remaining {
+			if size(task.Resreq, total) < size(remaining[smallest].Resreq, total) {
+				smallest = i
+			}
+		}
+
+		victims = append(victims, remaining[smallest])
+		reclaimed.Add(remaining[smallest].Resreq)
+		remaining = append(remaining[:smallest], remaining[smallest+1:]...)
+	}
+
+	if !excess.LessEqual(reclaimed) {
+		return nil
+	}
+
+	return victims
+}
+
+func (ra *reclaimAction) UnInitialize() {}
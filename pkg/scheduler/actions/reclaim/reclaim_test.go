@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reclaim
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+// fakeReclaimPlugin rejects the nodes in rejected for the pending tasks, and
+// protects the tasks named "protected*" from preemption.
+type fakeReclaimPlugin struct {
+	rejected map[string]bool
+}
+
+func (fp *fakeReclaimPlugin) Name() string {
+	return "fake"
+}
+
+func (fp *fakeReclaimPlugin) OnSessionOpen(ssn *framework.Session) {
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		if task.Status == api.Pending && fp.rejected[node.Name] {
+			return fmt.Errorf("node <%v> is rejected", node.Name)
+		}
+		return nil
+	})
+	ssn.AddPreemptableFn(func(l, r interface{}) bool {
+		return !strings.HasPrefix(r.(*api.TaskInfo).Name, "protected")
+	})
+}
+
+func (fp *fakeReclaimPlugin) OnSessionClose(ssn *framework.Session) {}
+
+func TestReclaim(t *testing.T) {
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	tests := []struct {
+		name     string
+		nodes    []*v1.Node
+		pods     []*v1.Pod
+		rejected map[string]bool
+		expected []string
+	}{
+		{
+			// The job of owner1 uses 3 of 4 CPUs, 1 CPU over its deserved
+			// share, and the pending task lacks 1 CPU; evicting the two
+			// small tasks reclaims it exactly, while the large one reclaims
+			// 1 CPU more.
+			name:  "least over-reclamation",
+			nodes: []*v1.Node{testutil.BuildNode("n1", testutil.BuildResourceList("4", "100Gi"))},
+			pods: []*v1.Pod{
+				testutil.BuildPod("c1", "large", "n1", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), owner1),
+				testutil.BuildPod("c1", "small1", "n1", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner1),
+				testutil.BuildPod("c1", "small2", "n1", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner1),
+				testutil.BuildPod("c2", "pending", "", v1.PodPending, testutil.BuildResourceList("2", "1Gi"), owner2),
+			},
+			expected: []string{"c1/small1", "c1/small2"},
+		},
+		{
+			name:  "no reclaim if the pending task fits idle resource",
+			nodes: []*v1.Node{testutil.BuildNode("n1", testutil.BuildResourceList("4", "100Gi"))},
+			pods: []*v1.Pod{
+				testutil.BuildPod("c1", "large", "n1", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), owner1),
+				testutil.BuildPod("c1", "small1", "n1", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner1),
+				testutil.BuildPod("c1", "small2", "n1", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner1),
+				testutil.BuildPod("c2", "pending", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+			},
+		},
+		{
+			name:  "the victims are not protected",
+			nodes: []*v1.Node{testutil.BuildNode("n1", testutil.BuildResourceList("4", "100Gi"))},
+			pods: []*v1.Pod{
+				testutil.BuildPod("c1", "large", "n1", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), owner1),
+				testutil.BuildPod("c1", "small1", "n1", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner1),
+				testutil.BuildPod("c1", "protected", "n1", v1.PodRunning, testutil.BuildResourceList("500m", "1Gi"), owner1),
+				testutil.BuildPod("c2", "pending", "", v1.PodPending, testutil.BuildResourceList("2", "1Gi"), owner2),
+			},
+			expected: []string{"c1/large"},
+		},
+		{
+			// The pending task fits the idle resource of n1, and the small
+			// task there covers the excess exactly, but the pending task can
+			// not run there.
+			name: "the victims are on the node where the pending task could run",
+			nodes: []*v1.Node{
+				testutil.BuildNode("n1", testutil.BuildResourceList("2", "100Gi")),
+				testutil.BuildNode("n2", testutil.BuildResourceList("2", "100Gi")),
+			},
+			pods: []*v1.Pod{
+				testutil.BuildPod("c1", "small", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+				testutil.BuildPod("c1", "large", "n2", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), owner1),
+				testutil.BuildPod("c2", "pending", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+			},
+			rejected: map[string]bool{"n1": true},
+			expected: []string{"c1/large"},
+		},
+	}
+
+	for _, test := range tests {
+		framework.RegisterPluginBuilder("fake", func(framework.Arguments) framework.Plugin {
+			return &fakeReclaimPlugin{rejected: test.rejected}
+		})
+
+		evictor := &testutil.FakeEvictor{}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: evictor,
+		}
+		for _, node := range test.nodes {
+			schedulerCache.AddNode(node)
+		}
+		for _, pod := range test.pods {
+			schedulerCache.AddPod(pod)
+		}
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner2))
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: "fake"}})
+		New().Execute(ssn)
+
+		var releasing []string
+		for _, task := range ssn.JobIndex["owner1"].TaskStatusIndex[api.Releasing] {
+			releasing = append(releasing, task.Namespace+"/"+task.Name)
+		}
+		sort.Strings(releasing)
+		framework.CloseSession(ssn)
+		framework.CleanupPluginBuilders()
+
+		if !reflect.DeepEqual(test.expected, releasing) {
+			t.Errorf("case %s: expected victims %v, got %v", test.name, test.expected, releasing)
+		}
+
+		for i := 0; i < 30 && len(evictor.Evicted()) < len(test.expected); i++ {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if got := evictor.Evicted(); len(got) != len(test.expected) || (len(got) != 0 && !reflect.DeepEqual(test.expected, got)) {
+			t.Errorf("case %s: expected evicts %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
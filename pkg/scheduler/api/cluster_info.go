@@ -19,9 +19,15 @@ import "fmt"
 
 // ClusterInfo is a snapshot of cluster by cache.
 type ClusterInfo struct {
+	// Iteration is the sequence number of the snapshot, it's increased by
+	// one for each snapshot of the cache.
+	Iteration int64
+
 	Jobs []*JobInfo
 
 	Nodes []*NodeInfo
+
+	Quotas []*QuotaInfo
 }
 
 func (ci ClusterInfo) String() string {
@@ -20,6 +20,8 @@ import (
 	"fmt"
 
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 	clientcache "k8s.io/client-go/tools/cache"
 )
 
@@ -50,6 +52,10 @@ func getTaskStatus(pod *v1.Pod) TaskStatus {
 		}
 		return Bound
 	case v1.PodUnknown:
+		if pod.DeletionTimestamp != nil {
+			return Releasing
+		}
+
 		return Unknown
 	case v1.PodSucceeded:
 		return Succeeded
@@ -60,6 +66,61 @@ func getTaskStatus(pod *v1.Pod) TaskStatus {
 	return Unknown
 }
 
+// getPodQOS returns the QoS class of pod by the cpu and memory requests and
+// limits of its containers, like kubelet: BestEffort if none is set,
+// Guaranteed if all containers limit both with requests equal to limits,
+// and Burstable otherwise.
+func getPodQOS(pod *v1.Pod) v1.PodQOSClass {
+	containers := append([]v1.Container{}, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+
+	qosResources := []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}
+
+	bestEffort := true
+	guaranteed := true
+	for _, c := range containers {
+		for _, rn := range qosResources {
+			req, hasReq := c.Resources.Requests[rn]
+			limit, hasLimit := c.Resources.Limits[rn]
+
+			if (hasReq && !req.IsZero()) || (hasLimit && !limit.IsZero()) {
+				bestEffort = false
+			}
+
+			// The request is the limit if not set.
+			if !hasLimit || limit.IsZero() || (hasReq && req.Cmp(limit) != 0) {
+				guaranteed = false
+			}
+		}
+	}
+
+	if bestEffort {
+		return v1.PodQOSBestEffort
+	}
+	if guaranteed {
+		return v1.PodQOSGuaranteed
+	}
+	return v1.PodQOSBurstable
+}
+
+// qosRank returns the rank of QoS class, the lower is evicted first.
+func qosRank(qos v1.PodQOSClass) int {
+	switch qos {
+	case v1.PodQOSBestEffort:
+		return 0
+	case v1.PodQOSBurstable:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// CompareQoS compares the QoS classes of tasks; it's negative if l is of
+// lower QoS class than r, e.g. BestEffort vs. Burstable.
+func CompareQoS(l, r *TaskInfo) int {
+	return qosRank(l.QoSClass) - qosRank(r.QoSClass)
+}
+
 func AllocatedStatus(status TaskStatus) bool {
 	switch status {
 	case Bound, Binding, Running, Allocated:
@@ -68,3 +129,57 @@ func AllocatedStatus(status TaskStatus) bool {
 		return false
 	}
 }
+
+// TerminatedStatus returns whether the task of status is terminated; it
+// neither occupies node resource nor waits to be scheduled.
+func TerminatedStatus(status TaskStatus) bool {
+	return status == Succeeded || status == Failed
+}
+
+var nodeSelectorOperators = map[v1.NodeSelectorOperator]selection.Operator{
+	v1.NodeSelectorOpIn:           selection.In,
+	v1.NodeSelectorOpNotIn:        selection.NotIn,
+	v1.NodeSelectorOpExists:       selection.Exists,
+	v1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	v1.NodeSelectorOpGt:           selection.GreaterThan,
+	v1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+// MatchNodeSelectorTerms returns true if the node labels match any of the
+// terms; the requirements in a term are ANDed.
+func MatchNodeSelectorTerms(terms []v1.NodeSelectorTerm, nodeLabels map[string]string) bool {
+	for _, term := range terms {
+		if len(term.MatchExpressions) == 0 {
+			continue
+		}
+
+		selector, err := nodeSelectorRequirementsAsSelector(term.MatchExpressions)
+		if err != nil {
+			continue
+		}
+
+		if selector.Matches(labels.Set(nodeLabels)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nodeSelectorRequirementsAsSelector(nsm []v1.NodeSelectorRequirement) (labels.Selector, error) {
+	selector := labels.NewSelector()
+	for _, expr := range nsm {
+		op, found := nodeSelectorOperators[expr.Operator]
+		if !found {
+			return nil, fmt.Errorf("%q is not a valid node selector operator", expr.Operator)
+		}
+
+		r, err := labels.NewRequirement(expr.Key, op, expr.Values)
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*r)
+	}
+
+	return selector, nil
+}
@@ -17,10 +17,17 @@ limitations under the License.
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
 
 	"k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/utils"
@@ -40,9 +47,19 @@ type TaskInfo struct {
 
 	NodeName string
 	Status   TaskStatus
+
+	// NominatedNodeName is the node that preemption released resource on
+	// for the task, so it's tried first.
+	NominatedNodeName string
+
 	Priority int32
 
+	// QoSClass is the QoS class of the pod, by its requests and limits.
+	QoSClass v1.PodQOSClass
+
 	Pod *v1.Pod
+
+	equivalenceKey string
 }
 
 func NewTaskInfo(pod *v1.Pod) *TaskInfo {
@@ -60,7 +77,11 @@ func NewTaskInfo(pod *v1.Pod) *TaskInfo {
 		Namespace: pod.Namespace,
 		NodeName:  pod.Spec.NodeName,
 		Status:    getTaskStatus(pod),
-		Priority:  1,
+
+		NominatedNodeName: pod.Status.NominatedNodeName,
+
+		Priority: 1,
+		QoSClass: getPodQOS(pod),
 
 		Pod:    pod,
 		Resreq: req,
@@ -70,9 +91,75 @@ func NewTaskInfo(pod *v1.Pod) *TaskInfo {
 		pi.Priority = *pod.Spec.Priority
 	}
 
+	pi.equivalenceKey = equivalenceKey(pi)
+
 	return pi
 }
 
+// DefaultRequest sets the request of task to req if the task requests
+// nothing, e.g. a BestEffort pod, so it consumes the capacity that the
+// scheduler tracks; the pod itself is unchanged.
+func (pi *TaskInfo) DefaultRequest(req *Resource) {
+	if req == nil || !pi.Resreq.IsEmpty() {
+		return
+	}
+	pi.Resreq = req.Clone()
+	pi.equivalenceKey = equivalenceKey(pi)
+}
+
+// equivalenceKey returns the hash of what predicates may check of task, or
+// "" if task is not interchangeable with others, e.g. the pods using PVCs
+// are bound to the nodes of their own volumes.
+func equivalenceKey(ti *TaskInfo) string {
+	pod := ti.Pod
+	owner := utils.GetController(pod)
+	if len(owner) == 0 {
+		return ""
+	}
+	for _, vol := range pod.Spec.Volumes {
+		if vol.PersistentVolumeClaim != nil {
+			return ""
+		}
+	}
+
+	data, err := json.Marshal(struct {
+		Namespace    string
+		Owner        types.UID
+		Resreq       *Resource
+		Priority     int32
+		QoSClass     v1.PodQOSClass
+		NodeSelector map[string]string
+		Affinity     *v1.Affinity
+		Tolerations  []v1.Toleration
+	}{
+		Namespace:    ti.Namespace,
+		Owner:        owner,
+		Resreq:       ti.Resreq,
+		Priority:     ti.Priority,
+		QoSClass:     ti.QoSClass,
+		NodeSelector: pod.Spec.NodeSelector,
+		Affinity:     pod.Spec.Affinity,
+		Tolerations:  pod.Spec.Tolerations,
+	})
+	if err != nil {
+		glog.Errorf("Failed to get equivalence key of Task <%v/%v>: %v", ti.Namespace, ti.Name, err)
+		return ""
+	}
+
+	h := fnv.New64a()
+	h.Write(data)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// EquivalenceKey returns the key of the equivalence class of task: the
+// tasks of the same key, e.g. the identical replicas of a job, are
+// interchangeable for predicates, so the result of one is the result of
+// all. It's "" if task has no equivalent, e.g. it's not owned by a
+// controller.
+func (pi *TaskInfo) EquivalenceKey() string {
+	return pi.equivalenceKey
+}
+
 func (pi *TaskInfo) Clone() *TaskInfo {
 	return &TaskInfo{
 		UID:       pi.UID,
@@ -81,9 +168,15 @@ func (pi *TaskInfo) Clone() *TaskInfo {
 		Namespace: pi.Namespace,
 		NodeName:  pi.NodeName,
 		Status:    pi.Status,
-		Priority:  pi.Priority,
-		Pod:       pi.Pod,
-		Resreq:    pi.Resreq.Clone(),
+
+		NominatedNodeName: pi.NominatedNodeName,
+
+		Priority: pi.Priority,
+		QoSClass: pi.QoSClass,
+		Pod:      pi.Pod,
+		Resreq:   pi.Resreq.Clone(),
+
+		equivalenceKey: pi.equivalenceKey,
 	}
 }
 
@@ -108,6 +201,10 @@ type JobInfo struct {
 	NodeSelector map[string]string
 	MinAvailable int
 
+	// MinResources is the least resource that the job needs to start; it's
+	// nil if not specified.
+	MinResources *Resource
+
 	// All tasks of the Job.
 	TaskStatusIndex map[TaskStatus]tasksMap
 	Tasks           tasksMap
@@ -118,6 +215,10 @@ type JobInfo struct {
 	// Candidate hosts for this job.
 	Candidates []*NodeInfo
 
+	// CreationTimestamp is the time when the job's SchedulingSpec, or PDB
+	// if no SchedulingSpec, was created.
+	CreationTimestamp metav1.Time
+
 	SchedSpec *arbv1.SchedulingSpec
 
 	// TODO(k82cn): keep backward compatbility, removed it when v1alpha1 finalized.
@@ -143,6 +244,12 @@ func (ps *JobInfo) SetSchedulingSpec(spec *arbv1.SchedulingSpec) {
 	ps.Name = spec.Name
 	ps.Namespace = spec.Namespace
 	ps.MinAvailable = spec.Spec.MinAvailable
+	ps.CreationTimestamp = spec.CreationTimestamp
+
+	ps.MinResources = nil
+	if len(spec.Spec.MinResources) != 0 {
+		ps.MinResources = NewResource(spec.Spec.MinResources)
+	}
 
 	for k, v := range spec.Spec.NodeSelector {
 		ps.NodeSelector[k] = v
@@ -154,10 +261,168 @@ func (ps *JobInfo) SetSchedulingSpec(spec *arbv1.SchedulingSpec) {
 func (ps *JobInfo) SetPDB(pbd *policyv1.PodDisruptionBudget) {
 	ps.Name = pbd.Name
 	ps.MinAvailable = int(pbd.Spec.MinAvailable.IntVal)
+	if ps.SchedSpec == nil {
+		ps.CreationTimestamp = pbd.CreationTimestamp
+	}
 
 	ps.PDB = pbd
 }
 
+// Annotations returns the annotations of job's SchedulingSpec, or PDB if no SchedulingSpec.
+func (ps *JobInfo) Annotations() map[string]string {
+	if ps.SchedSpec != nil {
+		return ps.SchedSpec.Annotations
+	}
+
+	if ps.PDB != nil {
+		return ps.PDB.Annotations
+	}
+
+	return nil
+}
+
+// Labels returns the labels of job's SchedulingSpec, or PDB if no
+// SchedulingSpec.
+func (ps *JobInfo) Labels() map[string]string {
+	if ps.SchedSpec != nil {
+		return ps.SchedSpec.Labels
+	}
+
+	if ps.PDB != nil {
+		return ps.PDB.Labels
+	}
+
+	return nil
+}
+
+// Preemptable returns false if job's tasks are protected from preemption
+// by the preemptable annotation.
+func (ps *JobInfo) Preemptable() bool {
+	v, found := ps.Annotations()[arbv1.PreemptableAnnotationKey]
+	if !found {
+		return true
+	}
+
+	preemptable, err := strconv.ParseBool(v)
+	if err != nil {
+		glog.Warningf("Invalid preemptable annotation <%v> of Job <%v:%v/%v>: %v",
+			v, ps.UID, ps.Namespace, ps.Name, err)
+		return true
+	}
+
+	return preemptable
+}
+
+// Paused returns true if job's pending tasks are kept from scheduling by the
+// paused annotation.
+func (ps *JobInfo) Paused() bool {
+	v, found := ps.Annotations()[arbv1.PausedAnnotationKey]
+	if !found {
+		return false
+	}
+
+	paused, err := strconv.ParseBool(v)
+	if err != nil {
+		glog.Warningf("Invalid paused annotation <%v> of Job <%v:%v/%v>: %v",
+			v, ps.UID, ps.Namespace, ps.Name, err)
+		return false
+	}
+
+	return paused
+}
+
+// Deadline returns the time that job is due by the deadline annotation; it's
+// zero if not set or invalid.
+func (ps *JobInfo) Deadline() time.Time {
+	v, found := ps.Annotations()[arbv1.DeadlineAnnotationKey]
+	if !found {
+		return time.Time{}
+	}
+
+	deadline, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		glog.Warningf("Invalid deadline annotation <%v> of Job <%v:%v/%v>: %v",
+			v, ps.UID, ps.Namespace, ps.Name, err)
+		return time.Time{}
+	}
+
+	return deadline
+}
+
+// Weight returns the weight of job in fair sharing by the weight annotation;
+// it's 1 if not specified or invalid.
+func (ps *JobInfo) Weight() float64 {
+	v, found := ps.Annotations()[arbv1.WeightAnnotationKey]
+	if !found {
+		return 1
+	}
+
+	weight, err := strconv.ParseFloat(v, 64)
+	if err != nil || weight <= 0 {
+		glog.Warningf("Invalid weight annotation <%v> of Job <%v:%v/%v>, use 1 instead",
+			v, ps.UID, ps.Namespace, ps.Name)
+		return 1
+	}
+
+	return weight
+}
+
+// PreemptionBudget returns the max number of victims evicted for job's tasks
+// in a session by the preemption-budget annotation; it's def if not specified
+// or invalid.
+func (ps *JobInfo) PreemptionBudget(def int) int {
+	v, found := ps.Annotations()[arbv1.PreemptionBudgetAnnotationKey]
+	if !found {
+		return def
+	}
+
+	budget, err := strconv.Atoi(v)
+	if err != nil || budget < 0 {
+		glog.Warningf("Invalid preemption budget annotation <%v> of Job <%v:%v/%v>, use %d instead",
+			v, ps.UID, ps.Namespace, ps.Name, def)
+		return def
+	}
+
+	return budget
+}
+
+// MinTasksPerNode returns the least number of tasks of job on a node by the
+// min-tasks-per-node annotation; it's 1 if not specified or invalid.
+func (ps *JobInfo) MinTasksPerNode() int {
+	v, found := ps.Annotations()[arbv1.MinTasksPerNodeAnnotationKey]
+	if !found {
+		return 1
+	}
+
+	floor, err := strconv.Atoi(v)
+	if err != nil || floor < 1 {
+		glog.Warningf("Invalid min-tasks-per-node annotation <%v> of Job <%v:%v/%v>, use 1 instead",
+			v, ps.UID, ps.Namespace, ps.Name)
+		return 1
+	}
+
+	return floor
+}
+
+// PendingSince returns the creation time of the earliest pending task of job;
+// it's zero if no pending task.
+func (ps *JobInfo) PendingSince() time.Time {
+	var since time.Time
+
+	for _, task := range ps.TaskStatusIndex[Pending] {
+		if task.Pod == nil {
+			continue
+		}
+
+		created := task.Pod.CreationTimestamp.Time
+		if since.IsZero() || created.Before(since) {
+			since = created
+		}
+	}
+
+	return since
+}
+
 func (ps *JobInfo) GetTasks(statuses ...TaskStatus) []*TaskInfo {
 	var res []*TaskInfo
 
@@ -184,19 +449,26 @@ func (ps *JobInfo) AddTaskInfo(pi *TaskInfo) {
 	ps.Tasks[pi.UID] = pi
 	ps.addTaskIndex(pi)
 
-	ps.TotalRequest.Add(pi.Resreq)
+	// The terminated task requests nothing anymore.
+	if !TerminatedStatus(pi.Status) {
+		ps.TotalRequest.Add(pi.Resreq)
+	}
 
 	if AllocatedStatus(pi.Status) {
 		ps.Allocated.Add(pi.Resreq)
 	}
 }
 
+// UpdateTaskStatus moves task to the index of status; it's refused if the
+// transition is not in TaskStatusTransitions.
 func (ps *JobInfo) UpdateTaskStatus(task *TaskInfo, status TaskStatus) error {
 	if err := validateStatusUpdate(task.Status, status); err != nil {
-		return err
+		return fmt.Errorf("failed to update status of task <%v/%v>: %v",
+			task.Namespace, task.Name, err)
 	}
 
-	// Remove the task from the task list firstly
+	// Remove the task from the task list and the index of its previous
+	// status firstly; it's only added if it was not in the job.
 	ps.DeleteTaskInfo(task)
 
 	// Update task's status to the target status
@@ -218,13 +490,19 @@ func (ps *JobInfo) deleteTaskIndex(pi *TaskInfo) {
 
 func (ps *JobInfo) DeleteTaskInfo(pi *TaskInfo) {
 	if task, found := ps.Tasks[pi.UID]; found {
-		ps.TotalRequest.Sub(task.Resreq)
+		if !TerminatedStatus(task.Status) {
+			ps.TotalRequest.Sub(task.Resreq)
+		}
 
 		if AllocatedStatus(task.Status) {
 			ps.Allocated.Sub(task.Resreq)
 		}
 
 		delete(ps.Tasks, pi.UID)
+
+		// The task is indexed by the status it was added with, which is
+		// not the status of pi if pi is another copy of it.
+		ps.deleteTaskIndex(task)
 	}
 
 	ps.deleteTaskIndex(pi)
@@ -235,20 +513,32 @@ func (ps *JobInfo) Clone() *JobInfo {
 		UID:       ps.UID,
 		Name:      ps.Name,
 		Namespace: ps.Namespace,
+		Priority:  ps.Priority,
 
 		MinAvailable: ps.MinAvailable,
 		NodeSelector: map[string]string{},
-		Allocated:    ps.Allocated.Clone(),
-		TotalRequest: ps.TotalRequest.Clone(),
+
+		CreationTimestamp: ps.CreationTimestamp,
+
+		// Allocated and TotalRequest are re-calculated when adding tasks.
+		Allocated:    EmptyResource(),
+		TotalRequest: EmptyResource(),
 
 		TaskStatusIndex: map[TaskStatus]tasksMap{},
 		Tasks:           tasksMap{},
+
+		SchedSpec: ps.SchedSpec,
+		PDB:       ps.PDB,
 	}
 
 	for k, v := range ps.NodeSelector {
 		info.NodeSelector[k] = v
 	}
 
+	if ps.MinResources != nil {
+		info.MinResources = ps.MinResources.Clone()
+	}
+
 	for _, task := range ps.Tasks {
 		info.AddTaskInfo(task.Clone())
 	}
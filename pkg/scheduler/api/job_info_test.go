@@ -32,6 +32,26 @@ func jobInfoEqual(l, r *JobInfo) bool {
 	return true
 }
 
+func TestCloneJobInfo(t *testing.T) {
+	owner := buildOwnerReference("owner1")
+	job := NewJobInfo("owner1")
+	for _, pod := range []*v1.Pod{
+		buildPod("c1", "p1", "", v1.PodPending, buildResourceList("1000m", "1G"), []metav1.OwnerReference{owner}, make(map[string]string)),
+		buildPod("c1", "p2", "n1", v1.PodRunning, buildResourceList("2000m", "2G"), []metav1.OwnerReference{owner}, make(map[string]string)),
+	} {
+		job.AddTaskInfo(NewTaskInfo(pod))
+	}
+
+	// The tasks added to the clone are counted once.
+	clone := job.Clone()
+	if !reflect.DeepEqual(buildResource("2000m", "2G"), clone.Allocated) {
+		t.Errorf("expected allocated %v of clone, got %v", buildResource("2000m", "2G"), clone.Allocated)
+	}
+	if !reflect.DeepEqual(buildResource("3000m", "3G"), clone.TotalRequest) {
+		t.Errorf("expected total request %v of clone, got %v", buildResource("3000m", "3G"), clone.TotalRequest)
+	}
+}
+
 func TestAddTaskInfo(t *testing.T) {
 	// case1
 	case01_uid := JobID("uid")
@@ -192,3 +212,137 @@ func TestDeleteTaskInfo(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateTaskStatus(t *testing.T) {
+	owner := buildOwnerReference("uid")
+	pod := buildPod("c1", "p1", "", v1.PodPending, buildResourceList("1000m", "1G"), []metav1.OwnerReference{owner}, make(map[string]string))
+	task := NewTaskInfo(pod)
+
+	job := NewJobInfo(JobID("uid"))
+	job.AddTaskInfo(task)
+
+	for _, status := range []TaskStatus{Allocated, Binding} {
+		if err := job.UpdateTaskStatus(task, status); err != nil {
+			t.Fatalf("failed to update task to %v: %v", status, err)
+		}
+	}
+
+	// The task is only in the index of its current status.
+	for status, tasks := range job.TaskStatusIndex {
+		if _, found := tasks[task.UID]; found && status != Binding {
+			t.Errorf("expected task not in index of %v", status)
+		}
+	}
+	if _, found := job.TaskStatusIndex[Binding][task.UID]; !found {
+		t.Errorf("expected task in index of %v", Binding)
+	}
+
+	if err := job.UpdateTaskStatus(task, Pending); err == nil {
+		t.Errorf("expected update from %v to %v refused", Binding, Pending)
+	}
+	if task.Status != Binding {
+		t.Errorf("expected task kept %v, got %v", Binding, task.Status)
+	}
+	if _, found := job.TaskStatusIndex[Pending]; found {
+		t.Errorf("expected no task in index of %v", Pending)
+	}
+}
+
+func TestUpdateTaskStatusIndex(t *testing.T) {
+	owner := buildOwnerReference("uid")
+
+	job := NewJobInfo(JobID("uid"))
+
+	var tasks []*TaskInfo
+	for _, name := range []string{"p1", "p2", "p3"} {
+		pod := buildPod("c1", name, "", v1.PodPending, buildResourceList("1000m", "1G"), []metav1.OwnerReference{owner}, make(map[string]string))
+		task := NewTaskInfo(pod)
+		job.AddTaskInfo(task)
+		tasks = append(tasks, task)
+	}
+
+	// A task that was not in the job is added.
+	pod := buildPod("c1", "p4", "", v1.PodPending, buildResourceList("1000m", "1G"), []metav1.OwnerReference{owner}, make(map[string]string))
+	unindexed := NewTaskInfo(pod)
+	tasks = append(tasks, unindexed)
+
+	transitions := []struct {
+		task   *TaskInfo
+		status TaskStatus
+	}{
+		{tasks[0], Allocated},
+		{tasks[0], Binding},
+		{tasks[1], Pipelined},
+		{tasks[1], Pending},
+		{tasks[2], Binding},
+		{tasks[2], Running},
+		{tasks[2], Releasing},
+		{unindexed, Allocated},
+	}
+
+	for i, tr := range transitions {
+		if err := job.UpdateTaskStatus(tr.task, tr.status); err != nil {
+			t.Fatalf("transition %d: %v", i, err)
+		}
+	}
+
+	// An updated copy of the task is moved from the index of the status it
+	// was added with.
+	clone := tasks[0].Clone()
+	clone.Status = Bound
+	if err := job.UpdateTaskStatus(clone, Running); err != nil {
+		t.Fatalf("failed to update copy of task: %v", err)
+	}
+
+	expected := map[TaskID]TaskStatus{
+		tasks[0].UID:  Running,
+		tasks[1].UID:  Pending,
+		tasks[2].UID:  Releasing,
+		unindexed.UID: Allocated,
+	}
+
+	indexed := map[TaskID]TaskStatus{}
+	for status, ts := range job.TaskStatusIndex {
+		for uid := range ts {
+			if prev, found := indexed[uid]; found {
+				t.Errorf("task %v is in index of both %v and %v", uid, prev, status)
+			}
+			indexed[uid] = status
+		}
+	}
+
+	if !reflect.DeepEqual(expected, indexed) {
+		t.Errorf("expected index %v, got %v", expected, indexed)
+	}
+	if len(job.Tasks) != len(expected) {
+		t.Errorf("expected %d tasks, got %d", len(expected), len(job.Tasks))
+	}
+}
+
+func TestTaskEquivalenceKey(t *testing.T) {
+	owner := buildOwnerReference("owner1")
+	owners := []metav1.OwnerReference{owner}
+
+	pod1 := buildPod("c1", "p1", "", v1.PodPending, buildResourceList("1000m", "1G"), owners, map[string]string{})
+	pod2 := buildPod("c1", "p2", "", v1.PodPending, buildResourceList("1000m", "1G"), owners, map[string]string{})
+	bigger := buildPod("c1", "p3", "", v1.PodPending, buildResourceList("2000m", "1G"), owners, map[string]string{})
+	selected := buildPod("c1", "p4", "", v1.PodPending, buildResourceList("1000m", "1G"), owners, map[string]string{})
+	selected.Spec.NodeSelector = map[string]string{"zone": "z1"}
+	orphan := buildPod("c1", "p5", "", v1.PodPending, buildResourceList("1000m", "1G"), nil, map[string]string{})
+
+	key := NewTaskInfo(pod1).EquivalenceKey()
+	if len(key) == 0 {
+		t.Fatalf("expected equivalence key of replica, got none")
+	}
+	if got := NewTaskInfo(pod2).EquivalenceKey(); got != key {
+		t.Errorf("expected identical replicas of the same key %v, got %v", key, got)
+	}
+	for _, pod := range []*v1.Pod{bigger, selected} {
+		if got := NewTaskInfo(pod).EquivalenceKey(); got == key {
+			t.Errorf("expected different key of %v/%v, got the same %v", pod.Namespace, pod.Name, got)
+		}
+	}
+	if got := NewTaskInfo(orphan).EquivalenceKey(); len(got) != 0 {
+		t.Errorf("expected no key of the pod without controller, got %v", got)
+	}
+}
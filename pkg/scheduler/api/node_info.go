@@ -17,9 +17,14 @@ limitations under the License.
 package api
 
 import (
+	"strings"
+
 	"github.com/golang/glog"
 
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
 )
 
 // NodeInfo is node level aggregated information.
@@ -35,10 +40,36 @@ type NodeInfo struct {
 	// pods
 	Used *Resource
 
+	// Allocatable is the resource for scheduling, i.e. the allocatable of
+	// node except the reservations by annotations; Capability is the whole
+	// capacity of node.
 	Allocatable *Resource
 	Capability  *Resource
 
+	// ExclusiveJob is the job that takes the whole node, so tasks of other
+	// jobs can not be placed on it; it's empty if none.
+	ExclusiveJob JobID
+
+	// Draining is true if the node has the drain annotation or is drained
+	// by SetDrained, so no task is placed onto it, and its tasks are evicted
+	// by the drain action.
+	Draining bool
+
+	// Reserved is the resource reserved for the tasks of each job by
+	// Reservations; the part not taken by the tasks of the job yet is not
+	// in Idle, see ReservedIdle.
+	Reserved map[JobID]*Resource
+
 	Tasks map[TaskID]*TaskInfo
+
+	// drained is true if the node is drained by SetDrained, regardless of
+	// its annotation.
+	drained bool
+
+	// overcommitted is true if the occupied resource is beyond allocatable,
+	// e.g. by a reservation below used, so Idle is clamped to zero and is
+	// rebuilt instead of added back when a task is removed.
+	overcommitted bool
 }
 
 func NewNodeInfo(node *v1.Node) *NodeInfo {
@@ -60,12 +91,14 @@ func NewNodeInfo(node *v1.Node) *NodeInfo {
 		Node: node,
 
 		Releasing: EmptyResource(),
-		Idle:      NewResource(node.Status.Allocatable),
+		Idle:      nodeAllocatable(node),
 		Used:      EmptyResource(),
 
-		Allocatable: NewResource(node.Status.Allocatable),
+		Allocatable: nodeAllocatable(node),
 		Capability:  NewResource(node.Status.Capacity),
 
+		Draining: nodeDraining(node),
+
 		Tasks: make(map[TaskID]*TaskInfo),
 	}
 }
@@ -77,6 +110,14 @@ func (ni *NodeInfo) Clone() *NodeInfo {
 		pods[PodKey(p.Pod)] = p.Clone()
 	}
 
+	var reserved map[JobID]*Resource
+	if ni.Reserved != nil {
+		reserved = make(map[JobID]*Resource, len(ni.Reserved))
+		for job, res := range ni.Reserved {
+			reserved[job] = res.Clone()
+		}
+	}
+
 	return &NodeInfo{
 		Name:        ni.Name,
 		Node:        ni.Node,
@@ -86,28 +127,161 @@ func (ni *NodeInfo) Clone() *NodeInfo {
 		Allocatable: ni.Allocatable.Clone(),
 		Capability:  ni.Capability.Clone(),
 
+		ExclusiveJob: ni.ExclusiveJob,
+		Draining:     ni.Draining,
+		Reserved:     reserved,
+
 		Tasks: pods,
+
+		drained:       ni.drained,
+		overcommitted: ni.overcommitted,
+	}
+}
+
+// nodeReserved returns the resource reserved by the annotations of node; the
+// invalid ones are ignored.
+func nodeReserved(node *v1.Node) *Resource {
+	rl := v1.ResourceList{}
+	for key, value := range node.Annotations {
+		if !strings.HasPrefix(key, arbv1.ReservedAnnotationPrefix) {
+			continue
+		}
+
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			glog.Warningf("Invalid reservation <%v: %v> of Node <%v>: %v", key, value, node.Name, err)
+			continue
+		}
+		rl[v1.ResourceName(strings.TrimPrefix(key, arbv1.ReservedAnnotationPrefix))] = q
 	}
+
+	return NewResource(rl)
+}
+
+// nodeDraining returns true if node has the drain annotation.
+func nodeDraining(node *v1.Node) bool {
+	return node.Annotations[arbv1.DrainAnnotationKey] == "true"
+}
+
+// nodeAllocatable returns the resource of node for scheduling, i.e. its
+// allocatable except the reservations.
+func nodeAllocatable(node *v1.Node) *Resource {
+	allocatable := NewResource(node.Status.Allocatable)
+	return allocatable.Sub(Min(nodeReserved(node), allocatable))
 }
 
 func (ni *NodeInfo) SetNode(node *v1.Node) {
 	if ni.Node == nil {
-		ni.Idle = NewResource(node.Status.Allocatable)
-
 		for _, task := range ni.Tasks {
 			if task.Status == Releasing {
 				ni.Releasing.Add(task.Resreq)
 			}
 
-			ni.Idle.Sub(task.Resreq)
 			ni.Used.Add(task.Resreq)
 		}
 	}
 
 	ni.Name = node.Name
 	ni.Node = node
-	ni.Allocatable = NewResource(node.Status.Allocatable)
+	ni.Allocatable = nodeAllocatable(node)
 	ni.Capability = NewResource(node.Status.Capacity)
+	ni.Draining = ni.drained || nodeDraining(node)
+
+	// Idle is rebuilt as allocatable may change, e.g. by reservations.
+	ni.rebuildIdle()
+}
+
+// SetDrained drains the node, or stops draining it if drained is false and
+// the node has no drain annotation.
+func (ni *NodeInfo) SetDrained(drained bool) {
+	ni.drained = drained
+	ni.Draining = drained || (ni.Node != nil && nodeDraining(ni.Node))
+}
+
+// SetReserved sets the resource reserved for the tasks of each job on node.
+func (ni *NodeInfo) SetReserved(reserved map[JobID]*Resource) {
+	if len(reserved) == 0 {
+		reserved = nil
+	}
+	ni.Reserved = reserved
+
+	if ni.Node != nil {
+		ni.rebuildIdle()
+	}
+}
+
+// Reserve reserves req for job on node in addition to what's reserved and
+// taken by job; so req is not in Idle until a task of job takes it, e.g. the
+// resource released for a nominated preemptor.
+func (ni *NodeInfo) Reserve(job JobID, req *Resource) {
+	if req.IsEmpty() {
+		return
+	}
+
+	taken := EmptyResource()
+	for _, task := range ni.Tasks {
+		if task.Job == job && task.Status != Pipelined {
+			taken.Add(task.Resreq)
+		}
+	}
+
+	reserved := taken
+	if r, found := ni.Reserved[job]; found {
+		reserved.SetMaxResource(r)
+	}
+
+	if ni.Reserved == nil {
+		ni.Reserved = map[JobID]*Resource{}
+	}
+	ni.Reserved[job] = reserved.Add(req)
+
+	if ni.Node != nil {
+		ni.rebuildIdle()
+	}
+}
+
+// rebuildIdle sets Idle to allocatable except the occupied and the reserved
+// resource; the node overcommitted by reservations has no idle resource.
+func (ni *NodeInfo) rebuildIdle() {
+	occupied := EmptyResource()
+	for _, task := range ni.Tasks {
+		if task.Status != Pipelined {
+			occupied.Add(task.Resreq)
+		}
+	}
+	for job := range ni.Reserved {
+		occupied.Add(ni.ReservedIdle(job))
+	}
+	ni.overcommitted = !occupied.LessEqual(ni.Allocatable)
+	ni.Idle = ni.Allocatable.Clone()
+	ni.Idle.Sub(Min(occupied, ni.Idle))
+}
+
+// ReservedIdle returns the resource reserved for job on node that is not
+// taken by the tasks of job yet.
+func (ni *NodeInfo) ReservedIdle(job JobID) *Resource {
+	reserved, found := ni.Reserved[job]
+	if !found {
+		return EmptyResource()
+	}
+
+	taken := EmptyResource()
+	for _, task := range ni.Tasks {
+		if task.Job == job && task.Status != Pipelined {
+			taken.Add(task.Resreq)
+		}
+	}
+
+	return reserved.Clone().Sub(Min(taken, reserved))
+}
+
+// IdleFor returns the idle resource of node for the tasks of job, i.e. Idle
+// and the resource reserved for job.
+func (ni *NodeInfo) IdleFor(job JobID) *Resource {
+	if _, found := ni.Reserved[job]; !found {
+		return ni.Idle
+	}
+	return ni.Idle.Clone().Add(ni.ReservedIdle(job))
 }
 
 func (ni *NodeInfo) PipelineTask(task *TaskInfo) {
@@ -134,11 +308,22 @@ func (ni *NodeInfo) AddTask(task *TaskInfo) {
 		return
 	}
 
+	if TerminatedStatus(task.Status) {
+		glog.V(4).Infof("Task <%v> on Node <%v> is terminated, ignore it.", key, ni.Name)
+		return
+	}
+
 	if ni.Node != nil {
 		if task.Status == Releasing {
 			ni.Releasing.Add(task.Resreq)
 		}
-		ni.Idle.Sub(task.Resreq)
+		// The task takes the resource reserved for its job first; the node
+		// overcommitted has no idle resource, as in rebuildIdle.
+		ni.Idle.Add(Min(task.Resreq, ni.ReservedIdle(task.Job)))
+		if !task.Resreq.LessEqual(ni.Idle) {
+			ni.overcommitted = true
+		}
+		ni.Idle.Sub(Min(task.Resreq, ni.Idle))
 		ni.Used.Add(task.Resreq)
 	}
 
@@ -157,16 +342,31 @@ func (ni *NodeInfo) RemoveTask(ti *TaskInfo) {
 	}
 
 	if ni.Node != nil {
-		if task.Status == Releasing {
+		switch task.Status {
+		case Pipelined:
+			// Pipelined task only occupies releasing resource, see PipelineTask.
+			ni.Releasing.Add(task.Resreq)
+		case Releasing:
 			ni.Releasing.Sub(task.Resreq)
+			ni.Idle.Add(task.Resreq)
+		default:
+			ni.Idle.Add(task.Resreq)
 		}
-
-		ni.Idle.Add(task.Resreq)
 		ni.Used.Sub(task.Resreq)
 	}
 
+	// The resource reserved for the job of task goes back to reservation.
+	reserved := ni.ReservedIdle(task.Job)
+	delete(ni.Tasks, key)
+	if ni.Node != nil && task.Status != Pipelined {
+		if ni.overcommitted {
+			// The clamped Idle does not tell how much is freed.
+			ni.rebuildIdle()
+		} else {
+			ni.Idle.Sub(ni.ReservedIdle(task.Job).Sub(reserved))
+		}
+	}
+
 	glog.V(3).Infof("After removed Task <%v> from Node <%v>: idle <%v>, used <%v>, releasing <%v>",
 		key, ni.Name, ni.Idle, ni.Used, ni.Releasing)
-
-	delete(ni.Tasks, key)
 }
@@ -22,6 +22,8 @@ import (
 
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
 )
 
 func nodeInfoEqual(l, r *NodeInfo) bool {
@@ -133,3 +135,152 @@ func TestNodeInfo_RemovePod(t *testing.T) {
 		}
 	}
 }
+
+func TestNodeInfo_RemovePipelinedTask(t *testing.T) {
+	node := buildNode("n1", buildResourceList("8000m", "10G"))
+	releasing := buildPod("c1", "p1", "n1", v1.PodRunning, buildResourceList("2000m", "2G"), []metav1.OwnerReference{}, make(map[string]string))
+	now := metav1.Now()
+	releasing.DeletionTimestamp = &now
+	pending := buildPod("c1", "p2", "", v1.PodPending, buildResourceList("1000m", "1G"), []metav1.OwnerReference{}, make(map[string]string))
+
+	ni := NewNodeInfo(node)
+	ni.AddTask(NewTaskInfo(releasing))
+	expected := ni.Clone()
+
+	task := NewTaskInfo(pending)
+	task.Status = Pipelined
+	ni.PipelineTask(task)
+
+	if !reflect.DeepEqual(ni.Releasing, buildResource("1000m", "1G")) {
+		t.Errorf("expected releasing %v after pipelined, got %v", buildResource("1000m", "1G"), ni.Releasing)
+	}
+
+	ni.RemoveTask(task)
+
+	if !nodeInfoEqual(ni, expected) {
+		t.Errorf("node info: \n expected %v, \n got %v \n", expected, ni)
+	}
+}
+
+func TestNodeInfo_Reserved(t *testing.T) {
+	node := buildNode("n1", buildResourceList("8000m", "10G"))
+	node.Annotations = map[string]string{
+		arbv1.ReservedAnnotationPrefix + "cpu": "2",
+	}
+
+	ni := NewNodeInfo(node)
+	if got := ni.Allocatable.MilliCPU; got != 6000 {
+		t.Errorf("expected 6000 milli CPU allocatable, got %v", got)
+	}
+	if got := ni.Idle.MilliCPU; got != 6000 {
+		t.Errorf("expected 6000 milli CPU idle, got %v", got)
+	}
+	if got := ni.Capability.MilliCPU; got != 8000 {
+		t.Errorf("expected 8000 milli CPU capability, got %v", got)
+	}
+	if got, expected := ni.Allocatable.Memory, float64(10*1000*1000*1000); got != expected {
+		t.Errorf("expected memory not reserved %v, got %v", expected, got)
+	}
+
+	// The reservation is raised on the node of a running task.
+	pod := buildPod("c1", "p1", "n1", v1.PodRunning, buildResourceList("1000m", "1G"), []metav1.OwnerReference{}, make(map[string]string))
+	ni.AddTask(NewTaskInfo(pod))
+
+	updated := buildNode("n1", buildResourceList("8000m", "10G"))
+	updated.Annotations = map[string]string{
+		arbv1.ReservedAnnotationPrefix + "cpu":    "4",
+		arbv1.ReservedAnnotationPrefix + "memory": "invalid",
+	}
+	ni.SetNode(updated)
+
+	if got := ni.Allocatable.MilliCPU; got != 4000 {
+		t.Errorf("expected 4000 milli CPU allocatable after update, got %v", got)
+	}
+	if got := ni.Idle.MilliCPU; got != 3000 {
+		t.Errorf("expected 3000 milli CPU idle after update, got %v", got)
+	}
+	if got, expected := ni.Idle.Memory, float64(9*1000*1000*1000); got != expected {
+		t.Errorf("expected invalid reservation of memory ignored, got idle %v", got)
+	}
+}
+
+func TestNodeInfo_ReservedBelowUsed(t *testing.T) {
+	ni := NewNodeInfo(buildNode("n1", buildResourceList("4000m", "10G")))
+
+	p1 := NewTaskInfo(buildPod("c1", "p1", "n1", v1.PodRunning, buildResourceList("3000m", "1G"), []metav1.OwnerReference{}, make(map[string]string)))
+	ni.AddTask(p1)
+
+	// The reservation leaves less allocatable than used.
+	updated := buildNode("n1", buildResourceList("4000m", "10G"))
+	updated.Annotations = map[string]string{
+		arbv1.ReservedAnnotationPrefix + "cpu": "2",
+	}
+	ni.SetNode(updated)
+
+	check := func(step string, idle float64) {
+		if got := ni.Idle.MilliCPU; got != idle {
+			t.Errorf("%s: expected %v milli CPU idle, got %v", step, idle, got)
+		}
+	}
+	check("reserved below used", 0)
+
+	// The pods keep coming to the overcommitted node, e.g. by kubelet.
+	p2 := NewTaskInfo(buildPod("c1", "p2", "n1", v1.PodRunning, buildResourceList("1000m", "1G"), []metav1.OwnerReference{}, make(map[string]string)))
+	ni.AddTask(p2)
+	check("task added to overcommitted node", 0)
+	if got := ni.Used.MilliCPU; got != 4000 {
+		t.Errorf("expected 4000 milli CPU used, got %v", got)
+	}
+
+	ni.RemoveTask(p1)
+	check("overcommitting task removed", 1000)
+
+	ni.RemoveTask(p2)
+	check("all tasks removed", 2000)
+}
+
+func TestNodeInfo_ReservedForJob(t *testing.T) {
+	node := buildNode("n1", buildResourceList("8000m", "10G"))
+	owner1 := []metav1.OwnerReference{buildOwnerReference("owner1")}
+	owner2 := []metav1.OwnerReference{buildOwnerReference("owner2")}
+
+	ni := NewNodeInfo(node)
+	ni.SetReserved(map[JobID]*Resource{"owner1": buildResource("2000m", "2G")})
+
+	cpu := func(r *Resource) float64 { return r.MilliCPU }
+	check := func(step string, idle, reserved float64) {
+		if got := cpu(ni.Idle); got != idle {
+			t.Errorf("%s: expected %v milli CPU idle, got %v", step, idle, got)
+		}
+		if got := cpu(ni.ReservedIdle("owner1")); got != reserved {
+			t.Errorf("%s: expected %v milli CPU reserved for owner1, got %v", step, reserved, got)
+		}
+		if got := cpu(ni.IdleFor("owner1")); got != idle+reserved {
+			t.Errorf("%s: expected %v milli CPU idle for owner1, got %v", step, idle+reserved, got)
+		}
+	}
+	check("reserved", 6000, 2000)
+
+	// The tasks of other jobs take the idle resource.
+	other := NewTaskInfo(buildPod("c1", "p1", "n1", v1.PodRunning, buildResourceList("1000m", "1G"), owner2, make(map[string]string)))
+	ni.AddTask(other)
+	check("other job added", 5000, 2000)
+
+	// The tasks of the job take the reservation first.
+	small := NewTaskInfo(buildPod("c1", "p2", "n1", v1.PodRunning, buildResourceList("1000m", "1G"), owner1, make(map[string]string)))
+	ni.AddTask(small)
+	check("reserved job added", 5000, 1000)
+
+	large := NewTaskInfo(buildPod("c1", "p3", "n1", v1.PodRunning, buildResourceList("3000m", "1G"), owner1, make(map[string]string)))
+	ni.AddTask(large)
+	check("reserved job beyond reservation", 3000, 0)
+
+	ni.RemoveTask(small)
+	check("reserved job removed", 4000, 0)
+
+	ni.RemoveTask(large)
+	check("reserved job all removed", 5000, 2000)
+
+	ni.SetReserved(nil)
+	check("reservation deleted", 7000, 0)
+}
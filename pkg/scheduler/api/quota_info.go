@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+// QuotaInfo is the hard limits of a ResourceQuota on the resource requested
+// by the pods in its namespace.
+type QuotaInfo struct {
+	Namespace string
+	Name      string
+
+	// Hard is the limits on the requests of pods, and Names are the
+	// resources limited by the quota; the others are not limited even
+	// if they're zero in Hard.
+	Hard  *Resource
+	Names []v1.ResourceName
+
+	Quota *v1.ResourceQuota
+}
+
+// quotaResourceName returns the resource name of pod's requests limited by
+// the hard quota of name, e.g. "cpu" and "requests.cpu" for CPU; it's empty
+// for the quotas not on requests, e.g. "limits.cpu" or "pods".
+func quotaResourceName(name v1.ResourceName) v1.ResourceName {
+	rn := v1.ResourceName(strings.TrimPrefix(string(name), "requests."))
+	switch rn {
+	case v1.ResourceCPU, v1.ResourceMemory, GPUResourceName:
+		return rn
+	}
+	if IsScalarResourceName(rn) {
+		return rn
+	}
+	return ""
+}
+
+func NewQuotaInfo(quota *v1.ResourceQuota) *QuotaInfo {
+	qi := &QuotaInfo{
+		Namespace: quota.Namespace,
+		Name:      quota.Name,
+		Quota:     quota,
+	}
+
+	hard := v1.ResourceList{}
+	for name, quant := range quota.Spec.Hard {
+		rn := quotaResourceName(name)
+		if len(rn) == 0 {
+			continue
+		}
+
+		// The stricter one wins if both "cpu" and "requests.cpu" are set.
+		if old, found := hard[rn]; found && old.Cmp(quant) <= 0 {
+			continue
+		}
+		if _, found := hard[rn]; !found {
+			qi.Names = append(qi.Names, rn)
+		}
+		hard[rn] = quant
+	}
+	qi.Hard = NewResource(hard)
+
+	return qi
+}
+
+func (qi *QuotaInfo) Clone() *QuotaInfo {
+	return &QuotaInfo{
+		Namespace: qi.Namespace,
+		Name:      qi.Name,
+		Hard:      qi.Hard.Clone(),
+		Names:     append([]v1.ResourceName(nil), qi.Names...),
+		Quota:     qi.Quota,
+	}
+}
+
+// Exceeded returns the names of the limited resources that used is more
+// than the hard quota of.
+func (qi *QuotaInfo) Exceeded(used *Resource) []v1.ResourceName {
+	limited := map[v1.ResourceName]bool{}
+	for _, rn := range qi.Names {
+		limited[rn] = true
+	}
+
+	var names []v1.ResourceName
+	for _, rn := range used.Insufficient(qi.Hard) {
+		if limited[rn] {
+			names = append(names, rn)
+		}
+	}
+
+	return names
+}
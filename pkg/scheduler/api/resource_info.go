@@ -19,6 +19,8 @@ package api
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 
 	"k8s.io/api/core/v1"
 )
@@ -26,19 +28,39 @@ import (
 type Resource struct {
 	MilliCPU float64
 	Memory   float64
-	GPU      int64
+	MilliGPU float64
+
+	// ScalarResources are the other extended resources, e.g. GPU memory,
+	// huge pages and ephemeral storage in milli-value; they're divisible,
+	// so several tasks can share one, except MIG profiles.
+	ScalarResources map[v1.ResourceName]float64
 }
 
 const (
 	// need to follow https://github.com/NVIDIA/k8s-device-plugin/blob/66a35b71ac4b5cbfb04714678b548bd77e5ba719/server.go#L20
 	GPUResourceName = "nvidia.com/gpu"
+
+	// GPUMemoryResourceName is the memory of node's GPUs, which the tasks
+	// share by requesting a part of it; each GPU of node has an equal part.
+	GPUMemoryResourceName = "arbitrator.incubator.k8s.io/gpu-memory"
+
+	// MIGResourcePrefix is the prefix of the resources of NVIDIA MIG profiles,
+	// e.g. "nvidia.com/mig-1g.5gb", which are the GPU instances of a profile
+	// advertised by node; unlike other scalar resources, an instance is not
+	// shared, so tasks request them in whole numbers.
+	MIGResourcePrefix = "nvidia.com/mig-"
 )
 
+// IsMIGResourceName returns true for the resource of a MIG profile.
+func IsMIGResourceName(rn v1.ResourceName) bool {
+	return strings.HasPrefix(string(rn), MIGResourcePrefix)
+}
+
 func EmptyResource() *Resource {
 	return &Resource{
 		MilliCPU: 0,
 		Memory:   0,
-		GPU:      0,
+		MilliGPU: 0,
 	}
 }
 
@@ -46,14 +68,26 @@ func (r *Resource) Clone() *Resource {
 	clone := &Resource{
 		MilliCPU: r.MilliCPU,
 		Memory:   r.Memory,
-		GPU:      r.GPU,
+		MilliGPU: r.MilliGPU,
 	}
+
+	for rn, v := range r.ScalarResources {
+		clone.SetScalar(rn, v)
+	}
+
 	return clone
 }
 
 var minMilliCPU float64 = 10
+var minMilliGPU float64 = 10
+var minMilliScalar float64 = 10
 var minMemory float64 = 10 * 1024 * 1024
 
+// NewResource converts the resource list, e.g. the requests of pod, to
+// Resource: CPU and GPU in milli-value, memory in bytes, and scalars in
+// milli-value. The values are integers from the quantities, rounded up as
+// kubelet does, so they're exact in float64 and the sums of requests are
+// compared to allocatable without rounding errors.
 func NewResource(rl v1.ResourceList) *Resource {
 	r := EmptyResource()
 	for rName, rQuant := range rl {
@@ -63,15 +97,55 @@ func NewResource(rl v1.ResourceList) *Resource {
 		case v1.ResourceMemory:
 			r.Memory += float64(rQuant.Value())
 		case GPUResourceName:
-			q, _ := rQuant.AsInt64()
-			r.GPU += q
+			// GPU is requested in whole numbers, as Kubernetes requires
+			// for extended resources; the tasks share GPUs by requesting
+			// GPUMemoryResourceName instead, see the gpushare plugin.
+			r.MilliGPU += float64(rQuant.MilliValue())
+		default:
+			if IsScalarResourceName(rName) {
+				r.AddScalar(rName, float64(rQuant.MilliValue()))
+			}
 		}
 	}
 	return r
 }
 
+// IsScalarResourceName returns true for huge pages, e.g. "hugepages-2Mi",
+// ephemeral storage, and the extended resources, which are fully-qualified
+// and not in the default "kubernetes.io/" namespace.
+func IsScalarResourceName(rn v1.ResourceName) bool {
+	name := string(rn)
+	if rn == v1.ResourceEphemeralStorage || strings.HasPrefix(name, v1.ResourceHugePagesPrefix) {
+		return true
+	}
+	return strings.Contains(name, "/") && !strings.HasPrefix(name, v1.ResourceDefaultNamespacePrefix)
+}
+
+// AddScalar adds the milli-value of scalar resource.
+func (r *Resource) AddScalar(rn v1.ResourceName, v float64) {
+	r.SetScalar(rn, r.ScalarResources[rn]+v)
+}
+
+// SetScalar sets the milli-value of scalar resource.
+func (r *Resource) SetScalar(rn v1.ResourceName, v float64) {
+	if r.ScalarResources == nil {
+		r.ScalarResources = map[v1.ResourceName]float64{}
+	}
+	r.ScalarResources[rn] = v
+}
+
 func (r *Resource) IsEmpty() bool {
-	return r.MilliCPU < minMilliCPU && r.Memory < minMemory && r.GPU == 0
+	if r.MilliCPU >= minMilliCPU || r.Memory >= minMemory || r.MilliGPU >= minMilliGPU {
+		return false
+	}
+
+	for _, v := range r.ScalarResources {
+		if v >= minMilliScalar {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (r *Resource) IsZero(rn v1.ResourceName) bool {
@@ -81,16 +155,24 @@ func (r *Resource) IsZero(rn v1.ResourceName) bool {
 	case v1.ResourceMemory:
 		return r.Memory < minMemory
 	case GPUResourceName:
-		return r.GPU == 0
+		return r.MilliGPU < minMilliGPU
 	default:
-		panic("unknown resource")
+		if !IsScalarResourceName(rn) {
+			panic("unknown resource")
+		}
+		return r.ScalarResources[rn] < minMilliScalar
 	}
 }
 
 func (r *Resource) Add(rr *Resource) *Resource {
 	r.MilliCPU += rr.MilliCPU
 	r.Memory += rr.Memory
-	r.GPU += rr.GPU
+	r.MilliGPU += rr.MilliGPU
+
+	for rn, v := range rr.ScalarResources {
+		r.AddScalar(rn, v)
+	}
+
 	return r
 }
 
@@ -99,7 +181,12 @@ func (r *Resource) Sub(rr *Resource) *Resource {
 	if rr.LessEqual(r) {
 		r.MilliCPU -= rr.MilliCPU
 		r.Memory -= rr.Memory
-		r.GPU -= rr.GPU
+		r.MilliGPU -= rr.MilliGPU
+
+		for rn, v := range rr.ScalarResources {
+			r.AddScalar(rn, -v)
+		}
+
 		return r
 	}
 
@@ -107,19 +194,144 @@ func (r *Resource) Sub(rr *Resource) *Resource {
 		r, rr))
 }
 
+// Multi multiplies each dimension of the resource by ratio.
+func (r *Resource) Multi(ratio float64) *Resource {
+	r.MilliCPU *= ratio
+	r.Memory *= ratio
+	r.MilliGPU *= ratio
+
+	for rn, v := range r.ScalarResources {
+		r.ScalarResources[rn] = v * ratio
+	}
+
+	return r
+}
+
+// SetMinResource sets each dimension of r to the minimum of r and rr; the
+// scalar resource that is not in one of them is zero.
+func (r *Resource) SetMinResource(rr *Resource) *Resource {
+	r.MilliCPU = math.Min(r.MilliCPU, rr.MilliCPU)
+	r.Memory = math.Min(r.Memory, rr.Memory)
+	r.MilliGPU = math.Min(r.MilliGPU, rr.MilliGPU)
+
+	for _, rn := range scalarResourceNames(r, rr) {
+		r.SetScalar(rn, math.Min(r.ScalarResources[rn], rr.ScalarResources[rn]))
+	}
+
+	return r
+}
+
+// SetMaxResource sets each dimension of r to the maximum of r and rr.
+func (r *Resource) SetMaxResource(rr *Resource) *Resource {
+	r.MilliCPU = math.Max(r.MilliCPU, rr.MilliCPU)
+	r.Memory = math.Max(r.Memory, rr.Memory)
+	r.MilliGPU = math.Max(r.MilliGPU, rr.MilliGPU)
+
+	for _, rn := range scalarResourceNames(r, rr) {
+		r.SetScalar(rn, math.Max(r.ScalarResources[rn], rr.ScalarResources[rn]))
+	}
+
+	return r
+}
+
+// Min returns the minimum of each dimension of l and r.
+func Min(l, r *Resource) *Resource {
+	return l.Clone().SetMinResource(r)
+}
+
+// Max returns the maximum of each dimension of l and r.
+func Max(l, r *Resource) *Resource {
+	return l.Clone().SetMaxResource(r)
+}
+
+// scalarResourceNames returns the names of scalar resources in any of rs.
+func scalarResourceNames(rs ...*Resource) []v1.ResourceName {
+	var names []v1.ResourceName
+	found := map[v1.ResourceName]bool{}
+	for _, r := range rs {
+		for rn := range r.ScalarResources {
+			if !found[rn] {
+				found[rn] = true
+				names = append(names, rn)
+			}
+		}
+	}
+
+	return names
+}
+
+// ScalarResourceNames returns the names of the scalar resources of r in
+// order.
+func (r *Resource) ScalarResourceNames() []v1.ResourceName {
+	names := scalarResourceNames(r)
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
 func (r *Resource) Less(rr *Resource) bool {
-	return r.MilliCPU < rr.MilliCPU && r.Memory < rr.Memory && r.GPU < rr.GPU
+	if !(r.MilliCPU < rr.MilliCPU && r.Memory < rr.Memory && r.MilliGPU < rr.MilliGPU) {
+		return false
+	}
+
+	for rn, v := range r.ScalarResources {
+		if v >= rr.ScalarResources[rn] {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (r *Resource) LessEqual(rr *Resource) bool {
-	return (r.MilliCPU < rr.MilliCPU || math.Abs(rr.MilliCPU-r.MilliCPU) < 0.01) &&
+	if !((r.MilliCPU < rr.MilliCPU || math.Abs(rr.MilliCPU-r.MilliCPU) < 0.01) &&
 		(r.Memory < rr.Memory || math.Abs(rr.Memory-r.Memory) < 1) &&
-		(r.GPU <= rr.GPU)
+		(r.MilliGPU < rr.MilliGPU || math.Abs(rr.MilliGPU-r.MilliGPU) < 0.01)) {
+		return false
+	}
+
+	for rn, v := range r.ScalarResources {
+		if rv := rr.ScalarResources[rn]; v > rv && math.Abs(rv-v) >= 0.01 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Insufficient returns the names of the resources that r needs more than
+// available, e.g. the dimensions that a task is short of on a node; it's
+// empty if r.LessEqual(available).
+func (r *Resource) Insufficient(available *Resource) []v1.ResourceName {
+	var names []v1.ResourceName
+
+	if r.MilliCPU > available.MilliCPU && math.Abs(available.MilliCPU-r.MilliCPU) >= 0.01 {
+		names = append(names, v1.ResourceCPU)
+	}
+	if r.Memory > available.Memory && math.Abs(available.Memory-r.Memory) >= 1 {
+		names = append(names, v1.ResourceMemory)
+	}
+	if r.MilliGPU > available.MilliGPU && math.Abs(available.MilliGPU-r.MilliGPU) >= 0.01 {
+		names = append(names, GPUResourceName)
+	}
+
+	for _, rn := range scalarResourceNames(r) {
+		if v, av := r.ScalarResources[rn], available.ScalarResources[rn]; v > av && math.Abs(av-v) >= 0.01 {
+			names = append(names, rn)
+		}
+	}
+
+	return names
 }
 
 func (r *Resource) String() string {
-	return fmt.Sprintf("cpu %0.2f, memory %0.2f, GPU %d",
-		r.MilliCPU, r.Memory, r.GPU)
+	str := fmt.Sprintf("cpu %0.2f, memory %0.2f, GPU %0.2f",
+		r.MilliCPU, r.Memory, r.MilliGPU)
+
+	for _, rn := range scalarResourceNames(r) {
+		str += fmt.Sprintf(", %v %0.2f", rn, r.ScalarResources[rn])
+	}
+
+	return str
 }
 
 func (r *Resource) Get(rn v1.ResourceName) float64 {
@@ -129,9 +341,12 @@ func (r *Resource) Get(rn v1.ResourceName) float64 {
 	case v1.ResourceMemory:
 		return r.Memory
 	case GPUResourceName:
-		return float64(r.GPU)
+		return r.MilliGPU
 	default:
-		panic("not support resource.")
+		if !IsScalarResourceName(rn) {
+			panic("not support resource.")
+		}
+		return r.ScalarResources[rn]
 	}
 }
 
@@ -0,0 +1,295 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestNewResource_Scalar(t *testing.T) {
+	idle := NewResource(v1.ResourceList{
+		GPUResourceName:       resource.MustParse("1"),
+		GPUMemoryResourceName: resource.MustParse("16"),
+	})
+	req := NewResource(v1.ResourceList{
+		GPUMemoryResourceName: resource.MustParse("6"),
+	})
+
+	if req.MilliGPU != 0 || req.ScalarResources[GPUMemoryResourceName] != 6000 {
+		t.Fatalf("expected 6000 milli gpu-memory only, got %v", req)
+	}
+
+	// Two requests fit, but the third one does not.
+	for i := 0; i < 2; i++ {
+		if !req.LessEqual(idle) {
+			t.Fatalf("expected request %d <%v> to fit <%v>", i, req, idle)
+		}
+		idle.Sub(req)
+	}
+
+	if req.LessEqual(idle) {
+		t.Errorf("expected request <%v> not to fit <%v>", req, idle)
+	}
+
+	if idle.MilliGPU != 1000 || idle.ScalarResources[GPUMemoryResourceName] != 4000 {
+		t.Errorf("expected 1000 milli GPU and 4000 milli gpu-memory idle, got %v", idle)
+	}
+}
+
+func TestNewResource_Precision(t *testing.T) {
+	tests := []struct {
+		name     v1.ResourceName
+		quantity string
+		expected float64
+		// The value under milli-CPU is rounded up.
+		roundedUp bool
+	}{
+		{name: v1.ResourceCPU, quantity: "100m", expected: 100},
+		{name: v1.ResourceCPU, quantity: "1.5", expected: 1500},
+		{name: v1.ResourceCPU, quantity: "0.0001", expected: 1, roundedUp: true},
+		{name: v1.ResourceMemory, quantity: "1536Mi", expected: 1536 * 1024 * 1024},
+		{name: v1.ResourceMemory, quantity: "1.5Gi", expected: 1.5 * 1024 * 1024 * 1024},
+		{name: v1.ResourceMemory, quantity: "1e3", expected: 1000},
+		{name: GPUResourceName, quantity: "250m", expected: 250},
+		{name: GPUResourceName, quantity: "1.5", expected: 1500},
+		{name: GPUMemoryResourceName, quantity: "1.5", expected: 1500},
+	}
+
+	for i, test := range tests {
+		q := resource.MustParse(test.quantity)
+		got := NewResource(v1.ResourceList{test.name: q}).Get(test.name)
+		if got != test.expected {
+			t.Errorf("case %d: expected %v of %v to be %v, got %v", i, test.quantity, test.name, test.expected, got)
+		}
+
+		// Back to quantity, it's the same as the original one.
+		var back *resource.Quantity
+		if test.name == v1.ResourceMemory {
+			back = resource.NewQuantity(int64(got), resource.BinarySI)
+		} else {
+			back = resource.NewMilliQuantity(int64(got), resource.DecimalSI)
+		}
+		if !test.roundedUp && back.Cmp(q) != 0 {
+			t.Errorf("case %d: expected %v of %v to be back as is, got %v", i, test.quantity, test.name, back)
+		}
+	}
+
+	// The sum of requests is exactly the total, e.g. 100m + 200m == 300m.
+	sum := NewResource(v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")})
+	sum.Add(NewResource(v1.ResourceList{v1.ResourceCPU: resource.MustParse("200m")}))
+	total := NewResource(v1.ResourceList{v1.ResourceCPU: resource.MustParse("300m")})
+	if sum.MilliCPU != total.MilliCPU {
+		t.Errorf("expected 100m + 200m to be %v milli CPU, got %v", total.MilliCPU, sum.MilliCPU)
+	}
+	if !sum.LessEqual(total) || !total.LessEqual(sum) {
+		t.Errorf("expected <%v> and <%v> to fit each other", sum, total)
+	}
+
+	// Ten tasks of 0.1 CPU fill a node of 1 CPU, and one more does not fit.
+	idle := NewResource(v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")})
+	req := NewResource(v1.ResourceList{v1.ResourceCPU: resource.MustParse("0.1")})
+	for i := 0; i < 10; i++ {
+		if !req.LessEqual(idle) {
+			t.Fatalf("expected request %d <%v> to fit <%v>", i, req, idle)
+		}
+		idle.Sub(req)
+	}
+	if idle.MilliCPU != 0 || req.LessEqual(idle) {
+		t.Errorf("expected node of 1 CPU to be full, got idle <%v>", idle)
+	}
+}
+
+func TestNewResource_HugePages(t *testing.T) {
+	hugePages2Mi := v1.ResourceName(v1.ResourceHugePagesPrefix + "2Mi")
+
+	node := NewNodeInfo(buildNode("n1", v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("4"),
+		v1.ResourceMemory: resource.MustParse("8Gi"),
+		hugePages2Mi:      resource.MustParse("4Gi"),
+	}))
+
+	if got, expected := node.Idle.ScalarResources[hugePages2Mi], float64(4*1024*1024*1024*1000); got != expected {
+		t.Fatalf("expected %v milli hugepages-2Mi idle, got %v", expected, got)
+	}
+	if got, expected := node.Idle.Memory, float64(8*1024*1024*1024); got != expected {
+		t.Errorf("expected huge pages not counted in memory %v, got %v", expected, got)
+	}
+
+	tests := []struct {
+		hugePages string
+		fit       bool
+	}{
+		{
+			hugePages: "2Gi",
+			fit:       true,
+		},
+		{
+			hugePages: "8Gi",
+			fit:       false,
+		},
+	}
+
+	for i, test := range tests {
+		req := NewResource(v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("1"),
+			v1.ResourceMemory: resource.MustParse("1Gi"),
+			hugePages2Mi:      resource.MustParse(test.hugePages),
+		})
+
+		if got := req.LessEqual(node.Idle); got != test.fit {
+			t.Errorf("case %d: expected request of %s hugepages-2Mi to fit %v, got %v",
+				i, test.hugePages, test.fit, got)
+		}
+	}
+}
+
+func TestNewResource_EphemeralStorage(t *testing.T) {
+	node := NewNodeInfo(buildNode("n1", v1.ResourceList{
+		v1.ResourceCPU:              resource.MustParse("4"),
+		v1.ResourceMemory:           resource.MustParse("8Gi"),
+		v1.ResourceEphemeralStorage: resource.MustParse("10Gi"),
+	}))
+
+	if got, expected := node.Idle.ScalarResources[v1.ResourceEphemeralStorage], float64(10*1024*1024*1024*1000); got != expected {
+		t.Fatalf("expected %v milli ephemeral-storage idle, got %v", expected, got)
+	}
+
+	tests := []struct {
+		storage string
+		fit     bool
+	}{
+		{
+			storage: "5Gi",
+			fit:     true,
+		},
+		{
+			storage: "20Gi",
+			fit:     false,
+		},
+	}
+
+	for i, test := range tests {
+		req := NewResource(v1.ResourceList{
+			v1.ResourceCPU:              resource.MustParse("1"),
+			v1.ResourceEphemeralStorage: resource.MustParse(test.storage),
+		})
+
+		if got := req.LessEqual(node.Idle); got != test.fit {
+			t.Errorf("case %d: expected request of %s ephemeral-storage to fit %v, got %v",
+				i, test.storage, test.fit, got)
+		}
+	}
+}
+
+func TestSetMinMaxResource(t *testing.T) {
+	GPUMemoryResourceName := v1.ResourceName("arbitrator.incubator.k8s.io/gpu-memory")
+	hugePages := v1.ResourceName(v1.ResourceHugePagesPrefix + "2Mi")
+
+	l := NewResource(v1.ResourceList{
+		v1.ResourceCPU:        resource.MustParse("2"),
+		v1.ResourceMemory:     resource.MustParse("1Gi"),
+		GPUMemoryResourceName: resource.MustParse("4"),
+	})
+	r := NewResource(v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("1"),
+		v1.ResourceMemory: resource.MustParse("4Gi"),
+		GPUResourceName:   resource.MustParse("1"),
+		hugePages:         resource.MustParse("1Gi"),
+	})
+
+	max := l.Clone().SetMaxResource(r)
+	expectedMax := NewResource(v1.ResourceList{
+		v1.ResourceCPU:        resource.MustParse("2"),
+		v1.ResourceMemory:     resource.MustParse("4Gi"),
+		GPUResourceName:       resource.MustParse("1"),
+		GPUMemoryResourceName: resource.MustParse("4"),
+		hugePages:             resource.MustParse("1Gi"),
+	})
+	if !reflect.DeepEqual(max, expectedMax) {
+		t.Errorf("expected max <%v>, got <%v>", expectedMax, max)
+	}
+
+	// The scalar resource in only one of them is zero in minimum.
+	min := l.Clone().SetMinResource(r)
+	expectedMin := NewResource(v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("1"),
+		v1.ResourceMemory: resource.MustParse("1Gi"),
+	})
+	expectedMin.SetScalar(GPUMemoryResourceName, 0)
+	expectedMin.SetScalar(hugePages, 0)
+	if !reflect.DeepEqual(min, expectedMin) {
+		t.Errorf("expected min <%v>, got <%v>", expectedMin, min)
+	}
+
+	// The operand is not changed.
+	if r.MilliCPU != 1000 || len(r.ScalarResources) != 1 {
+		t.Errorf("expected operand unchanged, got <%v>", r)
+	}
+}
+
+func TestInsufficient(t *testing.T) {
+	req := NewResource(v1.ResourceList{
+		v1.ResourceCPU:        resource.MustParse("1"),
+		v1.ResourceMemory:     resource.MustParse("1Gi"),
+		GPUResourceName:       resource.MustParse("1"),
+		GPUMemoryResourceName: resource.MustParse("4"),
+	})
+
+	tests := []struct {
+		name      string
+		available v1.ResourceList
+		expected  []v1.ResourceName
+	}{
+		{
+			name: "enough in all dimensions",
+			available: v1.ResourceList{
+				v1.ResourceCPU:        resource.MustParse("2"),
+				v1.ResourceMemory:     resource.MustParse("1Gi"),
+				GPUResourceName:       resource.MustParse("1"),
+				GPUMemoryResourceName: resource.MustParse("8"),
+			},
+		},
+		{
+			name: "short of GPU and GPU memory",
+			available: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("8"),
+				v1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+			expected: []v1.ResourceName{GPUResourceName, GPUMemoryResourceName},
+		},
+		{
+			name: "short of CPU",
+			available: v1.ResourceList{
+				v1.ResourceCPU:        resource.MustParse("500m"),
+				v1.ResourceMemory:     resource.MustParse("8Gi"),
+				GPUResourceName:       resource.MustParse("2"),
+				GPUMemoryResourceName: resource.MustParse("4"),
+			},
+			expected: []v1.ResourceName{v1.ResourceCPU},
+		},
+	}
+
+	for _, test := range tests {
+		if got := req.Insufficient(NewResource(test.available)); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("case %s: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
@@ -16,6 +16,10 @@ limitations under the License.
 
 package api
 
+import (
+	"fmt"
+)
+
 // TaskStatus defines the status of a task/pod.
 type TaskStatus int
 
@@ -57,6 +61,10 @@ func (ts TaskStatus) String() string {
 	switch ts {
 	case Pending:
 		return "Pending"
+	case Allocated:
+		return "Allocated"
+	case Pipelined:
+		return "Pipelined"
 	case Binding:
 		return "Binding"
 	case Bound:
@@ -74,9 +82,35 @@ func (ts TaskStatus) String() string {
 	}
 }
 
+// TaskStatusTransitions are the statuses that a task of each status can be
+// updated to by scheduler; updating to the same status is always allowed.
+// The statuses reported by apiserver, e.g. Running, are built from pods
+// instead of updated.
+var TaskStatusTransitions = map[TaskStatus][]TaskStatus{
+	// Cache binds pending tasks directly, as allocation is only in session.
+	Pending:   {Allocated, Pipelined, Binding},
+	Allocated: {Binding, Releasing},
+	Pipelined: {Pending},
+	Binding:   {Bound, Running, Releasing},
+	Bound:     {Running, Releasing},
+	Running:   {Releasing, Succeeded, Failed},
+	// Releasing tasks get back their status if the preemption is discarded.
+	Releasing: {Allocated, Binding, Bound, Running},
+}
+
 // validateStatusUpdate validates whether the status transfer is valid.
 func validateStatusUpdate(oldStatus, newStatus TaskStatus) error {
-	return nil
+	if oldStatus == newStatus {
+		return nil
+	}
+
+	for _, status := range TaskStatusTransitions[oldStatus] {
+		if status == newStatus {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("task status can not be updated from %v to %v", oldStatus, newStatus)
 }
 
 // LessFn is the func declaration used by sort or priority queue.
@@ -87,3 +121,30 @@ type CompareFn func(interface{}, interface{}) int
 
 // ValidateFn is the func declaration used to check object's status.
 type ValidateFn func(interface{}) bool
+
+// PredicateFn is the func declaration used to check whether task can be
+// placed on node; the error is the reason if not.
+type PredicateFn func(*TaskInfo, *NodeInfo) error
+
+// NodeOrderFn is the func declaration used to score node for task; the node
+// with higher score is preferred. The score may be negative, as a penalty
+// that discourages but doesn't forbid the node.
+type NodeOrderFn func(*TaskInfo, *NodeInfo) (float64, error)
+
+// PostBindFn is the func declaration called after task is bound to the
+// host by binder, i.e. the pod is really placed on the node.
+type PostBindFn func(task *TaskInfo, hostname string)
+
+// VictimOrderFn is the func declaration used to compare two victims of the
+// preemptor; it's negative if the left one is better to evict, and zero if
+// no preference. The preemptor is nil if the victims are reclaimed for no
+// particular task.
+type VictimOrderFn func(preemptor, l, r *TaskInfo) int
+
+// SessionGateFn is the func declaration used to veto the changes of session
+// to cluster, e.g. in a maintenance window; the error is the reason if vetoed.
+type SessionGateFn func() error
+
+// ReclaimableFn is the func declaration used to get the resource of job that
+// can be reclaimed for others.
+type ReclaimableFn func(job *JobInfo) *Resource
@@ -20,28 +20,73 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/glog"
 
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
 	clientv1 "k8s.io/client-go/informers/core/v1"
 	policyv1 "k8s.io/client-go/informers/policy/v1beta1"
+	storagev1 "k8s.io/client-go/informers/storage/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/reference"
+	"k8s.io/client-go/util/flowcontrol"
 
 	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/client"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/client/clientset"
 	informerfactory "github.com/kubernetes-incubator/kube-arbitrator/pkg/client/informers"
 	arbclient "github.com/kubernetes-incubator/kube-arbitrator/pkg/client/informers/v1"
 	arbapi "github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
 )
 
-// New returns a Cache implementation.
-func New(config *rest.Config, schedulerName string) Cache {
-	return newSchedulerCache(config, schedulerName)
+// Options are the options of the Cache returned by New.
+type Options struct {
+	// The pending pods of SchedulerName are scheduled by the cache.
+	SchedulerName string
+
+	// The binds and evictions are sent to the API server at most APIQPS per
+	// second with bursts of APIBurst, or without limit if APIQPS is not
+	// positive.
+	APIQPS   float32
+	APIBurst int
+
+	// The pods waiting longer than StartLatencySLO to be bound are flagged
+	// by an event, if it's positive.
+	StartLatencySLO time.Duration
+
+	// The pods that request nothing are scheduled as if they requested
+	// DefaultRequests of their namespace, or of "" if their namespace is
+	// not listed.
+	DefaultRequests map[string]v1.ResourceList
+
+	// The nominations of tasks expire after NominationTimeout, if it's
+	// positive.
+	NominationTimeout time.Duration
+}
+
+// New returns a Cache implementation configured by opts.
+func New(config *rest.Config, opts *Options) Cache {
+	sc := newSchedulerCache(config, opts.SchedulerName)
+	if opts.APIQPS > 0 {
+		sc.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(opts.APIQPS, opts.APIBurst)
+	}
+	sc.StartLatencySLO = opts.StartLatencySLO
+	sc.NominationTimeout = opts.NominationTimeout
+	if len(opts.DefaultRequests) != 0 {
+		sc.DefaultRequests = map[string]*arbapi.Resource{}
+		for ns, rl := range opts.DefaultRequests {
+			sc.DefaultRequests[ns] = arbapi.NewResource(rl)
+		}
+	}
+	return sc
 }
 
 type SchedulerCache struct {
@@ -49,16 +94,85 @@ type SchedulerCache struct {
 
 	kubeclient *kubernetes.Clientset
 
+	// The number of snapshots taken.
+	iteration int64
+
+	// The pending pods of schedulerName are scheduled by this scheduler.
+	schedulerName string
+
 	podInformer            clientv1.PodInformer
 	nodeInformer           clientv1.NodeInformer
 	pdbInformer            policyv1.PodDisruptionBudgetInformer
+	quotaInformer          clientv1.ResourceQuotaInformer
+	pvcInformer            clientv1.PersistentVolumeClaimInformer
+	pvInformer             clientv1.PersistentVolumeInformer
+	storageClassInformer   storagev1.StorageClassInformer
 	schedulingSpecInformer arbclient.SchedulingSpecInformer
-
-	Binder  Binder
-	Evictor Evictor
+	reservationInformer    arbclient.ReservationInformer
+
+	Binder        Binder
+	Evictor       Evictor
+	Recorder      EventRecorder
+	StatusUpdater StatusUpdater
+	VolumeChecker VolumeChecker
+	MetricsSource MetricsSource
+
+	// RateLimiter limits the binds and evictions sent to the API server, so
+	// the bursts of a large session don't trip the client side throttling;
+	// nil means no limit.
+	RateLimiter flowcontrol.RateLimiter
+
+	// Clock tells when the pods are pending and bound, and the time of
+	// sessions; nil means the real clock.
+	Clock Clock
+
+	// StartLatencySLO is the most time that a pod waits from pending to
+	// bound; the pod waiting longer is flagged by an event. Zero means no SLO.
+	StartLatencySLO time.Duration
+
+	// NominationTimeout is the most time that a task keeps its nominated
+	// node, on which the resource released by preemption is reserved for
+	// it, until it's bound; zero means no timeout.
+	NominationTimeout time.Duration
+
+	// DefaultRequests are the requests of the pods that request nothing, by
+	// namespace; "" is for the namespaces not listed. Nil means the pods
+	// request nothing.
+	DefaultRequests map[string]*arbapi.Resource
 
 	Jobs  map[arbapi.JobID]*arbapi.JobInfo
 	Nodes map[string]*arbapi.NodeInfo
+
+	// The ResourceQuotas by "namespace/name".
+	Quotas map[string]*arbapi.QuotaInfo
+
+	// The Reservations by "namespace/name".
+	Reservations map[string]*arbv1.Reservation
+
+	// The clones in the last snapshot, and the nodes/jobs changed since
+	// then; the clones of unchanged nodes/jobs are reused by next snapshot.
+	snapshotNodes map[string]*arbapi.NodeInfo
+	snapshotJobs  map[arbapi.JobID]*arbapi.JobInfo
+	dirtyNodes    map[string]bool
+	dirtyJobs     map[arbapi.JobID]bool
+
+	// The binds and evictions that are sent but not completed.
+	inflight sync.WaitGroup
+
+	// The states of plugins across sessions, by plugin name.
+	pluginStates map[string]interface{}
+
+	// The time that each pending task is pending since, until it's bound.
+	pendingSince map[arbapi.TaskID]time.Time
+
+	// The nominations of tasks, until they're bound or expired.
+	nominations map[arbapi.TaskID]*nomination
+}
+
+// nomination is the time that the task of job is nominated to a node since.
+type nomination struct {
+	job   arbapi.JobID
+	since time.Time
 }
 
 type defaultBinder struct {
@@ -79,14 +193,39 @@ func (db *defaultBinder) Bind(p *v1.Pod, hostname string) error {
 	return nil
 }
 
+const (
+	// PodEvictedCondition is the condition type of the pods evicted by scheduler.
+	PodEvictedCondition v1.PodConditionType = "Evicted"
+
+	// EvictedReason is the reason of the event and condition of evicted pods.
+	EvictedReason = "Evicted"
+)
+
 type defaultEvictor struct {
 	kubeclient *kubernetes.Clientset
+	recorder   EventRecorder
 }
 
-func (de *defaultEvictor) Evict(p *v1.Pod) error {
+func (de *defaultEvictor) Evict(p *v1.Pod, reason string) error {
 	// TODO (k82cn): makes grace period configurable.
 	threeSecs := int64(3)
 
+	glog.V(3).Infof("Evicting pod <%v/%v>: %v", p.Namespace, p.Name, reason)
+
+	// Record why the pod is evicted, so users know the reason of disappearing pods.
+	pod := p.DeepCopy()
+	updatePodCondition(&pod.Status, &v1.PodCondition{
+		Type:    PodEvictedCondition,
+		Status:  v1.ConditionTrue,
+		Reason:  EvictedReason,
+		Message: reason,
+	})
+	if _, err := de.kubeclient.CoreV1().Pods(pod.Namespace).UpdateStatus(pod); err != nil {
+		glog.Errorf("Failed to update condition of pod <%v/%v>: %#v", p.Namespace, p.Name, err)
+	}
+
+	de.recorder.Event(p, v1.EventTypeNormal, EvictedReason, reason)
+
 	if err := de.kubeclient.CoreV1().Pods(p.Namespace).Delete(p.Name, &metav1.DeleteOptions{
 		GracePeriodSeconds: &threeSecs,
 	}); err != nil {
@@ -96,10 +235,122 @@ func (de *defaultEvictor) Evict(p *v1.Pod) error {
 	return nil
 }
 
+// updatePodCondition adds the condition or replaces the one of the same type.
+func updatePodCondition(status *v1.PodStatus, condition *v1.PodCondition) {
+	condition.LastTransitionTime = metav1.Now()
+
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condition.Type {
+			status.Conditions[i] = *condition
+			return
+		}
+	}
+
+	status.Conditions = append(status.Conditions, *condition)
+}
+
+type defaultStatusUpdater struct {
+	kubeclient *kubernetes.Clientset
+	arbclient  *clientset.Clientset
+}
+
+func (du *defaultStatusUpdater) UpdatePodStatus(pod *v1.Pod) error {
+	if _, err := du.kubeclient.CoreV1().Pods(pod.Namespace).UpdateStatus(pod); err != nil {
+		glog.Errorf("Failed to update status of pod <%v/%v>: %#v", pod.Namespace, pod.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (du *defaultStatusUpdater) UpdateSchedulingSpecStatus(spec *arbv1.SchedulingSpec) error {
+	if _, err := du.arbclient.ArbV1().SchedulingSpecs(spec.Namespace).UpdateStatus(spec); err != nil {
+		glog.Errorf("Failed to update status of SchedulingSpec <%v/%v>: %#v", spec.Namespace, spec.Name, err)
+		return err
+	}
+	return nil
+}
+
+type defaultRecorder struct {
+	kubeclient *kubernetes.Clientset
+	component  string
+}
+
+// Event creates the event of object asynchronously on behalf of component.
+func (dr *defaultRecorder) Event(object runtime.Object, eventType, reason, message string) {
+	ref, err := reference.GetReference(scheme.Scheme, object)
+	if err != nil {
+		glog.Errorf("Failed to get reference of %#v: %v", object, err)
+		return
+	}
+
+	// The events of cluster scoped objects, e.g. Node, are in default namespace.
+	namespace := ref.Namespace
+	if len(namespace) == 0 {
+		namespace = metav1.NamespaceDefault
+	}
+
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", ref.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        message,
+		Source:         v1.EventSource{Component: dr.component},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventType,
+	}
+
+	go func() {
+		if _, err := dr.kubeclient.CoreV1().Events(namespace).Create(event); err != nil {
+			glog.Errorf("Failed to record event <%v> of %v <%v/%v>: %v",
+				reason, ref.Kind, ref.Namespace, ref.Name, err)
+		}
+	}()
+}
+
+// filterPod returns true if the pod is handled by cache: the pending pods of
+// this scheduler that are not gated, and the pods taking resource on nodes,
+// whatever scheduler they're of. The informer sends delete or add event if
+// the pod is changed across the filter, e.g. its schedulerName is updated, or
+// its scheduling gates are removed.
+func (sc *SchedulerCache) filterPod(obj interface{}) bool {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return false
+	}
+
+	switch pod.Status.Phase {
+	case v1.PodPending:
+		if len(pod.Spec.NodeName) != 0 {
+			return true
+		}
+		return pod.Spec.SchedulerName == sc.schedulerName && !gated(pod)
+	case v1.PodRunning:
+		return true
+	default:
+		return false
+	}
+}
+
+// gated returns true if the pod has scheduling gates.
+func gated(pod *v1.Pod) bool {
+	return len(strings.TrimSpace(pod.Annotations[arbv1.SchedulingGatesAnnotationKey])) != 0
+}
+
 func newSchedulerCache(config *rest.Config, schedulerName string) *SchedulerCache {
 	sc := &SchedulerCache{
-		Jobs:  make(map[arbapi.JobID]*arbapi.JobInfo),
-		Nodes: make(map[string]*arbapi.NodeInfo),
+		Jobs:   make(map[arbapi.JobID]*arbapi.JobInfo),
+		Nodes:  make(map[string]*arbapi.NodeInfo),
+		Quotas: make(map[string]*arbapi.QuotaInfo),
+
+		Reservations: make(map[string]*arbv1.Reservation),
+
+		schedulerName: schedulerName,
 	}
 
 	sc.kubeclient = kubernetes.NewForConfigOrDie(config)
@@ -108,8 +359,19 @@ func newSchedulerCache(config *rest.Config, schedulerName string) *SchedulerCach
 		kubeclient: sc.kubeclient,
 	}
 
+	sc.Recorder = &defaultRecorder{
+		kubeclient: sc.kubeclient,
+		component:  schedulerName,
+	}
+
+	sc.StatusUpdater = &defaultStatusUpdater{
+		kubeclient: sc.kubeclient,
+		arbclient:  clientset.NewForConfigOrDie(config),
+	}
+
 	sc.Evictor = &defaultEvictor{
 		kubeclient: sc.kubeclient,
+		recorder:   sc.Recorder,
 	}
 
 	informerFactory := informers.NewSharedInformerFactory(sc.kubeclient, 0)
@@ -129,18 +391,7 @@ func newSchedulerCache(config *rest.Config, schedulerName string) *SchedulerCach
 	sc.podInformer = informerFactory.Core().V1().Pods()
 	sc.podInformer.Informer().AddEventHandler(
 		cache.FilteringResourceEventHandler{
-			FilterFunc: func(obj interface{}) bool {
-				switch obj.(type) {
-				case *v1.Pod:
-					pod := obj.(*v1.Pod)
-					if strings.Compare(pod.Spec.SchedulerName, schedulerName) == 0 && pod.Status.Phase == v1.PodPending {
-						return true
-					}
-					return pod.Status.Phase == v1.PodRunning
-				default:
-					return false
-				}
-			},
+			FilterFunc: sc.filterPod,
 			Handler: cache.ResourceEventHandlerFuncs{
 				AddFunc:    sc.AddPod,
 				UpdateFunc: sc.UpdatePod,
@@ -158,6 +409,22 @@ func newSchedulerCache(config *rest.Config, schedulerName string) *SchedulerCach
 			},
 		})
 
+	sc.quotaInformer = informerFactory.Core().V1().ResourceQuotas()
+	sc.quotaInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    sc.AddResourceQuota,
+			UpdateFunc: sc.UpdateResourceQuota,
+			DeleteFunc: sc.DeleteResourceQuota,
+		})
+
+	sc.pvcInformer = informerFactory.Core().V1().PersistentVolumeClaims()
+	sc.pvInformer = informerFactory.Core().V1().PersistentVolumes()
+	sc.storageClassInformer = informerFactory.Storage().V1().StorageClasses()
+	sc.VolumeChecker = NewVolumeChecker(
+		sc.pvcInformer.Lister(),
+		sc.pvInformer.Lister(),
+		sc.storageClassInformer.Lister())
+
 	// create queue informer
 	queueClient, _, err := client.NewClient(config)
 	if err != nil {
@@ -185,6 +452,14 @@ func newSchedulerCache(config *rest.Config, schedulerName string) *SchedulerCach
 			},
 		})
 
+	sc.reservationInformer = schedulingSpecInformerFactory.Reservation().Reservations()
+	sc.reservationInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    sc.AddReservation,
+			UpdateFunc: sc.UpdateReservation,
+			DeleteFunc: sc.DeleteReservation,
+		})
+
 	return sc
 }
 
@@ -193,7 +468,12 @@ func (sc *SchedulerCache) Run(stopCh <-chan struct{}) {
 	go sc.podInformer.Informer().Run(stopCh)
 	go sc.pdbInformer.Informer().Run(stopCh)
 	go sc.nodeInformer.Informer().Run(stopCh)
+	go sc.quotaInformer.Informer().Run(stopCh)
+	go sc.pvcInformer.Informer().Run(stopCh)
+	go sc.pvInformer.Informer().Run(stopCh)
+	go sc.storageClassInformer.Informer().Run(stopCh)
 	go sc.schedulingSpecInformer.Informer().Run(stopCh)
+	go sc.reservationInformer.Informer().Run(stopCh)
 }
 
 func (sc *SchedulerCache) WaitForCacheSync(stopCh <-chan struct{}) bool {
@@ -201,6 +481,11 @@ func (sc *SchedulerCache) WaitForCacheSync(stopCh <-chan struct{}) bool {
 		sc.pdbInformer.Informer().HasSynced,
 		sc.podInformer.Informer().HasSynced,
 		sc.schedulingSpecInformer.Informer().HasSynced,
+		sc.reservationInformer.Informer().HasSynced,
+		sc.quotaInformer.Informer().HasSynced,
+		sc.pvcInformer.Informer().HasSynced,
+		sc.pvInformer.Informer().HasSynced,
+		sc.storageClassInformer.Informer().HasSynced,
 		sc.nodeInformer.Informer().HasSynced)
 }
 
@@ -220,7 +505,7 @@ func (sc *SchedulerCache) findJobAndTask(taskInfo *arbapi.TaskInfo) (*arbapi.Job
 	return job, task, nil
 }
 
-func (sc *SchedulerCache) Evict(taskInfo *arbapi.TaskInfo) error {
+func (sc *SchedulerCache) Evict(taskInfo *arbapi.TaskInfo, reason string) error {
 	sc.Mutex.Lock()
 	defer sc.Mutex.Unlock()
 
@@ -230,34 +515,91 @@ func (sc *SchedulerCache) Evict(taskInfo *arbapi.TaskInfo) error {
 		return err
 	}
 
+	return sc.evict(job, task, reason)
+}
+
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) evict(job *arbapi.JobInfo, task *arbapi.TaskInfo, reason string) error {
 	node, found := sc.Nodes[task.NodeName]
 	if !found {
 		return fmt.Errorf("failed to bind Task %v to host %v, host does not exist",
 			task.UID, task.NodeName)
 	}
 
+	sc.markJobDirty(job.UID)
+	sc.markNodeDirty(node.Name)
+
 	// Remove task from node because of eviction.
 	node.RemoveTask(task)
 
-	err = job.UpdateTaskStatus(task, arbapi.Releasing)
-	if err != nil {
-		return err
-	}
+	err := job.UpdateTaskStatus(task, arbapi.Releasing)
 
 	// Add task back to the node for releasing resources.
 	node.AddTask(task)
 
+	if err != nil {
+		return err
+	}
+
 	p := task.Pod
 
+	sc.inflight.Add(1)
 	go func() {
-		sc.Evictor.Evict(p)
+		defer sc.inflight.Done()
+		sc.throttle()
+		sc.Evictor.Evict(p, reason)
 	}()
 
 	return nil
 }
 
+func (sc *SchedulerCache) PluginState(name string) interface{} {
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	return sc.pluginStates[name]
+}
+
+func (sc *SchedulerCache) SetPluginState(name string, state interface{}) {
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	if sc.pluginStates == nil {
+		sc.pluginStates = map[string]interface{}{}
+	}
+	sc.pluginStates[name] = state
+}
+
+// DrainNode drains the node of name until UndrainNode; the tasks on it are
+// evicted by the drain action in sessions, so the evictions are accounted in
+// session, e.g. skipped in dry run.
+func (sc *SchedulerCache) DrainNode(name string) error {
+	return sc.setDrained(name, true)
+}
+
+// UndrainNode stops draining the node of name, unless it has the drain
+// annotation.
+func (sc *SchedulerCache) UndrainNode(name string) error {
+	return sc.setDrained(name, false)
+}
+
+func (sc *SchedulerCache) setDrained(name string, drained bool) error {
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	node, found := sc.Nodes[name]
+	if !found {
+		return fmt.Errorf("failed to find node <%v>", name)
+	}
+
+	node.SetDrained(drained)
+	sc.markNodeDirty(name)
+
+	return nil
+}
+
 // Bind binds task to the target host.
-func (sc *SchedulerCache) Bind(taskInfo *arbapi.TaskInfo, hostname string) error {
+func (sc *SchedulerCache) Bind(taskInfo *arbapi.TaskInfo, hostname string, bound func()) error {
 	sc.Mutex.Lock()
 	defer sc.Mutex.Unlock()
 
@@ -273,6 +615,9 @@ func (sc *SchedulerCache) Bind(taskInfo *arbapi.TaskInfo, hostname string) error
 			task.UID, hostname)
 	}
 
+	sc.markJobDirty(job.UID)
+	sc.markNodeDirty(node.Name)
+
 	err = job.UpdateTaskStatus(task, arbapi.Binding)
 	if err != nil {
 		return err
@@ -281,38 +626,474 @@ func (sc *SchedulerCache) Bind(taskInfo *arbapi.TaskInfo, hostname string) error
 	// Add task to the node.
 	node.AddTask(task)
 
+	sc.observeStartLatency(job, task)
+	delete(sc.nominations, task.UID)
+
 	p := task.Pod
 
+	sc.inflight.Add(1)
+	go func() {
+		defer sc.inflight.Done()
+		sc.throttle()
+		if err := sc.Binder.Bind(p, hostname); err != nil {
+			return
+		}
+		if bound != nil {
+			bound()
+		}
+	}()
+
+	return nil
+}
+
+// Now returns the time of the clock of cache, or the real time if no clock.
+func (sc *SchedulerCache) Now() time.Time {
+	if sc.Clock == nil {
+		return time.Now()
+	}
+	return sc.Clock.Now()
+}
+
+// StartLatencySLOReason is the reason of the event of the pods that wait
+// longer than StartLatencySLO to be bound.
+const StartLatencySLOReason = "StartLatencySLOViolated"
+
+// markPending records the time that pod of task is pending since, i.e. when
+// it's created, or when cache finds it if that's earlier, e.g. the
+// creation time is not set.
+func (sc *SchedulerCache) markPending(pod *v1.Pod, task arbapi.TaskID) {
+	if sc.pendingSince == nil {
+		sc.pendingSince = map[arbapi.TaskID]time.Time{}
+	}
+	if _, found := sc.pendingSince[task]; found {
+		return
+	}
+
+	since := sc.Now()
+	if created := pod.CreationTimestamp.Time; !created.IsZero() && created.Before(since) {
+		since = created
+	}
+	sc.pendingSince[task] = since
+}
+
+// observeStartLatency measures the time that task waited from pending to
+// bound, and flags it if longer than the SLO.
+func (sc *SchedulerCache) observeStartLatency(job *arbapi.JobInfo, task *arbapi.TaskInfo) {
+	since, found := sc.pendingSince[task.UID]
+	if !found {
+		return
+	}
+	delete(sc.pendingSince, task.UID)
+
+	latency := sc.Now().Sub(since)
+	metrics.ObservePodStartLatency(job.Namespace, latency)
+
+	if sc.StartLatencySLO > 0 && latency > sc.StartLatencySLO {
+		glog.V(3).Infof("Task <%v/%v> of Job <%v> waited %v to be bound, longer than SLO %v",
+			task.Namespace, task.Name, job.UID, latency, sc.StartLatencySLO)
+		sc.RecordEvent(task, v1.EventTypeWarning, StartLatencySLOReason,
+			fmt.Sprintf("Pod of job %v/%v waited %v to be bound, longer than SLO %v",
+				job.Namespace, job.Name, latency, sc.StartLatencySLO))
+	}
+}
+
+// throttle waits for the rate limiter before an API call of bind or evict;
+// the waits are counted in metrics.
+func (sc *SchedulerCache) throttle() {
+	if sc.RateLimiter == nil || sc.RateLimiter.TryAccept() {
+		return
+	}
+
+	start := time.Now()
+	sc.RateLimiter.Accept()
+	metrics.UpdateAPIThrottle(time.Since(start))
+}
+
+// WaitForInflight waits for the binds and evictions in flight to complete,
+// and returns false if they're not completed in timeout.
+func (sc *SchedulerCache) WaitForInflight(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		sc.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Backoff updates the PodScheduled condition of the pending tasks of job to
+// the reason, and records a warning event; nothing is done to the pods that
+// already have the same condition, so the event is recorded once when the
+// job gives up, not in every session.
+func (sc *SchedulerCache) Backoff(jobInfo *arbapi.JobInfo, reason, message string) error {
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	job, found := sc.Jobs[jobInfo.UID]
+	if !found {
+		return fmt.Errorf("failed to find Job %v", jobInfo.UID)
+	}
+
+	for _, task := range job.TaskStatusIndex[arbapi.Pending] {
+		if unschedulableCondition(task.Pod, reason, message) {
+			continue
+		}
+
+		pod := task.Pod.DeepCopy()
+		updatePodCondition(&pod.Status, &v1.PodCondition{
+			Type:    v1.PodScheduled,
+			Status:  v1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		})
+
+		if sc.Recorder != nil {
+			sc.Recorder.Event(pod, v1.EventTypeWarning, reason, message)
+		}
+
+		// The cached pod has the condition before the update comes back
+		// from informer, so the next session does not record it again.
+		task.Pod = pod
+		sc.updatePodStatus(pod)
+	}
+
+	return nil
+}
+
+// unschedulableCondition returns true if pod has the PodScheduled condition
+// of false with reason and message.
+func unschedulableCondition(pod *v1.Pod, reason, message string) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodScheduled && c.Status == v1.ConditionFalse &&
+			c.Reason == reason && c.Message == message {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordEvent records an event of task's pod; nothing is recorded if there's
+// no Recorder.
+func (sc *SchedulerCache) RecordEvent(task *arbapi.TaskInfo, eventType, reason, message string) error {
+	if task.Pod == nil {
+		return fmt.Errorf("no pod of Task %v", task.UID)
+	}
+
+	if sc.Recorder != nil {
+		sc.Recorder.Event(task.Pod, eventType, reason, message)
+	}
+
+	return nil
+}
+
+// TaskUnschedulable updates the PodScheduled condition of task's pod to
+// Unschedulable and records an event; nothing is done if the pod already
+// has the same condition, so the event is not repeated in every session.
+func (sc *SchedulerCache) TaskUnschedulable(task *arbapi.TaskInfo, message string) error {
+	if unschedulableCondition(task.Pod, v1.PodReasonUnschedulable, message) {
+		return nil
+	}
+
+	pod := task.Pod.DeepCopy()
+	updatePodCondition(&pod.Status, &v1.PodCondition{
+		Type:    v1.PodScheduled,
+		Status:  v1.ConditionFalse,
+		Reason:  v1.PodReasonUnschedulable,
+		Message: message,
+	})
+
+	if sc.Recorder != nil {
+		sc.Recorder.Event(pod, v1.EventTypeWarning, v1.PodReasonUnschedulable, message)
+	}
+
+	sc.updatePodStatus(pod)
+
+	return nil
+}
+
+// UpdateJobStatus updates the status of job's SchedulingSpec to status; the
+// transition time of each condition is kept if its status is not changed.
+// Nothing is updated if the status is the same as before, so the API server
+// is not updated in every session.
+func (sc *SchedulerCache) UpdateJobStatus(jobInfo *arbapi.JobInfo, status *arbv1.SchedulingSpecStatus) error {
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	job, found := sc.Jobs[jobInfo.UID]
+	if !found {
+		return fmt.Errorf("failed to find Job %v", jobInfo.UID)
+	}
+	if job.SchedSpec == nil {
+		return fmt.Errorf("no SchedulingSpec of Job %v", jobInfo.UID)
+	}
+
+	if schedulingSpecStatusEqual(&job.SchedSpec.Status, status) {
+		return nil
+	}
+
+	// The SchedulingSpec of informer is not changed in place.
+	spec := job.SchedSpec.DeepCopy()
+	now := metav1.NewTime(sc.Now())
+
+	conditions := make([]arbv1.SchedulingSpecCondition, 0, len(status.Conditions))
+	for _, c := range status.Conditions {
+		c.LastTransitionTime = now
+		for _, old := range spec.Status.Conditions {
+			if old.Type == c.Type && old.Status == c.Status {
+				c.LastTransitionTime = old.LastTransitionTime
+			}
+		}
+		conditions = append(conditions, c)
+	}
+
+	spec.Status.Running = status.Running
+	spec.Status.Pending = status.Pending
+	spec.Status.Conditions = conditions
+	job.SchedSpec = spec
+	sc.markJobDirty(job.UID)
+
+	if sc.StatusUpdater != nil {
+		go func() {
+			sc.StatusUpdater.UpdateSchedulingSpecStatus(spec)
+		}()
+	}
+
+	return nil
+}
+
+// schedulingSpecStatusEqual returns true if the status a and b are the same
+// except the transition time of conditions.
+func schedulingSpecStatusEqual(a, b *arbv1.SchedulingSpecStatus) bool {
+	if a.Running != b.Running || a.Pending != b.Pending || len(a.Conditions) != len(b.Conditions) {
+		return false
+	}
+
+	for i := range a.Conditions {
+		ac, bc := a.Conditions[i], b.Conditions[i]
+		if ac.Type != bc.Type || ac.Status != bc.Status || ac.Reason != bc.Reason || ac.Message != bc.Message {
+			return false
+		}
+	}
+
+	return true
+}
+
+// updatePodStatus updates the status of pod asynchronously.
+func (sc *SchedulerCache) updatePodStatus(pod *v1.Pod) {
+	if sc.StatusUpdater == nil {
+		return
+	}
+
 	go func() {
-		sc.Binder.Bind(p, hostname)
+		sc.StatusUpdater.UpdatePodStatus(pod)
 	}()
+}
+
+// NominateTask records hostname as the nominated node of task, both in cache
+// and in the status of its pod; the nomination is cleared if hostname is
+// empty.
+func (sc *SchedulerCache) NominateTask(taskInfo *arbapi.TaskInfo, hostname string) error {
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	job, task, err := sc.findJobAndTask(taskInfo)
+	if err != nil {
+		return err
+	}
+
+	sc.nominate(job, task, hostname)
 
 	return nil
 }
 
+// nominate sets hostname as the nominated node of task of job.
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) nominate(job *arbapi.JobInfo, task *arbapi.TaskInfo, hostname string) {
+	if task.NominatedNodeName == hostname {
+		return
+	}
+
+	if len(hostname) == 0 {
+		delete(sc.nominations, task.UID)
+	} else {
+		if sc.nominations == nil {
+			sc.nominations = map[arbapi.TaskID]*nomination{}
+		}
+		sc.nominations[task.UID] = &nomination{job: job.UID, since: sc.Now()}
+	}
+
+	sc.markJobDirty(job.UID)
+
+	// The pod is shared with snapshots, update a copy of it.
+	pod := task.Pod.DeepCopy()
+	pod.Status.NominatedNodeName = hostname
+
+	task.Pod = pod
+	task.NominatedNodeName = hostname
+
+	sc.updatePodStatus(pod)
+}
+
+// expireNominations clears the nominations of the pending tasks that are
+// not bound within NominationTimeout, so the resource reserved for them is
+// released to others.
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) expireNominations() {
+	if sc.NominationTimeout <= 0 {
+		return
+	}
+
+	now := sc.Now()
+	for id, n := range sc.nominations {
+		job, found := sc.Jobs[n.job]
+		if !found {
+			delete(sc.nominations, id)
+			continue
+		}
+		task, found := job.Tasks[id]
+		if !found || task.Status != arbapi.Pending {
+			delete(sc.nominations, id)
+			continue
+		}
+
+		if now.Sub(n.since) < sc.NominationTimeout {
+			continue
+		}
+
+		glog.V(3).Infof("Nomination of Task <%v/%v> to node <%v> expired after %v",
+			task.Namespace, task.Name, task.NominatedNodeName, sc.NominationTimeout)
+		sc.nominate(job, task, "")
+	}
+}
+
+// CheckVolumes checks whether the volumes of task are available on node;
+// all nodes are available if no VolumeChecker.
+func (sc *SchedulerCache) CheckVolumes(task *arbapi.TaskInfo, node *arbapi.NodeInfo) error {
+	if sc.VolumeChecker == nil || task.Pod == nil || node.Node == nil {
+		return nil
+	}
+
+	return sc.VolumeChecker.CheckVolumes(task.Pod, node.Node)
+}
+
+// NodeUtilization returns the real-time utilization of node by MetricsSource;
+// the utilization of all nodes is unavailable if no MetricsSource.
+func (sc *SchedulerCache) NodeUtilization(node *arbapi.NodeInfo) (map[v1.ResourceName]float64, error) {
+	if sc.MetricsSource == nil {
+		return nil, fmt.Errorf("no metrics source")
+	}
+	if node.Node == nil {
+		return nil, fmt.Errorf("node <%v> is not found", node.Name)
+	}
+
+	return sc.MetricsSource.NodeUtilization(node.Node)
+}
+
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) markNodeDirty(name string) {
+	if sc.dirtyNodes == nil {
+		sc.dirtyNodes = map[string]bool{}
+	}
+	sc.dirtyNodes[name] = true
+}
+
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) markJobDirty(id arbapi.JobID) {
+	if sc.dirtyJobs == nil {
+		sc.dirtyJobs = map[arbapi.JobID]bool{}
+	}
+	sc.dirtyJobs[id] = true
+}
+
+// Invalidate drops the clones of nodes and jobs in the last snapshot, e.g.
+// they're updated by session, so next snapshot will clone them again.
+func (sc *SchedulerCache) Invalidate(nodes []string, jobs []arbapi.JobID) {
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	for _, name := range nodes {
+		sc.markNodeDirty(name)
+	}
+
+	for _, id := range jobs {
+		sc.markJobDirty(id)
+	}
+}
+
+// Snapshot returns the clones of nodes and jobs in cache; the clones in the
+// last snapshot are reused if the nodes/jobs are not changed since then.
 func (sc *SchedulerCache) Snapshot() *arbapi.ClusterInfo {
 	sc.Mutex.Lock()
 	defer sc.Mutex.Unlock()
 
+	sc.iteration++
+
+	sc.expireNominations()
+
 	snapshot := &arbapi.ClusterInfo{
-		Nodes: make([]*arbapi.NodeInfo, 0, len(sc.Nodes)),
-		Jobs:  make([]*arbapi.JobInfo, 0, len(sc.Jobs)),
+		Iteration: sc.iteration,
+		Nodes:     make([]*arbapi.NodeInfo, 0, len(sc.Nodes)),
+		Jobs:      make([]*arbapi.JobInfo, 0, len(sc.Jobs)),
+		Quotas:    make([]*arbapi.QuotaInfo, 0, len(sc.Quotas)),
+	}
+
+	for _, quota := range sc.Quotas {
+		snapshot.Quotas = append(snapshot.Quotas, quota.Clone())
 	}
 
-	for _, value := range sc.Nodes {
-		snapshot.Nodes = append(snapshot.Nodes, value.Clone())
+	snapshotNodes := make(map[string]*arbapi.NodeInfo, len(sc.Nodes))
+	for name, value := range sc.Nodes {
+		node, found := sc.snapshotNodes[name]
+		if !found || sc.dirtyNodes[name] {
+			node = value.Clone()
+		}
+
+		snapshotNodes[name] = node
+		snapshot.Nodes = append(snapshot.Nodes, node)
 	}
 
-	for _, value := range sc.Jobs {
+	snapshotJobs := make(map[arbapi.JobID]*arbapi.JobInfo, len(sc.Jobs))
+	for id, value := range sc.Jobs {
 		// If no scheduling spec, does not handle it.
 		if value.SchedSpec == nil && value.PDB == nil {
 			glog.V(3).Infof("The scheduling spec of Job <%v> is nil, ignore it.", value.UID)
 			continue
 		}
 
-		snapshot.Jobs = append(snapshot.Jobs, value.Clone())
+		job, found := sc.snapshotJobs[id]
+		if !found || sc.dirtyJobs[id] {
+			job = value.Clone()
+
+			// The pending tasks of paused job are not scheduled; it's
+			// dirty once resumed, so they're back in next snapshot.
+			if job.Paused() {
+				for _, task := range job.TaskStatusIndex[arbapi.Pending] {
+					job.DeleteTaskInfo(task)
+				}
+			}
+		}
+
+		// The candidates are the nodes of last session, decorate will
+		// fetch them again.
+		job.Candidates = nil
+
+		snapshotJobs[id] = job
+		snapshot.Jobs = append(snapshot.Jobs, job)
 	}
 
+	glog.V(4).Infof("Snapshot <%d>: %d of %d nodes and %d of %d jobs are changed.",
+		sc.iteration, len(sc.dirtyNodes), len(sc.Nodes), len(sc.dirtyJobs), len(sc.Jobs))
+
+	sc.snapshotNodes = snapshotNodes
+	sc.snapshotJobs = snapshotJobs
+	sc.dirtyNodes = nil
+	sc.dirtyJobs = nil
+
 	return snapshot
 }
 
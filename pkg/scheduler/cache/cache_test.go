@@ -17,16 +17,24 @@ limitations under the License.
 package cache
 
 import (
+	"expvar"
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientcache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
 
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
 )
 
 func nodesEqual(l, r map[string]*api.NodeInfo) bool {
@@ -62,54 +70,6 @@ func cacheEqual(l, r *SchedulerCache) bool {
 		jobsEqual(l.Jobs, r.Jobs)
 }
 
-func buildNode(name string, alloc v1.ResourceList) *v1.Node {
-	return &v1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			UID:  types.UID(name),
-			Name: name,
-		},
-		Status: v1.NodeStatus{
-			Capacity:    alloc,
-			Allocatable: alloc,
-		},
-	}
-}
-
-func buildPod(ns, n, nn string,
-	p v1.PodPhase, req v1.ResourceList,
-	owner []metav1.OwnerReference, labels map[string]string) *v1.Pod {
-
-	return &v1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			UID:             types.UID(fmt.Sprintf("%v-%v", ns, n)),
-			Name:            n,
-			Namespace:       ns,
-			OwnerReferences: owner,
-			Labels:          labels,
-		},
-		Status: v1.PodStatus{
-			Phase: p,
-		},
-		Spec: v1.PodSpec{
-			NodeName: nn,
-			Containers: []v1.Container{
-				{
-					Resources: v1.ResourceRequirements{
-						Requests: req,
-					},
-				},
-			},
-		},
-	}
-}
-
-func buildResourceList(cpu string, memory string) v1.ResourceList {
-	return v1.ResourceList{
-		v1.ResourceCPU:    resource.MustParse(cpu),
-		v1.ResourceMemory: resource.MustParse(memory),
-	}
-}
-
 func buildResource(cpu string, memory string) *api.Resource {
 	return api.NewResource(v1.ResourceList{
 		v1.ResourceCPU:    resource.MustParse(cpu),
@@ -117,31 +77,21 @@ func buildResource(cpu string, memory string) *api.Resource {
 	})
 }
 
-func buildOwnerReference(owner string) metav1.OwnerReference {
-	controller := true
-	return metav1.OwnerReference{
-		Controller: &controller,
-		UID:        types.UID(owner),
-	}
-}
-
 func TestAddPod(t *testing.T) {
 
-	owner := buildOwnerReference("j1")
+	owner := testutil.BuildOwnerReference("j1")
 
 	// case 1:
-	pod1 := buildPod("c1", "p1", "", v1.PodPending, buildResourceList("1000m", "1G"),
-		[]metav1.OwnerReference{owner}, make(map[string]string))
+	pod1 := testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1000m", "1G"), owner)
 	pi1 := api.NewTaskInfo(pod1)
-	pod2 := buildPod("c1", "p2", "n1", v1.PodRunning, buildResourceList("1000m", "1G"),
-		[]metav1.OwnerReference{owner}, make(map[string]string))
+	pod2 := testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1000m", "1G"), owner)
 	pi2 := api.NewTaskInfo(pod2)
 
 	j1 := api.NewJobInfo(api.JobID("j1"))
 	j1.AddTaskInfo(pi1)
 	j1.AddTaskInfo(pi2)
 
-	node1 := buildNode("n1", buildResourceList("2000m", "10G"))
+	node1 := testutil.BuildNode("n1", testutil.BuildResourceList("2000m", "10G"))
 	ni1 := api.NewNodeInfo(node1)
 	ni1.AddTask(pi2)
 
@@ -188,11 +138,9 @@ func TestAddPod(t *testing.T) {
 func TestAddNode(t *testing.T) {
 
 	// case 1
-	node1 := buildNode("n1", buildResourceList("2000m", "10G"))
-	pod1 := buildPod("c1", "p1", "", v1.PodPending, buildResourceList("1000m", "1G"),
-		[]metav1.OwnerReference{}, make(map[string]string))
-	pod2 := buildPod("c1", "p2", "n1", v1.PodRunning, buildResourceList("1000m", "1G"),
-		[]metav1.OwnerReference{}, make(map[string]string))
+	node1 := testutil.BuildNode("n1", testutil.BuildResourceList("2000m", "10G"))
+	pod1 := testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1000m", "1G"), metav1.OwnerReference{})
+	pod2 := testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1000m", "1G"), metav1.OwnerReference{})
 	pi2 := api.NewTaskInfo(pod2)
 
 	ni1 := api.NewNodeInfo(node1)
@@ -234,3 +182,624 @@ func TestAddNode(t *testing.T) {
 		}
 	}
 }
+
+type fakeRecorder struct {
+	events []string
+}
+
+func (fr *fakeRecorder) Event(object runtime.Object, eventType, reason, message string) {
+	pod := object.(*v1.Pod)
+	fr.events = append(fr.events, fmt.Sprintf("%v/%v %v %v: %v", pod.Namespace, pod.Name, eventType, reason, message))
+}
+
+func TestBackoff(t *testing.T) {
+	owner := testutil.BuildOwnerReference("j1")
+
+	recorder := &fakeRecorder{}
+	cache := &SchedulerCache{
+		Nodes:    make(map[string]*api.NodeInfo),
+		Jobs:     make(map[api.JobID]*api.JobInfo),
+		Recorder: recorder,
+	}
+
+	cache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2000m", "10G")))
+	cache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1000m", "1G"), owner))
+	cache.AddPod(testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1000m", "1G"), owner))
+
+	if err := cache.Backoff(cache.Jobs["j1"], "Unschedulable", "not enough"); err != nil {
+		t.Fatalf("failed to backoff job: %v", err)
+	}
+
+	// Only the pending pods get the event.
+	expected := []string{"c1/p1 Warning Unschedulable: not enough"}
+	if !reflect.DeepEqual(expected, recorder.events) {
+		t.Errorf("expected events %v, got %v", expected, recorder.events)
+	}
+
+	// The event is not recorded again in following sessions.
+	if err := cache.Backoff(cache.Jobs["j1"], "Unschedulable", "not enough"); err != nil {
+		t.Fatalf("failed to backoff job: %v", err)
+	}
+	if !reflect.DeepEqual(expected, recorder.events) {
+		t.Errorf("expected events %v not repeated, got %v", expected, recorder.events)
+	}
+
+	// Nothing is recorded without Recorder.
+	cache.Recorder = nil
+	if err := cache.Backoff(cache.Jobs["j1"], "Unschedulable", "still not enough"); err != nil {
+		t.Fatalf("failed to backoff job without recorder: %v", err)
+	}
+
+	if err := cache.Backoff(&api.JobInfo{UID: "j2"}, "Unschedulable", "not enough"); err == nil {
+		t.Errorf("expected error for unknown job")
+	}
+}
+
+func TestDrainNode(t *testing.T) {
+	owner := testutil.BuildOwnerReference("j1")
+
+	evictor := &testutil.FakeEvictor{}
+	cache := &SchedulerCache{
+		Nodes:   make(map[string]*api.NodeInfo),
+		Jobs:    make(map[api.JobID]*api.JobInfo),
+		Evictor: evictor,
+	}
+
+	cache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("8", "16G")))
+	cache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("8", "16G")))
+	cache.AddPod(testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1G"), owner))
+	cache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "j1", owner))
+
+	if err := cache.DrainNode("n1"); err != nil {
+		t.Fatalf("failed to drain n1: %v", err)
+	}
+	if err := cache.DrainNode("n3"); err == nil {
+		t.Errorf("expected error for unknown node")
+	}
+
+	// The node is draining in snapshot, but nothing is evicted by cache;
+	// the drain action evicts the tasks in session.
+	snapshot := cache.Snapshot()
+	for _, node := range snapshot.Nodes {
+		if node.Draining != (node.Name == "n1") {
+			t.Errorf("expected only n1 draining in snapshot, got %v draining %v", node.Name, node.Draining)
+		}
+	}
+	cache.WaitForInflight(3 * time.Second)
+	if evicted := evictor.Evicted(); len(evicted) != 0 {
+		t.Errorf("expected no evictions by snapshot, got %v", evicted)
+	}
+	if task := cache.Nodes["n1"].Tasks["c1/p1"]; task == nil || task.Status != api.Running {
+		t.Errorf("expected c1/p1 still running on n1, got %v", task)
+	}
+
+	// The node is still drained after it's updated.
+	cache.UpdateNode(testutil.BuildNode("n1", testutil.BuildResourceList("8", "16G")), testutil.BuildNode("n1", testutil.BuildResourceList("8", "16G")))
+	if !cache.Nodes["n1"].Draining {
+		t.Errorf("expected n1 draining after update")
+	}
+
+	if err := cache.UndrainNode("n1"); err != nil {
+		t.Fatalf("failed to undrain n1: %v", err)
+	}
+	if cache.Nodes["n1"].Draining {
+		t.Errorf("expected n1 not draining after undrain")
+	}
+	snapshot = cache.Snapshot()
+	for _, node := range snapshot.Nodes {
+		if node.Draining {
+			t.Errorf("expected no node draining in snapshot after undrain, got %v", node.Name)
+		}
+	}
+
+	// The drain annotation keeps the node draining regardless of undrain.
+	annotated := testutil.BuildNode("n2", testutil.BuildResourceList("8", "16G"))
+	annotated.Annotations = map[string]string{arbv1.DrainAnnotationKey: "true"}
+	cache.UpdateNode(testutil.BuildNode("n2", testutil.BuildResourceList("8", "16G")), annotated)
+	if err := cache.UndrainNode("n2"); err != nil {
+		t.Fatalf("failed to undrain n2: %v", err)
+	}
+	if !cache.Nodes["n2"].Draining {
+		t.Errorf("expected n2 draining by annotation")
+	}
+}
+
+func TestFilterPodSchedulerName(t *testing.T) {
+	owner := testutil.BuildOwnerReference("j1")
+
+	cache := &SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+
+		schedulerName: "kar-scheduler",
+	}
+	handler := clientcache.FilteringResourceEventHandler{
+		FilterFunc: cache.filterPod,
+		Handler: clientcache.ResourceEventHandlerFuncs{
+			AddFunc:    cache.AddPod,
+			UpdateFunc: cache.UpdatePod,
+			DeleteFunc: cache.DeletePod,
+		},
+	}
+
+	withScheduler := func(pod *v1.Pod, name string) *v1.Pod {
+		pod = pod.DeepCopy()
+		pod.Spec.SchedulerName = name
+		return pod
+	}
+
+	cache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8G")))
+	pending := withScheduler(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner), "kar-scheduler")
+	bound := withScheduler(testutil.BuildPod("c1", "p2", "n1", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner), "kar-scheduler")
+	handler.OnAdd(pending)
+	handler.OnAdd(bound)
+
+	job := cache.Jobs["j1"]
+	if len(job.TaskStatusIndex[api.Pending]) != 1 || len(job.Tasks) != 2 {
+		t.Fatalf("expected 1 pending of 2 tasks, got %d of %d", len(job.TaskStatusIndex[api.Pending]), len(job.Tasks))
+	}
+
+	// The pods are handed to another scheduler.
+	handler.OnUpdate(pending, withScheduler(pending, "default-scheduler"))
+	handler.OnUpdate(bound, withScheduler(bound, "default-scheduler"))
+
+	if len(job.TaskStatusIndex[api.Pending]) != 0 || len(job.Tasks) != 1 {
+		t.Errorf("expected the pending task removed, got %d pending of %d tasks",
+			len(job.TaskStatusIndex[api.Pending]), len(job.Tasks))
+	}
+	if used := cache.Nodes["n1"].Used; !reflect.DeepEqual(used, buildResource("1", "1G")) {
+		t.Errorf("expected the bound task still used <%v> on node, got <%v>", buildResource("1", "1G"), used)
+	}
+
+	// The pending pod is handed back.
+	handler.OnUpdate(withScheduler(pending, "default-scheduler"), pending)
+	if len(job.TaskStatusIndex[api.Pending]) != 1 {
+		t.Errorf("expected the pending task added back, got %d pending", len(job.TaskStatusIndex[api.Pending]))
+	}
+}
+
+func TestDefaultRequests(t *testing.T) {
+	owner := testutil.BuildOwnerReference("j1")
+
+	cache := &SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+
+		DefaultRequests: map[string]*api.Resource{"": buildResource("500m", "0")},
+		schedulerName:   "kar-scheduler",
+	}
+
+	withScheduler := func(pod *v1.Pod, name string) *v1.Pod {
+		pod = pod.DeepCopy()
+		pod.Spec.SchedulerName = name
+		return pod
+	}
+
+	// The running pods requesting nothing are more than the defaults fit.
+	cache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("1", "8G")))
+	for i := 0; i < 3; i++ {
+		cache.AddPod(withScheduler(testutil.BuildPod("c1", fmt.Sprintf("r%d", i), "n1", v1.PodRunning, v1.ResourceList{}, owner), "kar-scheduler"))
+	}
+	cache.AddPod(withScheduler(testutil.BuildPod("c1", "pending", "", v1.PodPending, v1.ResourceList{}, owner), "kar-scheduler"))
+	cache.AddPod(withScheduler(testutil.BuildPod("c1", "other", "", v1.PodPending, v1.ResourceList{}, owner), "default-scheduler"))
+
+	if used := cache.Nodes["n1"].Used; !used.IsEmpty() {
+		t.Errorf("expected nothing used by the running pods, got <%v>", used)
+	}
+
+	job := cache.Jobs["j1"]
+	if req := job.Tasks["c1-pending"].Resreq; !reflect.DeepEqual(req, buildResource("500m", "0")) {
+		t.Errorf("expected the default request of pending pod, got <%v>", req)
+	}
+	if req := job.Tasks["c1-other"].Resreq; !req.IsEmpty() {
+		t.Errorf("expected no default request of the pod of other scheduler, got <%v>", req)
+	}
+}
+
+func TestFilterPodSchedulingGates(t *testing.T) {
+	owner := testutil.BuildOwnerReference("j1")
+
+	cache := &SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+
+		schedulerName: "kar-scheduler",
+	}
+	handler := clientcache.FilteringResourceEventHandler{
+		FilterFunc: cache.filterPod,
+		Handler: clientcache.ResourceEventHandlerFuncs{
+			AddFunc:    cache.AddPod,
+			UpdateFunc: cache.UpdatePod,
+			DeleteFunc: cache.DeletePod,
+		},
+	}
+
+	withGates := func(pod *v1.Pod, gates string) *v1.Pod {
+		pod = pod.DeepCopy()
+		pod.Spec.SchedulerName = "kar-scheduler"
+		pod.Annotations = map[string]string{arbv1.SchedulingGatesAnnotationKey: gates}
+		return pod
+	}
+
+	cache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8G")))
+	gated := withGates(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner), "quota-check")
+	bound := withGates(testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1G"), owner), "quota-check")
+	handler.OnAdd(gated)
+	handler.OnAdd(bound)
+
+	// The gated pod is not pending, but the bound one is still accounted.
+	job := cache.Jobs["j1"]
+	if len(job.TaskStatusIndex[api.Pending]) != 0 || len(job.Tasks) != 1 {
+		t.Fatalf("expected 0 pending of 1 task, got %d of %d", len(job.TaskStatusIndex[api.Pending]), len(job.Tasks))
+	}
+	if used := cache.Nodes["n1"].Used; !reflect.DeepEqual(used, buildResource("1", "1G")) {
+		t.Errorf("expected the bound task used <%v> on node, got <%v>", buildResource("1", "1G"), used)
+	}
+
+	// One more gate is still gated.
+	more := withGates(gated, "quota-check,image-prefetch")
+	handler.OnUpdate(gated, more)
+	if len(job.TaskStatusIndex[api.Pending]) != 0 {
+		t.Errorf("expected the gated task ignored, got %d pending", len(job.TaskStatusIndex[api.Pending]))
+	}
+
+	// The gates are removed, the pod enters scheduling.
+	handler.OnUpdate(more, withGates(gated, ""))
+	if len(job.TaskStatusIndex[api.Pending]) != 1 || len(job.Tasks) != 2 {
+		t.Errorf("expected 1 pending of 2 tasks, got %d of %d", len(job.TaskStatusIndex[api.Pending]), len(job.Tasks))
+	}
+}
+
+func TestSnapshotPausedJob(t *testing.T) {
+	owner := testutil.BuildOwnerReference("j1")
+
+	cache := &SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	cache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8G")))
+	cache.AddPod(testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1G"), owner))
+	cache.AddPod(testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner))
+
+	paused := testutil.BuildSchedulingSpec("c1", "j1", owner)
+	paused.Annotations = map[string]string{arbv1.PausedAnnotationKey: "true"}
+	cache.AddSchedulingSpec(paused)
+
+	job := snapshotJobs(cache.Snapshot())["j1"]
+	if len(job.TaskStatusIndex[api.Pending]) != 0 || len(job.Tasks) != 1 {
+		t.Errorf("expected 0 pending of 1 task in paused job, got %d of %d",
+			len(job.TaskStatusIndex[api.Pending]), len(job.Tasks))
+	}
+	if len(cache.Jobs["j1"].Tasks) != 2 {
+		t.Errorf("expected 2 tasks of job kept in cache, got %d", len(cache.Jobs["j1"].Tasks))
+	}
+
+	// The job is resumed.
+	resumed := paused.DeepCopy()
+	resumed.Annotations = nil
+	cache.UpdateSchedulingSpec(paused, resumed)
+
+	job = snapshotJobs(cache.Snapshot())["j1"]
+	if len(job.TaskStatusIndex[api.Pending]) != 1 {
+		t.Errorf("expected 1 pending task in resumed job, got %d", len(job.TaskStatusIndex[api.Pending]))
+	}
+}
+
+func TestSnapshotTerminatedPod(t *testing.T) {
+	owner := testutil.BuildOwnerReference("j1")
+
+	cache := &SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	cache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8G")))
+	cache.AddPod(testutil.BuildPod("c1", "p1", "n1", v1.PodSucceeded, testutil.BuildResourceList("1", "1G"), owner))
+	cache.AddPod(testutil.BuildPod("c1", "p2", "n1", v1.PodFailed, testutil.BuildResourceList("1", "1G"), owner))
+	terminating := testutil.BuildPod("c1", "p3", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1G"), owner)
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	cache.AddPod(terminating)
+	cache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "j1", owner))
+
+	snapshot := cache.Snapshot()
+
+	node := snapshotNodes(snapshot)["n1"]
+	if !reflect.DeepEqual(node.Idle, buildResource("3", "7G")) {
+		t.Errorf("expected idle <%v> of node, got <%v>", buildResource("3", "7G"), node.Idle)
+	}
+	if !reflect.DeepEqual(node.Releasing, buildResource("1", "1G")) {
+		t.Errorf("expected releasing <%v> of node, got <%v>", buildResource("1", "1G"), node.Releasing)
+	}
+	if len(node.Tasks) != 1 {
+		t.Errorf("expected 1 task on node, got %d", len(node.Tasks))
+	}
+
+	job := snapshotJobs(snapshot)["j1"]
+	if len(job.TaskStatusIndex[api.Pending]) != 0 {
+		t.Errorf("expected no pending task, got %d", len(job.TaskStatusIndex[api.Pending]))
+	}
+	if !reflect.DeepEqual(job.TotalRequest, buildResource("1", "1G")) {
+		t.Errorf("expected request <%v> of job, got <%v>", buildResource("1", "1G"), job.TotalRequest)
+	}
+}
+
+func snapshotNodes(snapshot *api.ClusterInfo) map[string]*api.NodeInfo {
+	nodes := map[string]*api.NodeInfo{}
+	for _, node := range snapshot.Nodes {
+		nodes[node.Name] = node
+	}
+	return nodes
+}
+
+func snapshotJobs(snapshot *api.ClusterInfo) map[api.JobID]*api.JobInfo {
+	jobs := map[api.JobID]*api.JobInfo{}
+	for _, job := range snapshot.Jobs {
+		jobs[job.UID] = job
+	}
+	return jobs
+}
+
+func TestSnapshotReuse(t *testing.T) {
+	owner1 := testutil.BuildOwnerReference("j1")
+	owner2 := testutil.BuildOwnerReference("j2")
+
+	cache := &SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	for _, name := range []string{"n1", "n2", "n3"} {
+		cache.AddNode(testutil.BuildNode(name, testutil.BuildResourceList("2000m", "10G")))
+	}
+	cache.AddPod(testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1000m", "1G"), owner1))
+	cache.AddPod(testutil.BuildPod("c1", "p2", "n2", v1.PodRunning, testutil.BuildResourceList("1000m", "1G"), owner2))
+	cache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "j1", owner1))
+	cache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "j2", owner2))
+
+	first := cache.Snapshot()
+	nodes1, jobs1 := snapshotNodes(first), snapshotJobs(first)
+
+	// Nothing changed, all clones are reused.
+	second := cache.Snapshot()
+	nodes2, jobs2 := snapshotNodes(second), snapshotJobs(second)
+	for name, node := range nodes1 {
+		if nodes2[name] != node {
+			t.Errorf("expected node <%v> reused", name)
+		}
+	}
+	for id, job := range jobs1 {
+		if jobs2[id] != job {
+			t.Errorf("expected job <%v> reused", id)
+		}
+	}
+
+	// A new pod of j1 on n1, only n1 and j1 are cloned again.
+	cache.AddPod(testutil.BuildPod("c1", "p3", "n1", v1.PodRunning, testutil.BuildResourceList("1000m", "1G"), owner1))
+
+	third := cache.Snapshot()
+	nodes3, jobs3 := snapshotNodes(third), snapshotJobs(third)
+	if nodes3["n1"] == nodes2["n1"] {
+		t.Errorf("expected node <n1> cloned again")
+	}
+	if len(nodes3["n1"].Tasks) != 2 {
+		t.Errorf("expected 2 tasks on node <n1>, got %d", len(nodes3["n1"].Tasks))
+	}
+	if jobs3["j1"] == jobs2["j1"] {
+		t.Errorf("expected job <j1> cloned again")
+	}
+	if nodes3["n2"] != nodes2["n2"] || nodes3["n3"] != nodes2["n3"] {
+		t.Errorf("expected nodes <n2> and <n3> reused")
+	}
+	if jobs3["j2"] != jobs2["j2"] {
+		t.Errorf("expected job <j2> reused")
+	}
+
+	// The nodes and jobs changed by session are cloned again.
+	cache.Invalidate([]string{"n3"}, []api.JobID{"j2"})
+
+	fourth := cache.Snapshot()
+	nodes4, jobs4 := snapshotNodes(fourth), snapshotJobs(fourth)
+	if nodes4["n3"] == nodes3["n3"] || jobs4["j2"] == jobs3["j2"] {
+		t.Errorf("expected node <n3> and job <j2> cloned again")
+	}
+	if nodes4["n1"] != nodes3["n1"] || jobs4["j1"] != jobs3["j1"] {
+		t.Errorf("expected node <n1> and job <j1> reused")
+	}
+
+	// The job whose status is updated is cloned again with the status.
+	status := &arbv1.SchedulingSpecStatus{Running: 2}
+	if err := cache.UpdateJobStatus(jobs4["j1"], status); err != nil {
+		t.Fatalf("failed to update status of job <j1>: %v", err)
+	}
+	if job := snapshotJobs(cache.Snapshot())["j1"]; job == jobs4["j1"] || job.SchedSpec.Status.Running != 2 {
+		t.Errorf("expected job <j1> cloned again with the updated status")
+	}
+
+	// The deleted node is not in snapshot any more.
+	cache.DeleteNode(testutil.BuildNode("n2", testutil.BuildResourceList("2000m", "10G")))
+	if _, found := snapshotNodes(cache.Snapshot())["n2"]; found {
+		t.Errorf("expected node <n2> removed from snapshot")
+	}
+}
+
+func buildBenchmarkCache(nodes, pods int) *SchedulerCache {
+	cache := &SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	for i := 0; i < nodes; i++ {
+		name := fmt.Sprintf("n%d", i)
+		owner := testutil.BuildOwnerReference(fmt.Sprintf("j%d", i))
+
+		cache.AddNode(testutil.BuildNode(name, testutil.BuildResourceList("64000m", "256G")))
+		for j := 0; j < pods; j++ {
+			cache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d-%d", i, j), name, v1.PodRunning, testutil.BuildResourceList("1000m", "1G"), owner))
+		}
+		cache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", fmt.Sprintf("j%d", i), owner))
+	}
+
+	return cache
+}
+
+// BenchmarkSnapshot takes snapshots of 1000 nodes with 10 pods each, one
+// node and job are changed between them.
+func BenchmarkSnapshot(b *testing.B) {
+	for _, bm := range []struct {
+		name  string
+		reuse bool
+	}{
+		{name: "Reuse", reuse: true},
+		{name: "Clone", reuse: false},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			cache := buildBenchmarkCache(1000, 10)
+
+			var nodes []string
+			var jobs []api.JobID
+			if !bm.reuse {
+				for name := range cache.Nodes {
+					nodes = append(nodes, name)
+				}
+				for id := range cache.Jobs {
+					jobs = append(jobs, id)
+				}
+			}
+
+			cache.Snapshot()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				cache.Invalidate([]string{"n0"}, []api.JobID{"j0"})
+				cache.Invalidate(nodes, jobs)
+				cache.Snapshot()
+			}
+		})
+	}
+}
+
+// fakeClock only moves forward by sleeping.
+type fakeClock struct {
+	sync.Mutex
+	now   time.Time
+	slept []time.Duration
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.Lock()
+	defer fc.Unlock()
+	return fc.now
+}
+
+func (fc *fakeClock) Sleep(d time.Duration) {
+	fc.Lock()
+	defer fc.Unlock()
+	fc.slept = append(fc.slept, d)
+	fc.now = fc.now.Add(d)
+}
+
+func TestRateLimiter(t *testing.T) {
+	owner := testutil.BuildOwnerReference("j1")
+
+	clock := &fakeClock{now: time.Now()}
+	binder := testutil.NewFakeBinder()
+	cache := &SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: binder,
+
+		// A burst of 2 calls, then 1 call per second.
+		RateLimiter: flowcontrol.NewTokenBucketRateLimiterWithClock(1, 2, clock),
+	}
+
+	cache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8G")))
+	for i := 0; i < 3; i++ {
+		cache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner))
+	}
+
+	throttled := metrics.APIThrottled.Value()
+
+	for _, task := range cache.Jobs["j1"].Tasks {
+		if err := cache.Bind(task, "n1", nil); err != nil {
+			t.Fatalf("failed to bind task <%v>: %v", task.Name, err)
+		}
+	}
+	if !cache.WaitForInflight(3 * time.Second) {
+		t.Fatalf("binds are not completed in time")
+	}
+
+	if binder.Length() != 3 {
+		t.Errorf("expected 3 binds, got %v", binder.Binds)
+	}
+
+	// The third call in the burst waits for one second.
+	if !reflect.DeepEqual(clock.slept, []time.Duration{time.Second}) {
+		t.Errorf("expected one wait of 1s, got %v", clock.slept)
+	}
+	if got := metrics.APIThrottled.Value() - throttled; got != 1 {
+		t.Errorf("expected 1 throttled call in metrics, got %d", got)
+	}
+}
+
+func TestStartLatencySLO(t *testing.T) {
+	owner := testutil.BuildOwnerReference("j1")
+
+	clock := &fakeClock{now: time.Now()}
+	recorder := &fakeRecorder{}
+	cache := &SchedulerCache{
+		Nodes:    make(map[string]*api.NodeInfo),
+		Jobs:     make(map[api.JobID]*api.JobInfo),
+		Binder:   testutil.NewFakeBinder(),
+		Recorder: recorder,
+		Clock:    clock,
+
+		StartLatencySLO: 30 * time.Second,
+	}
+
+	cache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8G")))
+	for _, name := range []string{"p1", "p2"} {
+		cache.AddPod(testutil.BuildPod("slo", name, "", v1.PodPending, testutil.BuildResourceList("1", "1G"), owner))
+	}
+	cache.AddSchedulingSpec(testutil.BuildSchedulingSpec("slo", "j1", owner))
+
+	// p1 is bound in 10s, and p2 in 40s.
+	for _, step := range []struct {
+		name string
+		wait time.Duration
+	}{
+		{name: "p1", wait: 10 * time.Second},
+		{name: "p2", wait: 30 * time.Second},
+	} {
+		clock.Sleep(step.wait)
+		task := cache.Jobs["j1"].Tasks[api.TaskID(fmt.Sprintf("slo-%v", step.name))]
+		if err := cache.Bind(task, "n1", nil); err != nil {
+			t.Fatalf("failed to bind task <%v>: %v", step.name, err)
+		}
+	}
+	if !cache.WaitForInflight(3 * time.Second) {
+		t.Fatalf("binds are not completed in time")
+	}
+
+	histogram, ok := metrics.PodStartLatency.Get("slo").(*expvar.Map)
+	if !ok {
+		t.Fatalf("expected start latency of namespace slo")
+	}
+	for key, expected := range map[string]string{
+		"10":    "1",
+		"30":    "1",
+		"60":    "2",
+		"+Inf":  "2",
+		"count": "2",
+		"sum":   "50",
+	} {
+		if got := histogram.Get(key); got == nil || got.String() != expected {
+			t.Errorf("expected %v of start latency %v, got %v", key, expected, got)
+		}
+	}
+
+	expected := []string{"slo/p2 Warning StartLatencySLOViolated: Pod of job slo/j1 waited 40s to be bound, longer than SLO 30s"}
+	if !reflect.DeepEqual(expected, recorder.events) {
+		t.Errorf("expected events %v, got %v", expected, recorder.events)
+	}
+}
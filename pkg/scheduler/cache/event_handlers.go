@@ -30,15 +30,35 @@ import (
 	arbapi "github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
 )
 
-func isTerminated(status arbapi.TaskStatus) bool {
-	return status == arbapi.Succeeded || status == arbapi.Failed
+// newTaskInfo returns the task of pod, with the default request of its
+// namespace if it requests nothing. All such pods of a namespace get the
+// same request, so they're still equivalent to each other. Only the pending
+// pods of this scheduler get it; the pods already on nodes are charged by
+// what they request, so the nodes are not overcommitted by the defaults.
+func (sc *SchedulerCache) newTaskInfo(pod *v1.Pod) *arbapi.TaskInfo {
+	pi := arbapi.NewTaskInfo(pod)
+	if pi.Status != arbapi.Pending || pod.Spec.SchedulerName != sc.schedulerName {
+		return pi
+	}
+	if req, found := sc.DefaultRequests[pod.Namespace]; found {
+		pi.DefaultRequest(req)
+	} else {
+		pi.DefaultRequest(sc.DefaultRequests[""])
+	}
+	return pi
 }
 
 // Assumes that lock is already acquired.
 func (sc *SchedulerCache) addPod(pod *v1.Pod) error {
-	pi := arbapi.NewTaskInfo(pod)
+	pi := sc.newTaskInfo(pod)
+
+	if pi.Status == arbapi.Pending {
+		sc.markPending(pod, pi.UID)
+	}
 
 	if len(pi.Job) != 0 {
+		sc.markJobDirty(pi.Job)
+
 		if _, found := sc.Jobs[pi.Job]; !found {
 			sc.Jobs[pi.Job] = arbapi.NewJobInfo(pi.Job)
 		}
@@ -56,16 +76,16 @@ func (sc *SchedulerCache) addPod(pod *v1.Pod) error {
 	if len(pi.NodeName) != 0 {
 		glog.V(3).Infof("Add task %v/%v into host %v", pi.Namespace, pi.Name, pi.NodeName)
 
+		sc.markNodeDirty(pi.NodeName)
+
 		if _, found := sc.Nodes[pi.NodeName]; !found {
 			sc.Nodes[pi.NodeName] = arbapi.NewNodeInfo(nil)
 		}
 
+		// The terminated task is not added, see NodeInfo.AddTask.
 		node := sc.Nodes[pi.NodeName]
 		node.RemoveTask(pi)
-
-		if !isTerminated(pi.Status) {
-			node.AddTask(pi)
-		}
+		node.AddTask(pi)
 	}
 
 	return nil
@@ -81,9 +101,11 @@ func (sc *SchedulerCache) updatePod(oldPod, newPod *v1.Pod) error {
 
 // Assumes that lock is already acquired.
 func (sc *SchedulerCache) deletePod(pod *v1.Pod) error {
-	pi := arbapi.NewTaskInfo(pod)
+	pi := sc.newTaskInfo(pod)
 
 	if len(pi.Job) != 0 {
+		sc.markJobDirty(pi.Job)
+
 		if job, found := sc.Jobs[pi.Job]; found {
 			job.DeleteTaskInfo(pi)
 		} else {
@@ -93,6 +115,8 @@ func (sc *SchedulerCache) deletePod(pod *v1.Pod) error {
 	}
 
 	if len(pi.NodeName) != 0 {
+		sc.markNodeDirty(pi.NodeName)
+
 		node := sc.Nodes[pi.NodeName]
 		if node != nil {
 			glog.V(3).Infof("Delete task %v/%v from host %v", pi.Namespace, pi.Name, pi.NodeName)
@@ -172,15 +196,20 @@ func (sc *SchedulerCache) DeletePod(obj interface{}) {
 		glog.Errorf("Failed to delete pod %v from cache: %v", pod.Name, err)
 		return
 	}
+	delete(sc.pendingSince, arbapi.TaskID(pod.UID))
+	delete(sc.nominations, arbapi.TaskID(pod.UID))
 	return
 }
 
 // Assumes that lock is already acquired.
 func (sc *SchedulerCache) addNode(node *v1.Node) error {
+	sc.markNodeDirty(node.Name)
+
 	if sc.Nodes[node.Name] != nil {
 		sc.Nodes[node.Name].SetNode(node)
 	} else {
 		sc.Nodes[node.Name] = arbapi.NewNodeInfo(node)
+		sc.reserveNode(node.Name)
 	}
 
 	return nil
@@ -188,6 +217,8 @@ func (sc *SchedulerCache) addNode(node *v1.Node) error {
 
 // Assumes that lock is already acquired.
 func (sc *SchedulerCache) updateNode(oldNode, newNode *v1.Node) error {
+	sc.markNodeDirty(newNode.Name)
+
 	// Did not delete the old node, just update related info, e.g. allocatable.
 	if sc.Nodes[newNode.Name] != nil {
 		sc.Nodes[newNode.Name].SetNode(newNode)
@@ -202,6 +233,7 @@ func (sc *SchedulerCache) deleteNode(node *v1.Node) error {
 	if _, ok := sc.Nodes[node.Name]; !ok {
 		return fmt.Errorf("node <%s> does not exist", node.Name)
 	}
+	sc.markNodeDirty(node.Name)
 	delete(sc.Nodes, node.Name)
 	return nil
 }
@@ -286,6 +318,8 @@ func (sc *SchedulerCache) setSchedulingSpec(ss *arbv1.SchedulingSpec) error {
 		return fmt.Errorf("the controller of SchedulingSpec is empty")
 	}
 
+	sc.markJobDirty(job)
+
 	if _, found := sc.Jobs[job]; !found {
 		sc.Jobs[job] = arbapi.NewJobInfo(job)
 	}
@@ -309,9 +343,13 @@ func (sc *SchedulerCache) deleteSchedulingSpec(ss *arbv1.SchedulingSpec) error {
 		return fmt.Errorf("can not found job %v:%v/%v", jobID, ss.Namespace, ss.Name)
 	}
 
+	sc.markJobDirty(jobID)
+
 	// Removed tasks from nodes.
 	for _, task := range job.Tasks {
 		if len(task.NodeName) != 0 {
+			sc.markNodeDirty(task.NodeName)
+
 			if node, found := sc.Nodes[task.NodeName]; found {
 				node.RemoveTask(task)
 			} else {
@@ -407,6 +445,8 @@ func (sc *SchedulerCache) setPDB(pdb *policyv1.PodDisruptionBudget) error {
 		return fmt.Errorf("the controller of SchedulingSpec is empty")
 	}
 
+	sc.markJobDirty(job)
+
 	if _, found := sc.Jobs[job]; !found {
 		sc.Jobs[job] = arbapi.NewJobInfo(job)
 	}
@@ -497,3 +537,184 @@ func (sc *SchedulerCache) DeletePDB(obj interface{}) {
 	}
 	return
 }
+
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) setResourceQuota(quota *v1.ResourceQuota) {
+	if sc.Quotas == nil {
+		sc.Quotas = map[string]*arbapi.QuotaInfo{}
+	}
+
+	sc.Quotas[quota.Namespace+"/"+quota.Name] = arbapi.NewQuotaInfo(quota)
+}
+
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) deleteResourceQuota(quota *v1.ResourceQuota) {
+	delete(sc.Quotas, quota.Namespace+"/"+quota.Name)
+}
+
+func (sc *SchedulerCache) AddResourceQuota(obj interface{}) {
+	quota, ok := obj.(*v1.ResourceQuota)
+	if !ok {
+		glog.Errorf("Cannot convert to *v1.ResourceQuota: %v", obj)
+		return
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	glog.V(4).Infof("Add ResourceQuota(%s/%s) into cache, spec(%#v)", quota.Namespace, quota.Name, quota.Spec)
+	sc.setResourceQuota(quota)
+}
+
+func (sc *SchedulerCache) UpdateResourceQuota(oldObj, newObj interface{}) {
+	newQuota, ok := newObj.(*v1.ResourceQuota)
+	if !ok {
+		glog.Errorf("Cannot convert newObj to *v1.ResourceQuota: %v", newObj)
+		return
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	glog.V(4).Infof("Update ResourceQuota(%s/%s) in cache, spec(%#v)", newQuota.Namespace, newQuota.Name, newQuota.Spec)
+	sc.setResourceQuota(newQuota)
+}
+
+func (sc *SchedulerCache) DeleteResourceQuota(obj interface{}) {
+	var quota *v1.ResourceQuota
+	switch t := obj.(type) {
+	case *v1.ResourceQuota:
+		quota = t
+	case cache.DeletedFinalStateUnknown:
+		var ok bool
+		quota, ok = t.Obj.(*v1.ResourceQuota)
+		if !ok {
+			glog.Errorf("Cannot convert to *v1.ResourceQuota: %v", t.Obj)
+			return
+		}
+	default:
+		glog.Errorf("Cannot convert to *v1.ResourceQuota: %v", t)
+		return
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	sc.deleteResourceQuota(quota)
+}
+
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) setReservation(r *arbv1.Reservation) error {
+	if len(utils.GetController(r)) == 0 {
+		return fmt.Errorf("the controller of Reservation is empty")
+	}
+
+	if sc.Reservations == nil {
+		sc.Reservations = map[string]*arbv1.Reservation{}
+	}
+
+	key := r.Namespace + "/" + r.Name
+	old, found := sc.Reservations[key]
+	sc.Reservations[key] = r
+
+	// The reservation may move to another node.
+	if found && old.Spec.NodeName != r.Spec.NodeName {
+		sc.reserveNode(old.Spec.NodeName)
+	}
+	sc.reserveNode(r.Spec.NodeName)
+
+	return nil
+}
+
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) deleteReservation(r *arbv1.Reservation) {
+	key := r.Namespace + "/" + r.Name
+	if old, found := sc.Reservations[key]; found {
+		delete(sc.Reservations, key)
+		sc.reserveNode(old.Spec.NodeName)
+	}
+}
+
+// reserveNode sets the reserved resource of node by the Reservations on it,
+// summed by job.
+// Assumes that lock is already acquired.
+func (sc *SchedulerCache) reserveNode(name string) {
+	reserved := map[arbapi.JobID]*arbapi.Resource{}
+	for _, r := range sc.Reservations {
+		if r.Spec.NodeName != name {
+			continue
+		}
+		job := arbapi.JobID(utils.GetController(r))
+		if _, found := reserved[job]; !found {
+			reserved[job] = arbapi.EmptyResource()
+		}
+		reserved[job].Add(arbapi.NewResource(r.Spec.Resources))
+	}
+
+	node, found := sc.Nodes[name]
+	if !found {
+		if len(reserved) == 0 {
+			return
+		}
+		node = arbapi.NewNodeInfo(nil)
+		sc.Nodes[name] = node
+	}
+
+	sc.markNodeDirty(name)
+	node.SetReserved(reserved)
+}
+
+func (sc *SchedulerCache) AddReservation(obj interface{}) {
+	r, ok := obj.(*arbv1.Reservation)
+	if !ok {
+		glog.Errorf("Cannot convert to *arbv1.Reservation: %v", obj)
+		return
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	glog.V(4).Infof("Add Reservation(%s/%s) into cache, spec(%#v)", r.Namespace, r.Name, r.Spec)
+	if err := sc.setReservation(r); err != nil {
+		glog.Errorf("Failed to add Reservation %s/%s into cache: %v", r.Namespace, r.Name, err)
+	}
+}
+
+func (sc *SchedulerCache) UpdateReservation(oldObj, newObj interface{}) {
+	r, ok := newObj.(*arbv1.Reservation)
+	if !ok {
+		glog.Errorf("Cannot convert newObj to *arbv1.Reservation: %v", newObj)
+		return
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	glog.V(4).Infof("Update Reservation(%s/%s) in cache, spec(%#v)", r.Namespace, r.Name, r.Spec)
+	if err := sc.setReservation(r); err != nil {
+		glog.Errorf("Failed to update Reservation %s/%s in cache: %v", r.Namespace, r.Name, err)
+	}
+}
+
+func (sc *SchedulerCache) DeleteReservation(obj interface{}) {
+	var r *arbv1.Reservation
+	switch t := obj.(type) {
+	case *arbv1.Reservation:
+		r = t
+	case cache.DeletedFinalStateUnknown:
+		var ok bool
+		r, ok = t.Obj.(*arbv1.Reservation)
+		if !ok {
+			glog.Errorf("Cannot convert to *arbv1.Reservation: %v", t.Obj)
+			return
+		}
+	default:
+		glog.Errorf("Cannot convert to *arbv1.Reservation: %v", t)
+		return
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	sc.deleteReservation(r)
+}
@@ -17,8 +17,12 @@ limitations under the License.
 package cache
 
 import (
+	"time"
+
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
 )
 
@@ -31,14 +35,68 @@ type Cache interface {
 	// Snapshot deep copy overall cache information into snapshot
 	Snapshot() *api.ClusterInfo
 
+	// Invalidate marks the nodes and jobs changed, e.g. by session, so
+	// next snapshot will not reuse their clones in the last one.
+	Invalidate(nodes []string, jobs []api.JobID)
+
 	// WaitForCacheSync waits for all cache synced
 	WaitForCacheSync(stopCh <-chan struct{}) bool
 
-	// Bind binds Task to the target host.
+	// WaitForInflight waits for the binds and evictions in flight to
+	// complete, up to timeout; it returns false on timeout.
+	WaitForInflight(timeout time.Duration) bool
+
+	// Bind binds Task to the target host; bound is called once the binder
+	// bound the pod successfully, it may be nil.
 	// TODO(jinzhej): clean up expire Tasks.
-	Bind(task *api.TaskInfo, hostname string) error
+	Bind(task *api.TaskInfo, hostname string, bound func()) error
+
+	// Evict evicts the task to release resources; the reason is recorded
+	// in the event and condition of its pod.
+	Evict(task *api.TaskInfo, reason string) error
+
+	// DrainNode drains the node of name until UndrainNode, so no task is
+	// placed onto it, and the drain action evicts its tasks in sessions.
+	DrainNode(name string) error
+
+	// UndrainNode stops draining the node of name, unless it has the drain
+	// annotation.
+	UndrainNode(name string) error
+
+	// Backoff records the reason why the job can not be scheduled
+	// to its pending tasks.
+	Backoff(job *api.JobInfo, reason, message string) error
+
+	// RecordEvent records an event of task's pod.
+	RecordEvent(task *api.TaskInfo, eventType, reason, message string) error
+
+	// TaskUnschedulable marks the pod of task unschedulable with the message.
+	TaskUnschedulable(task *api.TaskInfo, message string) error
+
+	// UpdateJobStatus updates the status of job's SchedulingSpec, e.g. its
+	// Scheduled condition.
+	UpdateJobStatus(job *api.JobInfo, status *arbv1.SchedulingSpecStatus) error
+
+	// CheckVolumes checks whether the volumes of task are available on node.
+	CheckVolumes(task *api.TaskInfo, node *api.NodeInfo) error
+
+	// NodeUtilization returns the real-time utilization of node, i.e. the
+	// used fraction of its allocatable resource by name.
+	NodeUtilization(node *api.NodeInfo) (map[v1.ResourceName]float64, error)
+
+	// NominateTask records hostname as the nominated node of task's pod.
+	NominateTask(task *api.TaskInfo, hostname string) error
 
-	Evict(task *api.TaskInfo) error
+	// PluginState returns the state saved by the plugin of name in previous
+	// sessions; it's nil if none.
+	PluginState(name string) interface{}
+
+	// SetPluginState saves the state of the plugin of name, which survives
+	// sessions until it's set again.
+	SetPluginState(name string, state interface{})
+
+	// Now returns the current time by the clock of cache.
+	Now() time.Time
 }
 
 type Binder interface {
@@ -46,5 +104,37 @@ type Binder interface {
 }
 
 type Evictor interface {
-	Evict(pod *v1.Pod) error
+	Evict(pod *v1.Pod, reason string) error
+}
+
+// StatusUpdater updates the status of pods and SchedulingSpecs.
+type StatusUpdater interface {
+	UpdatePodStatus(pod *v1.Pod) error
+	UpdateSchedulingSpecStatus(spec *arbv1.SchedulingSpec) error
+}
+
+// MetricsSource provides the real-time utilization of nodes, e.g. from
+// metrics-server or Prometheus.
+type MetricsSource interface {
+	// NodeUtilization returns the used fraction of node's allocatable
+	// resource by name, e.g. 0.8 of CPU; it returns an error if the metrics
+	// of node are unavailable.
+	NodeUtilization(node *v1.Node) (map[v1.ResourceName]float64, error)
+}
+
+// VolumeChecker checks whether the volumes of pod are available on node.
+type VolumeChecker interface {
+	CheckVolumes(pod *v1.Pod, node *v1.Node) error
+}
+
+// EventRecorder records the events of objects; it's the subset of
+// record.EventRecorder used by the scheduler, so tests can inject a fake one.
+type EventRecorder interface {
+	Event(object runtime.Object, eventType, reason, message string)
+}
+
+// Clock tells the current time, so tests can inject a fake one; the
+// time-based policies, e.g. aging and timeouts, read it by session.
+type Clock interface {
+	Now() time.Time
 }
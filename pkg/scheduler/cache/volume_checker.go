@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+)
+
+// betaStorageClassAnnotation is the deprecated annotation of PVC's storage class.
+const betaStorageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+type defaultVolumeChecker struct {
+	pvcLister   corelisters.PersistentVolumeClaimLister
+	pvLister    corelisters.PersistentVolumeLister
+	classLister storagelisters.StorageClassLister
+}
+
+// NewVolumeChecker returns a VolumeChecker which checks the node affinity of
+// the PersistentVolumes bound to pod's claims.
+func NewVolumeChecker(
+	pvcLister corelisters.PersistentVolumeClaimLister,
+	pvLister corelisters.PersistentVolumeLister,
+	classLister storagelisters.StorageClassLister,
+) VolumeChecker {
+	return &defaultVolumeChecker{
+		pvcLister:   pvcLister,
+		pvLister:    pvLister,
+		classLister: classLister,
+	}
+}
+
+func (vc *defaultVolumeChecker) CheckVolumes(pod *v1.Pod, node *v1.Node) error {
+	for _, vol := range pod.Spec.Volumes {
+		if vol.PersistentVolumeClaim == nil {
+			continue
+		}
+
+		claimName := vol.PersistentVolumeClaim.ClaimName
+		pvc, err := vc.pvcLister.PersistentVolumeClaims(pod.Namespace).Get(claimName)
+		if err != nil {
+			return fmt.Errorf("failed to get PVC <%v/%v>: %v", pod.Namespace, claimName, err)
+		}
+
+		if len(pvc.Spec.VolumeName) == 0 {
+			// The claim will be bound to a volume on the chosen node later.
+			if vc.isWaitForFirstConsumer(pvc) {
+				continue
+			}
+			return fmt.Errorf("PVC <%v/%v> is not bound", pod.Namespace, claimName)
+		}
+
+		pv, err := vc.pvLister.Get(pvc.Spec.VolumeName)
+		if err != nil {
+			return fmt.Errorf("failed to get PV <%v> of PVC <%v/%v>: %v",
+				pvc.Spec.VolumeName, pod.Namespace, claimName, err)
+		}
+
+		if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+			continue
+		}
+
+		if !api.MatchNodeSelectorTerms(pv.Spec.NodeAffinity.Required.NodeSelectorTerms, node.Labels) {
+			return fmt.Errorf("node <%v> does not match the node affinity of PV <%v>",
+				node.Name, pv.Name)
+		}
+	}
+
+	return nil
+}
+
+func (vc *defaultVolumeChecker) isWaitForFirstConsumer(pvc *v1.PersistentVolumeClaim) bool {
+	className := pvc.Annotations[betaStorageClassAnnotation]
+	if pvc.Spec.StorageClassName != nil {
+		className = *pvc.Spec.StorageClassName
+	}
+
+	if len(className) == 0 {
+		return false
+	}
+
+	class, err := vc.classLister.Get(className)
+	if err != nil {
+		return false
+	}
+
+	return class.VolumeBindingMode != nil &&
+		*class.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer
+}
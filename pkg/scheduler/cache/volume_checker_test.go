@@ -0,0 +1,161 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func buildLocalPV(name, hostname string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PersistentVolumeSpec{
+			NodeAffinity: &v1.VolumeNodeAffinity{
+				Required: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{
+							MatchExpressions: []v1.NodeSelectorRequirement{
+								{
+									Key:      "kubernetes.io/hostname",
+									Operator: v1.NodeSelectorOpIn,
+									Values:   []string{hostname},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func buildPVC(ns, name, volume, class string) *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Spec: v1.PersistentVolumeClaimSpec{
+			VolumeName:       volume,
+			StorageClassName: &class,
+		},
+	}
+}
+
+func buildPodWithClaim(ns, name, claim string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{
+				{
+					Name: "data",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+					},
+				},
+			},
+		},
+	}
+}
+
+func buildLabeledNode(name string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"kubernetes.io/hostname": name},
+		},
+	}
+}
+
+func TestCheckVolumes(t *testing.T) {
+	waitForFirstConsumer := storagev1.VolumeBindingWaitForFirstConsumer
+	immediate := storagev1.VolumeBindingImmediate
+
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	classIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	pvIndexer.Add(buildLocalPV("local-pv", "n1"))
+	pvcIndexer.Add(buildPVC("c1", "local", "local-pv", "local-storage"))
+	pvcIndexer.Add(buildPVC("c1", "delayed", "", "delayed"))
+	pvcIndexer.Add(buildPVC("c1", "unbound", "", "standard"))
+	classIndexer.Add(&storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "delayed"},
+		VolumeBindingMode: &waitForFirstConsumer,
+	})
+	classIndexer.Add(&storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "standard"},
+		VolumeBindingMode: &immediate,
+	})
+
+	checker := NewVolumeChecker(
+		corelisters.NewPersistentVolumeClaimLister(pvcIndexer),
+		corelisters.NewPersistentVolumeLister(pvIndexer),
+		storagelisters.NewStorageClassLister(classIndexer))
+
+	tests := []struct {
+		name  string
+		pod   *v1.Pod
+		node  *v1.Node
+		fails bool
+	}{
+		{
+			name: "no volume",
+			pod:  &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "c1", Name: "p1"}},
+			node: buildLabeledNode("n2"),
+		},
+		{
+			name: "local PV on its node",
+			pod:  buildPodWithClaim("c1", "p1", "local"),
+			node: buildLabeledNode("n1"),
+		},
+		{
+			name:  "local PV on other node",
+			pod:   buildPodWithClaim("c1", "p1", "local"),
+			node:  buildLabeledNode("n2"),
+			fails: true,
+		},
+		{
+			name: "unbound PVC waiting for first consumer",
+			pod:  buildPodWithClaim("c1", "p1", "delayed"),
+			node: buildLabeledNode("n2"),
+		},
+		{
+			name:  "unbound PVC bound immediately",
+			pod:   buildPodWithClaim("c1", "p1", "unbound"),
+			node:  buildLabeledNode("n2"),
+			fails: true,
+		},
+		{
+			name:  "PVC not found",
+			pod:   buildPodWithClaim("c1", "p1", "unknown"),
+			node:  buildLabeledNode("n1"),
+			fails: true,
+		},
+	}
+
+	for i, test := range tests {
+		err := checker.CheckVolumes(test.pod, test.node)
+		if test.fails != (err != nil) {
+			t.Errorf("case %d (%s): expected failure %v, got %v", i, test.name, test.fails, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// SessionPath is the path of the debug endpoint of the latest session.
+const SessionPath = "/debug/session"
+
+type taskState struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	NodeName  string `json:"nodeName,omitempty"`
+}
+
+type jobState struct {
+	UID          api.JobID     `json:"uid"`
+	Namespace    string        `json:"namespace"`
+	Name         string        `json:"name"`
+	MinAvailable int           `json:"minAvailable"`
+	Allocated    *api.Resource `json:"allocated"`
+	TotalRequest *api.Resource `json:"totalRequest"`
+	// Share is the dominant share of the allocated resource in cluster.
+	Share float64     `json:"share"`
+	Tasks []taskState `json:"tasks"`
+}
+
+type nodeState struct {
+	Name        string        `json:"name"`
+	Allocatable *api.Resource `json:"allocatable"`
+	Idle        *api.Resource `json:"idle"`
+	Used        *api.Resource `json:"used"`
+	Releasing   *api.Resource `json:"releasing"`
+}
+
+// sessionState is the state of jobs and nodes at the end of a session.
+type sessionState struct {
+	ID        string      `json:"id"`
+	Iteration int64       `json:"iteration"`
+	Jobs      []jobState  `json:"jobs"`
+	Nodes     []nodeState `json:"nodes"`
+
+	Fragmentation *fragmentation `json:"fragmentation"`
+}
+
+// newSessionState copies the state of ssn, so it's still valid after the
+// session is closed.
+func newSessionState(ssn *framework.Session) *sessionState {
+	state := &sessionState{
+		ID:        string(ssn.ID),
+		Iteration: ssn.Iteration,
+	}
+
+	total := api.EmptyResource()
+	for _, node := range ssn.Nodes {
+		total.Add(node.Allocatable)
+		state.Nodes = append(state.Nodes, nodeState{
+			Name:        node.Name,
+			Allocatable: node.Allocatable.Clone(),
+			Idle:        node.Idle.Clone(),
+			Used:        node.Used.Clone(),
+			Releasing:   node.Releasing.Clone(),
+		})
+	}
+
+	for _, job := range ssn.Jobs {
+		js := jobState{
+			UID:          job.UID,
+			Namespace:    job.Namespace,
+			Name:         job.Name,
+			MinAvailable: job.MinAvailable,
+			Allocated:    job.Allocated.Clone(),
+			TotalRequest: job.TotalRequest.Clone(),
+		}
+
+		for _, rn := range api.ResourceNames() {
+			if total.Get(rn) == 0 {
+				continue
+			}
+			if s := job.Allocated.Get(rn) / total.Get(rn); s > js.Share {
+				js.Share = s
+			}
+		}
+
+		for _, task := range job.Tasks {
+			js.Tasks = append(js.Tasks, taskState{
+				Namespace: task.Namespace,
+				Name:      task.Name,
+				Status:    task.Status.String(),
+				NodeName:  task.NodeName,
+			})
+		}
+		sort.Slice(js.Tasks, func(i, j int) bool {
+			return js.Tasks[i].Namespace+"/"+js.Tasks[i].Name < js.Tasks[j].Namespace+"/"+js.Tasks[j].Name
+		})
+
+		state.Jobs = append(state.Jobs, js)
+	}
+
+	sort.Slice(state.Jobs, func(i, j int) bool { return state.Jobs[i].UID < state.Jobs[j].UID })
+	sort.Slice(state.Nodes, func(i, j int) bool { return state.Nodes[i].Name < state.Nodes[j].Name })
+
+	return state
+}
+
+// ServeHTTP writes the state of the latest session as JSON; it's not found
+// if no session is closed yet.
+func (pc *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pc.mutex.Lock()
+	state := pc.lastSession
+	pc.mutex.Unlock()
+
+	if state == nil {
+		http.Error(w, "no session yet", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		glog.Errorf("Failed to write the state of session <%v>: %v", state.ID, err)
+	}
+}
@@ -19,21 +19,63 @@ package scheduler
 import (
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/decorate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/drain"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/preempt"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/reclaim"
 
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/age"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/benefit"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/deadline"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/drf"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/exclusive"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/extender"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/gang"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/gpushare"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/gputopology"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/headroom"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/lottery"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/namespace"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/nodecost"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/predicates"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/priority"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/quota"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/resourcefit"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/spot"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/spread"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/utilization"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/workloadtype"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/zonebalance"
 
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
 )
 
 func init() {
-	framework.RegisterPluginBuilder(priority.New)
-	framework.RegisterPluginBuilder(gang.New)
-	framework.RegisterPluginBuilder(drf.New)
+	framework.RegisterPluginBuilder(priority.PluginName, priority.New)
+	framework.RegisterPluginBuilder(gang.PluginName, gang.New)
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	framework.RegisterPluginBuilder(benefit.PluginName, benefit.New)
+	framework.RegisterPluginBuilder(namespace.PluginName, namespace.New)
+	framework.RegisterPluginBuilder(headroom.PluginName, headroom.New)
+	framework.RegisterPluginBuilder(predicates.PluginName, predicates.New)
+	framework.RegisterPluginBuilder(spread.PluginName, spread.New)
+	framework.RegisterPluginBuilder(lottery.PluginName, lottery.New)
+	framework.RegisterPluginBuilder(extender.PluginName, extender.New)
+	framework.RegisterPluginBuilder(nodecost.PluginName, nodecost.New)
+	framework.RegisterPluginBuilder(exclusive.PluginName, exclusive.New)
+	framework.RegisterPluginBuilder(gputopology.PluginName, gputopology.New)
+	framework.RegisterPluginBuilder(gpushare.PluginName, gpushare.New)
+	framework.RegisterPluginBuilder(resourcefit.PluginName, resourcefit.New)
+	framework.RegisterPluginBuilder(quota.PluginName, quota.New)
+	framework.RegisterPluginBuilder(deadline.PluginName, deadline.New)
+	framework.RegisterPluginBuilder(age.PluginName, age.New)
+	framework.RegisterPluginBuilder(zonebalance.PluginName, zonebalance.New)
+	framework.RegisterPluginBuilder(spot.PluginName, spot.New)
+	framework.RegisterPluginBuilder(utilization.PluginName, utilization.New)
+	framework.RegisterPluginBuilder(workloadtype.PluginName, workloadtype.New)
 
 	framework.RegisterAction(decorate.New())
+	framework.RegisterAction(drain.New())
 	framework.RegisterAction(allocate.New())
 	framework.RegisterAction(preempt.New())
+	framework.RegisterAction(reclaim.New())
 }
@@ -0,0 +1,87 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"k8s.io/api/core/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
+)
+
+// fragmentation is how the idle resource of cluster is scattered across the
+// schedulable nodes, i.e. the ones not unschedulable nor draining.
+type fragmentation struct {
+	// Idle is the total idle resource of the nodes.
+	Idle *api.Resource `json:"idle"`
+
+	// Largest is the most idle resource of a single node in each dimension;
+	// a task requesting more than it fits no node, even if Idle is enough.
+	// The dimensions may be of different nodes, so it's the upper bound of
+	// the task requesting several resources.
+	Largest *api.Resource `json:"largest"`
+}
+
+func newFragmentation(nodes []*api.NodeInfo) *fragmentation {
+	f := &fragmentation{
+		Idle:    api.EmptyResource(),
+		Largest: api.EmptyResource(),
+	}
+
+	for _, node := range nodes {
+		if node.Draining || (node.Node != nil && node.Node.Spec.Unschedulable) {
+			continue
+		}
+
+		f.Idle.Add(node.Idle)
+		f.Largest.SetMaxResource(node.Idle)
+	}
+
+	return f
+}
+
+// ratio returns the fraction of idle resource of rn that is not on the node
+// of the most idle; it's 0 if no idle resource.
+func (f *fragmentation) ratio(rn v1.ResourceName) float64 {
+	idle := f.Idle.Get(rn)
+	if idle == 0 {
+		return 0
+	}
+	return 1 - f.Largest.Get(rn)/idle
+}
+
+// updateFragmentationMetrics exports the idle resource of nodes and how it's
+// fragmented, so operators see why a task does not fit the free capacity.
+func updateFragmentationMetrics(nodes []*api.NodeInfo) *fragmentation {
+	metrics.ResetNodeMetrics()
+	for _, node := range nodes {
+		if node.Draining || (node.Node != nil && node.Node.Spec.Unschedulable) {
+			continue
+		}
+		for _, rn := range api.ResourceNames() {
+			metrics.UpdateJobResource(metrics.NodeIdle, node.Name, string(rn), node.Idle.Get(rn))
+		}
+	}
+
+	f := newFragmentation(nodes)
+	for _, rn := range api.ResourceNames() {
+		metrics.UpdateResource(metrics.LargestSchedulable, string(rn), f.Largest.Get(rn))
+		metrics.UpdateResource(metrics.Fragmentation, string(rn), f.ratio(rn))
+	}
+
+	return f
+}
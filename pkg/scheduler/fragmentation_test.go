@@ -0,0 +1,89 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"expvar"
+	"math"
+	"testing"
+
+	"k8s.io/api/core/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func getMetric(metric *expvar.Map, rn v1.ResourceName) float64 {
+	f, ok := metric.Get(string(rn)).(*expvar.Float)
+	if !ok {
+		return -1
+	}
+	return f.Value()
+}
+
+func TestFragmentation(t *testing.T) {
+	owner := testutil.BuildOwnerReference("owner1")
+
+	n1 := api.NewNodeInfo(testutil.BuildNode("n1", testutil.BuildResourceList("8", "16Gi")))
+	p1 := testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("5", "4Gi"), owner)
+	n1.AddTask(api.NewTaskInfo(p1))
+
+	n2 := api.NewNodeInfo(testutil.BuildNode("n2", testutil.BuildResourceList("4", "4Gi")))
+	n3 := api.NewNodeInfo(testutil.BuildNode("n3", testutil.BuildResourceList("1", "12Gi")))
+
+	// The draining node is not counted, though it's the biggest.
+	n4 := api.NewNodeInfo(testutil.BuildNode("n4", testutil.BuildResourceList("16", "32Gi")))
+	n4.Draining = true
+
+	f := updateFragmentationMetrics([]*api.NodeInfo{n1, n2, n3, n4})
+
+	// The largest schedulable figure is the biggest single node's idle: n2
+	// of cpu, and n3 of memory.
+	if f.Largest.MilliCPU != 4000 || f.Idle.MilliCPU != 8000 {
+		t.Errorf("expected largest cpu 4000 of idle 8000, got %v of %v", f.Largest.MilliCPU, f.Idle.MilliCPU)
+	}
+	if got := getMetric(metrics.LargestSchedulable, v1.ResourceCPU); got != 4000 {
+		t.Errorf("expected largest schedulable cpu 4000, got %v", got)
+	}
+	if got, expected := getMetric(metrics.LargestSchedulable, v1.ResourceMemory), float64(12*1024*1024*1024); got != expected {
+		t.Errorf("expected largest schedulable memory %v, got %v", expected, got)
+	}
+	if got := getMetric(metrics.Fragmentation, v1.ResourceCPU); math.Abs(got-0.5) > 1e-6 {
+		t.Errorf("expected cpu fragmentation 0.5, got %v", got)
+	}
+
+	if _, ok := metrics.NodeIdle.Get("n4").(*expvar.Map); ok {
+		t.Errorf("expected no idle metric of the draining node")
+	}
+	idle, ok := metrics.NodeIdle.Get("n1").(*expvar.Map)
+	if !ok {
+		t.Fatalf("expected idle metric of n1")
+	}
+	if cpu, ok := idle.Get(string(v1.ResourceCPU)).(*expvar.Float); !ok || cpu.Value() != 3000 {
+		t.Errorf("expected idle cpu 3000 of n1, got %v", idle.Get(string(v1.ResourceCPU)))
+	}
+
+	// No idle resource is not fragmented.
+	f = updateFragmentationMetrics(nil)
+	if f.Largest.MilliCPU != 0 || f.ratio(v1.ResourceCPU) != 0 {
+		t.Errorf("expected no fragmentation of empty cluster, got %+v", f)
+	}
+	if _, ok := metrics.NodeIdle.Get("n1").(*expvar.Map); ok {
+		t.Errorf("expected the idle metric of gone node removed")
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Arguments are the key/value configurations of a plugin.
+type Arguments map[string]string
+
+// GetInt sets ptr to the int value of key; ptr is unchanged if not found or invalid.
+func (a Arguments) GetInt(ptr *int, key string) {
+	if ptr == nil {
+		return
+	}
+
+	v, found := a[key]
+	if !found || len(v) == 0 {
+		return
+	}
+
+	value, err := strconv.Atoi(v)
+	if err != nil {
+		glog.Warningf("Could not parse argument: %s for key %s, with err %v", v, key, err)
+		return
+	}
+
+	*ptr = value
+}
+
+// GetFloat64 sets ptr to the float64 value of key; ptr is unchanged if not found or invalid.
+func (a Arguments) GetFloat64(ptr *float64, key string) {
+	if ptr == nil {
+		return
+	}
+
+	v, found := a[key]
+	if !found || len(v) == 0 {
+		return
+	}
+
+	value, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		glog.Warningf("Could not parse argument: %s for key %s, with err %v", v, key, err)
+		return
+	}
+
+	*ptr = value
+}
+
+// GetBool sets ptr to the bool value of key; ptr is unchanged if not found or invalid.
+func (a Arguments) GetBool(ptr *bool, key string) {
+	if ptr == nil {
+		return
+	}
+
+	v, found := a[key]
+	if !found || len(v) == 0 {
+		return
+	}
+
+	value, err := strconv.ParseBool(v)
+	if err != nil {
+		glog.Warningf("Could not parse argument: %s for key %s, with err %v", v, key, err)
+		return
+	}
+
+	*ptr = value
+}
+
+// GetDuration sets ptr to the duration value of key, e.g. "10m"; ptr is unchanged if not found or invalid.
+func (a Arguments) GetDuration(ptr *time.Duration, key string) {
+	if ptr == nil {
+		return
+	}
+
+	v, found := a[key]
+	if !found || len(v) == 0 {
+		return
+	}
+
+	value, err := time.ParseDuration(v)
+	if err != nil {
+		glog.Warningf("Could not parse argument: %s for key %s, with err %v", v, key, err)
+		return
+	}
+
+	*ptr = value
+}
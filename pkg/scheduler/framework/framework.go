@@ -17,20 +17,58 @@ limitations under the License.
 package framework
 
 import (
+	"github.com/golang/glog"
+
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
 )
 
-func OpenSession(cache cache.Cache) *Session {
+// NodeOrderWeight is the argument of every plugin, which is the weight of its
+// normalized node scores in the sum of all plugins; it's 1 by default.
+const NodeOrderWeight = "nodeOrderWeight"
+
+// PluginOption is the name and arguments of the plugin enabled in session.
+type PluginOption struct {
+	Name      string
+	Arguments Arguments
+}
+
+// SessionOption sets the session before its plugins are opened, e.g. the
+// Context or the preemption limits that the plugins may use in OnSessionOpen.
+type SessionOption func(ssn *Session)
+
+func OpenSession(cache cache.Cache, plugins []*PluginOption, opts ...SessionOption) *Session {
 	ssn := openSession(cache)
+	for _, opt := range opts {
+		opt(ssn)
+	}
 
-	for _, pb := range pluginBuilders {
-		ssn.plugins = append(ssn.plugins, pb())
+	for _, po := range plugins {
+		pb, found := GetPluginBuilder(po.Name)
+		if !found {
+			glog.Errorf("Failed to get plugin %s.", po.Name)
+			continue
+		}
+		ssn.plugins = append(ssn.plugins, pb(po.Arguments))
+		ssn.pluginOptions = append(ssn.pluginOptions, po)
 	}
 
-	for _, plugin := range ssn.plugins {
+	for i, plugin := range ssn.plugins {
+		offset := len(ssn.nodeOrderFns)
 		plugin.OnSessionOpen(ssn)
+
+		// Weight the NodeOrderFns registered by the plugin.
+		weight := 1.0
+		ssn.pluginOptions[i].Arguments.GetFloat64(&weight, NodeOrderWeight)
+		for _, nf := range ssn.nodeOrderFns[offset:] {
+			nf.weight = weight
+		}
 	}
 
+	// The unschedulable tasks are marked after the gates, so nothing is
+	// sent to cache in dry run.
+	ssn.evaluateGates()
+	ssn.validateTasks()
+
 	return ssn
 }
 
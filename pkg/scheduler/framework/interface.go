@@ -32,6 +32,13 @@ type Action interface {
 }
 
 type Plugin interface {
+	// The unique name of Plugin.
+	Name() string
+
+	// OnSessionOpen is called once a session is opened, before any action;
+	// plugins register their callbacks to the session here.
 	OnSessionOpen(ssn *Session)
+
+	// OnSessionClose is called once the session is closed, after all actions.
 	OnSessionClose(ssn *Session)
 }
@@ -20,21 +20,32 @@ import "sync"
 
 var pluginMutex sync.Mutex
 
+// PluginBuilder builds a plugin by its arguments.
+type PluginBuilder func(Arguments) Plugin
+
 // Plugin management
-var pluginBuilders []func() Plugin
+var pluginBuilders = map[string]PluginBuilder{}
 
-func RegisterPluginBuilder(pc func() Plugin) {
+func RegisterPluginBuilder(name string, pc PluginBuilder) {
 	pluginMutex.Lock()
 	defer pluginMutex.Unlock()
 
-	pluginBuilders = append(pluginBuilders, pc)
+	pluginBuilders[name] = pc
 }
 
 func CleanupPluginBuilders() {
 	pluginMutex.Lock()
 	defer pluginMutex.Unlock()
 
-	pluginBuilders = []func() Plugin{}
+	pluginBuilders = map[string]PluginBuilder{}
+}
+
+func GetPluginBuilder(name string) (PluginBuilder, bool) {
+	pluginMutex.Lock()
+	defer pluginMutex.Unlock()
+
+	pb, found := pluginBuilders[name]
+	return pb, found
 }
 
 // Action management
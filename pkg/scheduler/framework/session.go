@@ -17,11 +17,19 @@ limitations under the License.
 package framework
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
 	"github.com/golang/glog"
 
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/uuid"
 
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
 )
@@ -29,6 +37,10 @@ import (
 type Session struct {
 	ID types.UID
 
+	// Iteration is the sequence number of the session, e.g. for plugins
+	// to age jobs across sessions.
+	Iteration int64
+
 	cache cache.Cache
 
 	Jobs      []*api.JobInfo
@@ -37,24 +49,98 @@ type Session struct {
 	NodeIndex map[string]*api.NodeInfo
 	Backlog   []*api.JobInfo
 
-	plugins        []Plugin
-	eventHandlers  []*EventHandler
-	jobOrderFns    []api.CompareFn
-	taskOrderFns   []api.CompareFn
-	preemptableFns []api.LessFn
-	jobReadyFns    []api.ValidateFn
+	// Quotas are the ResourceQuotas of namespaces, read-only for plugins.
+	Quotas []*api.QuotaInfo
+
+	// PercentageOfNodesToScore is the percentage of nodes that actions stop
+	// at when looking for feasible nodes of a task; zero means all nodes.
+	PercentageOfNodesToScore int32
+
+	// MaxPreemptees is the max number of victims that preempt examines for
+	// a preemptor; zero means no limit.
+	MaxPreemptees int
+
+	// MaxPreemptions is the max number of tasks that preempt evicts in the
+	// session; zero means no limit.
+	MaxPreemptions int
+
+	// MaxPreemptionRounds is the max number of preemptions that preempt
+	// commits in the session, each for a preemptor job with some evictions;
+	// zero means no limit. The evicted victims are recreated and may preempt
+	// others in turn, so it bounds the cascade of preemptions.
+	MaxPreemptionRounds int
+
+	// MaxVictimsPerPreemptor is the max number of victims that preempt
+	// evicts for a preemptor; zero means no limit.
+	MaxVictimsPerPreemptor int
+
+	// MaxVictimsPerJob is the max number of victims that preempt evicts for
+	// the tasks of a preemptor job, unless the job overrides it by annotation;
+	// zero means no limit.
+	MaxVictimsPerJob int
+
+	// DryRun is true if a gate of plugins vetoed the session at open; the
+	// actions still make decisions in session, but nothing is sent to cache,
+	// e.g. binds, evictions and events.
+	DryRun bool
+
+	// Context is done when the session is timed out or the scheduler is
+	// shutting down; the actions stop at their loop boundaries then, and
+	// the decisions made so far are kept. Nil means no deadline.
+	Context context.Context
+
+	plugins         []Plugin
+	pluginOptions   []*PluginOption
+	eventHandlers   []*EventHandler
+	jobOrderFns     []api.CompareFn
+	taskOrderFns    []api.CompareFn
+	preemptableFns  []api.LessFn
+	jobReadyFns     []api.ValidateFn
+	jobPipelinedFns []api.ValidateFn
+	overusedFns     []api.ValidateFn
+	jobValidFns     []api.ValidateFn
+	predicateFns    []api.PredicateFn
+	nodeOrderFns    []*nodeOrderFn
+	victimOrderFns  []api.VictimOrderFn
+	reclaimableFns  []api.ReclaimableFn
+	postBindFns     []api.PostBindFn
+	sessionGateFns  []api.SessionGateFn
+
+	// The reasons of the tasks that can not be scheduled in any case.
+	invalidTasks map[api.TaskID]string
+
+	// The predicate failures of each node by the equivalence key of tasks,
+	// so the equivalent tasks are not checked again on the node. Only the
+	// failures are kept: a node that rejected a task may accept it only if
+	// the node changes or resource is released, e.g. by evicting tasks on
+	// other nodes, but a node that accepted a task may reject the next one,
+	// e.g. when the namespace reaches its quota.
+	predicateFailures map[string]map[string]error
+
+	// The nodes and jobs updated in this session; the cache will not reuse
+	// them in next snapshot. Plugins should update nodes and jobs by the
+	// functions of session, e.g. Allocate, instead of changing them directly.
+	dirtyNodes map[string]bool
+	dirtyJobs  map[api.JobID]bool
 }
 
 func openSession(cache cache.Cache) *Session {
 	ssn := &Session{
-		ID:        uuid.NewUUID(),
-		cache:     cache,
-		JobIndex:  map[api.JobID]*api.JobInfo{},
-		NodeIndex: map[string]*api.NodeInfo{},
+		ID:           uuid.NewUUID(),
+		cache:        cache,
+		JobIndex:     map[api.JobID]*api.JobInfo{},
+		NodeIndex:    map[string]*api.NodeInfo{},
+		invalidTasks: map[api.TaskID]string{},
+		dirtyNodes:   map[string]bool{},
+		dirtyJobs:    map[api.JobID]bool{},
+
+		predicateFailures: map[string]map[string]error{},
 	}
 
 	snapshot := cache.Snapshot()
 
+	ssn.Iteration = snapshot.Iteration
+
 	ssn.Jobs = snapshot.Jobs
 	for _, job := range ssn.Jobs {
 		ssn.JobIndex[job.UID] = job
@@ -65,31 +151,210 @@ func openSession(cache cache.Cache) *Session {
 		ssn.NodeIndex[node.Name] = node
 	}
 
+	ssn.Quotas = snapshot.Quotas
+
+	ssn.reserveNominated()
+
 	return ssn
 }
 
+// reserveNominated reserves the idle resource on the nominated nodes of the
+// pending tasks for their jobs, e.g. the resource that the victims of a
+// preemptor released in previous sessions; so it's not taken by other jobs
+// before the preemptor is bound, even if the preemptor is not scheduled in
+// this session.
+func (ssn *Session) reserveNominated() {
+	for _, job := range ssn.Jobs {
+		for _, task := range job.TaskStatusIndex[api.Pending] {
+			if len(task.NominatedNodeName) == 0 {
+				continue
+			}
+
+			node, found := ssn.NodeIndex[task.NominatedNodeName]
+			if !found || node.Node == nil {
+				continue
+			}
+
+			req := api.Min(task.Resreq, node.Idle)
+			if req.IsEmpty() {
+				continue
+			}
+
+			glog.V(4).Infof("Reserve <%v> on node <%v> for nominated Task <%v/%v>",
+				req, node.Name, task.Namespace, task.Name)
+			node.Reserve(task.Job, req)
+
+			// The reservation is of this session only, the node is cloned
+			// again by next snapshot.
+			ssn.dirtyNodes[node.Name] = true
+		}
+	}
+}
+
+// evaluateGates turns the session into dry run if any gate vetoes it.
+func (ssn *Session) evaluateGates() {
+	for _, gate := range ssn.sessionGateFns {
+		if err := gate(); err != nil {
+			glog.Infof("Session <%s> is vetoed, run it in dry run mode: %v", ssn.ID, err)
+			ssn.DryRun = true
+			return
+		}
+	}
+}
+
+// dryRun returns true if the session is in dry run mode, so op is not sent
+// to cache.
+func (ssn *Session) dryRun(op string, task *api.TaskInfo) bool {
+	if ssn.DryRun {
+		glog.V(4).Infof("Skip %s of Task <%v/%v> in dry run Session <%s>",
+			op, task.Namespace, task.Name, ssn.ID)
+	}
+	return ssn.DryRun
+}
+
+// validateTasks marks the pending tasks that request more resource than
+// the largest node as unschedulable, so actions will not retry them.
+func (ssn *Session) validateTasks() {
+	// The nodes may be not ready yet.
+	if len(ssn.Nodes) == 0 {
+		return
+	}
+
+	maxCapability := api.EmptyResource()
+	for _, node := range ssn.Nodes {
+		maxCapability.SetMaxResource(node.Capability)
+	}
+
+	for _, job := range ssn.Jobs {
+		for _, task := range job.TaskStatusIndex[api.Pending] {
+			msg := exceedMessage(task.Resreq, maxCapability)
+			if len(msg) == 0 {
+				continue
+			}
+
+			glog.V(3).Infof("Task <%v/%v> is unschedulable: %v", task.Namespace, task.Name, msg)
+
+			ssn.invalidTasks[task.UID] = msg
+			if err := ssn.TaskUnschedulable(task, msg); err != nil {
+				glog.Errorf("Failed to mark Task <%v/%v> unschedulable: %v",
+					task.Namespace, task.Name, err)
+			}
+		}
+	}
+}
+
+// exceedMessage describes the first resource dimension of req that exceeds
+// max; it's empty if req fits max.
+func exceedMessage(req, max *api.Resource) string {
+	switch {
+	case req.MilliCPU > max.MilliCPU:
+		return fmt.Sprintf("requests %v CPU; largest node has %v",
+			req.MilliCPU/1000, max.MilliCPU/1000)
+	case req.Memory > max.Memory:
+		return fmt.Sprintf("requests %v memory; largest node has %v",
+			resource.NewQuantity(int64(req.Memory), resource.BinarySI),
+			resource.NewQuantity(int64(max.Memory), resource.BinarySI))
+	case req.MilliGPU > max.MilliGPU:
+		return fmt.Sprintf("requests %v GPU; largest node has %v",
+			req.MilliGPU/1000, max.MilliGPU/1000)
+	}
+
+	for rn, v := range req.ScalarResources {
+		if v > max.ScalarResources[rn] {
+			if rn == v1.ResourceEphemeralStorage {
+				return fmt.Sprintf("requests %v %v; largest node has %v",
+					resource.NewQuantity(int64(v/1000), resource.BinarySI), rn,
+					resource.NewQuantity(int64(max.ScalarResources[rn]/1000), resource.BinarySI))
+			}
+			return fmt.Sprintf("requests %v %v; largest node has %v",
+				v/1000, rn, max.ScalarResources[rn]/1000)
+		}
+	}
+
+	return ""
+}
+
+// TaskValid returns false if the task can not be scheduled in any case,
+// e.g. it requests more resource than the largest node.
+func (ssn *Session) TaskValid(task *api.TaskInfo) bool {
+	_, found := ssn.invalidTasks[task.UID]
+	return !found
+}
+
+// touch marks the job and node of task updated in this session.
+func (ssn *Session) touch(task *api.TaskInfo) {
+	ssn.dirtyJobs[task.Job] = true
+	if len(task.NodeName) != 0 {
+		ssn.dirtyNodes[task.NodeName] = true
+		delete(ssn.predicateFailures, task.NodeName)
+	}
+}
+
+// release forgets all predicate failures after resource is released, e.g.
+// a task is evicted, as the other nodes may accept the tasks now, e.g. by
+// quota.
+func (ssn *Session) release() {
+	ssn.predicateFailures = map[string]map[string]error{}
+}
+
 func closeSession(ssn *Session) {
+	nodes := make([]string, 0, len(ssn.dirtyNodes))
+	for name := range ssn.dirtyNodes {
+		nodes = append(nodes, name)
+	}
+
+	jobs := make([]api.JobID, 0, len(ssn.dirtyJobs))
+	for id := range ssn.dirtyJobs {
+		jobs = append(jobs, id)
+	}
+
+	ssn.cache.Invalidate(nodes, jobs)
+
 	ssn.Jobs = nil
 	ssn.JobIndex = nil
 	ssn.Nodes = nil
 	ssn.NodeIndex = nil
 	ssn.Backlog = nil
+	ssn.Quotas = nil
+	ssn.invalidTasks = nil
+	ssn.dirtyNodes = nil
+	ssn.dirtyJobs = nil
 	ssn.plugins = nil
+	ssn.pluginOptions = nil
 	ssn.eventHandlers = nil
 	ssn.jobOrderFns = nil
 }
 
 func (ssn *Session) Pipeline(task *api.TaskInfo, hostname string) error {
-	// Only update status in session
+	if err := ssn.pipeline(task, hostname); err != nil {
+		return err
+	}
+
+	for _, eh := range ssn.eventHandlers {
+		if eh.AllocateFunc != nil {
+			eh.AllocateFunc(&Event{
+				Task: task,
+			})
+		}
+	}
+
+	return nil
+}
+
+// pipeline only updates status in session without callbacks.
+func (ssn *Session) pipeline(task *api.TaskInfo, hostname string) error {
 	job, found := ssn.JobIndex[task.Job]
 	if found {
-		job.UpdateTaskStatus(task, api.Pipelined)
+		if err := job.UpdateTaskStatus(task, api.Pipelined); err != nil {
+			return err
+		}
 	} else {
 		glog.Errorf("Failed to found Job <%s> in Session <%s> index when binding.",
 			task.Job, ssn.ID)
 	}
 
 	task.NodeName = hostname
+	ssn.touch(task)
 
 	if node, found := ssn.NodeIndex[hostname]; found {
 		node.PipelineTask(task)
@@ -98,9 +363,45 @@ func (ssn *Session) Pipeline(task *api.TaskInfo, hostname string) error {
 			hostname, ssn.ID)
 	}
 
+	return nil
+}
+
+// UnPipeline reverts Pipeline: the task releases the resource it occupied
+// on node and becomes Pending again, so it can be tried on other nodes.
+func (ssn *Session) UnPipeline(task *api.TaskInfo) error {
+	if task.Status != api.Pipelined {
+		return fmt.Errorf("task <%v/%v> is not pipelined but %v",
+			task.Namespace, task.Name, task.Status)
+	}
+
+	ssn.touch(task)
+	ssn.release()
+
+	// Remove the task from node before updating its status, as node
+	// releases resource according to the status.
+	if node, found := ssn.NodeIndex[task.NodeName]; found {
+		node.RemoveTask(task)
+	} else {
+		glog.Errorf("Failed to found Node <%s> in Session <%s> index when unpipelining.",
+			task.NodeName, ssn.ID)
+	}
+
+	job, found := ssn.JobIndex[task.Job]
+	if found {
+		if err := job.UpdateTaskStatus(task, api.Pending); err != nil {
+			glog.Errorf("Failed to unpipeline Task <%v/%v> in Session <%s>: %v",
+				task.Namespace, task.Name, ssn.ID, err)
+		}
+	} else {
+		glog.Errorf("Failed to found Job <%s> in Session <%s> index when unpipelining.",
+			task.Job, ssn.ID)
+	}
+
+	task.NodeName = ""
+
 	for _, eh := range ssn.eventHandlers {
-		if eh.AllocateFunc != nil {
-			eh.AllocateFunc(&Event{
+		if eh.EvictFunc != nil {
+			eh.EvictFunc(&Event{
 				Task: task,
 			})
 		}
@@ -113,13 +414,16 @@ func (ssn *Session) Allocate(task *api.TaskInfo, hostname string) error {
 	// Only update status in session
 	job, found := ssn.JobIndex[task.Job]
 	if found {
-		job.UpdateTaskStatus(task, api.Allocated)
+		if err := job.UpdateTaskStatus(task, api.Allocated); err != nil {
+			return err
+		}
 	} else {
 		glog.Errorf("Failed to found Job <%s> in Session <%s> index when binding.",
 			task.Job, ssn.ID)
 	}
 
 	task.NodeName = hostname
+	ssn.touch(task)
 
 	if node, found := ssn.NodeIndex[hostname]; found {
 		node.AddTask(task)
@@ -147,13 +451,28 @@ func (ssn *Session) Allocate(task *api.TaskInfo, hostname string) error {
 }
 
 func (ssn *Session) dispatch(task *api.TaskInfo) error {
-	if err := ssn.cache.Bind(task, task.NodeName); err != nil {
-		return err
+	// The bind completes in background, maybe after the session is closed.
+	postBindFns, hostname := ssn.postBindFns, task.NodeName
+	bound := func() {
+		for _, pbf := range postBindFns {
+			pbf(task, hostname)
+		}
+	}
+
+	if !ssn.dryRun("bind", task) {
+		if err := ssn.cache.Bind(task, task.NodeName, bound); err != nil {
+			return err
+		}
 	}
 
+	ssn.touch(task)
+
 	// Update status in session
 	if job, found := ssn.JobIndex[task.Job]; found {
-		job.UpdateTaskStatus(task, api.Binding)
+		if err := job.UpdateTaskStatus(task, api.Binding); err != nil {
+			glog.Errorf("Failed to dispatch Task <%v/%v> in Session <%s>: %v",
+				task.Namespace, task.Name, ssn.ID, err)
+		}
 	} else {
 		glog.Errorf("Failed to found Job <%s> in Session <%s> index when binding.",
 			task.Job, ssn.ID)
@@ -176,13 +495,46 @@ func (ssn *Session) Preemptable(preemptor, preemptee *api.TaskInfo) bool {
 	return true
 }
 
+// preemptReason is the reason of the eviction of preemptee.
+func preemptReason(preemptor *api.TaskInfo) string {
+	return fmt.Sprintf("Preempted by pod <%v/%v>", preemptor.Namespace, preemptor.Name)
+}
+
 func (ssn *Session) Preempt(preemptor, preemptee *api.TaskInfo) error {
-	if err := ssn.cache.Evict(preemptee); err != nil {
-		return err
+	if !ssn.dryRun("eviction", preemptee) {
+		if err := ssn.cache.Evict(preemptee, preemptReason(preemptor)); err != nil {
+			return err
+		}
+	}
+
+	ssn.preempt(preemptor, preemptee)
+
+	return nil
+}
+
+// preempt only updates status in session without evicting preemptee in
+// cache; it returns true if preemptor is pipelined to preemptee's node.
+func (ssn *Session) preempt(preemptor, preemptee *api.TaskInfo) bool {
+	ssn.touch(preemptor)
+
+	node := ssn.evict(preemptee)
+
+	pipelined := false
+	if node != nil {
+		// Promise the releasing resource to preemptor, so others will not
+		// preempt again for it.
+		if preemptor.Resreq.LessEqual(node.Releasing) {
+			if err := ssn.pipeline(preemptor, node.Name); err != nil {
+				glog.Errorf("Failed to pipeline Task <%v/%v> in Session <%s>: %v",
+					preemptor.Namespace, preemptor.Name, ssn.ID, err)
+			} else {
+				pipelined = true
+			}
+		}
 	}
 
 	for _, eh := range ssn.eventHandlers {
-		if eh.AllocateFunc != nil {
+		if pipelined && eh.AllocateFunc != nil {
 			eh.AllocateFunc(&Event{
 				Task: preemptor,
 			})
@@ -195,7 +547,75 @@ func (ssn *Session) Preempt(preemptor, preemptee *api.TaskInfo) error {
 		}
 	}
 
-	return nil
+	return pipelined
+}
+
+// evict only updates the status of task to Releasing in session without
+// callbacks; it returns the node of task, or nil if not found.
+func (ssn *Session) evict(task *api.TaskInfo) *api.NodeInfo {
+	ssn.touch(task)
+	ssn.release()
+
+	// Update status in session, the resource of task is releasing.
+	node, found := ssn.NodeIndex[task.NodeName]
+	if found {
+		node.RemoveTask(task)
+	} else {
+		glog.Errorf("Failed to found Node <%s> in Session <%s> index when evicting.",
+			task.NodeName, ssn.ID)
+	}
+
+	if job, found := ssn.JobIndex[task.Job]; found {
+		if err := job.UpdateTaskStatus(task, api.Releasing); err != nil {
+			glog.Errorf("Failed to evict Task <%v/%v> in Session <%s>: %v",
+				task.Namespace, task.Name, ssn.ID, err)
+		}
+	} else {
+		glog.Errorf("Failed to found Job <%s> in Session <%s> index when evicting.",
+			task.Job, ssn.ID)
+	}
+
+	if node != nil {
+		node.AddTask(task)
+	}
+
+	return node
+}
+
+// unpreempt reverts preempt in session: preemptee gets the status before
+// preemption back; the preemptor should be unpipelined before it.
+func (ssn *Session) unpreempt(preemptee *api.TaskInfo, status api.TaskStatus) {
+	ssn.touch(preemptee)
+
+	node, found := ssn.NodeIndex[preemptee.NodeName]
+	if found {
+		node.RemoveTask(preemptee)
+	} else {
+		glog.Errorf("Failed to found Node <%s> in Session <%s> index when unpreempting.",
+			preemptee.NodeName, ssn.ID)
+	}
+
+	if job, found := ssn.JobIndex[preemptee.Job]; found {
+		if err := job.UpdateTaskStatus(preemptee, status); err != nil {
+			glog.Errorf("Failed to unpreempt Task <%v/%v> in Session <%s>: %v",
+				preemptee.Namespace, preemptee.Name, ssn.ID, err)
+		}
+	} else {
+		glog.Errorf("Failed to found Job <%s> in Session <%s> index when unpreempting.",
+			preemptee.Job, ssn.ID)
+	}
+
+	if node != nil {
+		node.AddTask(preemptee)
+	}
+
+	for _, eh := range ssn.eventHandlers {
+		if eh.AllocateFunc != nil {
+			eh.AllocateFunc(&Event{
+				Task: preemptee,
+			})
+		}
+	}
 }
 
 func (ssn *Session) AddEventHandler(eh *EventHandler) {
@@ -218,7 +638,298 @@ func (ssn *Session) AddJobReadyFn(vf api.ValidateFn) {
 	ssn.jobReadyFns = append(ssn.jobReadyFns, vf)
 }
 
+func (ssn *Session) AddJobPipelinedFn(vf api.ValidateFn) {
+	ssn.jobPipelinedFns = append(ssn.jobPipelinedFns, vf)
+}
+
+// JobPipelined returns true if all plugins think the job can start once the
+// resource promised to its pipelined tasks is released.
+func (ssn *Session) JobPipelined(obj interface{}) bool {
+	for _, jpf := range ssn.jobPipelinedFns {
+		if !jpf(obj) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (ssn *Session) AddOverusedFn(vf api.ValidateFn) {
+	ssn.overusedFns = append(ssn.overusedFns, vf)
+}
+
+// Overused returns true if any plugin thinks the job has used more resource than it deserves.
+func (ssn *Session) Overused(obj interface{}) bool {
+	for _, of := range ssn.overusedFns {
+		if of(obj) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ssn *Session) AddReclaimableFn(rf api.ReclaimableFn) {
+	ssn.reclaimableFns = append(ssn.reclaimableFns, rf)
+}
+
+// Reclaimable returns the resource of job that can be reclaimed for others,
+// the least of all plugins; it's false if no plugin decides it.
+func (ssn *Session) Reclaimable(job *api.JobInfo) (*api.Resource, bool) {
+	var res *api.Resource
+	for _, rf := range ssn.reclaimableFns {
+		r := rf(job)
+		if res == nil {
+			res = r.Clone()
+		} else {
+			res = api.Min(res, r)
+		}
+	}
+
+	return res, res != nil
+}
+
+func (ssn *Session) AddJobValidFn(vf api.ValidateFn) {
+	ssn.jobValidFns = append(ssn.jobValidFns, vf)
+}
+
+// JobValid returns false if any plugin thinks the job should not be scheduled.
+func (ssn *Session) JobValid(obj interface{}) bool {
+	for _, jvf := range ssn.jobValidFns {
+		if !jvf(obj) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AddSessionGateFn adds the gate evaluated once all plugins are opened; if
+// any gate vetoes, the session is in dry run mode, see DryRun.
+func (ssn *Session) AddSessionGateFn(gf api.SessionGateFn) {
+	ssn.sessionGateFns = append(ssn.sessionGateFns, gf)
+}
+
+// AddPostBindFn adds the function called after a task dispatched in this
+// session is bound by the binder; unlike AllocateFunc of EventHandler, which
+// is called when the task is allocated in session, it's not called if the
+// bind fails. It's called in the goroutine of binding, maybe after the
+// session is closed.
+func (ssn *Session) AddPostBindFn(pbf api.PostBindFn) {
+	ssn.postBindFns = append(ssn.postBindFns, pbf)
+}
+
+func (ssn *Session) AddPredicateFn(pf api.PredicateFn) {
+	ssn.predicateFns = append(ssn.predicateFns, pf)
+}
+
+// PredicateFn returns the error of the first plugin that rejects to place
+// task on node, or nil if all plugins accept; the draining nodes are always
+// rejected. Once node rejects a task, it rejects the equivalent tasks by the
+// same error without checking them until the node changes in session, so
+// the PredicateFn of plugins must decide by what EquivalenceKey of task
+// covers, e.g. the job and resource request, instead of the name of task.
+func (ssn *Session) PredicateFn(task *api.TaskInfo, node *api.NodeInfo) error {
+	if node.Draining {
+		return fmt.Errorf("node <%v> is draining", node.Name)
+	}
+
+	key := task.EquivalenceKey()
+	if len(key) != 0 {
+		if err, found := ssn.predicateFailures[node.Name][key]; found {
+			return err
+		}
+	}
+
+	for _, pf := range ssn.predicateFns {
+		if err := pf(task, node); err != nil {
+			if len(key) != 0 {
+				if _, found := ssn.predicateFailures[node.Name]; !found {
+					ssn.predicateFailures[node.Name] = map[string]error{}
+				}
+				ssn.predicateFailures[node.Name][key] = err
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nodeOrderFn is the NodeOrderFn of a plugin with the weight of its scores.
+type nodeOrderFn struct {
+	fn     api.NodeOrderFn
+	weight float64
+}
+
+func (ssn *Session) AddNodeOrderFn(nf api.NodeOrderFn) {
+	ssn.nodeOrderFns = append(ssn.nodeOrderFns, &nodeOrderFn{fn: nf, weight: 1})
+}
+
+// NodeOrderFn returns the weighted sum of the raw scores of node for task by
+// all plugins; it's zero if no plugin scores nodes.
+func (ssn *Session) NodeOrderFn(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+	score := 0.0
+	for _, nf := range ssn.nodeOrderFns {
+		s, err := nf.fn(task, node)
+		if err != nil {
+			return 0, err
+		}
+		score += s * nf.weight
+	}
+
+	return score, nil
+}
+
+// maxNodeScore is the score of the best node by a plugin after normalization.
+const maxNodeScore = 100.0
+
+// NodeScores returns the scores of nodes for task, by node name; the scores of
+// each plugin are normalized to [0, maxNodeScore] among nodes, so plugins of
+// different scales are comparable, and then summed with their weights. The
+// normalization is by the range of scores, so a plugin penalizing some nodes
+// by negative scores ranks them below the others as a plugin rewarding the
+// others would. The nodes that any plugin fails to score are not in the
+// result.
+func (ssn *Session) NodeScores(task *api.TaskInfo, nodes []*api.NodeInfo) map[string]float64 {
+	scores := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		scores[node.Name] = 0
+	}
+
+	for _, nf := range ssn.nodeOrderFns {
+		raw := make(map[string]float64, len(scores))
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, node := range nodes {
+			if _, found := scores[node.Name]; !found {
+				continue
+			}
+
+			s, err := nf.fn(task, node)
+			if err != nil {
+				glog.V(3).Infof("Failed to score Task <%v/%v> on node <%v>: %v",
+					task.Namespace, task.Name, node.Name, err)
+				delete(scores, node.Name)
+				continue
+			}
+
+			raw[node.Name] = s
+			min = math.Min(min, s)
+			max = math.Max(max, s)
+		}
+
+		// All nodes are equal for the plugin, it makes no difference.
+		if max <= min {
+			continue
+		}
+
+		for name, s := range raw {
+			if _, found := scores[name]; found {
+				scores[name] += (s - min) / (max - min) * maxNodeScore * nf.weight
+			}
+		}
+	}
+
+	return scores
+}
+
+func (ssn *Session) AddVictimOrderFn(vf api.VictimOrderFn) {
+	ssn.victimOrderFns = append(ssn.victimOrderFns, vf)
+}
+
+// VictimOrderFn returns the preference of the first plugin that prefers one
+// of the victims l and r of preemptor, or zero if none.
+func (ssn *Session) VictimOrderFn(preemptor, l, r *api.TaskInfo) int {
+	for _, vf := range ssn.victimOrderFns {
+		if v := vf(preemptor, l, r); v != 0 {
+			return v
+		}
+	}
+
+	return 0
+}
+
+// RecordEvent records an event of task's pod.
+func (ssn *Session) RecordEvent(task *api.TaskInfo, eventType, reason, message string) error {
+	if ssn.dryRun("event", task) {
+		return nil
+	}
+	return ssn.cache.RecordEvent(task, eventType, reason, message)
+}
+
+// TaskUnschedulable marks the pod of task unschedulable with the message.
+func (ssn *Session) TaskUnschedulable(task *api.TaskInfo, message string) error {
+	if ssn.dryRun("unschedulable condition", task) {
+		return nil
+	}
+	return ssn.cache.TaskUnschedulable(task, message)
+}
+
+// UpdateJobStatus updates the status of job's SchedulingSpec.
+func (ssn *Session) UpdateJobStatus(job *api.JobInfo, status *arbv1.SchedulingSpecStatus) error {
+	if ssn.DryRun {
+		glog.V(4).Infof("Skip status update of Job <%v/%v> in dry run Session <%s>",
+			job.Namespace, job.Name, ssn.ID)
+		return nil
+	}
+	return ssn.cache.UpdateJobStatus(job, status)
+}
+
+// CheckVolumes checks whether the volumes of task are available on node.
+func (ssn *Session) CheckVolumes(task *api.TaskInfo, node *api.NodeInfo) error {
+	return ssn.cache.CheckVolumes(task, node)
+}
+
+// NodeUtilization returns the real-time utilization of node by the metrics
+// source of cache; it returns an error if the metrics are unavailable.
+func (ssn *Session) NodeUtilization(node *api.NodeInfo) (map[v1.ResourceName]float64, error) {
+	return ssn.cache.NodeUtilization(node)
+}
+
+// Cancelled returns true if Context of session is done, i.e. the actions
+// should stop making more decisions.
+func (ssn *Session) Cancelled() bool {
+	return ssn.Context != nil && ssn.Context.Err() != nil
+}
+
+// Now returns the current time by the clock of cache; the plugins read it
+// instead of the real time, so they're deterministic with a fake clock.
+func (ssn *Session) Now() time.Time {
+	return ssn.cache.Now()
+}
+
+// PluginState returns the state saved by the plugin of name in previous
+// sessions, e.g. the wait time of jobs for aging; it's nil if none. Plugins
+// are built for each session, so they read their state in OnSessionOpen.
+func (ssn *Session) PluginState(name string) interface{} {
+	return ssn.cache.PluginState(name)
+}
+
+// SetPluginState saves the state of the plugin of name for next sessions,
+// usually in OnSessionClose.
+func (ssn *Session) SetPluginState(name string, state interface{}) {
+	ssn.cache.SetPluginState(name, state)
+}
+
+// Backoff records the reason why the job can not be scheduled.
+func (ssn *Session) Backoff(job *api.JobInfo, reason, message string) error {
+	if ssn.DryRun {
+		glog.V(4).Infof("Skip backoff of Job <%v/%v> in dry run Session <%s>",
+			job.Namespace, job.Name, ssn.ID)
+		return nil
+	}
+	return ssn.cache.Backoff(job, reason, message)
+}
+
+// JobReady returns whether the allocated tasks of job can be dispatched. If
+// no plugin decides it, e.g. gang, the job is ready once its MinAvailable
+// tasks are allocated, or all valid tasks if no MinAvailable; so the job of
+// several pods without gang info is not dispatched partially by accident.
 func (ssn *Session) JobReady(obj interface{}) bool {
+	if len(ssn.jobReadyFns) == 0 {
+		return ssn.defaultJobReady(obj.(*api.JobInfo))
+	}
+
 	for _, jrf := range ssn.jobReadyFns {
 		if !jrf(obj) {
 			return false
@@ -228,6 +939,26 @@ func (ssn *Session) JobReady(obj interface{}) bool {
 	return true
 }
 
+func (ssn *Session) defaultJobReady(job *api.JobInfo) bool {
+	if job.MinAvailable > 0 {
+		occupied := 0
+		for status, tasks := range job.TaskStatusIndex {
+			if api.AllocatedStatus(status) || status == api.Succeeded {
+				occupied += len(tasks)
+			}
+		}
+		return occupied >= job.MinAvailable
+	}
+
+	for _, task := range job.TaskStatusIndex[api.Pending] {
+		if ssn.TaskValid(task) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (ssn *Session) JobOrderFn(l, r interface{}) bool {
 	for _, jof := range ssn.jobOrderFns {
 		if j := jof(l, r); j != 0 {
@@ -235,10 +966,15 @@ func (ssn *Session) JobOrderFn(l, r interface{}) bool {
 		}
 	}
 
-	// If no job order funcs, order job by UID.
+	// If no job order funcs, order job by creation time (FIFO), then by UID
+	// if created at the same time; so the order is stable across sessions.
 	lv := l.(*api.JobInfo)
 	rv := r.(*api.JobInfo)
 
+	if !lv.CreationTimestamp.Equal(&rv.CreationTimestamp) {
+		return lv.CreationTimestamp.Before(&rv.CreationTimestamp)
+	}
+
 	return lv.UID < rv.UID
 }
 
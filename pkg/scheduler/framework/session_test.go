@@ -0,0 +1,767 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func TestUnPipeline(t *testing.T) {
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+
+	releasing := testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), testutil.BuildOwnerReference("owner1"))
+	now := metav1.Now()
+	releasing.DeletionTimestamp = &now
+	schedulerCache.AddPod(releasing)
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner2")))
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j2",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{testutil.BuildOwnerReference("owner2")},
+		},
+	})
+
+	ssn := OpenSession(schedulerCache, nil)
+	defer CloseSession(ssn)
+
+	node := ssn.NodeIndex["n1"]
+	expected := node.Clone()
+
+	job := ssn.JobIndex["owner2"]
+	var task *api.TaskInfo
+	for _, t := range job.TaskStatusIndex[api.Pending] {
+		task = t
+	}
+
+	if err := ssn.Pipeline(task, "n1"); err != nil {
+		t.Fatalf("failed to pipeline task: %v", err)
+	}
+
+	if err := ssn.UnPipeline(task); err != nil {
+		t.Fatalf("failed to unpipeline task: %v", err)
+	}
+
+	if !reflect.DeepEqual(node, expected) {
+		t.Errorf("expected node %v, got %v", expected, node)
+	}
+
+	if len(job.TaskStatusIndex[api.Pending]) != 1 || task.Status != api.Pending || len(task.NodeName) != 0 {
+		t.Errorf("expected task to be pending without node, got status %v on <%v>", task.Status, task.NodeName)
+	}
+
+	if err := ssn.UnPipeline(task); err == nil {
+		t.Errorf("expected error when unpipelining pending task")
+	}
+}
+
+type fakePlugin struct {
+	opened *[]int64
+	closed *[]int64
+}
+
+func (fp *fakePlugin) Name() string {
+	return "fake"
+}
+
+func (fp *fakePlugin) OnSessionOpen(ssn *Session) {
+	*fp.opened = append(*fp.opened, ssn.Iteration)
+}
+
+func (fp *fakePlugin) OnSessionClose(ssn *Session) {
+	*fp.closed = append(*fp.closed, ssn.Iteration)
+}
+
+func TestIteration(t *testing.T) {
+	var opened, closed []int64
+	RegisterPluginBuilder("fake", func(args Arguments) Plugin {
+		return &fakePlugin{opened: &opened, closed: &closed}
+	})
+	defer CleanupPluginBuilders()
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	for i := 0; i < 3; i++ {
+		ssn := OpenSession(schedulerCache, []*PluginOption{{Name: "fake"}})
+		CloseSession(ssn)
+	}
+
+	expected := []int64{1, 2, 3}
+	if !reflect.DeepEqual(expected, opened) {
+		t.Errorf("expected plugin opened in iterations %v, got %v", expected, opened)
+	}
+	if !reflect.DeepEqual(expected, closed) {
+		t.Errorf("expected plugin closed in iterations %v, got %v", expected, closed)
+	}
+}
+
+// fakeContextPlugin saves the Context of session at open.
+type fakeContextPlugin struct {
+	ctx *context.Context
+}
+
+func (fp *fakeContextPlugin) Name() string {
+	return "fake"
+}
+
+func (fp *fakeContextPlugin) OnSessionOpen(ssn *Session) {
+	*fp.ctx = ssn.Context
+}
+
+func (fp *fakeContextPlugin) OnSessionClose(ssn *Session) {}
+
+func TestSessionOption(t *testing.T) {
+	var opened context.Context
+	RegisterPluginBuilder("fake", func(args Arguments) Plugin {
+		return &fakeContextPlugin{ctx: &opened}
+	})
+	defer CleanupPluginBuilders()
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ssn := OpenSession(schedulerCache, []*PluginOption{{Name: "fake"}}, func(ssn *Session) {
+		ssn.Context = ctx
+	})
+	CloseSession(ssn)
+
+	if opened != ctx {
+		t.Errorf("expected plugin opened with Context of session option, got %v", opened)
+	}
+}
+
+func TestSessionChangesNotReused(t *testing.T) {
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("2", "4Gi")))
+
+	releasing := testutil.BuildPod("c1", "p0", "n1", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), testutil.BuildOwnerReference("owner0"))
+	now := metav1.Now()
+	releasing.DeletionTimestamp = &now
+	schedulerCache.AddPod(releasing)
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner1")))
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{testutil.BuildOwnerReference("owner1")},
+		},
+	})
+
+	ssn := OpenSession(schedulerCache, nil)
+	n2 := ssn.NodeIndex["n2"]
+	for _, task := range ssn.JobIndex["owner1"].TaskStatusIndex[api.Pending] {
+		if err := ssn.Pipeline(task, "n1"); err != nil {
+			t.Fatalf("failed to pipeline task: %v", err)
+		}
+	}
+	CloseSession(ssn)
+
+	// The pipelined task is not bound, so next session starts from cache.
+	ssn = OpenSession(schedulerCache, nil)
+	defer CloseSession(ssn)
+
+	if len(ssn.NodeIndex["n1"].Tasks) != 1 {
+		t.Errorf("expected only releasing task on node <n1>, got %d", len(ssn.NodeIndex["n1"].Tasks))
+	}
+	if len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Pending]) != 1 {
+		t.Errorf("expected pending task in job <owner1>")
+	}
+	if ssn.NodeIndex["n2"] != n2 {
+		t.Errorf("expected node <n2> reused")
+	}
+}
+
+func TestJobOrderFIFO(t *testing.T) {
+	now := time.Now()
+
+	older := &api.JobInfo{UID: "j2", CreationTimestamp: metav1.NewTime(now.Add(-time.Minute))}
+	newer := &api.JobInfo{UID: "j1", CreationTimestamp: metav1.NewTime(now)}
+	tie := &api.JobInfo{UID: "j3", CreationTimestamp: metav1.NewTime(now)}
+
+	ssn := &Session{}
+
+	tests := []struct {
+		l, r     *api.JobInfo
+		expected bool
+	}{
+		// The older job goes first, even its UID is larger.
+		{l: older, r: newer, expected: true},
+		{l: newer, r: older, expected: false},
+		// The jobs created at the same time are ordered by UID.
+		{l: newer, r: tie, expected: true},
+		{l: tie, r: newer, expected: false},
+	}
+
+	for i, test := range tests {
+		if got := ssn.JobOrderFn(test.l, test.r); got != test.expected {
+			t.Errorf("case %d: expected JobOrderFn(%v, %v) %v, got %v",
+				i, test.l.UID, test.r.UID, test.expected, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(ssn *Session)
+		valid   bool
+	}{
+		{
+			name:    "consistent session with pipelined task",
+			corrupt: func(ssn *Session) {},
+			valid:   true,
+		},
+		{
+			name: "idle is not released with the task",
+			corrupt: func(ssn *Session) {
+				ssn.NodeIndex["n1"].Idle.MilliCPU -= 1000
+			},
+		},
+		{
+			name: "used is not the sum of tasks",
+			corrupt: func(ssn *Session) {
+				node := ssn.NodeIndex["n1"]
+				node.Used.MilliCPU += 500
+				node.Idle.MilliCPU -= 500
+			},
+		},
+		{
+			name: "task is indexed in two statuses",
+			corrupt: func(ssn *Session) {
+				job := ssn.JobIndex["owner1"]
+				for uid, task := range job.TaskStatusIndex[api.Running] {
+					job.TaskStatusIndex[api.Pending] = map[api.TaskID]*api.TaskInfo{uid: task}
+				}
+			},
+		},
+		{
+			name: "task status is changed without index",
+			corrupt: func(ssn *Session) {
+				for _, task := range ssn.JobIndex["owner1"].Tasks {
+					task.Status = api.Succeeded
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+
+		releasing := testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner2"))
+		now := metav1.Now()
+		releasing.DeletionTimestamp = &now
+		schedulerCache.AddPod(releasing)
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner1")))
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p3", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner3")))
+		for _, owner := range []string{"owner1", "owner2", "owner3"} {
+			schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            owner,
+					Namespace:       "c1",
+					OwnerReferences: []metav1.OwnerReference{testutil.BuildOwnerReference(owner)},
+				},
+			})
+		}
+
+		ssn := OpenSession(schedulerCache, nil)
+
+		for _, task := range ssn.JobIndex["owner3"].Tasks {
+			if err := ssn.Pipeline(task, "n1"); err != nil {
+				t.Fatalf("case %s: failed to pipeline task: %v", test.name, err)
+			}
+		}
+
+		test.corrupt(ssn)
+
+		if err := ssn.Validate(); (err == nil) != test.valid {
+			t.Errorf("case %s: expected valid %v, got err %v", test.name, test.valid, err)
+		}
+
+		CloseSession(ssn)
+	}
+}
+
+type fakeScorePlugin struct {
+	name   string
+	scores map[string]float64
+}
+
+func (fp *fakeScorePlugin) Name() string {
+	return fp.name
+}
+
+func (fp *fakeScorePlugin) OnSessionOpen(ssn *Session) {
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		return fp.scores[node.Name], nil
+	})
+}
+
+func (fp *fakeScorePlugin) OnSessionClose(ssn *Session) {}
+
+func TestNodeScores(t *testing.T) {
+	// The small plugin scores in [0, 2], and the large one in [0, 3000].
+	RegisterPluginBuilder("small", func(args Arguments) Plugin {
+		return &fakeScorePlugin{name: "small", scores: map[string]float64{"n1": 0, "n2": 1, "n3": 2}}
+	})
+	RegisterPluginBuilder("large", func(args Arguments) Plugin {
+		return &fakeScorePlugin{name: "large", scores: map[string]float64{"n1": 3000, "n2": 1000, "n3": 0}}
+	})
+	defer CleanupPluginBuilders()
+
+	tests := []struct {
+		name     string
+		weights  map[string]string
+		expected map[string]float64
+	}{
+		{
+			name:     "plugins of default weight contribute equally",
+			expected: map[string]float64{"n1": 100, "n2": 50 + 100.0/3, "n3": 100},
+		},
+		{
+			name:     "the small plugin of weight 3 wins",
+			weights:  map[string]string{"small": "3"},
+			expected: map[string]float64{"n1": 100, "n2": 150 + 100.0/3, "n3": 300},
+		},
+		{
+			name:     "the large plugin of weight 2 wins",
+			weights:  map[string]string{"large": "2"},
+			expected: map[string]float64{"n1": 200, "n2": 50 + 200.0/3, "n3": 100},
+		},
+	}
+
+	for _, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		}
+		for _, name := range []string{"n1", "n2", "n3"} {
+			schedulerCache.AddNode(testutil.BuildNode(name, testutil.BuildResourceList("2", "4Gi")))
+		}
+
+		var plugins []*PluginOption
+		for _, name := range []string{"small", "large"} {
+			args := Arguments{}
+			if w, found := test.weights[name]; found {
+				args[NodeOrderWeight] = w
+			}
+			plugins = append(plugins, &PluginOption{Name: name, Arguments: args})
+		}
+
+		ssn := OpenSession(schedulerCache, plugins)
+
+		task := api.NewTaskInfo(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner1")))
+		scores := ssn.NodeScores(task, ssn.Nodes)
+		if len(scores) != len(test.expected) {
+			t.Errorf("case %s: expected scores %v, got %v", test.name, test.expected, scores)
+		}
+		for name, expected := range test.expected {
+			if got, found := scores[name]; !found || math.Abs(got-expected) > 1e-6 {
+				t.Errorf("case %s: expected score %v of node %v, got %v", test.name, expected, name, got)
+			}
+		}
+
+		CloseSession(ssn)
+	}
+}
+
+func TestNodeScoresPenalty(t *testing.T) {
+	// The penalty plugin discourages n3 by a negative score, and is neutral
+	// to the others.
+	RegisterPluginBuilder("bonus", func(args Arguments) Plugin {
+		return &fakeScorePlugin{name: "bonus", scores: map[string]float64{"n1": 0, "n2": 1, "n3": 2}}
+	})
+	RegisterPluginBuilder("penalty", func(args Arguments) Plugin {
+		return &fakeScorePlugin{name: "penalty", scores: map[string]float64{"n1": 0, "n2": 0, "n3": -4}}
+	})
+	defer CleanupPluginBuilders()
+
+	tests := []struct {
+		name     string
+		weight   string
+		expected map[string]float64
+		sum      map[string]float64
+	}{
+		{
+			name:     "the penalty outweighs the bonus of n3 over n2",
+			expected: map[string]float64{"n1": 100, "n2": 150, "n3": 100},
+			sum:      map[string]float64{"n1": 0, "n2": 1, "n3": -2},
+		},
+		{
+			name:     "the penalty of weight 2 puts n3 last",
+			weight:   "2",
+			expected: map[string]float64{"n1": 200, "n2": 250, "n3": 100},
+			sum:      map[string]float64{"n1": 0, "n2": 1, "n3": -6},
+		},
+	}
+
+	for _, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		}
+		for _, name := range []string{"n1", "n2", "n3"} {
+			schedulerCache.AddNode(testutil.BuildNode(name, testutil.BuildResourceList("2", "4Gi")))
+		}
+
+		args := Arguments{}
+		if len(test.weight) != 0 {
+			args[NodeOrderWeight] = test.weight
+		}
+		ssn := OpenSession(schedulerCache, []*PluginOption{
+			{Name: "bonus", Arguments: Arguments{}},
+			{Name: "penalty", Arguments: args},
+		})
+
+		task := api.NewTaskInfo(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner1")))
+		scores := ssn.NodeScores(task, ssn.Nodes)
+		for name, expected := range test.expected {
+			if got, found := scores[name]; !found || math.Abs(got-expected) > 1e-6 {
+				t.Errorf("case %s: expected score %v of node %v, got %v", test.name, expected, name, got)
+			}
+		}
+		for _, node := range ssn.Nodes {
+			if got, _ := ssn.NodeOrderFn(task, node); math.Abs(got-test.sum[node.Name]) > 1e-6 {
+				t.Errorf("case %s: expected raw score %v of node %v, got %v", test.name, test.sum[node.Name], node.Name, got)
+			}
+		}
+
+		CloseSession(ssn)
+	}
+}
+
+// fakeActionLog records the events and evictions in the order they're sent.
+type fakeActionLog struct {
+	sync.Mutex
+	actions []string
+	evicted chan struct{}
+}
+
+func (fl *fakeActionLog) Event(object runtime.Object, eventType, reason, message string) {
+	pod := object.(*v1.Pod)
+
+	fl.Lock()
+	defer fl.Unlock()
+	fl.actions = append(fl.actions, fmt.Sprintf("event %v/%v %v: %v", pod.Namespace, pod.Name, reason, message))
+}
+
+func (fl *fakeActionLog) Evict(pod *v1.Pod, reason string) error {
+	fl.Lock()
+	fl.actions = append(fl.actions, fmt.Sprintf("evict %v/%v", pod.Namespace, pod.Name))
+	fl.Unlock()
+
+	fl.evicted <- struct{}{}
+	return nil
+}
+
+func TestStatementRecordsPreemptionBeforeEviction(t *testing.T) {
+	log := &fakeActionLog{evicted: make(chan struct{}, 2)}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:    make(map[string]*api.NodeInfo),
+		Jobs:     make(map[api.JobID]*api.JobInfo),
+		Recorder: log,
+		Evictor:  log,
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "v1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner1")))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "v2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner1")))
+	schedulerCache.AddPod(testutil.BuildPod("c2", "preemptor", "", v1.PodPending, testutil.BuildResourceList("2", "2Gi"), testutil.BuildOwnerReference("owner2")))
+	for _, owner := range []string{"owner1", "owner2"} {
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            owner,
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{testutil.BuildOwnerReference(owner)},
+			},
+		})
+	}
+
+	ssn := OpenSession(schedulerCache, nil)
+	defer CloseSession(ssn)
+
+	preemptor := ssn.JobIndex["owner2"].Tasks["c2-preemptor"]
+	stmt := ssn.Statement()
+	for _, name := range []string{"c1-v1", "c1-v2"} {
+		if err := stmt.Preempt(preemptor, ssn.JobIndex["owner1"].Tasks[api.TaskID(name)]); err != nil {
+			t.Fatalf("failed to preempt %v: %v", name, err)
+		}
+	}
+	stmt.Commit()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-log.evicted:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Failed to get eviction request.")
+		}
+	}
+
+	log.Lock()
+	defer log.Unlock()
+
+	if len(log.actions) != 3 {
+		t.Fatalf("expected 1 event and 2 evictions, got %v", log.actions)
+	}
+	if expected := "event c2/preemptor Preempting: Preempting <c1/v1>, <c1/v2> for it"; log.actions[0] != expected {
+		t.Errorf("expected %q first, got %v", expected, log.actions)
+	}
+}
+
+// fakeBinder fails to bind the pods in failed.
+type fakeBinder struct {
+	failed map[string]bool
+}
+
+func (fb *fakeBinder) Bind(p *v1.Pod, hostname string) error {
+	if fb.failed[p.Name] {
+		return fmt.Errorf("failed to bind %v/%v", p.Namespace, p.Name)
+	}
+	return nil
+}
+
+func TestPostBind(t *testing.T) {
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: &fakeBinder{failed: map[string]bool{"p2": true}},
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner1")))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner1")))
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{testutil.BuildOwnerReference("owner1")},
+		},
+	})
+
+	ssn := OpenSession(schedulerCache, nil)
+
+	var mutex sync.Mutex
+	bound := map[string]string{}
+	ssn.AddPostBindFn(func(task *api.TaskInfo, hostname string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		bound[task.Namespace+"/"+task.Name] = hostname
+	})
+
+	for _, task := range ssn.JobIndex["owner1"].Tasks {
+		if err := ssn.Allocate(task, "n1"); err != nil {
+			t.Fatalf("failed to allocate task %v/%v: %v", task.Namespace, task.Name, err)
+		}
+	}
+	CloseSession(ssn)
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Fatalf("binds are not completed in time")
+	}
+
+	// The task failed to bind is not reported.
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := map[string]string{"c1/p1": "n1"}
+	if !reflect.DeepEqual(expected, bound) {
+		t.Errorf("expected post-bind of %v, got %v", expected, bound)
+	}
+}
+
+// fakeAgingPlugin counts the sessions that each job waits with pending tasks.
+type fakeAgingPlugin struct {
+	waited map[api.JobID]int
+}
+
+func (fp *fakeAgingPlugin) Name() string {
+	return "aging"
+}
+
+func (fp *fakeAgingPlugin) OnSessionOpen(ssn *Session) {
+	fp.waited = map[api.JobID]int{}
+	if state, ok := ssn.PluginState(fp.Name()).(map[api.JobID]int); ok {
+		for id, n := range state {
+			fp.waited[id] = n
+		}
+	}
+
+	for _, job := range ssn.Jobs {
+		if len(job.TaskStatusIndex[api.Pending]) != 0 {
+			fp.waited[job.UID]++
+		}
+	}
+}
+
+func (fp *fakeAgingPlugin) OnSessionClose(ssn *Session) {
+	ssn.SetPluginState(fp.Name(), fp.waited)
+}
+
+func TestPluginState(t *testing.T) {
+	var plugins []*fakeAgingPlugin
+	RegisterPluginBuilder("aging", func(args Arguments) Plugin {
+		fp := &fakeAgingPlugin{}
+		plugins = append(plugins, fp)
+		return fp
+	})
+	defer CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner1))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner2))
+	for _, owner := range []metav1.OwnerReference{owner1, owner2} {
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+		})
+	}
+
+	for i := 0; i < 3; i++ {
+		ssn := OpenSession(schedulerCache, []*PluginOption{{Name: "aging"}})
+		CloseSession(ssn)
+	}
+
+	if len(plugins) != 3 {
+		t.Fatalf("expected the plugin built for each of 3 sessions, got %d", len(plugins))
+	}
+
+	// The plugin of each session starts from the state of the last one.
+	for i, fp := range plugins {
+		if got := fp.waited["owner1"]; got != i+1 {
+			t.Errorf("session %d: expected the pending job waited %d sessions, got %d", i+1, i+1, got)
+		}
+		if got := fp.waited["owner2"]; got != 0 {
+			t.Errorf("session %d: expected the running job never waited, got %d", i+1, got)
+		}
+	}
+}
+
+func TestDefaultJobReady(t *testing.T) {
+	tests := []struct {
+		name         string
+		minAvailable int
+		tasks        int
+		ready        bool
+	}{
+		{
+			name:  "the job without gang info waits for all its tasks",
+			tasks: 3,
+			ready: false,
+		},
+		{
+			name:  "the job without gang info is ready if all tasks are allocated",
+			tasks: 2,
+			ready: true,
+		},
+		{
+			name:         "the job of MinAvailable is ready if those are allocated",
+			minAvailable: 2,
+			tasks:        3,
+			ready:        true,
+		},
+	}
+
+	for _, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: &fakeBinder{},
+		}
+
+		// The node holds only two tasks.
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+		for i := 0; i < test.tasks; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner1")))
+		}
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{testutil.BuildOwnerReference("owner1")},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: test.minAvailable,
+			},
+		})
+
+		ssn := OpenSession(schedulerCache, nil)
+
+		job := ssn.JobIndex["owner1"]
+		for _, task := range job.TaskStatusIndex[api.Pending] {
+			if !task.Resreq.LessEqual(ssn.NodeIndex["n1"].Idle) {
+				break
+			}
+			if err := ssn.Allocate(task, "n1"); err != nil {
+				t.Fatalf("case %s: failed to allocate task %v/%v: %v", test.name, task.Namespace, task.Name, err)
+			}
+		}
+
+		if got := ssn.JobReady(job); got != test.ready {
+			t.Errorf("case %s: expected ready %v, got %v", test.name, test.ready, got)
+		}
+		// The tasks are dispatched only if the job is ready.
+		if got := len(job.TaskStatusIndex[api.Binding]) != 0; got != test.ready {
+			t.Errorf("case %s: expected dispatched %v, got %v", test.name, test.ready, got)
+		}
+
+		CloseSession(ssn)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+)
+
+type operationType int
+
+const (
+	evictOperation operationType = iota
+	pipelineOperation
+)
+
+type operation struct {
+	typ  operationType
+	task *api.TaskInfo
+
+	// The reason of eviction, and the preemptor if it's evicted by preemption.
+	reason    string
+	preemptor *api.TaskInfo
+	// The status of the task before eviction.
+	status api.TaskStatus
+}
+
+// Statement is a group of operations in session, e.g. the evictions for all
+// tasks of a gang job; they are committed to cache or discarded together.
+type Statement struct {
+	ssn        *Session
+	operations []operation
+}
+
+// Statement returns a new Statement of session.
+func (ssn *Session) Statement() *Statement {
+	return &Statement{
+		ssn: ssn,
+	}
+}
+
+// Preempt evicts preemptee for preemptor in session; the eviction is sent to
+// cache when the statement is committed.
+func (s *Statement) Preempt(preemptor, preemptee *api.TaskInfo) error {
+	status := preemptee.Status
+
+	pipelined := s.ssn.preempt(preemptor, preemptee)
+
+	s.operations = append(s.operations, operation{
+		typ:       evictOperation,
+		task:      preemptee,
+		reason:    preemptReason(preemptor),
+		preemptor: preemptor,
+		status:    status,
+	})
+
+	if pipelined {
+		s.operations = append(s.operations, operation{
+			typ:  pipelineOperation,
+			task: preemptor,
+		})
+	}
+
+	return nil
+}
+
+// Evict evicts task in session for reason, without any preemptor; the
+// eviction is sent to cache when the statement is committed.
+func (s *Statement) Evict(task *api.TaskInfo, reason string) error {
+	status := task.Status
+
+	s.ssn.evict(task)
+
+	for _, eh := range s.ssn.eventHandlers {
+		if eh.EvictFunc != nil {
+			eh.EvictFunc(&Event{
+				Task: task,
+			})
+		}
+	}
+
+	s.operations = append(s.operations, operation{
+		typ:    evictOperation,
+		task:   task,
+		reason: reason,
+		status: status,
+	})
+
+	return nil
+}
+
+// Pipeline pipelines task to hostname in session; it's reverted if the
+// statement is discarded.
+func (s *Statement) Pipeline(task *api.TaskInfo, hostname string) error {
+	if err := s.ssn.Pipeline(task, hostname); err != nil {
+		return err
+	}
+
+	s.operations = append(s.operations, operation{
+		typ:  pipelineOperation,
+		task: task,
+	})
+
+	return nil
+}
+
+// PreemptingReason is the reason of the event of the preemption plan.
+const PreemptingReason = "Preempting"
+
+// recordPreemptions records the victims of each preemptor in the statement as
+// an event of preemptor, so users see the pending preemption even if the
+// evictions take a while.
+func (s *Statement) recordPreemptions() {
+	var preemptors []*api.TaskInfo
+	victims := map[api.TaskID][]string{}
+
+	for _, op := range s.operations {
+		if op.typ != evictOperation || op.preemptor == nil {
+			continue
+		}
+
+		if _, found := victims[op.preemptor.UID]; !found {
+			preemptors = append(preemptors, op.preemptor)
+		}
+		victims[op.preemptor.UID] = append(victims[op.preemptor.UID],
+			fmt.Sprintf("<%v/%v>", op.task.Namespace, op.task.Name))
+	}
+
+	for _, preemptor := range preemptors {
+		message := fmt.Sprintf("Preempting %v for it", strings.Join(victims[preemptor.UID], ", "))
+		if err := s.ssn.cache.RecordEvent(preemptor, v1.EventTypeNormal, PreemptingReason, message); err != nil {
+			glog.Errorf("Failed to record preemption of Task <%v/%v>: %v",
+				preemptor.Namespace, preemptor.Name, err)
+		}
+	}
+}
+
+// Commit sends the evictions of the statement to cache, and nominates the
+// pipelined tasks to their nodes, so they can take the released resource
+// in next sessions. The preemption plan is recorded before the evictions.
+func (s *Statement) Commit() {
+	if s.ssn.DryRun {
+		glog.V(4).Infof("Skip %d operations of statement in dry run Session <%s>",
+			len(s.operations), s.ssn.ID)
+		s.operations = nil
+		return
+	}
+
+	s.recordPreemptions()
+
+	for _, op := range s.operations {
+		switch op.typ {
+		case evictOperation:
+			if err := s.ssn.cache.Evict(op.task, op.reason); err != nil {
+				glog.Errorf("Failed to evict Task <%v/%v>: %v",
+					op.task.Namespace, op.task.Name, err)
+			}
+		case pipelineOperation:
+			if err := s.ssn.cache.NominateTask(op.task, op.task.NodeName); err != nil {
+				glog.Errorf("Failed to nominate Task <%v/%v> to node <%v>: %v",
+					op.task.Namespace, op.task.Name, op.task.NodeName, err)
+			}
+		}
+	}
+
+	s.operations = nil
+}
+
+// Discard reverts the operations of the statement in session, in the
+// reverse order; nothing is sent to cache.
+func (s *Statement) Discard() {
+	for i := len(s.operations) - 1; i >= 0; i-- {
+		op := s.operations[i]
+
+		switch op.typ {
+		case evictOperation:
+			s.ssn.unpreempt(op.task, op.status)
+		case pipelineOperation:
+			if err := s.ssn.UnPipeline(op.task); err != nil {
+				glog.Errorf("Failed to unpipeline Task <%v/%v>: %v",
+					op.task.Namespace, op.task.Name, err)
+			}
+		}
+	}
+
+	s.operations = nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+)
+
+// resourceEqual returns true if l and r are equal in every dimension, within
+// the tolerance of LessEqual.
+func resourceEqual(l, r *api.Resource) bool {
+	return l.LessEqual(r) && r.LessEqual(l)
+}
+
+// validateNode checks the accounting of node against its tasks: Used is the
+// sum of the tasks, Releasing is the releasing tasks less the pipelined ones
+// which take their place, and Idle + Used is Allocatable plus the pipelined
+// tasks, as they don't take idle resource.
+func validateNode(node *api.NodeInfo) error {
+	if node.Node == nil {
+		return nil
+	}
+
+	used := api.EmptyResource()
+	releasing := api.EmptyResource()
+	pipelined := api.EmptyResource()
+	for _, task := range node.Tasks {
+		used.Add(task.Resreq)
+		switch task.Status {
+		case api.Releasing:
+			releasing.Add(task.Resreq)
+		case api.Pipelined:
+			pipelined.Add(task.Resreq)
+		}
+	}
+
+	if !resourceEqual(node.Used, used) {
+		return fmt.Errorf("node <%v>: used <%v> is not the sum of its tasks <%v>",
+			node.Name, node.Used, used)
+	}
+
+	// The resource reserved for jobs and not taken yet is not idle.
+	reserved := api.EmptyResource()
+	for job := range node.Reserved {
+		reserved.Add(node.ReservedIdle(job))
+	}
+
+	expected := node.Allocatable.Clone().Add(pipelined)
+	if total := node.Idle.Clone().Add(node.Used).Add(reserved); !resourceEqual(total, expected) {
+		return fmt.Errorf("node <%v>: idle <%v> + used <%v> + reserved <%v> is not allocatable <%v> + pipelined <%v>",
+			node.Name, node.Idle, node.Used, reserved, node.Allocatable, pipelined)
+	}
+
+	if !resourceEqual(node.Releasing.Clone().Add(pipelined), releasing) {
+		return fmt.Errorf("node <%v>: releasing <%v> is not releasing tasks <%v> - pipelined <%v>",
+			node.Name, node.Releasing, releasing, pipelined)
+	}
+
+	return nil
+}
+
+// validateJob checks that each task of job is in the index of its status,
+// and in none of the others.
+func validateJob(job *api.JobInfo) error {
+	indexed := 0
+	for status, tasks := range job.TaskStatusIndex {
+		for uid, task := range tasks {
+			if task.Status != status {
+				return fmt.Errorf("job <%v>: task <%v> of status %v is indexed as %v",
+					job.UID, uid, task.Status, status)
+			}
+			if _, found := job.Tasks[uid]; !found {
+				return fmt.Errorf("job <%v>: task <%v> is indexed as %v, but not in the job",
+					job.UID, uid, status)
+			}
+		}
+		indexed += len(tasks)
+	}
+
+	if indexed != len(job.Tasks) {
+		return fmt.Errorf("job <%v>: %d tasks are indexed, but the job has %d tasks",
+			job.UID, indexed, len(job.Tasks))
+	}
+
+	return nil
+}
+
+// Validate checks the invariants of the resource accounting of nodes and the
+// task index of jobs in the session, and returns the first violation; it's
+// for tests and debugging, as it walks all the tasks.
+func (ssn *Session) Validate() error {
+	for _, node := range ssn.Nodes {
+		if err := validateNode(node); err != nil {
+			glog.Errorf("Session <%v> is invalid: %v", ssn.ID, err)
+			return err
+		}
+	}
+
+	for _, job := range ssn.Jobs {
+		if err := validateJob(job); err != nil {
+			glog.Errorf("Session <%v> is invalid: %v", ssn.ID, err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+)
+
+// The metrics are exported by expvar, so they're available at "/debug/vars"
+// of the scheduler's http server.
+var (
+	// JobAllocated is the allocated resource of each job, by resource name.
+	JobAllocated = expvar.NewMap("kar_job_allocated")
+
+	// JobRequest is the total requested resource of each job, by resource name.
+	JobRequest = expvar.NewMap("kar_job_request")
+
+	// JobShare is the dominant share of each job.
+	JobShare = expvar.NewMap("kar_job_share")
+
+	// JobUnmetDemand is the resource that each gang job lacks for its
+	// MinAvailable tasks even with preemption, by resource name; it's the
+	// demand for cluster-autoscaler to scale up nodes.
+	JobUnmetDemand = expvar.NewMap("kar_job_unmet_demand")
+
+	// APIThrottled is the number of binds and evictions that waited for the
+	// rate limiter, and APIThrottleSeconds is the total time they waited.
+	APIThrottled       = expvar.NewInt("kar_api_throttled")
+	APIThrottleSeconds = expvar.NewFloat("kar_api_throttle_seconds")
+
+	// SessionTimeouts is the number of sessions that stopped early because
+	// they ran over the session timeout.
+	SessionTimeouts = expvar.NewInt("kar_session_timeouts")
+
+	// NodeIdle is the idle resource of each schedulable node, by resource name.
+	NodeIdle = expvar.NewMap("kar_node_idle")
+
+	// LargestSchedulable is the most idle resource of a single node, by
+	// resource name, i.e. the largest task of that resource that fits.
+	LargestSchedulable = expvar.NewMap("kar_largest_schedulable")
+
+	// Fragmentation is the fraction of the idle resource of cluster that is
+	// not on the node of the most idle, by resource name; it's 0 if all is
+	// on one node, and close to 1 if it's scattered across many nodes.
+	Fragmentation = expvar.NewMap("kar_fragmentation")
+
+	// PodStartLatency is the histogram of the seconds that pods wait from
+	// pending to bound, by namespace; each has the cumulative count of the
+	// pods within each bucket, e.g. "10" or "+Inf", and the "count" and
+	// "sum" of all pods.
+	PodStartLatency = expvar.NewMap("kar_pod_start_latency_seconds")
+)
+
+// startLatencyBuckets are the upper bounds in seconds of the buckets of
+// PodStartLatency.
+var startLatencyBuckets = []float64{1, 5, 10, 30, 60, 300, 600}
+
+// UpdateJobResource sets the value of resource for job in metric.
+func UpdateJobResource(metric *expvar.Map, job string, resource string, value float64) {
+	var jm *expvar.Map
+	if v, ok := metric.Get(job).(*expvar.Map); ok {
+		jm = v
+	} else {
+		jm = new(expvar.Map).Init()
+		metric.Set(job, jm)
+	}
+
+	UpdateResource(jm, resource, value)
+}
+
+// UpdateResource sets the value of resource in metric, e.g. LargestSchedulable.
+func UpdateResource(metric *expvar.Map, resource string, value float64) {
+	f := new(expvar.Float)
+	f.Set(value)
+	metric.Set(resource, f)
+}
+
+// UpdateJobShare sets the share of job.
+func UpdateJobShare(job string, share float64) {
+	f := new(expvar.Float)
+	f.Set(share)
+	JobShare.Set(job, f)
+}
+
+// ResetJobMetrics removes all jobs from the job metrics, so the jobs
+// that are gone will not be reported.
+func ResetJobMetrics() {
+	JobAllocated.Init()
+	JobRequest.Init()
+	JobShare.Init()
+}
+
+// UpdateAPIThrottle counts an API call that waited for the rate limiter.
+func UpdateAPIThrottle(wait time.Duration) {
+	APIThrottled.Add(1)
+	APIThrottleSeconds.Add(wait.Seconds())
+}
+
+// ObservePodStartLatency adds the latency of a pod in namespace to
+// PodStartLatency.
+func ObservePodStartLatency(namespace string, latency time.Duration) {
+	var hm *expvar.Map
+	if v, ok := PodStartLatency.Get(namespace).(*expvar.Map); ok {
+		hm = v
+	} else {
+		hm = new(expvar.Map).Init()
+		PodStartLatency.Set(namespace, hm)
+	}
+
+	seconds := latency.Seconds()
+	for _, bound := range startLatencyBuckets {
+		if seconds <= bound {
+			hm.Add(strconv.FormatFloat(bound, 'g', -1, 64), 1)
+		}
+	}
+	hm.Add("+Inf", 1)
+	hm.Add("count", 1)
+	hm.AddFloat("sum", seconds)
+}
+
+// ResetNodeMetrics removes all nodes from NodeIdle, so the nodes that are
+// gone will not be reported.
+func ResetNodeMetrics() {
+	NodeIdle.Init()
+}
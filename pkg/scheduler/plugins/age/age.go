@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package age
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "age"
+
+const (
+	// ProtectAfter is the argument of the running duration, e.g. "1h", after
+	// which tasks are not preemptable; 0 means no protection, and the younger
+	// victims are only preferred.
+	ProtectAfter = "protectAfter"
+)
+
+type agePlugin struct {
+	protectAfter time.Duration
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	ap := &agePlugin{}
+
+	args.GetDuration(&ap.protectAfter, ProtectAfter)
+
+	return ap
+}
+
+func (ap *agePlugin) Name() string {
+	return PluginName
+}
+
+// running returns how long task has run since its pod started; it's 0 if the
+// pod is not started yet.
+func running(task *api.TaskInfo, now time.Time) time.Duration {
+	if task.Pod == nil || task.Pod.Status.StartTime == nil {
+		return 0
+	}
+
+	return now.Sub(task.Pod.Status.StartTime.Time)
+}
+
+func (ap *agePlugin) OnSessionOpen(ssn *framework.Session) {
+	now := ssn.Now()
+
+	if ap.protectAfter > 0 {
+		ssn.AddPreemptableFn(func(l, r interface{}) bool {
+			preemptor := l.(*api.TaskInfo)
+			preemptee := r.(*api.TaskInfo)
+
+			if d := running(preemptee, now); d > ap.protectAfter {
+				glog.V(3).Infof("Can not preempt task <%v:%v/%v> for task <%v:%v/%v>: it has run for <%v>, longer than <%v>",
+					preemptee.UID, preemptee.Namespace, preemptee.Name,
+					preemptor.UID, preemptor.Namespace, preemptor.Name, d, ap.protectAfter)
+				return false
+			}
+
+			return true
+		})
+	}
+
+	// The younger victim is preferred, as evicting it wastes less work.
+	ssn.AddVictimOrderFn(func(preemptor, l, r *api.TaskInfo) int {
+		lRunning, rRunning := running(l, now), running(r, now)
+		if lRunning == rRunning {
+			return 0
+		}
+		if lRunning < rRunning {
+			return -1
+		}
+		return 1
+	})
+}
+
+func (ap *agePlugin) OnSessionClose(ssn *framework.Session) {}
@@ -0,0 +1,153 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package age
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+// now is the time of the fake clock of sessions, unless a test advances it.
+var now = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func buildTask(name string, running time.Duration) *api.TaskInfo {
+	start := metav1.NewTime(now.Add(-running))
+	return &api.TaskInfo{
+		UID:       api.TaskID(name),
+		Name:      name,
+		Namespace: "c1",
+		Priority:  1,
+		Resreq:    &api.Resource{MilliCPU: 1000},
+		Pod: &v1.Pod{
+			Status: v1.PodStatus{
+				Phase:     v1.PodRunning,
+				StartTime: &start,
+			},
+		},
+	}
+}
+
+func TestPreemptable(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	preemptor := buildTask("preemptor", 0)
+	old := buildTask("old", 2*time.Hour)
+	young := buildTask("young", 30*time.Second)
+
+	tests := []struct {
+		name     string
+		args     framework.Arguments
+		victim   *api.TaskInfo
+		expected bool
+	}{
+		{
+			name:     "the task running longer than threshold is protected",
+			args:     framework.Arguments{ProtectAfter: "1h"},
+			victim:   old,
+			expected: false,
+		},
+		{
+			name:     "the young task of equal priority is preemptable",
+			args:     framework.Arguments{ProtectAfter: "1h"},
+			victim:   young,
+			expected: true,
+		},
+		{
+			name:     "no threshold adds no preemptable function",
+			args:     framework.Arguments{},
+			victim:   young,
+			expected: false,
+		},
+	}
+
+	for i, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+			Clock: &testutil.FakeClock{Time: now},
+		}
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{
+				Name:      PluginName,
+				Arguments: test.args,
+			},
+		})
+
+		if got := ssn.Preemptable(preemptor, test.victim); got != test.expected {
+			t.Errorf("case %d (%s): expected %v, got %v", i, test.name, test.expected, got)
+		}
+
+		// The young victim goes first anyway.
+		if got := ssn.VictimOrderFn(preemptor, young, old); got >= 0 {
+			t.Errorf("case %d (%s): expected the young victim first, got %v", i, test.name, got)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestProtectionByAge(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	preemptor := buildTask("preemptor", 0)
+	victim := buildTask("victim", 0)
+
+	clock := &testutil.FakeClock{Time: now}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+		Clock: clock,
+	}
+
+	// The victim ages through the sessions, until it's protected.
+	for _, test := range []struct {
+		elapsed  time.Duration
+		expected bool
+	}{
+		{elapsed: 0, expected: true},
+		{elapsed: 30 * time.Minute, expected: true},
+		{elapsed: time.Hour, expected: true},
+		{elapsed: time.Hour + time.Second, expected: false},
+		{elapsed: 2 * time.Hour, expected: false},
+	} {
+		clock.Time = now.Add(test.elapsed)
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{
+				Name:      PluginName,
+				Arguments: framework.Arguments{ProtectAfter: "1h"},
+			},
+		})
+
+		if got := ssn.Preemptable(preemptor, victim); got != test.expected {
+			t.Errorf("expected preemptable %v after %v, got %v", test.expected, test.elapsed, got)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benefit
+
+import (
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "benefit"
+
+const (
+	// MinFraction is the argument of the minimal fraction of preemptee's resource
+	// that preemptor should use; 0 means no limitation.
+	MinFraction = "minFraction"
+)
+
+type benefitPlugin struct {
+	minFraction float64
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	bp := &benefitPlugin{}
+
+	args.GetFloat64(&bp.minFraction, MinFraction)
+
+	return bp
+}
+
+func (bp *benefitPlugin) Name() string {
+	return PluginName
+}
+
+// benefit returns the max fraction of preemptee's resource that preemptor uses
+// among all resource dimensions.
+func benefit(preemptor, preemptee *api.TaskInfo) float64 {
+	res := float64(0)
+	for _, rn := range api.ResourceNames() {
+		if preemptee.Resreq.IsZero(rn) {
+			continue
+		}
+
+		fraction := preemptor.Resreq.Get(rn) / preemptee.Resreq.Get(rn)
+		if fraction > res {
+			res = fraction
+		}
+	}
+
+	return res
+}
+
+func (bp *benefitPlugin) OnSessionOpen(ssn *framework.Session) {
+	if bp.minFraction <= 0 {
+		return
+	}
+
+	ssn.AddPreemptableFn(func(l, r interface{}) bool {
+		preemptor := l.(*api.TaskInfo)
+		preemptee := r.(*api.TaskInfo)
+
+		b := benefit(preemptor, preemptee)
+		if b < bp.minFraction {
+			glog.V(3).Infof("Can not preempt task <%v:%v/%v> for task <%v:%v/%v>: benefit <%v> is less than <%v>",
+				preemptee.UID, preemptee.Namespace, preemptee.Name,
+				preemptor.UID, preemptor.Namespace, preemptor.Name, b, bp.minFraction)
+			return false
+		}
+
+		return true
+	})
+}
+
+func (bp *benefitPlugin) OnSessionClose(ssn *framework.Session) {}
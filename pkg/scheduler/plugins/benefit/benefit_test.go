@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benefit
+
+import (
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+func buildTask(name string, milliCPU, memory float64) *api.TaskInfo {
+	return &api.TaskInfo{
+		UID:       api.TaskID(name),
+		Name:      name,
+		Namespace: "c1",
+		Resreq: &api.Resource{
+			MilliCPU: milliCPU,
+			Memory:   memory,
+		},
+	}
+}
+
+func TestPreemptable(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	victim := buildTask("victim", 4000, 4*1024*1024*1024)
+
+	tests := []struct {
+		name      string
+		args      framework.Arguments
+		preemptor *api.TaskInfo
+		expected  bool
+	}{
+		{
+			name:      "low benefit preemptor is rejected",
+			args:      framework.Arguments{MinFraction: "0.5"},
+			preemptor: buildTask("small", 100, 100*1024*1024),
+			expected:  false,
+		},
+		{
+			name:      "high benefit preemptor is accepted",
+			args:      framework.Arguments{MinFraction: "0.5"},
+			preemptor: buildTask("large", 3000, 1024*1024*1024),
+			expected:  true,
+		},
+		{
+			name:      "no threshold adds no preemptable function",
+			args:      framework.Arguments{},
+			preemptor: buildTask("large", 3000, 1024*1024*1024),
+			expected:  false,
+		},
+	}
+
+	for i, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		}
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{
+				Name:      PluginName,
+				Arguments: test.args,
+			},
+		})
+
+		if got := ssn.Preemptable(test.preemptor, victim); got != test.expected {
+			t.Errorf("case %d (%s): expected %v, got %v", i, test.name, test.expected, got)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "deadline"
+
+const (
+	// DropExpired is the argument of whether the jobs past their deadline are
+	// not scheduled any more; their running tasks are kept.
+	DropExpired = "dropExpired"
+
+	// DeadlineExceededReason is the reason of the event when a job is dropped.
+	DeadlineExceededReason = "DeadlineExceeded"
+)
+
+// deadlinePlugin orders jobs by the earliest deadline first; the jobs
+// without deadline are after the others.
+type deadlinePlugin struct {
+	dropExpired bool
+
+	// The jobs dropped in this session.
+	expired map[api.JobID]bool
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	dp := &deadlinePlugin{
+		expired: map[api.JobID]bool{},
+	}
+
+	args.GetBool(&dp.dropExpired, DropExpired)
+
+	return dp
+}
+
+func (dp *deadlinePlugin) Name() string {
+	return PluginName
+}
+
+// compare returns -1 if l is due before r, or only l has a deadline.
+func compare(l, r time.Time) int {
+	switch {
+	case l.Equal(r):
+		return 0
+	case r.IsZero():
+		return -1
+	case l.IsZero():
+		return 1
+	case l.Before(r):
+		return -1
+	default:
+		return 1
+	}
+}
+
+// drop marks the pending jobs past their deadline expired; the event is
+// recorded once as the dropped jobs are kept in the state across sessions.
+func (dp *deadlinePlugin) drop(ssn *framework.Session) {
+	reported, _ := ssn.PluginState(PluginName).(map[api.JobID]bool)
+
+	now := ssn.Now()
+	for _, job := range ssn.Jobs {
+		deadline := job.Deadline()
+		if deadline.IsZero() || now.Before(deadline) || len(job.TaskStatusIndex[api.Pending]) == 0 {
+			continue
+		}
+
+		dp.expired[job.UID] = true
+		if reported[job.UID] {
+			continue
+		}
+
+		glog.V(3).Infof("Job <%v:%v/%v> is past its deadline %v, drop it",
+			job.UID, job.Namespace, job.Name, deadline)
+
+		msg := fmt.Sprintf("the job is past its deadline %v", deadline.Format(time.RFC3339))
+		if err := ssn.Backoff(job, DeadlineExceededReason, msg); err != nil {
+			glog.Errorf("Failed to backoff Job <%v:%v/%v>: %v", job.UID, job.Namespace, job.Name, err)
+		}
+	}
+}
+
+func (dp *deadlinePlugin) OnSessionOpen(ssn *framework.Session) {
+	if dp.dropExpired {
+		dp.drop(ssn)
+	}
+
+	ssn.AddJobValidFn(func(obj interface{}) bool {
+		return !dp.expired[obj.(*api.JobInfo).UID]
+	})
+
+	ssn.AddJobOrderFn(func(l, r interface{}) int {
+		return compare(l.(*api.JobInfo).Deadline(), r.(*api.JobInfo).Deadline())
+	})
+}
+
+func (dp *deadlinePlugin) OnSessionClose(ssn *framework.Session) {
+	if dp.dropExpired {
+		ssn.SetPluginState(PluginName, dp.expired)
+	}
+	dp.expired = map[api.JobID]bool{}
+}
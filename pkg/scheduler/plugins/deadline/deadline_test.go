@@ -0,0 +1,171 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadline
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+// now is the time of the fake clock of sessions, unless a test advances it.
+var now = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// buildSchedulingSpec builds the spec of job due in d from now; no deadline if d is zero.
+func buildSchedulingSpec(owner metav1.OwnerReference, d time.Duration) *arbv1.SchedulingSpec {
+	ss := testutil.BuildSchedulingSpec("", "", owner)
+	if d != 0 {
+		ss.Annotations = map[string]string{
+			arbv1.DeadlineAnnotationKey: now.Add(d).Format(time.RFC3339),
+		}
+	}
+	return ss
+}
+
+type fakeRecorder struct {
+	events []string
+}
+
+func (fr *fakeRecorder) Event(object runtime.Object, eventType, reason, message string) {
+	pod := object.(*v1.Pod)
+	fr.events = append(fr.events, fmt.Sprintf("%v/%v %v", pod.Namespace, pod.Name, reason))
+}
+
+func TestJobOrder(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	// The jobs are created in reverse order of their deadlines.
+	for i, d := range []time.Duration{0, time.Hour, 5 * time.Minute} {
+		owner := testutil.BuildOwnerReference(fmt.Sprintf("owner%d", i))
+		schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+		schedulerCache.AddSchedulingSpec(buildSchedulingSpec(owner, d))
+	}
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	jobs := util.NewPriorityQueue(ssn.JobOrderFn)
+	for _, job := range ssn.Jobs {
+		jobs.Push(job)
+	}
+
+	// The job due in 5 minutes, then in an hour, and the one without deadline.
+	for _, expected := range []api.JobID{"owner2", "owner1", "owner0"} {
+		if job := jobs.Pop().(*api.JobInfo); job.UID != expected {
+			t.Errorf("expected job <%v>, got <%v>", expected, job.UID)
+		}
+	}
+}
+
+func TestDropExpired(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	recorder := &fakeRecorder{}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:    make(map[string]*api.NodeInfo),
+		Jobs:     make(map[api.JobID]*api.JobInfo),
+		Recorder: recorder,
+		Clock:    &testutil.FakeClock{Time: now},
+	}
+
+	expired, due := testutil.BuildOwnerReference("owner1"), testutil.BuildOwnerReference("owner2")
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), expired))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), due))
+	schedulerCache.AddSchedulingSpec(buildSchedulingSpec(expired, -time.Minute))
+	schedulerCache.AddSchedulingSpec(buildSchedulingSpec(due, time.Minute))
+
+	for i := 0; i < 2; i++ {
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{
+			Name:      PluginName,
+			Arguments: framework.Arguments{DropExpired: "true"},
+		}})
+
+		if ssn.JobValid(ssn.JobIndex["owner1"]) {
+			t.Errorf("session %d: expected the job past its deadline invalid", i)
+		}
+		if !ssn.JobValid(ssn.JobIndex["owner2"]) {
+			t.Errorf("session %d: expected the job before its deadline valid", i)
+		}
+
+		framework.CloseSession(ssn)
+	}
+
+	// The event is recorded once, not in every session.
+	expected := fmt.Sprintf("c1/p1 %v", DeadlineExceededReason)
+	if len(recorder.events) != 1 || recorder.events[0] != expected {
+		t.Errorf("expected event %q, got %v", expected, recorder.events)
+	}
+}
+
+func TestDropByClock(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	clock := &testutil.FakeClock{Time: now}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:    make(map[string]*api.NodeInfo),
+		Jobs:     make(map[api.JobID]*api.JobInfo),
+		Recorder: &fakeRecorder{},
+		Clock:    clock,
+	}
+
+	owner := testutil.BuildOwnerReference("owner1")
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+	schedulerCache.AddSchedulingSpec(buildSchedulingSpec(owner, 10*time.Minute))
+
+	// The job is due in 10 minutes, it's dropped once the clock passes it.
+	for _, test := range []struct {
+		elapsed time.Duration
+		valid   bool
+	}{
+		{elapsed: 0, valid: true},
+		{elapsed: 9 * time.Minute, valid: true},
+		{elapsed: 10 * time.Minute, valid: false},
+		{elapsed: time.Hour, valid: false},
+	} {
+		clock.Time = now.Add(test.elapsed)
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{
+			Name:      PluginName,
+			Arguments: framework.Arguments{DropExpired: "true"},
+		}})
+
+		if got := ssn.JobValid(ssn.JobIndex["owner1"]); got != test.valid {
+			t.Errorf("expected job valid %v after %v, got %v", test.valid, test.elapsed, got)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
@@ -17,11 +17,51 @@ limitations under the License.
 package drf
 
 import (
+	"fmt"
 	"math"
+	"strconv"
+	"strings"
 
 	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "drf"
+
+const (
+	// Stickiness is the share bonus of the jobs that have resource allocated
+	// when session opens, so marginal fairness gains do not flip the order
+	// of jobs and churn the running ones; it's zero by default.
+	Stickiness = "stickiness"
+
+	// Aggregation is the argument of how the shares of all resources of a job
+	// make its share: MaxAggregation, i.e. the dominant share, by default,
+	// SumAggregation or L2Aggregation; so a job can not hoard one resource,
+	// e.g. GPU, while it looks fair on its dominant one.
+	Aggregation = "aggregation"
+
+	// Weights is the argument of the weight of each resource's share, e.g.
+	// "cpu=1,nvidia.com/gpu=4"; it's 1 for the resources not listed. The
+	// resources are cpu, memory, nvidia.com/gpu and the scalar resources,
+	// e.g. "example.com/gpu-memory"; the weights of others are rejected.
+	Weights = "weights"
+)
+
+const (
+	// MaxAggregation takes the largest weighted share of resources.
+	MaxAggregation = "max"
+
+	// SumAggregation takes the sum of weighted shares of resources.
+	SumAggregation = "sum"
+
+	// L2Aggregation takes the Euclidean norm of weighted shares of resources.
+	L2Aggregation = "l2"
 )
 
 var shareDelta = 0.000001
@@ -30,6 +70,12 @@ type drfAttr struct {
 	share            float64
 	dominantResource string
 	allocated        *api.Resource
+
+	// sticky is true if the job has resource allocated when session opens.
+	sticky bool
+
+	// weight is the weight of job, the share is divided by it for fairness.
+	weight float64
 }
 
 type drfPlugin struct {
@@ -37,17 +83,78 @@ type drfPlugin struct {
 
 	// Key is Job ID
 	jobOpts map[api.JobID]*drfAttr
+
+	stickiness float64
+
+	aggregation string
+	weights     map[v1.ResourceName]float64
 }
 
-func New() framework.Plugin {
-	return &drfPlugin{
+func New(args framework.Arguments) framework.Plugin {
+	drf := &drfPlugin{
 		totalResource: api.EmptyResource(),
 		jobOpts:       map[api.JobID]*drfAttr{},
+		aggregation:   MaxAggregation,
+		weights:       map[v1.ResourceName]float64{},
 	}
+
+	args.GetFloat64(&drf.stickiness, Stickiness)
+
+	switch a := args[Aggregation]; a {
+	case "":
+	case MaxAggregation, SumAggregation, L2Aggregation:
+		drf.aggregation = a
+	default:
+		glog.Warningf("Unknown aggregation <%v> of %v, use %v instead", a, PluginName, MaxAggregation)
+	}
+
+	if v, found := args[Weights]; found {
+		weights, err := parseWeights(v)
+		if err != nil {
+			glog.Warningf("Invalid weights <%v> of %v, ignore them: %v", v, PluginName, err)
+		} else {
+			drf.weights = weights
+		}
+	}
+
+	return drf
+}
+
+// parseWeights parses the weights of resources, e.g. "cpu=1,nvidia.com/gpu=4".
+func parseWeights(s string) (map[v1.ResourceName]float64, error) {
+	weights := map[v1.ResourceName]float64{}
+	for _, item := range strings.Split(s, ",") {
+		fields := strings.Split(item, "=")
+		if len(fields) != 2 || len(strings.TrimSpace(fields[0])) == 0 {
+			return nil, fmt.Errorf("weight <%v> is not in the format of <resource>=<weight>", item)
+		}
+
+		weight, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight <%v> is not a non-negative number", fields[1])
+		}
+		rn := v1.ResourceName(strings.TrimSpace(fields[0]))
+		if !isShareResourceName(rn) {
+			return nil, fmt.Errorf("resource <%v> is not in the share of jobs", rn)
+		}
+		weights[rn] = weight
+	}
+
+	return weights, nil
+}
+
+// isShareResourceName returns true if the share of jobs counts rn.
+func isShareResourceName(rn v1.ResourceName) bool {
+	for _, name := range api.ResourceNames() {
+		if rn == name {
+			return true
+		}
+	}
+	return api.IsScalarResourceName(rn)
 }
 
 func (drf *drfPlugin) Name() string {
-	return "drf"
+	return PluginName
 }
 
 func (drf *drfPlugin) OnSessionOpen(ssn *framework.Session) {
@@ -59,6 +166,7 @@ func (drf *drfPlugin) OnSessionOpen(ssn *framework.Session) {
 	for _, job := range ssn.Jobs {
 		attr := &drfAttr{
 			allocated: api.EmptyResource(),
+			weight:    job.Weight(),
 		}
 
 		for status, tasks := range job.TaskStatusIndex {
@@ -69,6 +177,9 @@ func (drf *drfPlugin) OnSessionOpen(ssn *framework.Session) {
 			}
 		}
 
+		drf.updateShare(attr)
+		attr.sticky = !attr.allocated.IsEmpty()
+
 		drf.jobOpts[job.UID] = attr
 	}
 
@@ -86,8 +197,11 @@ func (drf *drfPlugin) OnSessionOpen(ssn *framework.Session) {
 		lalloc := latt.allocated.Clone().Add(lv.Resreq)
 		ralloc := ratt.allocated.Clone().Sub(rv.Resreq)
 
-		ls := drf.calculateShare(lalloc, drf.totalResource)
-		rs := drf.calculateShare(ralloc, drf.totalResource)
+		ls := drf.calculateShare(lalloc, drf.totalResource) / latt.weight
+		rs := drf.calculateShare(ralloc, drf.totalResource) / ratt.weight
+		if ratt.sticky {
+			rs -= drf.stickiness
+		}
 
 		glog.V(3).Infof("DRF PreemptableFn: preemptor <%v:%v/%v>, alloc <%v>, share <%v>; preemptee <%v:%v/%v>, alloc <%v>, share <%v>",
 			lv.UID, lv.Namespace, lv.Name, lalloc, ls, rv.UID, rv.Namespace, rv.Name, ralloc, rs)
@@ -100,11 +214,14 @@ func (drf *drfPlugin) OnSessionOpen(ssn *framework.Session) {
 		lv := l.(*api.JobInfo)
 		rv := r.(*api.JobInfo)
 
-		if drf.jobOpts[lv.UID].share == drf.jobOpts[rv.UID].share {
+		ls := drf.orderShare(drf.jobOpts[lv.UID])
+		rs := drf.orderShare(drf.jobOpts[rv.UID])
+
+		if ls == rs {
 			return 0
 		}
 
-		if drf.jobOpts[lv.UID].share < drf.jobOpts[rv.UID].share {
+		if ls < rs {
 			return -1
 		}
 
@@ -134,24 +251,77 @@ func (drf *drfPlugin) OnSessionOpen(ssn *framework.Session) {
 	})
 }
 
+// orderShare returns the share of job divided by its weight to order jobs
+// by, with the bonus of stickiness.
+func (drf *drfPlugin) orderShare(attr *drfAttr) float64 {
+	share := attr.share / attr.weight
+	if attr.sticky {
+		return share - drf.stickiness
+	}
+	return share
+}
+
 func (drf *drfPlugin) updateShare(attr *drfAttr) {
 	attr.share = drf.calculateShare(attr.allocated, drf.totalResource)
 }
 
+// calculateShare returns the share of allocated in totalResource, i.e. the
+// weighted shares of resources, including the scalar ones, aggregated by the
+// aggregation of plugin.
 func (drf *drfPlugin) calculateShare(allocated, totalResource *api.Resource) float64 {
 	res := float64(0)
-	for _, rn := range api.ResourceNames() {
+	for _, rn := range append(api.ResourceNames(), totalResource.ScalarResourceNames()...) {
+		if totalResource.Get(rn) <= 0 {
+			continue
+		}
+
 		share := allocated.Get(rn) / totalResource.Get(rn)
-		if share > res {
-			res = share
+		if weight, found := drf.weights[rn]; found {
+			share *= weight
+		}
+
+		switch drf.aggregation {
+		case SumAggregation:
+			res += share
+		case L2Aggregation:
+			res += share * share
+		default:
+			if share > res {
+				res = share
+			}
 		}
 	}
 
+	if drf.aggregation == L2Aggregation {
+		return math.Sqrt(res)
+	}
+
 	return res
 }
 
 func (drf *drfPlugin) OnSessionClose(session *framework.Session) {
+	drf.updateMetrics(session)
+
 	// Clean schedule data.
 	drf.totalResource = api.EmptyResource()
 	drf.jobOpts = map[api.JobID]*drfAttr{}
 }
+
+// updateMetrics exports the allocated, requested resource and share of jobs.
+func (drf *drfPlugin) updateMetrics(ssn *framework.Session) {
+	metrics.ResetJobMetrics()
+
+	for _, job := range ssn.Jobs {
+		attr, found := drf.jobOpts[job.UID]
+		if !found {
+			continue
+		}
+
+		name := fmt.Sprintf("%s/%s", job.Namespace, job.Name)
+		for _, rn := range api.ResourceNames() {
+			metrics.UpdateJobResource(metrics.JobAllocated, name, string(rn), attr.allocated.Get(rn))
+			metrics.UpdateJobResource(metrics.JobRequest, name, string(rn), job.TotalRequest.Get(rn))
+		}
+		metrics.UpdateJobShare(name, attr.share)
+	}
+}
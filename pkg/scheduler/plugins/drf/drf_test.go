@@ -0,0 +1,371 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drf
+
+import (
+	"expvar"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func getJobMetric(metric *expvar.Map, job, rn string) float64 {
+	jm, ok := metric.Get(job).(*expvar.Map)
+	if !ok {
+		return -1
+	}
+	f, ok := jm.Get(rn).(*expvar.Float)
+	if !ok {
+		return -1
+	}
+	return f.Value()
+}
+
+func getJobShare(job string) float64 {
+	f, ok := metrics.JobShare.Get(job).(*expvar.Float)
+	if !ok {
+		return -1
+	}
+	return f.Value()
+}
+
+func TestJobMetrics(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+	for _, pod := range []*v1.Pod{
+		testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c2", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("2", "1Gi"), owner2),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "j1", owner1))
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c2", "j2", owner2))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	framework.CloseSession(ssn)
+
+	tests := []struct {
+		job       string
+		allocated float64
+		request   float64
+		share     float64
+	}{
+		{
+			job:       "c1/j1",
+			allocated: 1000,
+			request:   2000,
+			share:     0.25,
+		},
+		{
+			job:       "c2/j2",
+			allocated: 2000,
+			request:   2000,
+			share:     0.5,
+		},
+	}
+
+	for i, test := range tests {
+		cpu := string(v1.ResourceCPU)
+		if got := getJobMetric(metrics.JobAllocated, test.job, cpu); got != test.allocated {
+			t.Errorf("case %d (%s): expected allocated cpu %v, got %v", i, test.job, test.allocated, got)
+		}
+		if got := getJobMetric(metrics.JobRequest, test.job, cpu); got != test.request {
+			t.Errorf("case %d (%s): expected request cpu %v, got %v", i, test.job, test.request, got)
+		}
+		if got := getJobShare(test.job); math.Abs(got-test.share) > shareDelta {
+			t.Errorf("case %d (%s): expected share %v, got %v", i, test.job, test.share, got)
+		}
+	}
+}
+
+func TestJobOrderStickiness(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name       string
+		stickiness string
+		first      api.JobID
+	}{
+		{
+			name:  "no stickiness, the job of lower share goes first",
+			first: "owner2",
+		},
+		{
+			name:       "the running job keeps ahead in near tie",
+			stickiness: "0.15",
+			first:      "owner1",
+		},
+		{
+			name:       "the running job is behind if it's far above",
+			stickiness: "0.05",
+			first:      "owner2",
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("10", "100Gi")))
+		for _, pod := range []*v1.Pod{
+			testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1),
+			testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner1),
+			testutil.BuildPod("c2", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+		} {
+			schedulerCache.AddPod(pod)
+		}
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "j1", owner1))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c2", "j2", owner2))
+
+		args := framework.Arguments{}
+		if len(test.stickiness) != 0 {
+			args[Stickiness] = test.stickiness
+		}
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName, Arguments: args}})
+
+		j1, j2 := ssn.JobIndex["owner1"], ssn.JobIndex["owner2"]
+		first := j2.UID
+		if ssn.JobOrderFn(j1, j2) {
+			first = j1.UID
+		}
+		if first != test.first {
+			t.Errorf("case %s: expected %v first, got %v", test.name, test.first, first)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestWeightedShare(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: testutil.NewFakeBinder(),
+	}
+
+	// Both jobs want the whole cluster.
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("12", "24Gi")))
+	for i := 0; i < 12; i++ {
+		schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner1))
+		schedulerCache.AddPod(testutil.BuildPod("c2", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2))
+	}
+
+	heavy := testutil.BuildSchedulingSpec("c1", "j1", owner1)
+	heavy.Annotations = map[string]string{arbv1.WeightAnnotationKey: "2"}
+	schedulerCache.AddSchedulingSpec(heavy)
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c2", "j2", owner2))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	allocate.New().Execute(ssn)
+
+	allocated := func(job api.JobID) int {
+		n := 0
+		for status, tasks := range ssn.JobIndex[job].TaskStatusIndex {
+			if api.AllocatedStatus(status) {
+				n += len(tasks)
+			}
+		}
+		return n
+	}
+
+	// The job of weight 2 gets twice the share of the other.
+	if heavy, light := allocated("owner1"), allocated("owner2"); heavy != 8 || light != 4 {
+		t.Errorf("expected 8 and 4 tasks allocated to the jobs of weight 2 and 1, got %d and %d", heavy, light)
+	}
+}
+
+func TestShareAggregation(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name  string
+		args  framework.Arguments
+		first api.JobID
+	}{
+		{
+			name:  "the dominant share of GPU hoarder is lower",
+			args:  framework.Arguments{},
+			first: "owner1",
+		},
+		{
+			name:  "the sum of shares of GPU hoarder is higher",
+			args:  framework.Arguments{Aggregation: SumAggregation},
+			first: "owner2",
+		},
+		{
+			name:  "the L2 norm of shares of GPU hoarder is higher",
+			args:  framework.Arguments{Aggregation: L2Aggregation},
+			first: "owner2",
+		},
+		{
+			name:  "the weighted dominant share of GPU hoarder is higher",
+			args:  framework.Arguments{Weights: "nvidia.com/gpu=2"},
+			first: "owner2",
+		},
+		{
+			name:  "the unknown aggregation falls back to the dominant share",
+			args:  framework.Arguments{Aggregation: "min"},
+			first: "owner1",
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		}
+
+		// owner1 takes 30% of CPU and 30% of GPU, owner2 takes 40% of CPU.
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceListWithGPU("10", "100Gi", "10")))
+		for _, pod := range []*v1.Pod{
+			testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceListWithGPU("3", "1Gi", "3"), owner1),
+			testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceListWithGPU("1", "1Gi", "1"), owner1),
+			testutil.BuildPod("c2", "p1", "n1", v1.PodRunning, testutil.BuildResourceListWithGPU("4", "1Gi", "0"), owner2),
+			testutil.BuildPod("c2", "p2", "", v1.PodPending, testutil.BuildResourceListWithGPU("1", "1Gi", "0"), owner2),
+		} {
+			schedulerCache.AddPod(pod)
+		}
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "j1", owner1))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c2", "j2", owner2))
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName, Arguments: test.args}})
+
+		j1, j2 := ssn.JobIndex["owner1"], ssn.JobIndex["owner2"]
+		first := j2.UID
+		if ssn.JobOrderFn(j1, j2) {
+			first = j1.UID
+		}
+		if first != test.first {
+			t.Errorf("case %s: expected %v first, got %v", test.name, test.first, first)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected map[v1.ResourceName]float64
+		err      bool
+	}{
+		{
+			value:    "cpu=1, nvidia.com/gpu=4",
+			expected: map[v1.ResourceName]float64{v1.ResourceCPU: 1, api.GPUResourceName: 4},
+		},
+		{
+			value:    "example.com/gpu-memory=2",
+			expected: map[v1.ResourceName]float64{"example.com/gpu-memory": 2},
+		},
+		{value: "pods=1", err: true},
+		{value: "cpu", err: true},
+		{value: "=1", err: true},
+		{value: "cpu=-1", err: true},
+		{value: "cpu=x", err: true},
+	}
+
+	for _, test := range tests {
+		weights, err := parseWeights(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("case %q: expected error, got weights %v", test.value, weights)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(weights, test.expected) {
+			t.Errorf("case %q: expected weights %v, got %v (%v)", test.value, test.expected, weights, err)
+		}
+	}
+}
+
+func TestScalarShare(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     framework.Arguments
+		expected float64
+	}{
+		{
+			name:     "the scalar resource is dominant",
+			args:     framework.Arguments{},
+			expected: 0.4,
+		},
+		{
+			name:     "the weighted scalar resource is dominant",
+			args:     framework.Arguments{Weights: "example.com/gpu-memory=2"},
+			expected: 0.8,
+		},
+		{
+			name:     "the shares of all resources are summed",
+			args:     framework.Arguments{Aggregation: SumAggregation},
+			expected: 0.5,
+		},
+	}
+
+	for _, test := range tests {
+		drf := New(test.args).(*drfPlugin)
+
+		total := api.NewResource(testutil.BuildResourceList("10", "10Gi"))
+		total.SetScalar("example.com/gpu-memory", 10000)
+		allocated := api.NewResource(testutil.BuildResourceList("1", "0"))
+		allocated.SetScalar("example.com/gpu-memory", 4000)
+
+		if share := drf.calculateShare(allocated, total); math.Abs(share-test.expected) > shareDelta {
+			t.Errorf("case %s: expected share %v, got %v", test.name, test.expected, share)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exclusive
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "exclusive"
+
+// jobPrefix is the prefix of the anti-affinity item that names a job.
+const jobPrefix = "job:"
+
+type exclusivePlugin struct {
+	// The jobs that take whole nodes in this session.
+	exclusive map[api.JobID]bool
+
+	// The anti-affinity of jobs in this session, and whether each pair of
+	// jobs is already known to be incompatible.
+	antiAffinity map[api.JobID]*antiAffinity
+	incompatible map[[2]api.JobID]bool
+}
+
+// antiAffinity is the jobs, by "<namespace>/<name>" or by labels, that a job
+// does not share nodes with.
+type antiAffinity struct {
+	jobs      map[string]bool
+	selectors []labels.Selector
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	return &exclusivePlugin{
+		exclusive:    map[api.JobID]bool{},
+		antiAffinity: map[api.JobID]*antiAffinity{},
+		incompatible: map[[2]api.JobID]bool{},
+	}
+}
+
+func (ep *exclusivePlugin) Name() string {
+	return PluginName
+}
+
+// hasOtherJobs returns true if node has tasks of jobs other than job.
+func hasOtherJobs(job api.JobID, node *api.NodeInfo) bool {
+	for _, task := range node.Tasks {
+		if task.Job != job {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAntiAffinity parses the anti-affinity annotation, e.g.
+// "job:ns1/job1;team=ads".
+func parseAntiAffinity(v string) (*antiAffinity, error) {
+	aa := &antiAffinity{jobs: map[string]bool{}}
+	for _, item := range strings.Split(v, ";") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(item, jobPrefix) {
+			name := strings.TrimPrefix(item, jobPrefix)
+			if parts := strings.Split(name, "/"); len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+				return nil, fmt.Errorf("invalid job <%v>, expected <namespace>/<name>", name)
+			}
+			aa.jobs[name] = true
+			continue
+		}
+
+		selector, err := labels.Parse(item)
+		if err != nil {
+			return nil, err
+		}
+		aa.selectors = append(aa.selectors, selector)
+	}
+
+	return aa, nil
+}
+
+// matches returns true if job is one of the jobs of aa.
+func (aa *antiAffinity) matches(job *api.JobInfo) bool {
+	if aa.jobs[job.Namespace+"/"+job.Name] {
+		return true
+	}
+
+	jobLabels := labels.Set(job.Labels())
+	for _, selector := range aa.selectors {
+		if selector.Matches(jobLabels) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isIncompatible returns true if the tasks of job a and b can not share
+// nodes by the anti-affinity of either of them.
+func (ep *exclusivePlugin) isIncompatible(a, b *api.JobInfo) bool {
+	if a.UID == b.UID {
+		return false
+	}
+
+	key := [2]api.JobID{a.UID, b.UID}
+	if incompatible, found := ep.incompatible[key]; found {
+		return incompatible
+	}
+
+	incompatible := false
+	if aa, found := ep.antiAffinity[a.UID]; found && aa.matches(b) {
+		incompatible = true
+	}
+	if aa, found := ep.antiAffinity[b.UID]; found && aa.matches(a) {
+		incompatible = true
+	}
+	ep.incompatible[key] = incompatible
+
+	return incompatible
+}
+
+// updateExclusiveJob sets the exclusive job of node by its tasks.
+func (ep *exclusivePlugin) updateExclusiveJob(node *api.NodeInfo) {
+	node.ExclusiveJob = ""
+	for _, task := range node.Tasks {
+		if ep.exclusive[task.Job] {
+			node.ExclusiveJob = task.Job
+			return
+		}
+	}
+}
+
+func (ep *exclusivePlugin) OnSessionOpen(ssn *framework.Session) {
+	for _, job := range ssn.Jobs {
+		if v, found := job.Annotations()[arbv1.AntiAffinityAnnotationKey]; found {
+			aa, err := parseAntiAffinity(v)
+			if err != nil {
+				glog.Warningf("Invalid anti-affinity annotation <%v> of Job <%v:%v/%v>, ignore it: %v",
+					v, job.UID, job.Namespace, job.Name, err)
+			} else {
+				ep.antiAffinity[job.UID] = aa
+			}
+		}
+
+		v, found := job.Annotations()[arbv1.ExclusiveAnnotationKey]
+		if !found {
+			continue
+		}
+
+		exclusive, err := strconv.ParseBool(v)
+		if err != nil {
+			glog.Warningf("Invalid exclusive annotation <%v> of Job <%v:%v/%v>, ignore it.",
+				v, job.UID, job.Namespace, job.Name)
+			continue
+		}
+		if exclusive {
+			ep.exclusive[job.UID] = true
+		}
+	}
+
+	// The nodes may be reused from last session, so the exclusive job of
+	// every node is updated.
+	for _, node := range ssn.Nodes {
+		ep.updateExclusiveJob(node)
+	}
+
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		if len(node.ExclusiveJob) != 0 && node.ExclusiveJob != task.Job {
+			return fmt.Errorf("node <%v> is exclusive to Job <%v>", node.Name, node.ExclusiveJob)
+		}
+
+		if ep.exclusive[task.Job] && hasOtherJobs(task.Job, node) {
+			return fmt.Errorf("node <%v> has tasks of other jobs, but Job <%v> is exclusive",
+				node.Name, task.Job)
+		}
+
+		if len(ep.antiAffinity) == 0 {
+			return nil
+		}
+		job, found := ssn.JobIndex[task.Job]
+		if !found {
+			return nil
+		}
+		for _, t := range node.Tasks {
+			other, found := ssn.JobIndex[t.Job]
+			if found && ep.isIncompatible(job, other) {
+				return fmt.Errorf("node <%v> has tasks of Job <%v/%v>, which is anti-affine to Job <%v/%v>",
+					node.Name, other.Namespace, other.Name, job.Namespace, job.Name)
+			}
+		}
+
+		return nil
+	})
+
+	ssn.AddEventHandler(&framework.EventHandler{
+		AllocateFunc: func(event *framework.Event) {
+			if node, found := ssn.NodeIndex[event.Task.NodeName]; found && ep.exclusive[event.Task.Job] {
+				node.ExclusiveJob = event.Task.Job
+			}
+		},
+		EvictFunc: func(event *framework.Event) {
+			if !ep.exclusive[event.Task.Job] {
+				return
+			}
+
+			// The node of unpipelined task is already reset, so check all
+			// nodes of the job.
+			for _, node := range ssn.Nodes {
+				if node.ExclusiveJob == event.Task.Job {
+					ep.updateExclusiveJob(node)
+				}
+			}
+		},
+	})
+}
+
+func (ep *exclusivePlugin) OnSessionClose(ssn *framework.Session) {
+	ep.exclusive = map[api.JobID]bool{}
+	ep.antiAffinity = map[api.JobID]*antiAffinity{}
+	ep.incompatible = map[[2]api.JobID]bool{}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exclusive
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func TestExclusiveJob(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	owner0 := testutil.BuildOwnerReference("owner0")
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: testutil.NewFakeBinder(),
+	}
+
+	for _, name := range []string{"n1", "n2", "n3"} {
+		schedulerCache.AddNode(testutil.BuildNode(name, testutil.BuildResourceList("4", "8Gi")))
+	}
+
+	// n1 already runs a task of owner0; the job of owner1 is exclusive and
+	// goes first as it's older.
+	for _, pod := range []*v1.Pod{
+		testutil.BuildPod("c1", "p0", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner0),
+		testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner1),
+		testutil.BuildPod("c1", "p3", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+		testutil.BuildPod("c1", "p4", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+	} {
+		schedulerCache.AddPod(pod)
+	}
+
+	now := time.Now()
+	for i, owner := range []metav1.OwnerReference{owner0, owner1, owner2} {
+		spec := &arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              fmt.Sprintf("j%d", i),
+				Namespace:         "c1",
+				CreationTimestamp: metav1.NewTime(now.Add(time.Duration(i) * time.Minute)),
+				OwnerReferences:   []metav1.OwnerReference{owner},
+			},
+		}
+		if owner.UID == owner1.UID {
+			spec.Annotations = map[string]string{arbv1.ExclusiveAnnotationKey: "true"}
+		}
+		schedulerCache.AddSchedulingSpec(spec)
+	}
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	allocate.New().Execute(ssn)
+
+	exclusiveNodes := map[string]bool{}
+	for _, task := range ssn.JobIndex["owner1"].Tasks {
+		if len(task.NodeName) == 0 || task.NodeName == "n1" {
+			t.Errorf("expected exclusive task %v on empty node, got %q", task.Name, task.NodeName)
+		}
+		exclusiveNodes[task.NodeName] = true
+	}
+
+	for _, task := range ssn.JobIndex["owner2"].Tasks {
+		if len(task.NodeName) == 0 {
+			t.Errorf("expected task %v allocated", task.Name)
+		}
+		if exclusiveNodes[task.NodeName] {
+			t.Errorf("expected task %v not on exclusive node %v", task.Name, task.NodeName)
+		}
+	}
+
+	for name := range exclusiveNodes {
+		if got := ssn.NodeIndex[name].ExclusiveJob; got != "owner1" {
+			t.Errorf("expected node %v exclusive to owner1, got %q", name, got)
+		}
+	}
+}
+
+func TestAntiAffinity(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name string
+		// The annotations of the job running on n1, and of the pending job.
+		running map[string]string
+		pending map[string]string
+		// The nodes of pending tasks.
+		nodes map[string]bool
+	}{
+		{
+			name:    "no anti-affinity",
+			running: map[string]string{},
+			pending: map[string]string{},
+			nodes:   map[string]bool{"n1": true, "n2": true},
+		},
+		{
+			name:    "the pending job is anti-affine to the labels of running job",
+			running: map[string]string{},
+			pending: map[string]string{arbv1.AntiAffinityAnnotationKey: "team=noisy"},
+			nodes:   map[string]bool{"n2": true},
+		},
+		{
+			name:    "the pending job is anti-affine to the running job by name",
+			running: map[string]string{},
+			pending: map[string]string{arbv1.AntiAffinityAnnotationKey: "tier=web; job:c1/j0"},
+			nodes:   map[string]bool{"n2": true},
+		},
+		{
+			name:    "the running job is anti-affine to the pending job",
+			running: map[string]string{arbv1.AntiAffinityAnnotationKey: "job:c1/j1"},
+			pending: map[string]string{},
+			nodes:   map[string]bool{"n2": true},
+		},
+		{
+			name:    "the labels do not match",
+			running: map[string]string{},
+			pending: map[string]string{arbv1.AntiAffinityAnnotationKey: "team!=noisy"},
+			nodes:   map[string]bool{"n1": true, "n2": true},
+		},
+	}
+
+	for _, test := range tests {
+		owner0 := testutil.BuildOwnerReference("owner0")
+		owner1 := testutil.BuildOwnerReference("owner1")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		}
+
+		// The pending tasks fit either node alone.
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+		schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("3", "8Gi")))
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p0", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner0))
+		for i := 1; i <= 3; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending,
+				testutil.BuildResourceList("1", "1Gi"), owner1))
+		}
+
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j0",
+				Namespace:       "c1",
+				Labels:          map[string]string{"team": "noisy"},
+				Annotations:     test.running,
+				OwnerReferences: []metav1.OwnerReference{owner0},
+			},
+		})
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				Annotations:     test.pending,
+				OwnerReferences: []metav1.OwnerReference{owner1},
+			},
+		})
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+
+		allocate.New().Execute(ssn)
+
+		for _, task := range ssn.JobIndex["owner1"].Tasks {
+			if !test.nodes[task.NodeName] {
+				t.Errorf("case %s: expected task %v on one of %v, got %q", test.name, task.Name, test.nodes, task.NodeName)
+			}
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestParseAntiAffinity(t *testing.T) {
+	tests := []struct {
+		value string
+		jobs  int
+		sels  int
+		err   bool
+	}{
+		{value: "job:ns1/j1", jobs: 1},
+		{value: "team=ads,tier!=web", sels: 1},
+		{value: "job:ns1/j1; team in (ads, web) ;", jobs: 1, sels: 1},
+		{value: "job:j1", err: true},
+		{value: "job:ns1/", err: true},
+		{value: "=ads", err: true},
+	}
+
+	for _, test := range tests {
+		aa, err := parseAntiAffinity(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("case %q: expected error, got none", test.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %q: unexpected error: %v", test.value, err)
+			continue
+		}
+		if len(aa.jobs) != test.jobs || len(aa.selectors) != test.sels {
+			t.Errorf("case %q: expected %d jobs and %d selectors, got %d and %d",
+				test.value, test.jobs, test.sels, len(aa.jobs), len(aa.selectors))
+		}
+	}
+}
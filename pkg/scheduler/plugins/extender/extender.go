@@ -0,0 +1,303 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "extender"
+
+const (
+	// URLPrefix is the prefix of the extender's URL, e.g. "http://127.0.0.1:8888/scheduler".
+	URLPrefix = "urlPrefix"
+	// FilterVerb is appended to URLPrefix for filter; no filter if empty.
+	FilterVerb = "filterVerb"
+	// PrioritizeVerb is appended to URLPrefix for prioritize; no prioritize if empty.
+	PrioritizeVerb = "prioritizeVerb"
+	// Weight is multiplied to the scores of prioritize, the default is 1.
+	Weight = "weight"
+	// Timeout is the timeout of each call, e.g. "5s", the default is 5s.
+	Timeout = "timeout"
+	// NodeCacheCapable is whether the extender caches nodes, so only the
+	// names of nodes are sent to it.
+	NodeCacheCapable = "nodeCacheCapable"
+	// Ignorable is whether the failures of extender are ignored, i.e. all
+	// nodes pass filter with zero score; otherwise, all nodes are rejected.
+	Ignorable = "ignorable"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// ExtenderArgs is the arguments of filter and prioritize, the same as
+// kube-scheduler's extender protocol.
+type ExtenderArgs struct {
+	Pod       *v1.Pod      `json:"pod"`
+	Nodes     *v1.NodeList `json:"nodes,omitempty"`
+	NodeNames *[]string    `json:"nodenames,omitempty"`
+}
+
+// ExtenderFilterResult is the result of filter.
+type ExtenderFilterResult struct {
+	Nodes       *v1.NodeList      `json:"nodes,omitempty"`
+	NodeNames   *[]string         `json:"nodenames,omitempty"`
+	FailedNodes map[string]string `json:"failedNodes,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// HostPriority is the score of a node by prioritize.
+type HostPriority struct {
+	Host  string `json:"host"`
+	Score int    `json:"score"`
+}
+
+// HostPriorityList is the result of prioritize.
+type HostPriorityList []HostPriority
+
+type extenderPlugin struct {
+	urlPrefix        string
+	filterVerb       string
+	prioritizeVerb   string
+	weight           int
+	nodeCacheCapable bool
+	ignorable        bool
+
+	client *http.Client
+
+	// The results of each task in this session; the extender is called
+	// once for all nodes instead of once for each node.
+	filtered       map[api.TaskID]map[string]error
+	filterFailures map[api.TaskID]error
+	scores         map[api.TaskID]map[string]float64
+	scoreFailures  map[api.TaskID]error
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	ep := &extenderPlugin{
+		urlPrefix:      args[URLPrefix],
+		filterVerb:     args[FilterVerb],
+		prioritizeVerb: args[PrioritizeVerb],
+		weight:         1,
+
+		filtered:       map[api.TaskID]map[string]error{},
+		filterFailures: map[api.TaskID]error{},
+		scores:         map[api.TaskID]map[string]float64{},
+		scoreFailures:  map[api.TaskID]error{},
+	}
+
+	timeout := defaultTimeout
+	args.GetInt(&ep.weight, Weight)
+	args.GetDuration(&timeout, Timeout)
+	args.GetBool(&ep.nodeCacheCapable, NodeCacheCapable)
+	args.GetBool(&ep.ignorable, Ignorable)
+
+	ep.client = &http.Client{Timeout: timeout}
+
+	return ep
+}
+
+func (ep *extenderPlugin) Name() string {
+	return PluginName
+}
+
+// send posts args to the verb of extender, and decodes the response to result;
+// the request is cancelled when the Context of session is done.
+func (ep *extenderPlugin) send(ssn *framework.Session, verb string, args interface{}, result interface{}) error {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimRight(ep.urlPrefix, "/") + "/" + verb
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	ctx := ssn.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	resp, err := ep.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed %v with extender at URL %v, code %v", verb, url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (ep *extenderPlugin) extenderArgs(task *api.TaskInfo, nodes []*api.NodeInfo) *ExtenderArgs {
+	args := &ExtenderArgs{Pod: task.Pod}
+
+	if ep.nodeCacheCapable {
+		names := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			names = append(names, node.Name)
+		}
+		args.NodeNames = &names
+	} else {
+		list := &v1.NodeList{}
+		for _, node := range nodes {
+			if node.Node != nil {
+				list.Items = append(list.Items, *node.Node)
+			}
+		}
+		args.Nodes = list
+	}
+
+	return args
+}
+
+// filter calls the filter of extender for task with all nodes of session;
+// the result is the error of each rejected node.
+func (ep *extenderPlugin) filter(ssn *framework.Session, task *api.TaskInfo) (map[string]error, error) {
+	result := &ExtenderFilterResult{}
+	if err := ep.send(ssn, ep.filterVerb, ep.extenderArgs(task, ssn.Nodes), result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Error) != 0 {
+		return nil, fmt.Errorf("%v", result.Error)
+	}
+
+	passed := map[string]bool{}
+	if result.NodeNames != nil {
+		for _, name := range *result.NodeNames {
+			passed[name] = true
+		}
+	} else if result.Nodes != nil {
+		for _, node := range result.Nodes.Items {
+			passed[node.Name] = true
+		}
+	}
+
+	rejected := map[string]error{}
+	for _, node := range ssn.Nodes {
+		if passed[node.Name] {
+			continue
+		}
+
+		reason := result.FailedNodes[node.Name]
+		if len(reason) == 0 {
+			reason = "rejected by extender"
+		}
+		rejected[node.Name] = fmt.Errorf("node <%v>: %v", node.Name, reason)
+	}
+
+	return rejected, nil
+}
+
+// prioritize calls the prioritize of extender for task with all nodes of
+// session; the result is the weighted score of each node.
+func (ep *extenderPlugin) prioritize(ssn *framework.Session, task *api.TaskInfo) (map[string]float64, error) {
+	result := HostPriorityList{}
+	if err := ep.send(ssn, ep.prioritizeVerb, ep.extenderArgs(task, ssn.Nodes), &result); err != nil {
+		return nil, err
+	}
+
+	scores := map[string]float64{}
+	for _, hp := range result {
+		scores[hp.Host] = float64(hp.Score * ep.weight)
+	}
+
+	return scores, nil
+}
+
+// failed handles the failure of extender for task: it's ignored if the
+// extender is ignorable, otherwise it's returned to reject the node.
+func (ep *extenderPlugin) failed(task *api.TaskInfo, err error) error {
+	if ep.ignorable {
+		glog.Warningf("Ignored the failure of extender for Task <%v/%v>: %v",
+			task.Namespace, task.Name, err)
+		return nil
+	}
+
+	glog.Errorf("Failed to call extender for Task <%v/%v>: %v",
+		task.Namespace, task.Name, err)
+	return err
+}
+
+func (ep *extenderPlugin) OnSessionOpen(ssn *framework.Session) {
+	if len(ep.urlPrefix) == 0 {
+		return
+	}
+
+	if len(ep.filterVerb) != 0 {
+		ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+			if _, found := ep.filtered[task.UID]; !found {
+				rejected, err := ep.filter(ssn, task)
+				if err != nil {
+					ep.filterFailures[task.UID] = ep.failed(task, err)
+					rejected = map[string]error{}
+				}
+				ep.filtered[task.UID] = rejected
+			}
+
+			if err := ep.filterFailures[task.UID]; err != nil {
+				return err
+			}
+
+			return ep.filtered[task.UID][node.Name]
+		})
+	}
+
+	if len(ep.prioritizeVerb) != 0 {
+		ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+			if _, found := ep.scores[task.UID]; !found {
+				scores, err := ep.prioritize(ssn, task)
+				if err != nil {
+					ep.scoreFailures[task.UID] = ep.failed(task, err)
+					scores = map[string]float64{}
+				}
+				ep.scores[task.UID] = scores
+			}
+
+			if err := ep.scoreFailures[task.UID]; err != nil {
+				return 0, err
+			}
+
+			return ep.scores[task.UID][node.Name], nil
+		})
+	}
+}
+
+func (ep *extenderPlugin) OnSessionClose(ssn *framework.Session) {
+	ep.filtered = map[api.TaskID]map[string]error{}
+	ep.filterFailures = map[api.TaskID]error{}
+	ep.scores = map[api.TaskID]map[string]float64{}
+	ep.scoreFailures = map[api.TaskID]error{}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extender
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+// callCounter counts the calls of each verb of extender.
+type callCounter struct {
+	sync.Mutex
+	calls map[string]int
+}
+
+func (cc *callCounter) inc(verb string) {
+	cc.Lock()
+	defer cc.Unlock()
+
+	cc.calls[verb]++
+}
+
+func (cc *callCounter) get(verb string) int {
+	cc.Lock()
+	defer cc.Unlock()
+
+	return cc.calls[verb]
+}
+
+// newExtenderServer returns a stub extender which passes n2 and n3, and
+// prefers n3.
+func newExtenderServer(delay time.Duration, calls *callCounter) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/filter", func(w http.ResponseWriter, r *http.Request) {
+		calls.inc("filter")
+		time.Sleep(delay)
+
+		args := &ExtenderArgs{}
+		json.NewDecoder(r.Body).Decode(args)
+
+		names := []string{}
+		failed := map[string]string{}
+		for _, name := range *args.NodeNames {
+			if name == "n1" {
+				failed[name] = "no license"
+				continue
+			}
+			names = append(names, name)
+		}
+
+		json.NewEncoder(w).Encode(&ExtenderFilterResult{
+			NodeNames:   &names,
+			FailedNodes: failed,
+		})
+	})
+
+	mux.HandleFunc("/prioritize", func(w http.ResponseWriter, r *http.Request) {
+		calls.inc("prioritize")
+		time.Sleep(delay)
+
+		json.NewEncoder(w).Encode(HostPriorityList{
+			{Host: "n2", Score: 1},
+			{Host: "n3", Score: 5},
+		})
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestExtender(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		ignorable string
+		rejected  map[string]bool
+		scores    map[string]float64
+		failed    bool
+		// Whether the session is cancelled, so the extender is not called.
+		cancelled bool
+	}{
+		{
+			name:     "node verdicts",
+			rejected: map[string]bool{"n1": true},
+			scores:   map[string]float64{"n1": 0, "n2": 2, "n3": 10},
+		},
+		{
+			name:      "fail open on timeout",
+			delay:     200 * time.Millisecond,
+			ignorable: "true",
+			rejected:  map[string]bool{},
+			scores:    map[string]float64{"n1": 0, "n2": 0, "n3": 0},
+		},
+		{
+			name:      "fail closed on timeout",
+			delay:     200 * time.Millisecond,
+			ignorable: "false",
+			rejected:  map[string]bool{"n1": true, "n2": true, "n3": true},
+			failed:    true,
+		},
+		{
+			name:      "fail closed on cancelled session",
+			ignorable: "false",
+			rejected:  map[string]bool{"n1": true, "n2": true, "n3": true},
+			failed:    true,
+			cancelled: true,
+		},
+	}
+
+	for i, test := range tests {
+		calls := &callCounter{calls: map[string]int{}}
+		server := newExtenderServer(test.delay, calls)
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		}
+		for _, name := range []string{"n1", "n2", "n3"} {
+			schedulerCache.AddNode(testutil.BuildNode(name, testutil.BuildResourceList("4", "8Gi")))
+		}
+		owner := testutil.BuildOwnerReference("owner1")
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if test.cancelled {
+			cancel()
+		}
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{
+				Name: PluginName,
+				Arguments: framework.Arguments{
+					URLPrefix:        server.URL,
+					FilterVerb:       "filter",
+					PrioritizeVerb:   "prioritize",
+					Weight:           "2",
+					Timeout:          "50ms",
+					NodeCacheCapable: "true",
+					Ignorable:        test.ignorable,
+				},
+			},
+		}, func(ssn *framework.Session) {
+			ssn.Context = ctx
+		})
+
+		var task *api.TaskInfo
+		for _, t := range ssn.JobIndex["owner1"].Tasks {
+			task = t
+		}
+
+		for _, node := range ssn.Nodes {
+			err := ssn.PredicateFn(task, node)
+			if (err != nil) != test.rejected[node.Name] {
+				t.Errorf("case %d (%s): expected node <%v> rejected %v, got err %v",
+					i, test.name, node.Name, test.rejected[node.Name], err)
+			}
+
+			score, err := ssn.NodeOrderFn(task, node)
+			if (err != nil) != test.failed {
+				t.Errorf("case %d (%s): expected score failure %v on node <%v>, got err %v",
+					i, test.name, test.failed, node.Name, err)
+			}
+			if !test.failed && score != test.scores[node.Name] {
+				t.Errorf("case %d (%s): expected score %v on node <%v>, got %v",
+					i, test.name, test.scores[node.Name], node.Name, score)
+			}
+		}
+
+		framework.CloseSession(ssn)
+		server.Close()
+		cancel()
+
+		// The extender is called once for all nodes.
+		expected := 1
+		if test.cancelled {
+			expected = 0
+		}
+		if calls.get("filter") != expected || calls.get("prioritize") != expected {
+			t.Errorf("case %d (%s): expected %d call of each verb, got %v", i, test.name, expected, calls.calls)
+		}
+	}
+}
@@ -17,17 +17,94 @@ limitations under the License.
 package gang
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/golang/glog"
 
+	"k8s.io/api/core/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "gang"
+
+const (
+	// Timeout is the argument of the default duration that a job waits for its
+	// MinAvailable tasks; 0 means waiting forever. It's overridden by the
+	// gang-timeout annotation of job.
+	Timeout = "timeout"
+
+	// TimeoutPolicy is the argument of what to do when gang scheduling times out,
+	// GiveUpPolicy or DegradePolicy.
+	TimeoutPolicy = "timeoutPolicy"
+
+	// MarkUnschedulable is the argument of whether to mark the pods of unmet
+	// demand unschedulable, i.e. the PodScheduled condition that
+	// cluster-autoscaler scales up nodes for; the default is false.
+	MarkUnschedulable = "markUnschedulable"
+)
+
+const (
+	// GiveUpPolicy marks the timed out job unschedulable and stops scheduling it.
+	GiveUpPolicy = "giveup"
+
+	// DegradePolicy dispatches whatever tasks of the timed out job are allocated.
+	DegradePolicy = "degrade"
+
+	// UnschedulableReason is the reason of the event when gang scheduling times out.
+	UnschedulableReason = "Unschedulable"
+
+	// UnmetDemandReason is the reason of the event when the gang job lacks
+	// resource for its MinAvailable tasks even with preemption.
+	UnmetDemandReason = "InsufficientResources"
+
+	// NotEnoughTasksReason is the reason of the Unschedulable condition when
+	// the job has fewer tasks than MinAvailable.
+	NotEnoughTasksReason = "NotEnoughTasks"
+
+	// BackloggedReason is the reason of the Unschedulable condition when the
+	// MinResources of job are not available.
+	BackloggedReason = "Backlogged"
 )
 
 type gangPlugin struct {
+	timeout           time.Duration
+	timeoutPolicy     string
+	markUnschedulable bool
+
+	// The jobs timed out in this session.
+	unschedulable map[api.JobID]bool
+	degraded      map[api.JobID]bool
+
+	// The jobs whose MinResources are not available in this session.
+	backlogged map[api.JobID]bool
 }
 
-func New() framework.Plugin {
-	return &gangPlugin{}
+func New(args framework.Arguments) framework.Plugin {
+	gp := &gangPlugin{
+		timeoutPolicy: GiveUpPolicy,
+		unschedulable: map[api.JobID]bool{},
+		degraded:      map[api.JobID]bool{},
+		backlogged:    map[api.JobID]bool{},
+	}
+
+	args.GetDuration(&gp.timeout, Timeout)
+	if policy, found := args[TimeoutPolicy]; found {
+		gp.timeoutPolicy = policy
+	}
+	args.GetBool(&gp.markUnschedulable, MarkUnschedulable)
+
+	return gp
+}
+
+func (gp *gangPlugin) Name() string {
+	return PluginName
 }
 
 func readyTaskNum(job *api.JobInfo) int {
@@ -49,7 +126,99 @@ func jobReady(obj interface{}) bool {
 	return occupid >= job.MinAvailable
 }
 
+// jobTimeout returns the gang timeout of job, the annotation of job overrides
+// the argument of plugin.
+func (gp *gangPlugin) jobTimeout(job *api.JobInfo) time.Duration {
+	if v, found := job.Annotations()[arbv1.GangTimeoutAnnotationKey]; found {
+		timeout, err := time.ParseDuration(v)
+		if err == nil {
+			return timeout
+		}
+		glog.Warningf("Invalid gang timeout <%v> of Job <%v:%v/%v>: %v",
+			v, job.UID, job.Namespace, job.Name, err)
+	}
+
+	return gp.timeout
+}
+
+// checkTimeout handles the jobs that can not get MinAvailable tasks in time.
+func (gp *gangPlugin) checkTimeout(ssn *framework.Session) {
+	for _, job := range ssn.Jobs {
+		timeout := gp.jobTimeout(job)
+		if timeout <= 0 || jobReady(job) {
+			continue
+		}
+
+		since := job.PendingSince()
+		if since.IsZero() || ssn.Now().Sub(since) < timeout {
+			continue
+		}
+
+		glog.V(3).Infof("Gang scheduling of Job <%v:%v/%v> timed out after %v, policy is %v",
+			job.UID, job.Namespace, job.Name, timeout, gp.timeoutPolicy)
+
+		switch gp.timeoutPolicy {
+		case DegradePolicy:
+			gp.degraded[job.UID] = true
+		default:
+			gp.unschedulable[job.UID] = true
+
+			msg := fmt.Sprintf("%v/%v tasks in gang are ready, less than MinAvailable %v after %v",
+				readyTaskNum(job), len(job.Tasks), job.MinAvailable, timeout)
+			if err := ssn.Backoff(job, UnschedulableReason, msg); err != nil {
+				glog.Errorf("Failed to backoff Job <%v:%v/%v>: %v",
+					job.UID, job.Namespace, job.Name, err)
+			}
+		}
+	}
+}
+
+// enqueue admits the jobs that have not started by their MinResources, in
+// the order of creation, while the idle resource of cluster is enough; the
+// others are kept in the backlog of session until next one.
+func (gp *gangPlugin) enqueue(ssn *framework.Session) {
+	idle := api.EmptyResource()
+	for _, node := range ssn.Nodes {
+		idle.Add(node.Idle)
+	}
+
+	var jobs []*api.JobInfo
+	for _, job := range ssn.Jobs {
+		if job.MinResources != nil && readyTaskNum(job) == 0 {
+			jobs = append(jobs, job)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		l, r := jobs[i], jobs[j]
+		if !l.CreationTimestamp.Equal(&r.CreationTimestamp) {
+			return l.CreationTimestamp.Before(&r.CreationTimestamp)
+		}
+		return l.UID < r.UID
+	})
+
+	for _, job := range jobs {
+		if job.MinResources.LessEqual(idle) {
+			idle.Sub(job.MinResources)
+			continue
+		}
+
+		glog.V(3).Infof("MinResources <%v> of Job <%v:%v/%v> is more than idle <%v>, keep it in backlog",
+			job.MinResources, job.UID, job.Namespace, job.Name, idle)
+
+		gp.backlogged[job.UID] = true
+		ssn.Backlog = append(ssn.Backlog, job)
+	}
+}
+
 func (gp *gangPlugin) OnSessionOpen(ssn *framework.Session) {
+	gp.checkTimeout(ssn)
+	gp.enqueue(ssn)
+
+	ssn.AddJobValidFn(func(obj interface{}) bool {
+		job := obj.(*api.JobInfo)
+		return !gp.unschedulable[job.UID] && !gp.backlogged[job.UID]
+	})
 	ssn.AddPreemptableFn(func(l, v interface{}) bool {
 		preemptee := v.(*api.TaskInfo)
 
@@ -86,19 +255,175 @@ func (gp *gangPlugin) OnSessionOpen(ssn *framework.Session) {
 			return -1
 		}
 
-		if !lReady && !rReady {
-			if lv.UID < rv.UID {
-				return -1
+		// The order of the jobs not ready is left to the following plugins,
+		// e.g. drf or deadline, and to the FIFO default of session.
+		return 0
+	})
+
+	ssn.AddJobReadyFn(func(obj interface{}) bool {
+		job := obj.(*api.JobInfo)
+		return gp.degraded[job.UID] || jobReady(job)
+	})
+
+	ssn.AddJobPipelinedFn(func(obj interface{}) bool {
+		job := obj.(*api.JobInfo)
+		occupid := readyTaskNum(job) + len(job.TaskStatusIndex[api.Pipelined])
+		return gp.degraded[job.UID] || occupid >= job.MinAvailable
+	})
+}
+
+// unmetDemand returns the pending tasks that job lacks for its MinAvailable
+// ones besides the ready and pipelined tasks, in the order of tasks, and the
+// total resource they request.
+func unmetDemand(ssn *framework.Session, job *api.JobInfo) ([]*api.TaskInfo, *api.Resource) {
+	demand := api.EmptyResource()
+
+	missing := job.MinAvailable - readyTaskNum(job) - len(job.TaskStatusIndex[api.Pipelined])
+	if missing <= 0 {
+		return nil, demand
+	}
+
+	var pending []*api.TaskInfo
+	for _, task := range job.TaskStatusIndex[api.Pending] {
+		pending = append(pending, task)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return ssn.TaskOrderFn(pending[i], pending[j])
+	})
+	if len(pending) > missing {
+		pending = pending[:missing]
+	}
+
+	for _, task := range pending {
+		demand.Add(task.Resreq)
+	}
+
+	return pending, demand
+}
+
+// signalUnmetDemand reports the gang jobs that can not start even with
+// preemption by an event and metric of the resource they lack, so
+// cluster-autoscaler or a custom controller can scale up nodes for them;
+// it's called after all actions of session. The metric is updated in every
+// session, but the event only when the unmet demand of job changes: the
+// demand last reported of each job is kept in the state across sessions, so
+// the jobs whose demand is met are reported again if they lack resource later.
+func (gp *gangPlugin) signalUnmetDemand(ssn *framework.Session) {
+	metrics.JobUnmetDemand.Init()
+
+	reported, _ := ssn.PluginState(PluginName).(map[api.JobID]string)
+	unmet := map[api.JobID]string{}
+	defer ssn.SetPluginState(PluginName, unmet)
+
+	for _, job := range ssn.Jobs {
+		// The jobs given up or degraded are not waiting for more resource.
+		if job.MinAvailable == 0 || gp.unschedulable[job.UID] || gp.degraded[job.UID] {
+			continue
+		}
+
+		tasks, demand := unmetDemand(ssn, job)
+		if len(tasks) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("%s/%s", job.Namespace, job.Name)
+		for _, rn := range api.ResourceNames() {
+			metrics.UpdateJobResource(metrics.JobUnmetDemand, name, string(rn), demand.Get(rn))
+		}
+
+		msg := fmt.Sprintf("%v more tasks in gang can not be scheduled, lacking <%v>",
+			len(tasks), demand)
+		glog.V(3).Infof("Job <%v:%v/%v>: %s", job.UID, job.Namespace, job.Name, msg)
+
+		unmet[job.UID] = msg
+		if reported[job.UID] == msg {
+			continue
+		}
+
+		for _, task := range tasks {
+			if err := ssn.RecordEvent(task, v1.EventTypeWarning, UnmetDemandReason, msg); err != nil {
+				glog.Errorf("Failed to record event of Task <%v:%v/%v>: %v",
+					task.UID, task.Namespace, task.Name, err)
+			}
+
+			if !gp.markUnschedulable {
+				continue
+			}
+			if err := ssn.TaskUnschedulable(task, msg); err != nil {
+				glog.Errorf("Failed to mark Task <%v:%v/%v> unschedulable: %v",
+					task.UID, task.Namespace, task.Name, err)
 			}
-			return 1
 		}
+	}
+}
 
-		return 0
-	})
+// jobStatus returns the status of job's SchedulingSpec: Scheduled if its
+// MinAvailable tasks are bound, including the ones dispatched in this
+// session, Unschedulable with the reason otherwise. The tasks allocated but
+// not dispatched are still pending.
+func (gp *gangPlugin) jobStatus(job *api.JobInfo) *arbv1.SchedulingSpecStatus {
+	status := &arbv1.SchedulingSpecStatus{}
+	for s, tasks := range job.TaskStatusIndex {
+		switch s {
+		case api.Binding, api.Bound, api.Running, api.Succeeded:
+			status.Running += int32(len(tasks))
+		case api.Pending, api.Pipelined, api.Allocated:
+			status.Pending += int32(len(tasks))
+		}
+	}
+	ready := int(status.Running)
 
-	ssn.AddJobReadyFn(jobReady)
+	if ready >= job.MinAvailable {
+		status.Conditions = []arbv1.SchedulingSpecCondition{
+			{Type: arbv1.SchedulingSpecScheduled, Status: v1.ConditionTrue},
+			{Type: arbv1.SchedulingSpecUnschedulable, Status: v1.ConditionFalse},
+		}
+		return status
+	}
+
+	reason := UnmetDemandReason
+	switch {
+	case gp.unschedulable[job.UID]:
+		reason = UnschedulableReason
+	case gp.backlogged[job.UID]:
+		reason = BackloggedReason
+	case len(job.Tasks) < job.MinAvailable:
+		reason = NotEnoughTasksReason
+	}
+
+	status.Conditions = []arbv1.SchedulingSpecCondition{
+		{Type: arbv1.SchedulingSpecScheduled, Status: v1.ConditionFalse},
+		{
+			Type:   arbv1.SchedulingSpecUnschedulable,
+			Status: v1.ConditionTrue,
+			Reason: reason,
+			Message: fmt.Sprintf("%v/%v tasks in gang are scheduled, %v pending, less than MinAvailable %v",
+				ready, len(job.Tasks), status.Pending, job.MinAvailable),
+		},
+	}
+
+	return status
+}
+
+// updateJobStatus updates the status of the SchedulingSpec of every job by
+// the result of session.
+func (gp *gangPlugin) updateJobStatus(ssn *framework.Session) {
+	for _, job := range ssn.Jobs {
+		if job.SchedSpec == nil {
+			continue
+		}
+
+		if err := ssn.UpdateJobStatus(job, gp.jobStatus(job)); err != nil {
+			glog.Errorf("Failed to update status of Job <%v:%v/%v>: %v",
+				job.UID, job.Namespace, job.Name, err)
+		}
+	}
 }
 
 func (gp *gangPlugin) OnSessionClose(ssn *framework.Session) {
+	gp.signalUnmetDemand(ssn)
+	gp.updateJobStatus(ssn)
 
+	gp.unschedulable = map[api.JobID]bool{}
+	gp.degraded = map[api.JobID]bool{}
+	gp.backlogged = map[api.JobID]bool{}
 }
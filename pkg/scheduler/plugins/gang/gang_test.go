@@ -0,0 +1,593 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gang
+
+import (
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/drf"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/predicates"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/priority"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func buildPod(ns, n string, created time.Time, req v1.ResourceList, owner metav1.OwnerReference) *v1.Pod {
+	pod := testutil.BuildPod(ns, n, "", v1.PodPending, req, owner)
+	pod.CreationTimestamp = metav1.NewTime(created)
+	return pod
+}
+
+// fakeCache records the reasons of Backoff instead of creating events.
+type fakeCache struct {
+	*cache.SchedulerCache
+
+	backoffs map[api.JobID]string
+}
+
+func (fc *fakeCache) Backoff(job *api.JobInfo, reason, message string) error {
+	fc.backoffs[job.UID] = reason
+	return nil
+}
+
+// fakeEventCache records the reasons of events by task.
+type fakeEventCache struct {
+	*cache.SchedulerCache
+
+	events map[string]string
+}
+
+func (fc *fakeEventCache) RecordEvent(task *api.TaskInfo, eventType, reason, message string) error {
+	fc.events[task.Name] = reason
+	return nil
+}
+
+func TestGangTimeout(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name     string
+		args     framework.Arguments
+		timeout  string
+		created  time.Time
+		binds    int
+		backoffs map[api.JobID]string
+	}{
+		{
+			name:     "waiting for gang",
+			args:     framework.Arguments{},
+			timeout:  "10m",
+			created:  time.Now(),
+			binds:    0,
+			backoffs: map[api.JobID]string{},
+		},
+		{
+			name:     "give up after timeout",
+			args:     framework.Arguments{},
+			timeout:  "10m",
+			created:  time.Now().Add(-time.Hour),
+			binds:    0,
+			backoffs: map[api.JobID]string{"owner1": UnschedulableReason},
+		},
+		{
+			name:     "degrade after timeout",
+			args:     framework.Arguments{TimeoutPolicy: DegradePolicy},
+			timeout:  "10m",
+			created:  time.Now().Add(-time.Hour),
+			binds:    2,
+			backoffs: map[api.JobID]string{},
+		},
+		{
+			name:     "timeout by argument",
+			args:     framework.Arguments{Timeout: "10m", TimeoutPolicy: DegradePolicy},
+			created:  time.Now().Add(-time.Hour),
+			binds:    2,
+			backoffs: map[api.JobID]string{},
+		},
+	}
+
+	for i, test := range tests {
+		binder := testutil.NewFakeBinder()
+		schedulerCache := &fakeCache{
+			SchedulerCache: &cache.SchedulerCache{
+				Nodes:  make(map[string]*api.NodeInfo),
+				Jobs:   make(map[api.JobID]*api.JobInfo),
+				Binder: binder,
+			},
+			backoffs: map[api.JobID]string{},
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+
+		// The job requires 3 tasks at least, but the cluster can only run 2 of them.
+		owner := testutil.BuildOwnerReference("owner1")
+		for j := 0; j < 3; j++ {
+			schedulerCache.AddPod(buildPod("c1", fmt.Sprintf("p%d", j), test.created, testutil.BuildResourceList("1", "1Gi"), owner))
+		}
+
+		spec := &arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 3,
+			},
+		}
+		if len(test.timeout) != 0 {
+			spec.Annotations = map[string]string{arbv1.GangTimeoutAnnotationKey: test.timeout}
+		}
+		schedulerCache.AddSchedulingSpec(spec)
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName, Arguments: test.args}})
+
+		allocate.New().Execute(ssn)
+
+		framework.CloseSession(ssn)
+
+		// The binding is asynchronous, wait for it if any.
+		for k := 0; k < 30 && binder.Length() < test.binds; k++ {
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		if got := binder.Length(); got != test.binds {
+			t.Errorf("case %d (%s): expected %d binds, got %d", i, test.name, test.binds, got)
+		}
+
+		if len(test.backoffs) != len(schedulerCache.backoffs) {
+			t.Errorf("case %d (%s): expected backoffs %v, got %v", i, test.name, test.backoffs, schedulerCache.backoffs)
+			continue
+		}
+		for job, reason := range test.backoffs {
+			if got := schedulerCache.backoffs[job]; got != reason {
+				t.Errorf("case %d (%s): expected backoff reason %v of job %v, got %v", i, test.name, reason, job, got)
+			}
+		}
+	}
+}
+
+func TestGangTimeoutByClock(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	created := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &testutil.FakeClock{Time: created}
+	schedulerCache := &fakeCache{
+		SchedulerCache: &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+			Clock: clock,
+		},
+		backoffs: map[api.JobID]string{},
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+
+	// The job requires 3 tasks at least, but the cluster can only run 2 of them.
+	owner := testutil.BuildOwnerReference("owner1")
+	for j := 0; j < 3; j++ {
+		schedulerCache.AddPod(buildPod("c1", fmt.Sprintf("p%d", j), created, testutil.BuildResourceList("1", "1Gi"), owner))
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec: arbv1.SchedulingSpecTemplate{
+			MinAvailable: 3,
+		},
+	})
+
+	// The job waits for its gang through the sessions, until it times out.
+	for _, test := range []struct {
+		elapsed   time.Duration
+		backedOff bool
+	}{
+		{elapsed: 0, backedOff: false},
+		{elapsed: 9 * time.Minute, backedOff: false},
+		{elapsed: 10 * time.Minute, backedOff: true},
+	} {
+		clock.Time = created.Add(test.elapsed)
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{
+			Name:      PluginName,
+			Arguments: framework.Arguments{Timeout: "10m"},
+		}})
+		framework.CloseSession(ssn)
+
+		if _, got := schedulerCache.backoffs["owner1"]; got != test.backedOff {
+			t.Errorf("expected backed off %v after %v, got %v", test.backedOff, test.elapsed, schedulerCache.backoffs)
+		}
+	}
+}
+
+func TestGangMinResources(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+
+	// Only one pod of each job is created, but the job of owner2 needs more
+	// than the cluster has to start.
+	now := time.Now()
+	tests := []struct {
+		owner        string
+		created      time.Time
+		minResources v1.ResourceList
+		backlogged   bool
+	}{
+		{
+			owner:        "owner1",
+			created:      now.Add(-time.Minute),
+			minResources: testutil.BuildResourceList("3", "1Gi"),
+		},
+		{
+			owner:        "owner2",
+			created:      now,
+			minResources: testutil.BuildResourceList("8", "1Gi"),
+			backlogged:   true,
+		},
+		{
+			owner: "owner3",
+		},
+	}
+
+	for _, test := range tests {
+		owner := testutil.BuildOwnerReference(test.owner)
+		schedulerCache.AddPod(buildPod(test.owner, "p1", test.created, testutil.BuildResourceList("1", "1Gi"), owner))
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "j1",
+				Namespace:         test.owner,
+				CreationTimestamp: metav1.NewTime(test.created),
+				OwnerReferences:   []metav1.OwnerReference{owner},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 1,
+				MinResources: test.minResources,
+			},
+		})
+	}
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	backlog := map[api.JobID]bool{}
+	for _, job := range ssn.Backlog {
+		backlog[job.UID] = true
+	}
+
+	for _, test := range tests {
+		job := ssn.JobIndex[api.JobID(test.owner)]
+		if backlog[job.UID] != test.backlogged {
+			t.Errorf("job %v: expected backlogged %v, got %v", test.owner, test.backlogged, backlog[job.UID])
+		}
+		if valid := ssn.JobValid(job); valid == test.backlogged {
+			t.Errorf("job %v: expected valid %v, got %v", test.owner, !test.backlogged, valid)
+		}
+	}
+}
+
+func getJobMetric(metric *expvar.Map, job, rn string) float64 {
+	jm, ok := metric.Get(job).(*expvar.Map)
+	if !ok {
+		return -1
+	}
+	f, ok := jm.Get(rn).(*expvar.Float)
+	if !ok {
+		return -1
+	}
+	return f.Value()
+}
+
+func TestGangUnmetDemand(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	schedulerCache := &fakeEventCache{
+		SchedulerCache: &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		},
+		events: map[string]string{},
+	}
+
+	withGPU := func(res v1.ResourceList, gpu string) v1.ResourceList {
+		res[api.GPUResourceName] = resource.MustParse(gpu)
+		return res
+	}
+
+	// The node holds one task of the gang, it needs 2 more of 2 cpu and 1 GPU.
+	schedulerCache.AddNode(testutil.BuildNode("n1", withGPU(testutil.BuildResourceList("4", "8Gi"), "1")))
+	owner := testutil.BuildOwnerReference("owner1")
+	for j := 0; j < 4; j++ {
+		schedulerCache.AddPod(buildPod("c1", fmt.Sprintf("p%d", j), time.Now(), withGPU(testutil.BuildResourceList("2", "1Gi"), "1"), owner))
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec: arbv1.SchedulingSpecTemplate{
+			MinAvailable: 3,
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	allocate.New().Execute(ssn)
+	framework.CloseSession(ssn)
+
+	if got := getJobMetric(metrics.JobUnmetDemand, "c1/j1", string(v1.ResourceCPU)); got != 4000 {
+		t.Errorf("expected unmet demand of cpu 4000, got %v", got)
+	}
+	if got := getJobMetric(metrics.JobUnmetDemand, "c1/j1", api.GPUResourceName); got != 2000 {
+		t.Errorf("expected unmet demand of GPU 2000, got %v", got)
+	}
+
+	// The events are recorded to the tasks of unmet demand only.
+	if len(schedulerCache.events) != 2 {
+		t.Errorf("expected events of 2 tasks, got %v", schedulerCache.events)
+	}
+	for task, reason := range schedulerCache.events {
+		if reason != UnmetDemandReason {
+			t.Errorf("expected event reason %v of task %v, got %v", UnmetDemandReason, task, reason)
+		}
+	}
+
+	// The events are not recorded again if the unmet demand is not changed.
+	schedulerCache.events = map[string]string{}
+	ssn = framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	allocate.New().Execute(ssn)
+	framework.CloseSession(ssn)
+
+	if len(schedulerCache.events) != 0 {
+		t.Errorf("expected no events of unchanged demand, got %v", schedulerCache.events)
+	}
+	if got := getJobMetric(metrics.JobUnmetDemand, "c1/j1", string(v1.ResourceCPU)); got != 4000 {
+		t.Errorf("expected unmet demand of cpu 4000 still, got %v", got)
+	}
+}
+
+// fakeStatusUpdater sends the updated SchedulingSpecs to c.
+type fakeStatusUpdater struct {
+	c chan *arbv1.SchedulingSpec
+}
+
+func (fu *fakeStatusUpdater) UpdatePodStatus(pod *v1.Pod) error {
+	return nil
+}
+
+func (fu *fakeStatusUpdater) UpdateSchedulingSpecStatus(spec *arbv1.SchedulingSpec) error {
+	fu.c <- spec
+	return nil
+}
+
+func TestGangJobStatus(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	created := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &testutil.FakeClock{Time: created}
+	updater := &fakeStatusUpdater{c: make(chan *arbv1.SchedulingSpec, 3)}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:         make(map[string]*api.NodeInfo),
+		Jobs:          make(map[api.JobID]*api.JobInfo),
+		Binder:        testutil.NewFakeBinder(),
+		StatusUpdater: updater,
+		Clock:         clock,
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+
+	// The job requires 3 tasks at least, but the cluster can only run 2 of
+	// them until n2 is added.
+	owner := testutil.BuildOwnerReference("owner1")
+	for j := 0; j < 3; j++ {
+		schedulerCache.AddPod(buildPod("c1", fmt.Sprintf("p%d", j), created, testutil.BuildResourceList("1", "1Gi"), owner))
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec: arbv1.SchedulingSpecTemplate{
+			MinAvailable: 3,
+		},
+	})
+
+	for _, test := range []struct {
+		name    string
+		addNode bool
+		elapsed time.Duration
+		// Whether the status is updated, and the expected status.
+		updated   bool
+		running   int32
+		pending   int32
+		scheduled v1.ConditionStatus
+		reason    string
+	}{
+		{
+			name:      "the gang does not fit",
+			updated:   true,
+			pending:   3,
+			scheduled: v1.ConditionFalse,
+			reason:    UnmetDemandReason,
+		},
+		{
+			name:      "the status is not updated again if not changed",
+			elapsed:   time.Minute,
+			updated:   false,
+			pending:   3,
+			scheduled: v1.ConditionFalse,
+			reason:    UnmetDemandReason,
+		},
+		{
+			name:      "the gang is scheduled once n2 is added",
+			addNode:   true,
+			elapsed:   2 * time.Minute,
+			updated:   true,
+			running:   3,
+			scheduled: v1.ConditionTrue,
+		},
+	} {
+		if test.addNode {
+			schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("2", "4Gi")))
+		}
+		clock.Time = created.Add(test.elapsed)
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+		allocate.New().Execute(ssn)
+		framework.CloseSession(ssn)
+
+		var spec *arbv1.SchedulingSpec
+		select {
+		case spec = <-updater.c:
+		case <-time.After(100 * time.Millisecond):
+		}
+		if updated := spec != nil; updated != test.updated {
+			t.Fatalf("case %s: expected status updated %v, got %v", test.name, test.updated, updated)
+		}
+		if spec == nil {
+			spec = schedulerCache.Jobs["owner1"].SchedSpec
+		}
+
+		status := spec.Status
+		if status.Running != test.running || status.Pending != test.pending {
+			t.Errorf("case %s: expected %d running and %d pending, got %d and %d",
+				test.name, test.running, test.pending, status.Running, status.Pending)
+		}
+		if len(status.Conditions) != 2 {
+			t.Fatalf("case %s: expected 2 conditions, got %v", test.name, status.Conditions)
+		}
+
+		scheduled, unschedulable := status.Conditions[0], status.Conditions[1]
+		if scheduled.Type != arbv1.SchedulingSpecScheduled || scheduled.Status != test.scheduled {
+			t.Errorf("case %s: expected Scheduled %v, got %v", test.name, test.scheduled, scheduled)
+		}
+		if unschedulable.Type != arbv1.SchedulingSpecUnschedulable || unschedulable.Reason != test.reason ||
+			(unschedulable.Status == v1.ConditionTrue) != (test.scheduled == v1.ConditionFalse) {
+			t.Errorf("case %s: expected Unschedulable of reason %q, got %v", test.name, test.reason, unschedulable)
+		}
+
+		// The condition transitioned only when the gang is scheduled.
+		transition := created
+		if test.scheduled == v1.ConditionTrue {
+			transition = clock.Time
+		}
+		if !scheduled.LastTransitionTime.Time.Equal(transition) {
+			t.Errorf("case %s: expected Scheduled transitioned at %v, got %v",
+				test.name, transition, scheduled.LastTransitionTime)
+		}
+	}
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Fatalf("binds are not completed in time")
+	}
+}
+
+func TestGangJobOrderFIFO(t *testing.T) {
+	// The default plugins of scheduler.
+	framework.RegisterPluginBuilder(priority.PluginName, priority.New)
+	framework.RegisterPluginBuilder(PluginName, New)
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	framework.RegisterPluginBuilder(predicates.PluginName, predicates.New)
+	defer framework.CleanupPluginBuilders()
+
+	binder := testutil.NewFakeBinder()
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: binder,
+	}
+
+	// Only one of the gangs fits.
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("2", "4Gi")))
+
+	// The job of the smaller UID is created later.
+	created := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, j := range []struct {
+		name    string
+		created time.Time
+	}{
+		{name: "a", created: created.Add(time.Minute)},
+		{name: "b", created: created},
+	} {
+		owner := testutil.BuildOwnerReference("owner-" + j.name)
+		for i := 0; i < 2; i++ {
+			schedulerCache.AddPod(buildPod("c1", fmt.Sprintf("%s%d", j.name, i), j.created, testutil.BuildResourceList("1", "1Gi"), owner))
+		}
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              j.name,
+				Namespace:         "c1",
+				CreationTimestamp: metav1.NewTime(j.created),
+				OwnerReferences:   []metav1.OwnerReference{owner},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 2,
+			},
+		})
+	}
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+		{Name: priority.PluginName},
+		{Name: PluginName},
+		{Name: drf.PluginName},
+		{Name: predicates.PluginName},
+	})
+	allocate.New().Execute(ssn)
+	framework.CloseSession(ssn)
+
+	if !schedulerCache.WaitForInflight(3 * time.Second) {
+		t.Fatalf("binds are not completed in time")
+	}
+
+	binder.Lock()
+	defer binder.Unlock()
+	expected := map[string]string{"c1/b0": "n1", "c1/b1": "n1"}
+	if len(binder.Binds) != len(expected) {
+		t.Fatalf("expected binds %v of the older job, got %v", expected, binder.Binds)
+	}
+	for pod, host := range expected {
+		if binder.Binds[pod] != host {
+			t.Errorf("expected binds %v of the older job, got %v", expected, binder.Binds)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpushare
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "gpushare"
+
+type gpuSharePlugin struct {
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	return &gpuSharePlugin{}
+}
+
+func (gp *gpuSharePlugin) Name() string {
+	return PluginName
+}
+
+// gpuMemory returns the GPU memory of res, in milli-value.
+func gpuMemory(res *api.Resource) float64 {
+	return res.ScalarResources[api.GPUMemoryResourceName]
+}
+
+// gpuIndex returns the index of the GPU that the device plugin assigned to
+// the task, i.e. the first one in its annotation, or -1 if not assigned yet.
+func gpuIndex(task *api.TaskInfo, gpus int) int {
+	if task.Pod == nil {
+		return -1
+	}
+
+	list := task.Pod.Annotations[arbv1.GPUIndexesAnnotationKey]
+	index, err := strconv.Atoi(strings.TrimSpace(strings.Split(list, ",")[0]))
+	if err != nil || index < 0 || index >= gpus {
+		return -1
+	}
+
+	return index
+}
+
+// freeGPUMemory returns the free GPU memory of each GPU of node. The tasks
+// assigned to a GPU take their part of it; the others, e.g. the tasks
+// allocated in this session, are not assigned by the device plugin yet, so
+// they take the most free GPU in turn, the larger ones first.
+func freeGPUMemory(node *api.NodeInfo) []float64 {
+	gpus := int(node.Allocatable.MilliGPU / 1000)
+	if gpus == 0 {
+		return nil
+	}
+
+	free := make([]float64, gpus)
+	for i := range free {
+		free[i] = gpuMemory(node.Allocatable) / float64(gpus)
+	}
+
+	var unassigned []float64
+	for _, task := range node.Tasks {
+		req := gpuMemory(task.Resreq)
+		if req == 0 {
+			continue
+		}
+		if index := gpuIndex(task, gpus); index >= 0 {
+			free[index] -= req
+		} else {
+			unassigned = append(unassigned, req)
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.Float64Slice(unassigned)))
+	for _, req := range unassigned {
+		most := 0
+		for i := range free {
+			if free[i] > free[most] {
+				most = i
+			}
+		}
+		free[most] -= req
+	}
+
+	return free
+}
+
+func (gp *gpuSharePlugin) OnSessionOpen(ssn *framework.Session) {
+	// The GPU memory that task requests must be free on one GPU of node, as
+	// a task can not span GPUs; the node's total is checked by allocate.
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		req := gpuMemory(task.Resreq)
+		if req == 0 {
+			return nil
+		}
+
+		for _, free := range freeGPUMemory(node) {
+			if req <= free {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("no GPU of node <%v> has %v GPU memory free",
+			node.Name, resource.NewMilliQuantity(int64(req), resource.DecimalSI))
+	})
+}
+
+func (gp *gpuSharePlugin) OnSessionClose(ssn *framework.Session) {}
@@ -0,0 +1,139 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpushare
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func buildResourceList(cpu, memory, gpus, gpuMemory string) v1.ResourceList {
+	res := testutil.BuildResourceListWithGPU(cpu, memory, gpus)
+	res[api.GPUMemoryResourceName] = resource.MustParse(gpuMemory)
+	return res
+}
+
+func buildPod(ns, n, nn string, p v1.PodPhase, req v1.ResourceList, owner metav1.OwnerReference, gpus string) *v1.Pod {
+	pod := testutil.BuildPod(ns, n, nn, p, req, owner)
+	if len(gpus) != 0 {
+		pod.Annotations = map[string]string{arbv1.GPUIndexesAnnotationKey: gpus}
+	}
+	return pod
+}
+
+func TestGPUShare(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	owner1 := testutil.BuildOwnerReference("owner1")
+	owner2 := testutil.BuildOwnerReference("owner2")
+
+	tests := []struct {
+		name     string
+		node     v1.ResourceList
+		pods     []*v1.Pod
+		expected int
+	}{
+		{
+			name: "two halves of GPU memory fit one GPU, the third does not",
+			node: buildResourceList("8", "16Gi", "1", "16"),
+			pods: []*v1.Pod{
+				buildPod("c2", "p1", "", v1.PodPending, buildResourceList("1", "1Gi", "0", "8"), owner2, ""),
+				buildPod("c2", "p2", "", v1.PodPending, buildResourceList("1", "1Gi", "0", "8"), owner2, ""),
+				buildPod("c2", "p3", "", v1.PodPending, buildResourceList("1", "1Gi", "0", "8"), owner2, ""),
+			},
+			expected: 2,
+		},
+		{
+			name: "the free GPU memory of node is split across GPUs",
+			node: buildResourceList("8", "16Gi", "2", "16"),
+			pods: []*v1.Pod{
+				buildPod("c1", "p1", "n1", v1.PodRunning, buildResourceList("1", "1Gi", "0", "5"), owner1, "0"),
+				buildPod("c1", "p2", "n1", v1.PodRunning, buildResourceList("1", "1Gi", "0", "5"), owner1, "1"),
+				buildPod("c2", "p1", "", v1.PodPending, buildResourceList("1", "1Gi", "0", "4"), owner2, ""),
+			},
+			expected: 0,
+		},
+		{
+			name: "the GPU memory fits the less used GPU",
+			node: buildResourceList("8", "16Gi", "2", "16"),
+			pods: []*v1.Pod{
+				buildPod("c1", "p1", "n1", v1.PodRunning, buildResourceList("1", "1Gi", "0", "5"), owner1, "0"),
+				buildPod("c2", "p1", "", v1.PodPending, buildResourceList("1", "1Gi", "0", "6"), owner2, ""),
+			},
+			expected: 1,
+		},
+	}
+
+	for _, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", test.node))
+		for _, pod := range test.pods {
+			schedulerCache.AddPod(pod)
+		}
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c1", "j1", owner1))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("c2", "j2", owner2))
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+
+		allocate.New().Execute(ssn)
+
+		allocated := 0
+		for _, task := range ssn.JobIndex["owner2"].Tasks {
+			if len(task.NodeName) != 0 {
+				allocated++
+			}
+		}
+		if allocated != test.expected {
+			t.Errorf("case %s: expected %d tasks allocated, got %d", test.name, test.expected, allocated)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestFreeGPUMemory(t *testing.T) {
+	owner := testutil.BuildOwnerReference("owner1")
+
+	node := api.NewNodeInfo(testutil.BuildNode("n1", buildResourceList("8", "16Gi", "2", "16")))
+	for i, gpus := range []string{"1", "", ""} {
+		pod := buildPod("c1", fmt.Sprintf("p%d", i), "n1", v1.PodRunning, buildResourceList("1", "1Gi", "0", "3"), owner, gpus)
+		node.AddTask(api.NewTaskInfo(pod))
+	}
+
+	// The unassigned tasks take the most free GPU in turn.
+	free := freeGPUMemory(node)
+	if len(free) != 2 || free[0] != 2000 || free[1] != 5000 {
+		t.Errorf("expected free GPU memory [2000 5000], got %v", free)
+	}
+}
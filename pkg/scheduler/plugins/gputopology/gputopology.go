@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gputopology
+
+import (
+	"math"
+	"strings"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "gputopology"
+
+type gpuTopologyPlugin struct {
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	return &gpuTopologyPlugin{}
+}
+
+func (gp *gpuTopologyPlugin) Name() string {
+	return PluginName
+}
+
+// parseIndexes returns the GPU indexes in the comma-separated list.
+func parseIndexes(list string) []string {
+	var indexes []string
+	for _, index := range strings.Split(list, ",") {
+		if index = strings.TrimSpace(index); len(index) != 0 {
+			indexes = append(indexes, index)
+		}
+	}
+
+	return indexes
+}
+
+// domains returns the NVLink domains of node's GPUs, or nil if the node has
+// no topology annotation.
+func domains(node *api.NodeInfo) [][]string {
+	if node.Node == nil {
+		return nil
+	}
+
+	topology, found := node.Node.Annotations[arbv1.GPUTopologyAnnotationKey]
+	if !found {
+		return nil
+	}
+
+	var result [][]string
+	for _, group := range strings.Split(topology, ";") {
+		if indexes := parseIndexes(group); len(indexes) != 0 {
+			result = append(result, indexes)
+		}
+	}
+
+	return result
+}
+
+// usedGPUs returns the indexes of the GPUs assigned to the tasks on node.
+func usedGPUs(node *api.NodeInfo) map[string]bool {
+	used := map[string]bool{}
+	for _, task := range node.Tasks {
+		if task.Pod == nil {
+			continue
+		}
+		for _, index := range parseIndexes(task.Pod.Annotations[arbv1.GPUIndexesAnnotationKey]) {
+			used[index] = true
+		}
+	}
+
+	return used
+}
+
+// fitsInDomain returns true if the free GPUs in one NVLink domain of node
+// are enough for the GPUs that task requests.
+func fitsInDomain(task *api.TaskInfo, node *api.NodeInfo) bool {
+	gpus := int(math.Ceil(task.Resreq.MilliGPU / 1000))
+
+	used := usedGPUs(node)
+	for _, domain := range domains(node) {
+		free := 0
+		for _, index := range domain {
+			if !used[index] {
+				free++
+			}
+		}
+		if free >= gpus {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (gp *gpuTopologyPlugin) OnSessionOpen(ssn *framework.Session) {
+	// The nodes whose free GPUs in one NVLink domain fit the multi-GPU task
+	// are preferred; it scores nothing for the single-GPU tasks and the nodes
+	// without topology.
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		if task.Resreq.MilliGPU <= 1000 {
+			return 0, nil
+		}
+
+		if fitsInDomain(task, node) {
+			return 1, nil
+		}
+
+		return 0, nil
+	})
+}
+
+func (gp *gpuTopologyPlugin) OnSessionClose(ssn *framework.Session) {}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gputopology
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func buildNode(name, topology string, alloc v1.ResourceList) *v1.Node {
+	if len(topology) == 0 {
+		return testutil.BuildNode(name, alloc)
+	}
+	return testutil.BuildNodeWithAnnotations(name, alloc, map[string]string{arbv1.GPUTopologyAnnotationKey: topology})
+}
+
+func buildPod(ns, n, nn string, p v1.PodPhase, req v1.ResourceList, owner metav1.OwnerReference, gpus string) *v1.Pod {
+	pod := testutil.BuildPod(ns, n, nn, p, req, owner)
+	if len(gpus) != 0 {
+		pod.Annotations = map[string]string{arbv1.GPUIndexesAnnotationKey: gpus}
+	}
+	return pod
+}
+
+func TestGPUTopology(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name     string
+		topology string
+		expected string
+	}{
+		{
+			name:     "the node whose free GPUs share NVLink",
+			topology: "0,1;2,3",
+			expected: "n2",
+		},
+		{
+			name: "no-op without topology, either node",
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		}
+
+		// Both nodes have two free GPUs: 1 and 3 on n1 are not connected,
+		// 2 and 3 on n2 are.
+		schedulerCache.AddNode(buildNode("n1", test.topology, testutil.BuildResourceListWithGPU("8", "16Gi", "4")))
+		schedulerCache.AddNode(buildNode("n2", test.topology, testutil.BuildResourceListWithGPU("8", "16Gi", "4")))
+		for _, pod := range []*v1.Pod{
+			buildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceListWithGPU("1", "1Gi", "1"), owner1, "0"),
+			buildPod("c1", "p2", "n1", v1.PodRunning, testutil.BuildResourceListWithGPU("1", "1Gi", "1"), owner1, "2"),
+			buildPod("c1", "p3", "n2", v1.PodRunning, testutil.BuildResourceListWithGPU("1", "1Gi", "2"), owner1, "0,1"),
+			buildPod("c2", "p1", "", v1.PodPending, testutil.BuildResourceListWithGPU("1", "1Gi", "2"), owner2, ""),
+		} {
+			schedulerCache.AddPod(pod)
+		}
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner2))
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+
+		allocate.New().Execute(ssn)
+
+		task := ssn.JobIndex["owner2"].Tasks["c2-p1"]
+		if len(test.expected) == 0 {
+			if len(task.NodeName) == 0 {
+				t.Errorf("case %s: expected task placed, got none", test.name)
+			}
+		} else if task.NodeName != test.expected {
+			t.Errorf("case %s: expected task placed on %v, got %q", test.name, test.expected, task.NodeName)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
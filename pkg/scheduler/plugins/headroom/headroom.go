@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headroom
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/golang/glog"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "headroom"
+
+const (
+	// Fraction is the argument of the default fraction of node's allocatable
+	// resource reserved for system daemons; it's overridden by the
+	// system-reserved annotation of node.
+	Fraction = "fraction"
+)
+
+type headroomPlugin struct {
+	fraction float64
+
+	// The reserved resource of each node in this session.
+	reserved map[string]*api.Resource
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	hp := &headroomPlugin{
+		reserved: map[string]*api.Resource{},
+	}
+
+	args.GetFloat64(&hp.fraction, Fraction)
+
+	return hp
+}
+
+func (hp *headroomPlugin) Name() string {
+	return PluginName
+}
+
+// nodeFraction returns the reserved fraction of node, the annotation of node
+// overrides the argument of plugin.
+func (hp *headroomPlugin) nodeFraction(node *api.NodeInfo) float64 {
+	if node.Node == nil {
+		return hp.fraction
+	}
+
+	if v, found := node.Node.Annotations[arbv1.SystemReservedAnnotationKey]; found {
+		fraction, err := strconv.ParseFloat(v, 64)
+		if err == nil && fraction >= 0 && fraction <= 1 {
+			return fraction
+		}
+		glog.Warningf("Invalid system reserved fraction <%v> of Node <%v>", v, node.Name)
+	}
+
+	return hp.fraction
+}
+
+func (hp *headroomPlugin) OnSessionOpen(ssn *framework.Session) {
+	for _, node := range ssn.Nodes {
+		fraction := hp.nodeFraction(node)
+		if fraction <= 0 {
+			continue
+		}
+
+		hp.reserved[node.Name] = node.Allocatable.Clone().Multi(fraction)
+	}
+
+	// Reject the nodes whose idle resource, including the releasing one, can
+	// not hold both task and headroom.
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		reserved, found := hp.reserved[node.Name]
+		if !found {
+			return nil
+		}
+
+		idle := node.Idle.Clone().Add(node.Releasing)
+		if !reserved.Clone().Add(task.Resreq).LessEqual(idle) {
+			return fmt.Errorf("node <%v> reserves <%v> for system, idle <%v> is not enough for <%v>",
+				node.Name, reserved, idle, task.Resreq)
+		}
+
+		return nil
+	})
+}
+
+func (hp *headroomPlugin) OnSessionClose(ssn *framework.Session) {
+	hp.reserved = map[string]*api.Resource{}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headroom
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func TestHeadroom(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name        string
+		args        framework.Arguments
+		annotations map[string]string
+		pending     int
+	}{
+		{
+			name:    "no headroom",
+			args:    framework.Arguments{},
+			pending: 0,
+		},
+		{
+			name:    "headroom by argument",
+			args:    framework.Arguments{Fraction: "0.1"},
+			pending: 1,
+		},
+		{
+			name:        "headroom by annotation",
+			args:        framework.Arguments{},
+			annotations: map[string]string{arbv1.SystemReservedAnnotationKey: "0.1"},
+			pending:     1,
+		},
+		{
+			name:        "annotation overrides argument",
+			args:        framework.Arguments{Fraction: "0.1"},
+			annotations: map[string]string{arbv1.SystemReservedAnnotationKey: "0"},
+			pending:     0,
+		},
+	}
+
+	for i, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		}
+
+		// The task fills the node exactly.
+		schedulerCache.AddNode(testutil.BuildNodeWithAnnotations("n1", testutil.BuildResourceList("4", "4Gi"), test.annotations))
+
+		owner := testutil.BuildOwnerReference("owner1")
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("4", "1Gi"), owner))
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+		})
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName, Arguments: test.args}})
+
+		allocate.New().Execute(ssn)
+
+		job := ssn.JobIndex[api.JobID("owner1")]
+		if got := len(job.TaskStatusIndex[api.Pending]); got != test.pending {
+			t.Errorf("case %d (%s): expected %d pending tasks, got %d", i, test.name, test.pending, got)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lottery
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "lottery"
+
+const (
+	// Seed is the seed of random numbers, so the order of jobs is reproducible;
+	// it's random if not set.
+	Seed = "seed"
+)
+
+// minWeight is the weight of the jobs that get their deserved share, so they
+// still have a chance to go first.
+const minWeight = 0.001
+
+type lotteryPlugin struct {
+	seed    int64
+	hasSeed bool
+
+	// The rank of each job in this session, the lower goes first.
+	ranks map[api.JobID]int
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	lp := &lotteryPlugin{
+		ranks: map[api.JobID]int{},
+	}
+
+	seed := 0
+	if _, found := args[Seed]; found {
+		args.GetInt(&seed, Seed)
+		lp.seed = int64(seed)
+		lp.hasSeed = true
+	}
+
+	return lp
+}
+
+func (lp *lotteryPlugin) Name() string {
+	return PluginName
+}
+
+// dominantShare returns the max share of allocated in total by resource.
+func dominantShare(allocated, total *api.Resource) float64 {
+	res := float64(0)
+	for _, rn := range api.ResourceNames() {
+		if total.Get(rn) == 0 {
+			continue
+		}
+		if share := allocated.Get(rn) / total.Get(rn); share > res {
+			res = share
+		}
+	}
+
+	return res
+}
+
+// newRand returns the random source of session; the seed is combined with
+// the iteration, so each session draws different numbers.
+func (lp *lotteryPlugin) newRand(ssn *framework.Session) *rand.Rand {
+	seed := time.Now().UnixNano()
+	if lp.hasSeed {
+		seed = lp.seed + ssn.Iteration
+	}
+
+	return rand.New(rand.NewSource(seed))
+}
+
+func (lp *lotteryPlugin) OnSessionOpen(ssn *framework.Session) {
+	if len(ssn.Jobs) == 0 {
+		return
+	}
+
+	total := api.EmptyResource()
+	for _, node := range ssn.Nodes {
+		total.Add(node.Allocatable)
+	}
+
+	// Every job deserves the same share of the cluster.
+	deserved := 1 / float64(len(ssn.Jobs))
+
+	// Sort jobs by UID, so the same seed draws the same order.
+	jobs := make([]*api.JobInfo, len(ssn.Jobs))
+	copy(jobs, ssn.Jobs)
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].UID < jobs[j].UID
+	})
+
+	// Draw the order of jobs by weighted random sampling: each job gets a
+	// key of exponential distribution with its weight as rate, the smaller
+	// key goes first; so the probability of a job to go before others is
+	// proportional to its weight, i.e. its deficit from deserved share.
+	r := lp.newRand(ssn)
+	keys := make(map[api.JobID]float64, len(jobs))
+	for _, job := range jobs {
+		weight := math.Max(deserved-dominantShare(job.Allocated, total), minWeight)
+		keys[job.UID] = r.ExpFloat64() / weight
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return keys[jobs[i].UID] < keys[jobs[j].UID]
+	})
+	for i, job := range jobs {
+		lp.ranks[job.UID] = i
+
+		glog.V(4).Infof("Lottery rank of Job <%v:%v/%v> is %d", job.UID, job.Namespace, job.Name, i)
+	}
+
+	ssn.AddJobOrderFn(func(l, r interface{}) int {
+		lv := l.(*api.JobInfo)
+		rv := r.(*api.JobInfo)
+
+		lr, lfound := lp.ranks[lv.UID]
+		rr, rfound := lp.ranks[rv.UID]
+		if !lfound || !rfound || lr == rr {
+			return 0
+		}
+
+		if lr < rr {
+			return -1
+		}
+		return 1
+	})
+}
+
+func (lp *lotteryPlugin) OnSessionClose(ssn *framework.Session) {
+	lp.ranks = map[api.JobID]int{}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lottery
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+// buildCache returns a cache with a pending task of each job, and the
+// running tasks of job i is the i-th element of running.
+func buildCache(running []int) *cache.SchedulerCache {
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("10", "20Gi")))
+	for i, n := range running {
+		owner := testutil.BuildOwnerReference(fmt.Sprintf("j%d", i))
+		for j := 0; j < n; j++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d-%d", i, j), "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner))
+		}
+		schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("j%d", i),
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+		})
+	}
+
+	return schedulerCache
+}
+
+// jobOrder returns the jobs of session in the order of JobOrderFn.
+func jobOrder(ssn *framework.Session) []api.JobID {
+	jobs := make([]*api.JobInfo, len(ssn.Jobs))
+	copy(jobs, ssn.Jobs)
+	sort.Slice(jobs, func(i, j int) bool {
+		return ssn.JobOrderFn(jobs[i], jobs[j])
+	})
+
+	var order []api.JobID
+	for _, job := range jobs {
+		order = append(order, job.UID)
+	}
+	return order
+}
+
+func TestLotteryOrderReproducible(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	plugins := []*framework.PluginOption{{Name: PluginName, Arguments: framework.Arguments{Seed: "42"}}}
+
+	var orders [][]api.JobID
+	for i := 0; i < 2; i++ {
+		ssn := framework.OpenSession(buildCache([]int{0, 1, 2, 3, 4}), plugins)
+		orders = append(orders, jobOrder(ssn))
+		framework.CloseSession(ssn)
+	}
+
+	expected := []api.JobID{"j0", "j1", "j4", "j3", "j2"}
+	for i, order := range orders {
+		if !reflect.DeepEqual(expected, order) {
+			t.Errorf("run %d: expected order %v, got %v", i, expected, order)
+		}
+	}
+}
+
+func TestLotteryPrefersDeficit(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	// j0 has nothing, j1 uses more than its deserved share.
+	first := map[api.JobID]int{}
+	for seed := 0; seed < 100; seed++ {
+		plugins := []*framework.PluginOption{{Name: PluginName, Arguments: framework.Arguments{Seed: strconv.Itoa(seed)}}}
+
+		ssn := framework.OpenSession(buildCache([]int{0, 6}), plugins)
+		first[jobOrder(ssn)[0]]++
+		framework.CloseSession(ssn)
+	}
+
+	// The satisfied job only goes first with minWeight.
+	if first["j0"] < 95 {
+		t.Errorf("expected j0 goes first in most sessions, got %v", first)
+	}
+}
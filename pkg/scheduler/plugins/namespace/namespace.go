@@ -0,0 +1,313 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespace
+
+import (
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "namespace"
+
+const (
+	// BorrowLimit is the argument of the most fraction of cluster, e.g. "0.2",
+	// that a namespace borrows beyond its equal share, from the share that
+	// other namespaces do not request; 0 means no limit. The borrowed resource
+	// is reclaimed once the lenders need it.
+	BorrowLimit = "borrowLimit"
+
+	// PriorityShareWeight is the argument of the priority, e.g. "100", that a
+	// namespace loses for using the whole cluster; a task preempts only the
+	// tasks of no higher priority after the share of their namespaces is
+	// weighed. So a namespace over its fair share preempts less than a starved
+	// one with the same priority; 0 means namespace share is not weighed.
+	PriorityShareWeight = "priorityShareWeight"
+)
+
+type namespaceAttr struct {
+	name  string
+	share float64
+
+	deserved  *api.Resource
+	allocated *api.Resource
+	request   *api.Resource
+}
+
+// namespacePlugin treats each namespace as an implicit queue with equal weight.
+type namespacePlugin struct {
+	totalResource *api.Resource
+	borrowLimit   float64
+	shareWeight   float64
+
+	// Key is namespace
+	namespaceOpts map[string]*namespaceAttr
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	np := &namespacePlugin{
+		totalResource: api.EmptyResource(),
+		namespaceOpts: map[string]*namespaceAttr{},
+	}
+
+	args.GetFloat64(&np.borrowLimit, BorrowLimit)
+	args.GetFloat64(&np.shareWeight, PriorityShareWeight)
+
+	return np
+}
+
+func (np *namespacePlugin) Name() string {
+	return PluginName
+}
+
+func (np *namespacePlugin) OnSessionOpen(ssn *framework.Session) {
+	for _, n := range ssn.Nodes {
+		np.totalResource.Add(n.Allocatable)
+	}
+
+	for _, job := range ssn.Jobs {
+		attr, found := np.namespaceOpts[job.Namespace]
+		if !found {
+			attr = &namespaceAttr{
+				name:      job.Namespace,
+				deserved:  api.EmptyResource(),
+				allocated: api.EmptyResource(),
+				request:   api.EmptyResource(),
+			}
+			np.namespaceOpts[job.Namespace] = attr
+		}
+
+		for status, tasks := range job.TaskStatusIndex {
+			if api.AllocatedStatus(status) {
+				for _, t := range tasks {
+					attr.allocated.Add(t.Resreq)
+					attr.request.Add(t.Resreq)
+				}
+			} else if status == api.Pending {
+				for _, t := range tasks {
+					attr.request.Add(t.Resreq)
+				}
+			}
+		}
+	}
+
+	np.calculateDeserved()
+
+	for _, attr := range np.namespaceOpts {
+		np.updateShare(attr)
+
+		glog.V(3).Infof("Namespace <%s>: deserved <%v>, allocated <%v>, request <%v>, share <%v>",
+			attr.name, attr.deserved, attr.allocated, attr.request, attr.share)
+	}
+
+	ssn.AddJobOrderFn(func(l, r interface{}) int {
+		lv := l.(*api.JobInfo)
+		rv := r.(*api.JobInfo)
+
+		ls := np.namespaceOpts[lv.Namespace].share
+		rs := np.namespaceOpts[rv.Namespace].share
+
+		if ls == rs {
+			return 0
+		}
+
+		if ls < rs {
+			return -1
+		}
+
+		return 1
+	})
+
+	ssn.AddOverusedFn(func(obj interface{}) bool {
+		job := obj.(*api.JobInfo)
+		attr := np.namespaceOpts[job.Namespace]
+
+		overused := attr.deserved.LessEqual(attr.allocated)
+		if overused {
+			glog.V(3).Infof("Namespace <%v> is overused: deserved <%v>, allocated <%v>",
+				attr.name, attr.deserved, attr.allocated)
+		}
+
+		return overused
+	})
+
+	// The resource that namespace uses over its deserved share, i.e. what it
+	// borrowed before the lenders request it, is reclaimable if they wait.
+	ssn.AddReclaimableFn(func(job *api.JobInfo) *api.Resource {
+		attr := np.namespaceOpts[job.Namespace]
+
+		borrowed := attr.allocated.Clone().Sub(api.Min(attr.allocated, attr.deserved))
+		if borrowed.IsEmpty() {
+			return borrowed
+		}
+
+		needed := np.needed(job.Namespace)
+		glog.V(3).Infof("Namespace <%v> borrowed <%v>, other namespaces need <%v>",
+			attr.name, borrowed, needed)
+
+		return api.Min(api.Min(borrowed, needed), job.Allocated)
+	})
+
+	if np.shareWeight > 0 {
+		ssn.AddPreemptableFn(func(l, r interface{}) bool {
+			preemptor := l.(*api.TaskInfo)
+			preemptee := r.(*api.TaskInfo)
+
+			lp, rp := np.weighedPriority(preemptor), np.weighedPriority(preemptee)
+			if lp < rp {
+				glog.V(3).Infof("Task <%v/%v> of weighed priority <%v> can not preempt task <%v/%v> of <%v>",
+					preemptor.Namespace, preemptor.Name, lp, preemptee.Namespace, preemptee.Name, rp)
+				return false
+			}
+
+			return true
+		})
+	}
+
+	ssn.AddEventHandler(&framework.EventHandler{
+		AllocateFunc: func(event *framework.Event) {
+			attr := np.namespaceOpts[event.Task.Namespace]
+			if attr == nil {
+				return
+			}
+			attr.allocated.Add(event.Task.Resreq)
+			np.updateShare(attr)
+		},
+		EvictFunc: func(event *framework.Event) {
+			attr := np.namespaceOpts[event.Task.Namespace]
+			if attr == nil {
+				return
+			}
+			attr.allocated.Sub(event.Task.Resreq)
+			np.updateShare(attr)
+		},
+	})
+}
+
+// needed returns the resource that the namespaces other than borrower wait
+// for, i.e. their pending request under their deserved share.
+func (np *namespacePlugin) needed(borrower string) *api.Resource {
+	res := api.EmptyResource()
+	for name, attr := range np.namespaceOpts {
+		if name == borrower {
+			continue
+		}
+
+		deficit := api.Min(attr.deserved, attr.request)
+		deficit.Sub(api.Min(attr.allocated, deficit))
+		res.Add(deficit)
+	}
+
+	return res
+}
+
+// calculateDeserved divides total resource among namespaces with equal
+// weight; the deserved resource of a namespace is no more than its request,
+// and the remaining resource is divided among other namespaces. With borrow
+// limit, a namespace gets at most that much more than its equal share, and
+// the rest is left idle for the lenders.
+func (np *namespacePlugin) calculateDeserved() {
+	np.divideDeserved()
+
+	if np.borrowLimit <= 0 || len(np.namespaceOpts) == 0 {
+		return
+	}
+
+	equal := divideResource(np.totalResource, float64(len(np.namespaceOpts)))
+	limit := equal.Add(np.totalResource.Clone().Multi(np.borrowLimit))
+	for _, attr := range np.namespaceOpts {
+		attr.deserved = api.Min(attr.deserved, limit)
+	}
+}
+
+// divideDeserved divides total resource among namespaces by their requests.
+func (np *namespacePlugin) divideDeserved() {
+	remaining := np.totalResource.Clone()
+	meet := map[string]bool{}
+
+	for {
+		unmet := 0
+		for name := range np.namespaceOpts {
+			if !meet[name] {
+				unmet++
+			}
+		}
+
+		if unmet == 0 || remaining.IsEmpty() {
+			break
+		}
+
+		increased := api.EmptyResource()
+		for name, attr := range np.namespaceOpts {
+			if meet[name] {
+				continue
+			}
+
+			old := attr.deserved.Clone()
+			attr.deserved.Add(divideResource(remaining, float64(unmet)))
+			attr.deserved = api.Min(attr.deserved, attr.request)
+
+			if attr.request.LessEqual(attr.deserved) {
+				meet[name] = true
+			}
+
+			increased.Add(attr.deserved.Clone().Sub(old))
+		}
+
+		if increased.IsEmpty() {
+			break
+		}
+		remaining.Sub(api.Min(increased, remaining))
+	}
+}
+
+func (np *namespacePlugin) updateShare(attr *namespaceAttr) {
+	res := float64(0)
+	for _, rn := range api.ResourceNames() {
+		if np.totalResource.IsZero(rn) {
+			continue
+		}
+		share := attr.allocated.Get(rn) / np.totalResource.Get(rn)
+		if share > res {
+			res = share
+		}
+	}
+
+	attr.share = res
+}
+
+// weighedPriority returns the priority of task less the share of its
+// namespace by weight.
+func (np *namespacePlugin) weighedPriority(task *api.TaskInfo) float64 {
+	share := float64(0)
+	if attr := np.namespaceOpts[task.Namespace]; attr != nil {
+		share = attr.share
+	}
+
+	return float64(task.Priority) - np.shareWeight*share
+}
+
+func divideResource(r *api.Resource, n float64) *api.Resource {
+	return r.Clone().Multi(1 / n)
+}
+
+func (np *namespacePlugin) OnSessionClose(ssn *framework.Session) {
+	np.totalResource = api.EmptyResource()
+	np.namespaceOpts = map[string]*namespaceAttr{}
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespace
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/preempt"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/reclaim"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/gang"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+type fakeBinder struct {
+	sync.Mutex
+	binds map[string]int
+	c     chan string
+}
+
+func (fb *fakeBinder) Bind(p *v1.Pod, hostname string) error {
+	fb.Lock()
+	fb.binds[p.Namespace]++
+	fb.Unlock()
+
+	fb.c <- p.Name
+	return nil
+}
+
+func TestNamespaceFairShare(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	binder := &fakeBinder{
+		binds: map[string]int{},
+		c:     make(chan string),
+	}
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: binder,
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("6", "100Gi")))
+
+	// Each namespace has a job with 4 pending pods, so the cluster can only
+	// run half of them.
+	for _, ns := range []string{"c1", "c2", "c3"} {
+		owner := testutil.BuildOwnerReference(ns)
+		for i := 0; i < 4; i++ {
+			schedulerCache.AddPod(testutil.BuildPod(ns, fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+		}
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            ns,
+				Namespace:       ns,
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 1,
+			},
+		})
+	}
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	allocate.New().Execute(ssn)
+
+	for i := 0; i < 6; i++ {
+		select {
+		case <-binder.c:
+		case <-time.After(3 * time.Second):
+			t.Errorf("Failed to get binding request.")
+		}
+	}
+
+	expected := map[string]int{"c1": 2, "c2": 2, "c3": 2}
+	if !reflect.DeepEqual(expected, binder.binds) {
+		t.Errorf("expected: %v, got %v ", expected, binder.binds)
+	}
+}
+
+func TestNamespaceBorrow(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	framework.RegisterPluginBuilder(gang.PluginName, gang.New)
+	defer framework.CleanupPluginBuilders()
+
+	args := framework.Arguments{BorrowLimit: "0.25"}
+	ownerA := testutil.BuildOwnerReference("ownerA")
+	ownerB := testutil.BuildOwnerReference("ownerB")
+
+	buildSchedulingSpec := func(ns string, owner metav1.OwnerReference) *arbv1.SchedulingSpec {
+		return &arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            ns,
+				Namespace:       ns,
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 1,
+			},
+		}
+	}
+
+	// Namespace B is idle, so A borrows a quarter of cluster beyond its half.
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: &fakeBinder{binds: map[string]int{}, c: make(chan string, 10)},
+	}
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "4Gi")))
+	for i := 0; i < 4; i++ {
+		schedulerCache.AddPod(testutil.BuildPod("a", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), ownerA))
+	}
+	schedulerCache.AddSchedulingSpec(buildSchedulingSpec("a", ownerA))
+	schedulerCache.AddSchedulingSpec(buildSchedulingSpec("b", ownerB))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName, Arguments: args}})
+	allocate.New().Execute(ssn)
+
+	allocated := 0
+	for status, tasks := range ssn.JobIndex["ownerA"].TaskStatusIndex {
+		if api.AllocatedStatus(status) {
+			allocated += len(tasks)
+		}
+	}
+	if allocated != 3 {
+		t.Errorf("expected 3 tasks of namespace A allocated, got %d", allocated)
+	}
+	framework.CloseSession(ssn)
+
+	tests := []struct {
+		name      string
+		pendingB  int
+		reclaimed int
+	}{
+		{
+			name:      "the borrowed resource is kept while the lender is idle",
+			pendingB:  0,
+			reclaimed: 0,
+		},
+		{
+			name:      "the borrowed resource is reclaimed once the lender is active",
+			pendingB:  2,
+			reclaimed: 1,
+		},
+	}
+
+	for _, test := range tests {
+		evictor := &testutil.FakeEvictor{}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Binder:  testutil.NewFakeBinder(),
+			Evictor: evictor,
+		}
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "4Gi")))
+		for i := 0; i < 3; i++ {
+			pod := testutil.BuildPod("a", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), ownerA)
+			pod.Spec.NodeName = "n1"
+			pod.Status.Phase = v1.PodRunning
+			schedulerCache.AddPod(pod)
+		}
+		for i := 0; i < test.pendingB; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("b", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), ownerB))
+		}
+		schedulerCache.AddSchedulingSpec(buildSchedulingSpec("a", ownerA))
+		schedulerCache.AddSchedulingSpec(buildSchedulingSpec("b", ownerB))
+
+		// The resource is reclaimed for the tasks of B that allocate can not
+		// place; gang allows the tasks of A over its MinAvailable preempted.
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{Name: PluginName, Arguments: args},
+			{Name: gang.PluginName},
+		})
+		allocate.New().Execute(ssn)
+		reclaim.New().Execute(ssn)
+
+		if got := len(ssn.JobIndex["ownerA"].TaskStatusIndex[api.Releasing]); got != test.reclaimed {
+			t.Errorf("case %s: expected %d tasks of namespace A reclaimed, got %d", test.name, test.reclaimed, got)
+		}
+		framework.CloseSession(ssn)
+		schedulerCache.WaitForInflight(3 * time.Second)
+	}
+}
+
+func TestNamespacePreemptByShare(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	args := framework.Arguments{PriorityShareWeight: "100"}
+	owners := map[string]metav1.OwnerReference{
+		"a": testutil.BuildOwnerReference("ownerA"),
+		"b": testutil.BuildOwnerReference("ownerB"),
+		"c": testutil.BuildOwnerReference("ownerC"),
+	}
+
+	buildSchedulingSpec := func(ns string) *arbv1.SchedulingSpec {
+		return &arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            ns,
+				Namespace:       ns,
+				OwnerReferences: []metav1.OwnerReference{owners[ns]},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 1,
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		preemptor string
+		pipelined bool
+	}{
+		{
+			name:      "the namespace over its share can not preempt",
+			preemptor: "a",
+			pipelined: false,
+		},
+		{
+			name:      "the starved namespace preempts with the same priority",
+			preemptor: "b",
+			pipelined: true,
+		},
+	}
+
+	for _, test := range tests {
+		evictor := &testutil.FakeEvictor{}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: evictor,
+		}
+
+		// Namespace A uses half of cluster, C uses 0.3 and B uses 0.1.
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("10", "10Gi")))
+		for ns, running := range map[string]int{"a": 5, "b": 1, "c": 3} {
+			for i := 0; i < running; i++ {
+				pod := testutil.BuildPod(ns, fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owners[ns])
+				pod.Spec.NodeName = "n1"
+				pod.Status.Phase = v1.PodRunning
+				schedulerCache.AddPod(pod)
+			}
+			schedulerCache.AddSchedulingSpec(buildSchedulingSpec(ns))
+		}
+		schedulerCache.AddPod(testutil.BuildPod(test.preemptor, "preemptor", "", v1.PodPending, testutil.BuildResourceList("2", "2Gi"), owners[test.preemptor]))
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName, Arguments: args}})
+		preempt.New().Execute(ssn)
+
+		job := ssn.JobIndex[api.JobID(owners[test.preemptor].UID)]
+		if got := len(job.TaskStatusIndex[api.Pipelined]) != 0; got != test.pipelined {
+			t.Errorf("case %s: expected pipelined %v, got %v (evicted %v)", test.name, test.pipelined, got, evictor.Evicts)
+		}
+		if !test.pipelined && len(evictor.Evicts) != 0 {
+			t.Errorf("case %s: expected no evictions, got %v", test.name, evictor.Evicts)
+		}
+		framework.CloseSession(ssn)
+	}
+}
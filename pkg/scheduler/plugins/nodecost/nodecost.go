@@ -0,0 +1,157 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodecost
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "nodecost"
+
+const (
+	// Label is the label of nodes whose value is looked up in cost table.
+	Label = "label"
+
+	// DefaultCost is the cost of the nodes that are not in cost table.
+	DefaultCost = "defaultCost"
+
+	// PricePrefix is the prefix of the arguments of cost table, e.g.
+	// "price.m5.large=0.096" for the nodes of instance type "m5.large".
+	PricePrefix = "price."
+)
+
+const defaultLabel = "node.kubernetes.io/instance-type"
+
+type nodeCostPlugin struct {
+	label       string
+	defaultCost float64
+
+	// The cost of nodes by the value of label.
+	prices map[string]float64
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	ncp := &nodeCostPlugin{
+		label:  defaultLabel,
+		prices: map[string]float64{},
+	}
+
+	if label, found := args[Label]; found && len(label) != 0 {
+		ncp.label = label
+	}
+	args.GetFloat64(&ncp.defaultCost, DefaultCost)
+
+	for key, value := range args {
+		if !strings.HasPrefix(key, PricePrefix) {
+			continue
+		}
+
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			glog.Warningf("Could not parse price %s of %s: %v", value, key, err)
+			continue
+		}
+		ncp.prices[strings.TrimPrefix(key, PricePrefix)] = price
+	}
+
+	return ncp
+}
+
+func (ncp *nodeCostPlugin) Name() string {
+	return PluginName
+}
+
+// cost returns the cost of node by the value of its label.
+func (ncp *nodeCostPlugin) cost(node *api.NodeInfo) float64 {
+	if node == nil || node.Node == nil {
+		return ncp.defaultCost
+	}
+
+	if price, found := ncp.prices[node.Node.Labels[ncp.label]]; found {
+		return price
+	}
+
+	return ncp.defaultCost
+}
+
+// share returns the dominant share of task's request in node's allocatable.
+func share(task *api.TaskInfo, node *api.NodeInfo) float64 {
+	res := 0.0
+	for _, rn := range api.ResourceNames() {
+		if node.Allocatable.Get(rn) == 0 {
+			continue
+		}
+		if s := task.Resreq.Get(rn) / node.Allocatable.Get(rn); s > res {
+			res = s
+		}
+	}
+
+	return res
+}
+
+// savings returns the cost released by evicting victim, net of the cost of
+// placing preemptor on victim's node instead, if any.
+func (ncp *nodeCostPlugin) savings(preemptor, victim *api.TaskInfo, node *api.NodeInfo) float64 {
+	if preemptor == nil {
+		return ncp.cost(node) * share(victim, node)
+	}
+	return ncp.cost(node) * (share(victim, node) - share(preemptor, node))
+}
+
+func (ncp *nodeCostPlugin) OnSessionOpen(ssn *framework.Session) {
+	// Cheaper nodes are preferred for placement.
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		return -ncp.cost(node), nil
+	})
+
+	// The victim on cheaper node is preferred, as preemptor takes its place;
+	// the one on more expensive node goes first only if evicting it saves
+	// more than preemptor costs there.
+	ssn.AddVictimOrderFn(func(preemptor, l, r *api.TaskInfo) int {
+		lNode, rNode := ssn.NodeIndex[l.NodeName], ssn.NodeIndex[r.NodeName]
+		if lNode == nil || rNode == nil {
+			return 0
+		}
+
+		lSavings := ncp.savings(preemptor, l, lNode)
+		rSavings := ncp.savings(preemptor, r, rNode)
+		if (lSavings > 0 || rSavings > 0) && lSavings != rSavings {
+			if lSavings > rSavings {
+				return -1
+			}
+			return 1
+		}
+
+		lCost, rCost := ncp.cost(lNode), ncp.cost(rNode)
+		if lCost == rCost {
+			return 0
+		}
+		if lCost < rCost {
+			return -1
+		}
+		return 1
+	})
+}
+
+func (ncp *nodeCostPlugin) OnSessionClose(ssn *framework.Session) {}
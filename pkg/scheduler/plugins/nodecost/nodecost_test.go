@@ -0,0 +1,139 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodecost
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/preempt"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/drf"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func buildNode(name, instanceType string, alloc v1.ResourceList) *v1.Node {
+	return testutil.BuildNodeWithLabels(name, alloc, map[string]string{defaultLabel: instanceType})
+}
+
+var costArgs = framework.Arguments{
+	PricePrefix + "small": "1",
+	PricePrefix + "large": "3",
+}
+
+func TestNodeCostPlacement(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	owner := testutil.BuildOwnerReference("owner1")
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: testutil.NewFakeBinder(),
+	}
+
+	schedulerCache.AddNode(buildNode("n1", "large", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddNode(buildNode("n2", "small", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+		{Name: PluginName, Arguments: costArgs},
+	})
+	defer framework.CloseSession(ssn)
+
+	allocate.New().Execute(ssn)
+
+	task := ssn.JobIndex["owner1"].Tasks["c1-p1"]
+	if task.NodeName != "n2" {
+		t.Errorf("expected task placed on cheaper node n2, got %q", task.NodeName)
+	}
+}
+
+func TestNodeCostPreemption(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	// The victims fill their nodes, so there's no idle resource for preemptor.
+	tests := []struct {
+		name       string
+		cheapCPU   string
+		costlyCPU  string
+		victimNode string
+	}{
+		{
+			name:       "equal victims, the one on cheaper node",
+			cheapCPU:   "1",
+			costlyCPU:  "1",
+			victimNode: "n1",
+		},
+		{
+			name:       "the victim on expensive node saves more than preemptor costs",
+			cheapCPU:   "1",
+			costlyCPU:  "2",
+			victimNode: "n2",
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: &testutil.FakeEvictor{},
+		}
+
+		schedulerCache.AddNode(buildNode("n1", "small", testutil.BuildResourceList(test.cheapCPU, "4Gi")))
+		schedulerCache.AddNode(buildNode("n2", "large", testutil.BuildResourceList(test.costlyCPU, "4Gi")))
+		for _, pod := range []*v1.Pod{
+			testutil.BuildPod("c1", "p1", "n1", v1.PodRunning, testutil.BuildResourceList(test.cheapCPU, "1Gi"), owner1),
+			testutil.BuildPod("c1", "p2", "n2", v1.PodRunning, testutil.BuildResourceList(test.costlyCPU, "1Gi"), owner1),
+			testutil.BuildPod("c2", "preemptor", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2),
+		} {
+			schedulerCache.AddPod(pod)
+		}
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner2))
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{Name: drf.PluginName},
+			{Name: PluginName, Arguments: costArgs},
+		})
+
+		preempt.New().Execute(ssn)
+
+		releasing := ssn.JobIndex["owner1"].TaskStatusIndex[api.Releasing]
+		if len(releasing) != 1 {
+			t.Errorf("case %s: expected 1 victim, got %d", test.name, len(releasing))
+		}
+		for _, task := range releasing {
+			if task.NodeName != test.victimNode {
+				t.Errorf("case %s: expected victim on %s, got %s", test.name, test.victimNode, task.NodeName)
+			}
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicates
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// topologyIndex indexes the tasks placed on nodes by the topology domains
+// of the nodes, e.g. the zones, for the topology keys of the required pod
+// anti-affinity of tasks in session; so the anti-affinity is checked
+// against the tasks of the whole domain instead of the candidate node.
+type topologyIndex struct {
+	// The tasks by topology key and the value of the key.
+	domains map[string]map[string]map[api.TaskID]*api.TaskInfo
+
+	// The node where each indexed task is, as the task may have left the
+	// node when it's removed, e.g. unpipelined.
+	nodes map[api.TaskID]string
+}
+
+// antiAffinityTerms returns the required pod anti-affinity terms of task.
+func antiAffinityTerms(task *api.TaskInfo) []v1.PodAffinityTerm {
+	if task.Pod == nil || task.Pod.Spec.Affinity == nil || task.Pod.Spec.Affinity.PodAntiAffinity == nil {
+		return nil
+	}
+	return task.Pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+}
+
+// newTopologyIndex returns the index of the topology keys of tasks in
+// session, or nil if no task has required pod anti-affinity.
+func newTopologyIndex(ssn *framework.Session) *topologyIndex {
+	keys := sets.NewString()
+	for _, job := range ssn.Jobs {
+		for _, task := range job.TaskStatusIndex[api.Pending] {
+			for _, term := range antiAffinityTerms(task) {
+				keys.Insert(term.TopologyKey)
+			}
+		}
+	}
+	if keys.Len() == 0 {
+		return nil
+	}
+
+	ti := &topologyIndex{
+		domains: map[string]map[string]map[api.TaskID]*api.TaskInfo{},
+		nodes:   map[api.TaskID]string{},
+	}
+	for _, key := range keys.List() {
+		ti.domains[key] = map[string]map[api.TaskID]*api.TaskInfo{}
+	}
+
+	for _, node := range ssn.Nodes {
+		for _, task := range node.Tasks {
+			ti.add(task, node)
+		}
+	}
+
+	return ti
+}
+
+// add indexes task on node into the domains of node; the releasing tasks
+// are not indexed, as they're leaving.
+func (ti *topologyIndex) add(task *api.TaskInfo, node *api.NodeInfo) {
+	if task.Status == api.Releasing || node.Node == nil {
+		return
+	}
+
+	for key, domains := range ti.domains {
+		value, found := node.Node.Labels[key]
+		if !found {
+			continue
+		}
+		if _, found := domains[value]; !found {
+			domains[value] = map[api.TaskID]*api.TaskInfo{}
+		}
+		domains[value][task.UID] = task
+	}
+	ti.nodes[task.UID] = node.Name
+}
+
+// remove drops task from the domains of the node it was indexed on.
+func (ti *topologyIndex) remove(task *api.TaskInfo, nodes map[string]*api.NodeInfo) {
+	name, found := ti.nodes[task.UID]
+	if !found {
+		return
+	}
+	delete(ti.nodes, task.UID)
+
+	node, found := nodes[name]
+	if !found || node.Node == nil {
+		return
+	}
+	for key, domains := range ti.domains {
+		if value, found := node.Node.Labels[key]; found {
+			delete(domains[value], task.UID)
+		}
+	}
+}
+
+// matches returns whether other is selected by term of task: it's in the
+// namespaces of term, or the namespace of task if none, and its labels
+// match the selector of term.
+func matches(task *api.TaskInfo, term *v1.PodAffinityTerm, selector labels.Selector, other *api.TaskInfo) bool {
+	if other.Pod == nil || other.UID == task.UID {
+		return false
+	}
+
+	namespaces := sets.NewString(term.Namespaces...)
+	if namespaces.Len() == 0 {
+		namespaces.Insert(task.Namespace)
+	}
+	if !namespaces.Has(other.Namespace) {
+		return false
+	}
+
+	return selector.Matches(labels.Set(other.Pod.Labels))
+}
+
+// check returns an error if the domain of node has a task matching the
+// required pod anti-affinity of task; the term whose topology key is not
+// a label of node does not apply to it.
+func (ti *topologyIndex) check(task *api.TaskInfo, node *api.NodeInfo) error {
+	if node.Node == nil {
+		return nil
+	}
+
+	terms := antiAffinityTerms(task)
+	for i := range terms {
+		term := &terms[i]
+
+		value, found := node.Node.Labels[term.TopologyKey]
+		if !found {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			glog.Errorf("Failed to parse the anti-affinity selector of Task <%v/%v>: %v",
+				task.Namespace, task.Name, err)
+			return fmt.Errorf("invalid pod anti-affinity selector: %v", err)
+		}
+
+		for _, other := range ti.domains[term.TopologyKey][value] {
+			if matches(task, term, selector, other) {
+				return fmt.Errorf("node <%v> is in %v=%v of Task <%v/%v>, which is against the pod anti-affinity",
+					node.Name, term.TopologyKey, value, other.Namespace, other.Name)
+			}
+		}
+	}
+
+	return nil
+}
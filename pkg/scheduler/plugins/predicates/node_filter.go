@@ -0,0 +1,124 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicates
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/api/core/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+)
+
+// nodeFilter keeps the results of the static predicates, the ones only on
+// the node object and the equivalence class of task, across sessions in the
+// state of plugin: most of nodes do not change between sessions, so it's not
+// necessary to check them again. The results of a node are dropped once the
+// fields that the static predicates read change, see nodeKey.
+type nodeFilter struct {
+	sync.Mutex
+
+	nodes map[string]*nodeFilterEntry
+}
+
+type nodeFilterEntry struct {
+	key string
+
+	// The result of static predicates by equivalence class of tasks.
+	results map[string]error
+}
+
+func newNodeFilter() *nodeFilter {
+	return &nodeFilter{
+		nodes: map[string]*nodeFilterEntry{},
+	}
+}
+
+// nodeKey returns the fields of node that the static predicates read: the
+// disk and memory pressure by checkNodePressure, and the MIG profiles that
+// node advertises by checkMIGProfiles. It must be updated with staticFns.
+func nodeKey(node *api.NodeInfo) string {
+	key := fmt.Sprintf("disk=%v,memory=%v",
+		nodeCondition(node.Node, v1.NodeDiskPressure), nodeCondition(node.Node, v1.NodeMemoryPressure))
+
+	var profiles []string
+	for rn := range node.Allocatable.ScalarResources {
+		if api.IsMIGResourceName(rn) && !node.Allocatable.IsZero(rn) {
+			profiles = append(profiles, string(rn))
+		}
+	}
+	sort.Strings(profiles)
+	for _, profile := range profiles {
+		key += "," + profile
+	}
+
+	return key
+}
+
+// equivalenceClass returns the class of task; the tasks of the same class
+// get the same results of static predicates on a node, i.e. they have the
+// same QoS class and request the same instances of MIG profiles.
+func equivalenceClass(task *api.TaskInfo) string {
+	class := string(task.QoSClass)
+	for _, profile := range migProfiles(task) {
+		class += fmt.Sprintf(",%v=%v", profile, task.Resreq.Get(v1.ResourceName(profile)))
+	}
+	return class
+}
+
+// check returns the result of fn for the class on node, fn is only called
+// if the node changed or the class is not checked on it yet.
+func (nf *nodeFilter) check(class string, node *api.NodeInfo, fn func() error) error {
+	if node.Node == nil {
+		return fn()
+	}
+	key := nodeKey(node)
+
+	nf.Lock()
+	defer nf.Unlock()
+
+	entry, found := nf.nodes[node.Name]
+	if !found || entry.key != key {
+		entry = &nodeFilterEntry{
+			key:     key,
+			results: map[string]error{},
+		}
+		nf.nodes[node.Name] = entry
+	}
+
+	err, found := entry.results[class]
+	if !found {
+		err = fn()
+		entry.results[class] = err
+	}
+
+	return err
+}
+
+// prune drops the results of the nodes which are gone.
+func (nf *nodeFilter) prune(nodes map[string]*api.NodeInfo) {
+	nf.Lock()
+	defer nf.Unlock()
+
+	for name := range nf.nodes {
+		if _, found := nodes[name]; !found {
+			delete(nf.nodes, name)
+		}
+	}
+}
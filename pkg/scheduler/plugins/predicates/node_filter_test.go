@@ -0,0 +1,227 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicates
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+// countingBuilder returns the builder of predicates plugin whose static
+// predicates are counted in checks.
+func countingBuilder(checks *int) framework.PluginBuilder {
+	return func(args framework.Arguments) framework.Plugin {
+		pp := New(args).(*predicatesPlugin)
+		pp.staticFns = []api.PredicateFn{func(task *api.TaskInfo, node *api.NodeInfo) error {
+			*checks++
+			return checkNodePressure(task, node)
+		}}
+		return pp
+	}
+}
+
+func buildVersionedNode(name, version string) *v1.Node {
+	node := buildNode(name, testutil.BuildResourceList("4", "8Gi"))
+	node.ResourceVersion = version
+	return node
+}
+
+// checkNodes runs the predicates of all tasks on all nodes in a session.
+func checkNodes(schedulerCache cache.Cache) {
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	for _, job := range ssn.Jobs {
+		for _, task := range job.Tasks {
+			for _, node := range ssn.Nodes {
+				ssn.PredicateFn(task, node)
+			}
+		}
+	}
+}
+
+func TestNodeFilter(t *testing.T) {
+	checks := 0
+	framework.RegisterPluginBuilder(PluginName, countingBuilder(&checks))
+	defer framework.CleanupPluginBuilders()
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	for _, n := range []string{"n1", "n2", "n3"} {
+		schedulerCache.AddNode(buildVersionedNode(n, "1"))
+	}
+
+	// Two tasks of the same QoS class.
+	owner := testutil.BuildOwnerReference("owner1")
+	for _, n := range []string{"p1", "p2"} {
+		schedulerCache.AddPod(buildPod("c1", n, testutil.BuildResourceList("1", "1Gi"), owner, ""))
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	})
+
+	checkNodes(schedulerCache)
+	if checks != 3 {
+		t.Errorf("expected 3 checks in first session, got %d", checks)
+	}
+
+	// Nothing changed, all nodes are skipped.
+	checks = 0
+	checkNodes(schedulerCache)
+	if checks != 0 {
+		t.Errorf("expected no checks of unchanged nodes, got %d", checks)
+	}
+
+	// The update of the fields that static predicates do not read, e.g.
+	// labels, does not drop the results.
+	checks = 0
+	labeled := buildVersionedNode("n1", "2")
+	labeled.Labels = map[string]string{"zone": "z1"}
+	schedulerCache.UpdateNode(buildVersionedNode("n1", "1"), labeled)
+	checkNodes(schedulerCache)
+	if checks != 0 {
+		t.Errorf("expected no checks of node with new labels, got %d", checks)
+	}
+
+	// Only the node under pressure is checked again.
+	checks = 0
+	pressured := buildVersionedNode("n1", "3")
+	pressured.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeDiskPressure, Status: v1.ConditionTrue}}
+	schedulerCache.UpdateNode(labeled, pressured)
+	checkNodes(schedulerCache)
+	if checks != 1 {
+		t.Errorf("expected 1 check of node under pressure, got %d", checks)
+	}
+
+	// The results of deleted node are dropped.
+	schedulerCache.DeleteNode(buildVersionedNode("n3", "1"))
+	checkNodes(schedulerCache)
+	if _, found := schedulerCache.PluginState(PluginName).(*nodeFilter).nodes["n3"]; found {
+		t.Errorf("expected results of deleted node n3 dropped")
+	}
+}
+
+func TestNodeFilterMIG(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+	// Only n2 advertises the MIG profile.
+	profile := v1.ResourceName(api.MIGResourcePrefix + "1g.5gb")
+	n2 := buildVersionedNode("n2", "1")
+	n2.Status.Capacity[profile] = resource.MustParse("1")
+	n2.Status.Allocatable[profile] = resource.MustParse("1")
+	schedulerCache.AddNode(buildVersionedNode("n1", "1"))
+	schedulerCache.AddNode(n2)
+
+	// The tasks of the same QoS class, but only one requests MIG profile.
+	mig := testutil.BuildResourceList("1", "1Gi")
+	mig[profile] = resource.MustParse("1")
+	owner := testutil.BuildOwnerReference("owner1")
+	schedulerCache.AddPod(buildPod("c1", "mig", mig, owner, ""))
+	schedulerCache.AddPod(buildPod("c1", "plain", testutil.BuildResourceList("1", "1Gi"), owner, ""))
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	job, node := ssn.JobIndex["owner1"], ssn.NodeIndex["n1"]
+	if err := ssn.PredicateFn(job.Tasks["c1-mig"], node); err == nil {
+		t.Errorf("expected the MIG task rejected by node without the profile")
+	}
+	if err := ssn.PredicateFn(job.Tasks["c1-plain"], node); err != nil {
+		t.Errorf("expected the plain task not rejected by the result of MIG task, got <%v>", err)
+	}
+}
+
+// benchmarkNodeFilter runs the sessions of 10 tasks on 1000 nodes; the
+// results of static predicates are dropped before each session if not cached.
+func benchmarkNodeFilter(b *testing.B, cached bool) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	for i := 0; i < 1000; i++ {
+		node := buildVersionedNode(fmt.Sprintf("n%d", i), "1")
+		for _, ct := range []v1.NodeConditionType{v1.NodeReady, v1.NodeMemoryPressure, v1.NodeDiskPressure, v1.NodeOutOfDisk} {
+			status := v1.ConditionFalse
+			if ct == v1.NodeReady {
+				status = v1.ConditionTrue
+			}
+			node.Status.Conditions = append(node.Status.Conditions, v1.NodeCondition{Type: ct, Status: status})
+		}
+		schedulerCache.AddNode(node)
+	}
+
+	owner := testutil.BuildOwnerReference("owner1")
+	for i := 0; i < 10; i++ {
+		schedulerCache.AddPod(buildPod("c1", fmt.Sprintf("p%d", i), testutil.BuildResourceList("1", "1Gi"), owner, ""))
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !cached {
+			schedulerCache.SetPluginState(PluginName, nil)
+		}
+		checkNodes(schedulerCache)
+	}
+}
+
+func BenchmarkNodeFilterCached(b *testing.B) {
+	benchmarkNodeFilter(b, true)
+}
+
+func BenchmarkNodeFilterUncached(b *testing.B) {
+	benchmarkNodeFilter(b, false)
+}
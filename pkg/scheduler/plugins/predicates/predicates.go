@@ -0,0 +1,206 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicates
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"k8s.io/api/core/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "predicates"
+
+// NodeWarmUp is the argument of the duration after a node becomes Ready,
+// during which no task is placed onto it, e.g. "30s"; it's disabled by default.
+const NodeWarmUp = "nodeWarmUp"
+
+type predicatesPlugin struct {
+	warmUp time.Duration
+
+	// The predicates only on the node object and the equivalence class of
+	// task, their results are cached until the node changes; so their errors
+	// are shared by the tasks of a class, and must not name the task.
+	staticFns []api.PredicateFn
+
+	// The results of staticFns, kept in the state of plugin across sessions.
+	filter *nodeFilter
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	pp := &predicatesPlugin{
+		staticFns: []api.PredicateFn{checkNodePressure, checkMIGProfiles},
+	}
+	args.GetDuration(&pp.warmUp, NodeWarmUp)
+	return pp
+}
+
+func (pp *predicatesPlugin) Name() string {
+	return PluginName
+}
+
+// nodeCondition returns true if node has the condition of type.
+func nodeCondition(node *v1.Node, conditionType v1.NodeConditionType) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == conditionType && c.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkNodePressure rejects the node under disk pressure for all pods, or
+// under memory pressure for BestEffort pods, as kubelet may evict them.
+func checkNodePressure(task *api.TaskInfo, node *api.NodeInfo) error {
+	if node.Node == nil || task.Pod == nil {
+		return nil
+	}
+
+	if nodeCondition(node.Node, v1.NodeDiskPressure) {
+		return fmt.Errorf("node <%v> is under disk pressure", node.Name)
+	}
+
+	if nodeCondition(node.Node, v1.NodeMemoryPressure) && task.QoSClass == v1.PodQOSBestEffort {
+		return fmt.Errorf("node <%v> is under memory pressure, BestEffort tasks are rejected", node.Name)
+	}
+
+	return nil
+}
+
+// migProfiles returns the MIG profiles requested by task, sorted; the error
+// of checkMIGProfiles is shared by the equivalent tasks, so it names the
+// same profile every time.
+func migProfiles(task *api.TaskInfo) []string {
+	var profiles []string
+	for rn := range task.Resreq.ScalarResources {
+		if api.IsMIGResourceName(rn) && !task.Resreq.IsZero(rn) {
+			profiles = append(profiles, string(rn))
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// checkMIGProfiles rejects the node that does not advertise the MIG profiles
+// requested by task, or any node if task requests part of a MIG instance,
+// which can not be shared. Whether there are enough idle instances is
+// checked by the fit of task's request as other resources.
+func checkMIGProfiles(task *api.TaskInfo, node *api.NodeInfo) error {
+	for _, profile := range migProfiles(task) {
+		rn := v1.ResourceName(profile)
+		if req := task.Resreq.Get(rn); math.Mod(req, 1000) != 0 {
+			return fmt.Errorf("MIG profile <%v> is requested by %v instances, not a whole number",
+				profile, req/1000)
+		}
+		if node.Allocatable.IsZero(rn) {
+			return fmt.Errorf("node <%v> has no MIG profile <%v>", node.Name, profile)
+		}
+	}
+
+	return nil
+}
+
+// checkNodeWarmUp rejects the node which became Ready within the warm-up
+// window before now, so its daemons (e.g. device plugins) have time to settle.
+func (pp *predicatesPlugin) checkNodeWarmUp(now time.Time, node *api.NodeInfo) error {
+	if node.Node == nil {
+		return nil
+	}
+
+	for _, c := range node.Node.Status.Conditions {
+		if c.Type != v1.NodeReady || c.Status != v1.ConditionTrue {
+			continue
+		}
+		if ready := now.Sub(c.LastTransitionTime.Time); ready < pp.warmUp {
+			return fmt.Errorf("node <%v> became ready %v ago, within warm-up %v",
+				node.Name, ready.Round(time.Second), pp.warmUp)
+		}
+	}
+
+	return nil
+}
+
+// checkStatic returns the first error of static predicates of task on node.
+func (pp *predicatesPlugin) checkStatic(task *api.TaskInfo, node *api.NodeInfo) error {
+	for _, fn := range pp.staticFns {
+		if err := fn(task, node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (pp *predicatesPlugin) OnSessionOpen(ssn *framework.Session) {
+	filter, found := ssn.PluginState(PluginName).(*nodeFilter)
+	if !found {
+		filter = newNodeFilter()
+	}
+	filter.prune(ssn.NodeIndex)
+	pp.filter = filter
+
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		if task.Pod == nil {
+			return pp.checkStatic(task, node)
+		}
+		return filter.check(equivalenceClass(task), node, func() error {
+			return pp.checkStatic(task, node)
+		})
+	})
+
+	if pp.warmUp > 0 {
+		now := ssn.Now()
+		ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+			return pp.checkNodeWarmUp(now, node)
+		})
+	}
+
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		// The pods using local volumes must be on the node holding the volumes.
+		return ssn.CheckVolumes(task, node)
+	})
+
+	// The required pod anti-affinity is checked against the tasks in the
+	// topology domain of node, so it's kept up to date with the placements
+	// in session.
+	if ti := newTopologyIndex(ssn); ti != nil {
+		ssn.AddPredicateFn(ti.check)
+
+		ssn.AddEventHandler(&framework.EventHandler{
+			AllocateFunc: func(event *framework.Event) {
+				if node, found := ssn.NodeIndex[event.Task.NodeName]; found {
+					ti.add(event.Task, node)
+				}
+			},
+			EvictFunc: func(event *framework.Event) {
+				ti.remove(event.Task, ssn.NodeIndex)
+			},
+		})
+	}
+}
+
+func (pp *predicatesPlugin) OnSessionClose(ssn *framework.Session) {
+	ssn.SetPluginState(PluginName, pp.filter)
+	pp.filter = nil
+}
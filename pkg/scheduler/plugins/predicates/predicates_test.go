@@ -0,0 +1,431 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicates
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	clientcache "k8s.io/client-go/tools/cache"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func buildNode(name string, alloc v1.ResourceList) *v1.Node {
+	return testutil.BuildNodeWithLabels(name, alloc, map[string]string{"kubernetes.io/hostname": name})
+}
+
+func buildPod(ns, n string, req v1.ResourceList, owner metav1.OwnerReference, claim string) *v1.Pod {
+	pod := testutil.BuildPod(ns, n, "", v1.PodPending, req, owner)
+	pod.Spec.Volumes = []v1.Volume{
+		{
+			Name: "data",
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			},
+		},
+	}
+	return pod
+}
+
+func TestLocalVolume(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	pvIndexer := clientcache.NewIndexer(clientcache.MetaNamespaceKeyFunc, clientcache.Indexers{})
+	pvcIndexer := clientcache.NewIndexer(clientcache.MetaNamespaceKeyFunc, clientcache.Indexers{})
+	classIndexer := clientcache.NewIndexer(clientcache.MetaNamespaceKeyFunc, clientcache.Indexers{})
+
+	// The local PV is pinned to n2.
+	pvIndexer.Add(&v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "local-pv"},
+		Spec: v1.PersistentVolumeSpec{
+			NodeAffinity: &v1.VolumeNodeAffinity{
+				Required: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{
+							MatchExpressions: []v1.NodeSelectorRequirement{
+								{
+									Key:      "kubernetes.io/hostname",
+									Operator: v1.NodeSelectorOpIn,
+									Values:   []string{"n2"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	pvcIndexer.Add(&v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "c1", Name: "local"},
+		Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "local-pv"},
+	})
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: testutil.NewFakeBinder(),
+		VolumeChecker: cache.NewVolumeChecker(
+			corelisters.NewPersistentVolumeClaimLister(pvcIndexer),
+			corelisters.NewPersistentVolumeLister(pvIndexer),
+			storagelisters.NewStorageClassLister(classIndexer)),
+	}
+
+	for _, n := range []string{"n1", "n2", "n3"} {
+		schedulerCache.AddNode(buildNode(n, testutil.BuildResourceList("4", "4Gi")))
+	}
+
+	owner := testutil.BuildOwnerReference("owner1")
+	schedulerCache.AddPod(buildPod("c1", "p1", testutil.BuildResourceList("1", "1Gi"), owner, "local"))
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	allocate.New().Execute(ssn)
+
+	job := ssn.JobIndex["owner1"]
+	for _, task := range job.Tasks {
+		if task.NodeName != "n2" {
+			t.Errorf("expected task <%v/%v> on n2, got <%v>", task.Namespace, task.Name, task.NodeName)
+		}
+	}
+}
+
+func TestNodePressure(t *testing.T) {
+	owner := testutil.BuildOwnerReference("owner1")
+
+	withConditions := func(node *v1.Node, conditions ...v1.NodeConditionType) *v1.Node {
+		for _, ct := range conditions {
+			node.Status.Conditions = append(node.Status.Conditions, v1.NodeCondition{
+				Type:   ct,
+				Status: v1.ConditionTrue,
+			})
+		}
+		return node
+	}
+
+	bestEffort := buildPod("c1", "p1", nil, owner, "")
+	guaranteed := buildPod("c1", "p2", testutil.BuildResourceList("1", "1Gi"), owner, "")
+	guaranteed.Spec.Containers[0].Resources.Limits = testutil.BuildResourceList("1", "1Gi")
+
+	tests := []struct {
+		name     string
+		node     *v1.Node
+		pod      *v1.Pod
+		rejected bool
+	}{
+		{
+			name: "BestEffort on healthy node",
+			node: buildNode("n1", testutil.BuildResourceList("4", "8Gi")),
+			pod:  bestEffort,
+		},
+		{
+			name:     "BestEffort on memory pressure node",
+			node:     withConditions(buildNode("n1", testutil.BuildResourceList("4", "8Gi")), v1.NodeMemoryPressure),
+			pod:      bestEffort,
+			rejected: true,
+		},
+		{
+			name: "Guaranteed on memory pressure node",
+			node: withConditions(buildNode("n1", testutil.BuildResourceList("4", "8Gi")), v1.NodeMemoryPressure),
+			pod:  guaranteed,
+		},
+		{
+			name:     "Guaranteed on disk pressure node",
+			node:     withConditions(buildNode("n1", testutil.BuildResourceList("4", "8Gi")), v1.NodeDiskPressure),
+			pod:      guaranteed,
+			rejected: true,
+		},
+	}
+
+	for i, test := range tests {
+		err := checkNodePressure(api.NewTaskInfo(test.pod), api.NewNodeInfo(test.node))
+		if (err != nil) != test.rejected {
+			t.Errorf("case %d (%s): expected rejected %v, got err %v", i, test.name, test.rejected, err)
+		}
+	}
+}
+
+func TestNodeWarmUp(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	owner := testutil.BuildOwnerReference("owner1")
+	pod := buildPod("c1", "p1", testutil.BuildResourceList("1", "1Gi"), owner, "")
+	pod.Spec.Volumes = nil
+
+	ready := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	node := buildNode("n1", testutil.BuildResourceList("4", "8Gi"))
+	node.Status.Conditions = []v1.NodeCondition{{
+		Type:               v1.NodeReady,
+		Status:             v1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(ready),
+	}}
+
+	// The clock goes on through the sessions of each case.
+	tests := []struct {
+		name     string
+		warmUp   string
+		elapsed  []time.Duration
+		rejected []bool
+	}{
+		{
+			name:     "node is rejected within 30s warm-up, and accepted after",
+			warmUp:   "30s",
+			elapsed:  []time.Duration{0, 5 * time.Second, 29 * time.Second, 30 * time.Second, time.Minute},
+			rejected: []bool{true, true, true, false, false},
+		},
+		{
+			name:     "warm-up disabled by default",
+			elapsed:  []time.Duration{0, 5 * time.Second},
+			rejected: []bool{false, false},
+		},
+	}
+
+	for _, test := range tests {
+		clock := &testutil.FakeClock{}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+			Clock: clock,
+		}
+		schedulerCache.AddNode(node)
+
+		args := framework.Arguments{}
+		if len(test.warmUp) != 0 {
+			args[NodeWarmUp] = test.warmUp
+		}
+
+		for i, elapsed := range test.elapsed {
+			clock.Time = ready.Add(elapsed)
+
+			ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName, Arguments: args}})
+			err := ssn.PredicateFn(api.NewTaskInfo(pod), ssn.NodeIndex["n1"])
+			framework.CloseSession(ssn)
+
+			if (err != nil) != test.rejected[i] {
+				t.Errorf("case %s: expected rejected %v after %v, got err %v", test.name, test.rejected[i], elapsed, err)
+			}
+		}
+	}
+}
+
+func TestPodAntiAffinityZone(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	zoneKey := "failure-domain.beta.kubernetes.io/zone"
+	web := map[string]string{"app": "web"}
+
+	buildReplica := func(ns, n string, owner metav1.OwnerReference) *v1.Pod {
+		pod := buildPod(ns, n, testutil.BuildResourceList("1", "1Gi"), owner, "")
+		pod.Labels = web
+		pod.Spec.Volumes = nil
+		pod.Spec.Affinity = &v1.Affinity{
+			PodAntiAffinity: &v1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+					{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: web},
+						TopologyKey:   zoneKey,
+					},
+				},
+			},
+		}
+		return pod
+	}
+
+	tests := []struct {
+		name     string
+		existing string
+		replicas int
+		zones    map[string]int
+	}{
+		{
+			name:     "the zone of the existing pod is avoided",
+			existing: "c1",
+			replicas: 2,
+			zones:    map[string]int{"z2": 1, "z3": 1},
+		},
+		{
+			name:     "the existing pod of other namespace does not count",
+			existing: "c2",
+			replicas: 3,
+			zones:    map[string]int{"z1": 1, "z2": 1, "z3": 1},
+		},
+	}
+
+	for _, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		}
+
+		// Two nodes in z1, so a replica could land on the other node of
+		// the zone if only the node of the existing pod were checked.
+		for n, zone := range map[string]string{"n1": "z1", "n2": "z1", "n3": "z2", "n4": "z3"} {
+			node := buildNode(n, testutil.BuildResourceList("4", "4Gi"))
+			node.Labels[zoneKey] = zone
+			schedulerCache.AddNode(node)
+		}
+
+		existing := buildPod(test.existing, "web", testutil.BuildResourceList("1", "1Gi"), testutil.BuildOwnerReference("owner0"), "")
+		existing.Labels = web
+		existing.Spec.Volumes = nil
+		existing.Spec.NodeName = "n1"
+		existing.Status.Phase = v1.PodRunning
+		schedulerCache.AddPod(existing)
+
+		owner := testutil.BuildOwnerReference("owner1")
+		for i := 0; i < test.replicas; i++ {
+			schedulerCache.AddPod(buildReplica("c1", fmt.Sprintf("p%d", i), owner))
+		}
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+		})
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+
+		allocate.New().Execute(ssn)
+
+		zones := map[string]int{}
+		for _, task := range ssn.JobIndex["owner1"].Tasks {
+			if node, found := ssn.NodeIndex[task.NodeName]; found {
+				zones[node.Node.Labels[zoneKey]]++
+			}
+		}
+		if !reflect.DeepEqual(zones, test.zones) {
+			t.Errorf("case %s: expected replicas in zones %v, got %v", test.name, test.zones, zones)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestMIGProfiles(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	mig := v1.ResourceName(api.MIGResourcePrefix + "1g.5gb")
+	buildMIGResourceList := func(instances string) v1.ResourceList {
+		rl := testutil.BuildResourceList("1", "1Gi")
+		rl[mig] = resource.MustParse(instances)
+		return rl
+	}
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: testutil.NewFakeBinder(),
+	}
+
+	// n1 advertises two instances of the profile, n2 has none.
+	n1 := buildNode("n1", testutil.BuildResourceList("8", "8Gi"))
+	n1.Status.Allocatable[mig] = resource.MustParse("2")
+	schedulerCache.AddNode(n1)
+	schedulerCache.AddNode(buildNode("n2", testutil.BuildResourceList("8", "8Gi")))
+
+	owner := testutil.BuildOwnerReference("owner1")
+	for i := 0; i < 3; i++ {
+		pod := buildPod("c1", fmt.Sprintf("p%d", i), buildMIGResourceList("1"), owner, "")
+		pod.Spec.Volumes = nil
+		schedulerCache.AddPod(pod)
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec: arbv1.SchedulingSpecTemplate{
+			MinAvailable: 1,
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	allocate.New().Execute(ssn)
+
+	// Two tasks take the instances of n1, and the third fits nowhere.
+	nodes := map[string]int{}
+	for _, task := range ssn.JobIndex["owner1"].Tasks {
+		if len(task.NodeName) != 0 {
+			nodes[task.NodeName]++
+		}
+	}
+	if expected := map[string]int{"n1": 2}; !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("expected tasks on nodes %v, got %v", expected, nodes)
+	}
+
+	tests := []struct {
+		name      string
+		instances string
+		node      string
+		err       string
+	}{
+		{
+			name:      "the node advertises the profile",
+			instances: "1",
+			node:      "n1",
+		},
+		{
+			name:      "the node does not advertise the profile",
+			instances: "1",
+			node:      "n2",
+			err:       "node <n2> has no MIG profile <nvidia.com/mig-1g.5gb>",
+		},
+		{
+			name:      "part of an instance is requested",
+			instances: "500m",
+			node:      "n1",
+			err:       "MIG profile <nvidia.com/mig-1g.5gb> is requested by 0.5 instances, not a whole number",
+		},
+	}
+
+	for _, test := range tests {
+		task := api.NewTaskInfo(buildPod("c2", "p0", buildMIGResourceList(test.instances), owner, ""))
+		err := checkMIGProfiles(task, ssn.NodeIndex[test.node])
+		if got := fmt.Sprint(err); (err == nil && len(test.err) != 0) || (err != nil && got != test.err) {
+			t.Errorf("case %s: expected error <%v>, got <%v>", test.name, test.err, err)
+		}
+	}
+}
@@ -21,13 +21,20 @@ import (
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
 )
 
+// PluginName indicates name of the plugin.
+const PluginName = "priority"
+
 type priorityPlugin struct {
 }
 
-func New() framework.Plugin {
+func New(args framework.Arguments) framework.Plugin {
 	return &priorityPlugin{}
 }
 
+func (gp *priorityPlugin) Name() string {
+	return PluginName
+}
+
 func (gp *priorityPlugin) OnSessionOpen(ssn *framework.Session) {
 	// Add Task Order function
 	ssn.AddTaskOrderFn(func(l interface{}, r interface{}) int {
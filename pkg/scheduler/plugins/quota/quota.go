@@ -0,0 +1,152 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "quota"
+
+// quotaPlugin enforces the hard ResourceQuotas of namespaces at scheduling
+// time: the API server admits pods by their requests when they're created,
+// but the resource they take is counted here as tasks are allocated in
+// session, so a namespace never holds more than its quota on nodes.
+type quotaPlugin struct {
+	// The quotas and the resource requested by the pods on nodes, by namespace.
+	quotas map[string][]*api.QuotaInfo
+	used   map[string]*api.Resource
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	return &quotaPlugin{
+		quotas: map[string][]*api.QuotaInfo{},
+		used:   map[string]*api.Resource{},
+	}
+}
+
+func (qp *quotaPlugin) Name() string {
+	return PluginName
+}
+
+// check returns the error if placing task exceeds any quota of its namespace.
+func (qp *quotaPlugin) check(task *api.TaskInfo) error {
+	quotas := qp.quotas[task.Namespace]
+	if len(quotas) == 0 {
+		return nil
+	}
+
+	used := task.Resreq.Clone()
+	if u, found := qp.used[task.Namespace]; found {
+		used.Add(u)
+	}
+
+	for _, quota := range quotas {
+		if names := quota.Exceeded(used); len(names) != 0 {
+			return fmt.Errorf("task <%v/%v> exceeds %v of ResourceQuota <%v/%v>: used <%v>, hard <%v>",
+				task.Namespace, task.Name, names, quota.Namespace, quota.Name, qp.used[task.Namespace], quota.Hard)
+		}
+	}
+
+	return nil
+}
+
+// admits returns true if any pending task of job fits the quotas of its
+// namespace; the job that can not use any more resource is not scheduled.
+func (qp *quotaPlugin) admits(job *api.JobInfo) bool {
+	if len(qp.quotas[job.Namespace]) == 0 {
+		return true
+	}
+
+	var err error
+	for _, task := range job.TaskStatusIndex[api.Pending] {
+		if err = qp.check(task); err == nil {
+			return true
+		}
+	}
+
+	if err != nil {
+		glog.V(3).Infof("Job <%v:%v/%v> is out of quota: %v", job.UID, job.Namespace, job.Name, err)
+	}
+
+	return err == nil
+}
+
+func (qp *quotaPlugin) OnSessionOpen(ssn *framework.Session) {
+	for _, quota := range ssn.Quotas {
+		qp.quotas[quota.Namespace] = append(qp.quotas[quota.Namespace], quota)
+	}
+
+	// All pods on nodes are counted, including the ones of other schedulers.
+	for _, node := range ssn.Nodes {
+		for _, task := range node.Tasks {
+			if _, found := qp.quotas[task.Namespace]; !found {
+				continue
+			}
+			if _, found := qp.used[task.Namespace]; !found {
+				qp.used[task.Namespace] = api.EmptyResource()
+			}
+			qp.used[task.Namespace].Add(task.Resreq)
+		}
+	}
+
+	for ns, used := range qp.used {
+		glog.V(3).Infof("Namespace <%v> uses <%v> of %d quotas", ns, used, len(qp.quotas[ns]))
+	}
+
+	ssn.AddJobValidFn(func(obj interface{}) bool {
+		return qp.admits(obj.(*api.JobInfo))
+	})
+
+	ssn.AddOverusedFn(func(obj interface{}) bool {
+		return !qp.admits(obj.(*api.JobInfo))
+	})
+
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		return qp.check(task)
+	})
+
+	ssn.AddEventHandler(&framework.EventHandler{
+		AllocateFunc: func(event *framework.Event) {
+			if _, found := qp.quotas[event.Task.Namespace]; !found {
+				return
+			}
+			if _, found := qp.used[event.Task.Namespace]; !found {
+				qp.used[event.Task.Namespace] = api.EmptyResource()
+			}
+			qp.used[event.Task.Namespace].Add(event.Task.Resreq)
+		},
+		EvictFunc: func(event *framework.Event) {
+			used, found := qp.used[event.Task.Namespace]
+			if !found {
+				return
+			}
+			used.Sub(api.Min(event.Task.Resreq, used))
+		},
+	})
+}
+
+func (qp *quotaPlugin) OnSessionClose(ssn *framework.Session) {
+	qp.quotas = map[string][]*api.QuotaInfo{}
+	qp.used = map[string]*api.Resource{}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func buildSchedulingSpec(ns, n string, owner metav1.OwnerReference) *arbv1.SchedulingSpec {
+	ss := testutil.BuildSchedulingSpec(ns, n, owner)
+	// The tasks in quota are dispatched, though the rest are pending.
+	ss.Spec.MinAvailable = 1
+	return ss
+}
+
+func buildResourceQuota(ns, n string, hard v1.ResourceList) *v1.ResourceQuota {
+	return &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n,
+			Namespace: ns,
+		},
+		Spec: v1.ResourceQuotaSpec{
+			Hard: hard,
+		},
+	}
+}
+
+func TestQuota(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name      string
+		hard      v1.ResourceList
+		running   string
+		allocated int
+	}{
+		{
+			name:      "no limit on requests",
+			hard:      v1.ResourceList{"limits.cpu": resource.MustParse("4")},
+			running:   "2",
+			allocated: 4,
+		},
+		{
+			name:      "the quota is partly used",
+			hard:      v1.ResourceList{"requests.cpu": resource.MustParse("4")},
+			running:   "2",
+			allocated: 2,
+		},
+		{
+			name:      "the namespace is at its quota",
+			hard:      v1.ResourceList{v1.ResourceCPU: resource.MustParse("4")},
+			running:   "4",
+			allocated: 0,
+		},
+		{
+			name: "the stricter of cpu and requests.cpu",
+			hard: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("3"),
+				"requests.cpu": resource.MustParse("5"),
+			},
+			running:   "2",
+			allocated: 1,
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("16", "16Gi")))
+		schedulerCache.AddPod(testutil.BuildPod("c1", "running", "n1", v1.PodRunning,
+			testutil.BuildResourceList(test.running, "1Gi"), owner1))
+		for i := 0; i < 4; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending,
+				testutil.BuildResourceList("1", "1Gi"), owner1))
+			schedulerCache.AddPod(testutil.BuildPod("c2", fmt.Sprintf("p%d", i), "", v1.PodPending,
+				testutil.BuildResourceList("1", "1Gi"), owner2))
+		}
+		schedulerCache.AddSchedulingSpec(buildSchedulingSpec("c1", "j1", owner1))
+		schedulerCache.AddSchedulingSpec(buildSchedulingSpec("c2", "j2", owner2))
+		schedulerCache.AddResourceQuota(buildResourceQuota("c1", "q1", test.hard))
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+
+		allocate.New().Execute(ssn)
+
+		if got := len(ssn.JobIndex["owner1"].TaskStatusIndex[api.Binding]); got != test.allocated {
+			t.Errorf("case %s: expected %d tasks allocated in quota, got %d", test.name, test.allocated, got)
+		}
+		// The namespace without quota is not limited.
+		if got := len(ssn.JobIndex["owner2"].TaskStatusIndex[api.Binding]); got != 4 {
+			t.Errorf("case %s: expected 4 tasks allocated out of quota, got %d", test.name, got)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcefit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "resourcefit"
+
+const (
+	// Strategy is the argument of how nodes are scored by their utilization
+	// after placing the task, one of the strategies below; the default is
+	// LeastAllocated.
+	Strategy = "strategy"
+
+	// Shape is the argument of the RequestedToCapacityRatio strategy, the
+	// points of utilization (in [0, 100]) to score (in [0, 10]), e.g.
+	// "0:10,100:0"; the scores between points are interpolated linearly.
+	Shape = "shape"
+
+	// Threshold is the argument of the FillThenSpread strategy, the
+	// utilization (in [0, 100]) up to which nodes are filled; the default is
+	// 80.
+	Threshold = "threshold"
+
+	// GPUModelLabel is the argument of the node label of GPU model, which is
+	// matched against the preferred GPU model of jobs.
+	GPUModelLabel = "gpuModelLabel"
+)
+
+const (
+	// LeastAllocated prefers the nodes of lower utilization, i.e. spreads tasks.
+	LeastAllocated = "LeastAllocated"
+
+	// MostAllocated prefers the nodes of higher utilization, i.e. bin-packs tasks.
+	MostAllocated = "MostAllocated"
+
+	// RequestedToCapacityRatio scores nodes by the shape of utilization.
+	RequestedToCapacityRatio = "RequestedToCapacityRatio"
+
+	// FillThenSpread bin-packs tasks onto the nodes that stay within the
+	// threshold of utilization, and spreads them among the nodes beyond it;
+	// so nodes are filled up to the threshold, but not into hotspots.
+	FillThenSpread = "FillThenSpread"
+)
+
+// maxShapeScore is the max score in the points of shape.
+const maxShapeScore = 10
+
+// defaultShape bin-packs tasks, as MostAllocated does.
+const defaultShape = "0:0,100:10"
+
+// defaultThreshold is the utilization that FillThenSpread fills nodes up to.
+const defaultThreshold = 80.0
+
+// defaultGPUModelLabel is the label set by GPU feature discovery, e.g.
+// "NVIDIA-A100-SXM4-40GB".
+const defaultGPUModelLabel = "nvidia.com/gpu.product"
+
+// point is a point of shape from utilization to score.
+type point struct {
+	utilization float64
+	score       float64
+}
+
+type resourceFitPlugin struct {
+	strategy      string
+	shape         []point
+	threshold     float64
+	gpuModelLabel string
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	rfp := &resourceFitPlugin{
+		strategy:      LeastAllocated,
+		threshold:     defaultThreshold,
+		gpuModelLabel: defaultGPUModelLabel,
+	}
+
+	if label, found := args[GPUModelLabel]; found && len(label) != 0 {
+		rfp.gpuModelLabel = label
+	}
+
+	switch s := args[Strategy]; s {
+	case "":
+	case LeastAllocated, MostAllocated, RequestedToCapacityRatio, FillThenSpread:
+		rfp.strategy = s
+	default:
+		glog.Warningf("Unknown strategy <%v> of %v, use %v instead", s, PluginName, LeastAllocated)
+	}
+
+	if rfp.strategy == RequestedToCapacityRatio {
+		shape, err := parseShape(args[Shape])
+		if err != nil {
+			glog.Warningf("Invalid shape <%v> of %v, use <%v> instead: %v", args[Shape], PluginName, defaultShape, err)
+			shape, _ = parseShape(defaultShape)
+		}
+		rfp.shape = shape
+	}
+
+	if rfp.strategy == FillThenSpread {
+		args.GetFloat64(&rfp.threshold, Threshold)
+		if rfp.threshold < 0 || rfp.threshold > 100 {
+			glog.Warningf("Invalid threshold <%v> of %v, use <%v> instead", rfp.threshold, PluginName, defaultThreshold)
+			rfp.threshold = defaultThreshold
+		}
+	}
+
+	return rfp
+}
+
+func (rfp *resourceFitPlugin) Name() string {
+	return PluginName
+}
+
+// parseShape parses the points of shape, e.g. "0:10,100:0"; they're sorted by
+// utilization, which must be distinct.
+func parseShape(s string) ([]point, error) {
+	if len(s) == 0 {
+		s = defaultShape
+	}
+
+	var shape []point
+	for _, p := range strings.Split(s, ",") {
+		fields := strings.Split(p, ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("point <%v> is not in the format of <utilization>:<score>", p)
+		}
+
+		utilization, err := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+		if err != nil || utilization < 0 || utilization > 100 {
+			return nil, fmt.Errorf("utilization <%v> is not in [0, 100]", fields[0])
+		}
+		score, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil || score < 0 || score > maxShapeScore {
+			return nil, fmt.Errorf("score <%v> is not in [0, %d]", fields[1], maxShapeScore)
+		}
+
+		shape = append(shape, point{utilization: utilization, score: score})
+	}
+
+	sort.Slice(shape, func(i, j int) bool {
+		return shape[i].utilization < shape[j].utilization
+	})
+	for i := 1; i < len(shape); i++ {
+		if shape[i].utilization == shape[i-1].utilization {
+			return nil, fmt.Errorf("utilization <%v> is duplicated", shape[i].utilization)
+		}
+	}
+
+	return shape, nil
+}
+
+// shapeScore returns the score of utilization by shape, in [0, 100]; it's the
+// score of the first or last point beyond them.
+func shapeScore(shape []point, utilization float64) float64 {
+	if utilization <= shape[0].utilization {
+		return shape[0].score * 100 / maxShapeScore
+	}
+
+	for i := 1; i < len(shape); i++ {
+		if utilization <= shape[i].utilization {
+			l, r := shape[i-1], shape[i]
+			score := l.score + (r.score-l.score)*(utilization-l.utilization)/(r.utilization-l.utilization)
+			return score * 100 / maxShapeScore
+		}
+	}
+
+	return shape[len(shape)-1].score * 100 / maxShapeScore
+}
+
+// fillThenSpreadScore returns the score of utilization, in [0, 100]: the
+// utilization within threshold scores in [50, 100] the higher the fuller, and
+// the one beyond scores in [0, 50) the higher the emptier; so any node within
+// threshold is preferred to any node beyond it.
+func fillThenSpreadScore(threshold, utilization float64) float64 {
+	if utilization <= threshold {
+		if threshold == 0 {
+			return 100
+		}
+		return 50 + 50*utilization/threshold
+	}
+
+	return 50 * (100 - utilization) / (100 - threshold)
+}
+
+// score returns the mean score of node's resources for task, in [0, 100],
+// by the utilization of each resource after placing task on node.
+func (rfp *resourceFitPlugin) score(task *api.TaskInfo, node *api.NodeInfo) float64 {
+	total, count := 0.0, 0
+	for _, rn := range api.ResourceNames() {
+		allocatable := node.Allocatable.Get(rn)
+		if allocatable == 0 {
+			continue
+		}
+
+		requested := allocatable - node.Idle.Get(rn) + task.Resreq.Get(rn)
+		utilization := 100 * requested / allocatable
+		if utilization > 100 {
+			utilization = 100
+		}
+
+		switch rfp.strategy {
+		case MostAllocated:
+			total += utilization
+		case RequestedToCapacityRatio:
+			total += shapeScore(rfp.shape, utilization)
+		case FillThenSpread:
+			total += fillThenSpreadScore(rfp.threshold, utilization)
+		default:
+			total += 100 - utilization
+		}
+		count++
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return total / float64(count)
+}
+
+// gpuModelScore returns 100 if task requests GPU and node's GPU model matches
+// the preferred model of its job, case-insensitively as part of the label,
+// e.g. "a100" matches "NVIDIA-A100-SXM4-40GB"; otherwise it's 0.
+func (rfp *resourceFitPlugin) gpuModelScore(job *api.JobInfo, task *api.TaskInfo, node *api.NodeInfo) float64 {
+	if job == nil || task.Resreq.MilliGPU == 0 || node.Node == nil {
+		return 0
+	}
+
+	model := strings.ToLower(strings.TrimSpace(job.Annotations()[arbv1.GPUModelAnnotationKey]))
+	if len(model) == 0 {
+		return 0
+	}
+
+	if strings.Contains(strings.ToLower(node.Node.Labels[rfp.gpuModelLabel]), model) {
+		return 100
+	}
+
+	return 0
+}
+
+func (rfp *resourceFitPlugin) OnSessionOpen(ssn *framework.Session) {
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		return rfp.score(task, node), nil
+	})
+
+	// The preferred GPU model is scored apart from utilization, so it's
+	// weighed as much as the fit of resource after normalization.
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		return rfp.gpuModelScore(ssn.JobIndex[task.Job], task, node), nil
+	})
+}
+
+func (rfp *resourceFitPlugin) OnSessionClose(ssn *framework.Session) {}
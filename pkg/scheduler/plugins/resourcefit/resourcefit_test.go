@@ -0,0 +1,229 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcefit
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func TestStrategies(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name     string
+		args     framework.Arguments
+		expected []string
+	}{
+		{
+			name:     "LeastAllocated by default",
+			expected: []string{"n1", "n2", "n3"},
+		},
+		{
+			name:     "MostAllocated",
+			args:     framework.Arguments{Strategy: MostAllocated},
+			expected: []string{"n3", "n2", "n1"},
+		},
+		{
+			name:     "RequestedToCapacityRatio of default shape bin-packs",
+			args:     framework.Arguments{Strategy: RequestedToCapacityRatio},
+			expected: []string{"n3", "n2", "n1"},
+		},
+		{
+			name:     "RequestedToCapacityRatio peaked at 60% utilization",
+			args:     framework.Arguments{Strategy: RequestedToCapacityRatio, Shape: "0:0,60:10,100:0"},
+			expected: []string{"n2", "n3", "n1"},
+		},
+		{
+			name:     "RequestedToCapacityRatio of invalid shape bin-packs",
+			args:     framework.Arguments{Strategy: RequestedToCapacityRatio, Shape: "0:0,60:20"},
+			expected: []string{"n3", "n2", "n1"},
+		},
+		{
+			name:     "FillThenSpread packs below 80% by default, the node beyond it goes last",
+			args:     framework.Arguments{Strategy: FillThenSpread},
+			expected: []string{"n2", "n1", "n3"},
+		},
+		{
+			name:     "FillThenSpread of 90% packs all nodes",
+			args:     framework.Arguments{Strategy: FillThenSpread, Threshold: "90"},
+			expected: []string{"n3", "n2", "n1"},
+		},
+		{
+			name:     "FillThenSpread of 50% spreads among the nodes beyond it",
+			args:     framework.Arguments{Strategy: FillThenSpread, Threshold: "50"},
+			expected: []string{"n1", "n2", "n3"},
+		},
+		{
+			name:     "FillThenSpread of invalid threshold is of 80%",
+			args:     framework.Arguments{Strategy: FillThenSpread, Threshold: "150"},
+			expected: []string{"n2", "n1", "n3"},
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		}
+
+		// After placing the task, the nodes are used by 12.5%, 62.5% and 87.5%.
+		for _, name := range []string{"n1", "n2", "n3"} {
+			schedulerCache.AddNode(testutil.BuildNode(name, testutil.BuildResourceList("8", "16Gi")))
+		}
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "n2", v1.PodRunning, testutil.BuildResourceList("4", "8Gi"), owner1))
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p2", "n3", v1.PodRunning, testutil.BuildResourceList("6", "12Gi"), owner1))
+		schedulerCache.AddPod(testutil.BuildPod("c2", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "2Gi"), owner2))
+		for _, owner := range []metav1.OwnerReference{owner1, owner2} {
+			schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					OwnerReferences: []metav1.OwnerReference{owner},
+				},
+			})
+		}
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName, Arguments: test.args}})
+
+		task := ssn.JobIndex["owner2"].Tasks["c2-p1"]
+		scores := ssn.NodeScores(task, ssn.Nodes)
+
+		var ranking []string
+		for name := range scores {
+			ranking = append(ranking, name)
+		}
+		sort.Slice(ranking, func(i, j int) bool {
+			return scores[ranking[i]] > scores[ranking[j]]
+		})
+
+		if !reflect.DeepEqual(ranking, test.expected) {
+			t.Errorf("case %s: expected ranking %v, got %v (%v)", test.name, test.expected, ranking, scores)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestShapeScore(t *testing.T) {
+	shape, err := parseShape("100:0, 0:10")
+	if err != nil {
+		t.Fatalf("failed to parse shape: %v", err)
+	}
+
+	for utilization, expected := range map[float64]float64{0: 100, 25: 75, 100: 0} {
+		if got := shapeScore(shape, utilization); got != expected {
+			t.Errorf("expected score %v of utilization %v, got %v", expected, utilization, got)
+		}
+	}
+
+	for _, s := range []string{"0:0,0:10", "0-10", "120:0"} {
+		if _, err := parseShape(s); err == nil {
+			t.Errorf("expected shape <%v> invalid", s)
+		}
+	}
+}
+
+func TestFillThenSpreadScore(t *testing.T) {
+	for _, test := range []struct {
+		threshold   float64
+		utilization float64
+		expected    float64
+	}{
+		{threshold: 80, utilization: 0, expected: 50},
+		{threshold: 80, utilization: 40, expected: 75},
+		{threshold: 80, utilization: 80, expected: 100},
+		{threshold: 80, utilization: 90, expected: 25},
+		{threshold: 80, utilization: 100, expected: 0},
+		{threshold: 0, utilization: 0, expected: 100},
+		{threshold: 0, utilization: 50, expected: 25},
+		{threshold: 100, utilization: 100, expected: 100},
+	} {
+		if got := fillThenSpreadScore(test.threshold, test.utilization); got != test.expected {
+			t.Errorf("expected score %v of utilization %v within threshold %v, got %v",
+				test.expected, test.utilization, test.threshold, got)
+		}
+	}
+}
+
+func TestGPUModel(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	owner := testutil.BuildOwnerReference("owner1")
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: testutil.NewFakeBinder(),
+	}
+
+	// The nodes of one GPU differ only in GPU model.
+	for name, model := range map[string]string{"n1": "NVIDIA-A100-SXM4-40GB", "n2": "Tesla-V100-SXM2-16GB"} {
+		alloc := testutil.BuildResourceList("8", "16Gi")
+		alloc[api.GPUResourceName] = resource.MustParse("1")
+		node := testutil.BuildNode(name, alloc)
+		node.Labels = map[string]string{defaultGPUModelLabel: model}
+		schedulerCache.AddNode(node)
+	}
+	for _, name := range []string{"p1", "p2"} {
+		req := testutil.BuildResourceList("1", "1Gi")
+		req[api.GPUResourceName] = resource.MustParse("1")
+		schedulerCache.AddPod(testutil.BuildPod("c1", name, "", v1.PodPending, req, owner))
+	}
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations:     map[string]string{arbv1.GPUModelAnnotationKey: "a100"},
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	})
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+	defer framework.CloseSession(ssn)
+
+	task := ssn.JobIndex["owner1"].Tasks["c1-p1"]
+	if scores := ssn.NodeScores(task, ssn.Nodes); scores["n1"] <= scores["n2"] {
+		t.Errorf("expected the node of preferred GPU model scored higher, got %v", scores)
+	}
+
+	// The first task takes the GPU of the preferred node, the other one
+	// still runs on the node of another model.
+	allocate.New().Execute(ssn)
+
+	hosts := []string{}
+	for _, task := range ssn.JobIndex["owner1"].Tasks {
+		hosts = append(hosts, task.NodeName)
+	}
+	sort.Strings(hosts)
+	if !reflect.DeepEqual(hosts, []string{"n1", "n2"}) {
+		t.Errorf("expected tasks placed on n1 and n2, got %v", hosts)
+	}
+}
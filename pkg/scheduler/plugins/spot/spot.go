@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spot
+
+import (
+	"math"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "spot"
+
+const (
+	// Label is the label of spot nodes, i.e. the preemptible nodes that the
+	// cloud provider may reclaim at any time.
+	Label = "label"
+
+	// Value is the value of label of spot nodes.
+	Value = "value"
+
+	// CriticalPriority is the lowest priority of the tasks that avoid spot
+	// nodes; all tasks avoid them by default.
+	CriticalPriority = "criticalPriority"
+)
+
+const (
+	defaultLabel = "node.kubernetes.io/lifecycle"
+	defaultValue = "spot"
+)
+
+type spotPlugin struct {
+	label            string
+	value            string
+	criticalPriority int
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	sp := &spotPlugin{
+		label:            defaultLabel,
+		value:            defaultValue,
+		criticalPriority: math.MinInt32,
+	}
+
+	if label, found := args[Label]; found && len(label) != 0 {
+		sp.label = label
+	}
+	if value, found := args[Value]; found && len(value) != 0 {
+		sp.value = value
+	}
+	args.GetInt(&sp.criticalPriority, CriticalPriority)
+
+	return sp
+}
+
+func (sp *spotPlugin) Name() string {
+	return PluginName
+}
+
+// isSpot returns whether node is a spot node by its label.
+func (sp *spotPlugin) isSpot(node *api.NodeInfo) bool {
+	return node != nil && node.Node != nil && node.Node.Labels[sp.label] == sp.value
+}
+
+func (sp *spotPlugin) OnSessionOpen(ssn *framework.Session) {
+	// The spot nodes are penalized for critical tasks, but not forbidden:
+	// the task still goes to a spot node if no other node fits.
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		if int(task.Priority) < sp.criticalPriority || !sp.isSpot(node) {
+			return 0, nil
+		}
+		return -1, nil
+	})
+
+	// The victims on spot nodes are preferred for preemption and reclaim,
+	// as they're expected to be disrupted anyway.
+	ssn.AddVictimOrderFn(func(preemptor, l, r *api.TaskInfo) int {
+		lSpot, rSpot := sp.isSpot(ssn.NodeIndex[l.NodeName]), sp.isSpot(ssn.NodeIndex[r.NodeName])
+		if lSpot == rSpot {
+			return 0
+		}
+		if lSpot {
+			return -1
+		}
+		return 1
+	})
+}
+
+func (sp *spotPlugin) OnSessionClose(ssn *framework.Session) {}
@@ -0,0 +1,200 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spot
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/preempt"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/reclaim"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/drf"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func buildNode(name, lifecycle string, alloc v1.ResourceList) *v1.Node {
+	return testutil.BuildNodeWithLabels(name, alloc, map[string]string{defaultLabel: lifecycle})
+}
+
+func buildPod(ns, n, nn string, p v1.PodPhase, req v1.ResourceList, owner metav1.OwnerReference, priority int32) *v1.Pod {
+	pod := testutil.BuildPod(ns, n, nn, p, req, owner)
+	pod.Spec.Priority = &priority
+	return pod
+}
+
+func TestSpotPenalty(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name        string
+		onDemandCPU string
+		expected    string
+	}{
+		{
+			name:        "the critical pod avoids the spot node",
+			onDemandCPU: "2",
+			expected:    "n2",
+		},
+		{
+			name:        "the critical pod goes to the spot node if no other fits",
+			onDemandCPU: "500m",
+			expected:    "n1",
+		},
+	}
+
+	for _, test := range tests {
+		owner := testutil.BuildOwnerReference("owner1")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		}
+
+		schedulerCache.AddNode(buildNode("n1", defaultValue, testutil.BuildResourceList("2", "4Gi")))
+		schedulerCache.AddNode(buildNode("n2", "normal", testutil.BuildResourceList(test.onDemandCPU, "4Gi")))
+		schedulerCache.AddPod(buildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner, 1000))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{Name: PluginName, Arguments: framework.Arguments{CriticalPriority: "100"}},
+		})
+
+		allocate.New().Execute(ssn)
+
+		task := ssn.JobIndex["owner1"].Tasks["c1-p1"]
+		if task.NodeName != test.expected {
+			t.Errorf("case %s: expected task placed on %s, got %q", test.name, test.expected, task.NodeName)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestSpotPenaltyNonCritical(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	owner := testutil.BuildOwnerReference("owner1")
+
+	schedulerCache := &cache.SchedulerCache{
+		Nodes: make(map[string]*api.NodeInfo),
+		Jobs:  make(map[api.JobID]*api.JobInfo),
+	}
+
+	schedulerCache.AddNode(buildNode("n1", defaultValue, testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddNode(buildNode("n2", "normal", testutil.BuildResourceList("2", "4Gi")))
+	schedulerCache.AddPod(buildPod("c1", "critical", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner, 1000))
+	schedulerCache.AddPod(buildPod("c1", "batch", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner, 10))
+	schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+
+	ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+		{Name: PluginName, Arguments: framework.Arguments{CriticalPriority: "100"}},
+	})
+	defer framework.CloseSession(ssn)
+
+	job := ssn.JobIndex["owner1"]
+	spot, normal := ssn.NodeIndex["n1"], ssn.NodeIndex["n2"]
+
+	// Only the critical task is penalized on the spot node.
+	for _, test := range []struct {
+		task     string
+		node     *api.NodeInfo
+		expected float64
+	}{
+		{task: "c1-critical", node: spot, expected: -1},
+		{task: "c1-critical", node: normal, expected: 0},
+		{task: "c1-batch", node: spot, expected: 0},
+		{task: "c1-batch", node: normal, expected: 0},
+	} {
+		score, err := ssn.NodeOrderFn(job.Tasks[api.TaskID(test.task)], test.node)
+		if err != nil || score != test.expected {
+			t.Errorf("expected score %v of %s on %s, got %v, %v", test.expected, test.task, test.node.Name, score, err)
+		}
+	}
+}
+
+func TestSpotVictims(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	framework.RegisterPluginBuilder(drf.PluginName, drf.New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name   string
+		action framework.Action
+	}{
+		{
+			name:   "preempt",
+			action: preempt.New(),
+		},
+		{
+			// owner1 uses 2 of 2 CPUs, 1 CPU over its deserved share.
+			name:   "reclaim",
+			action: reclaim.New(),
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		evictor := &testutil.FakeEvictor{}
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:   make(map[string]*api.NodeInfo),
+			Jobs:    make(map[api.JobID]*api.JobInfo),
+			Evictor: evictor,
+		}
+
+		// The victims are the same but the one on spot node n1.
+		schedulerCache.AddNode(buildNode("n1", defaultValue, testutil.BuildResourceList("1", "4Gi")))
+		schedulerCache.AddNode(buildNode("n2", "normal", testutil.BuildResourceList("1", "4Gi")))
+		for i := 0; i < 2; i++ {
+			node := "n2"
+			if i == 0 {
+				node = "n1"
+			}
+			schedulerCache.AddPod(buildPod("c1", fmt.Sprintf("p%d", i), node, v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1, 1))
+		}
+		schedulerCache.AddPod(buildPod("c2", "preemptor", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner2, 1))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner1))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner2))
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{Name: drf.PluginName},
+			{Name: PluginName},
+		})
+
+		test.action.Execute(ssn)
+		framework.CloseSession(ssn)
+
+		schedulerCache.WaitForInflight(3 * time.Second)
+		evictor.Lock()
+		if len(evictor.Evicts) != 1 || evictor.Evicts[0] != "c1/p0" {
+			t.Errorf("case %s: expected only c1/p0 on spot node evicted, got %v", test.name, evictor.Evicts)
+		}
+		evictor.Unlock()
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spread
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "spread"
+
+// The policies of SpreadReplicasAnnotationKey.
+const (
+	// RequiredPolicy forbids two tasks of the job on one node.
+	RequiredPolicy = "required"
+	// PreferredPolicy prefers the nodes with fewer tasks of the job.
+	PreferredPolicy = "preferred"
+)
+
+type spreadPlugin struct {
+	// The spread policy of each job in this session.
+	policies map[api.JobID]string
+
+	// The node label of the topology domain that the tasks of each job share.
+	topologies map[api.JobID]string
+
+	// The domains of the jobs in topologies.
+	domains *domainIndex
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	return &spreadPlugin{
+		policies:   map[api.JobID]string{},
+		topologies: map[api.JobID]string{},
+		domains:    newDomainIndex(),
+	}
+}
+
+func (sp *spreadPlugin) Name() string {
+	return PluginName
+}
+
+// jobTasksOnNode returns the number of job's tasks on node, except the
+// releasing ones.
+func jobTasksOnNode(job api.JobID, node *api.NodeInfo) int {
+	count := 0
+	for _, task := range node.Tasks {
+		if task.Job == job && task.Status != api.Releasing {
+			count++
+		}
+	}
+	return count
+}
+
+// domainIndex counts the tasks of the jobs sharing a topology domain by
+// the value of the label of the nodes they're on, except the releasing
+// ones, including the placements of this session; it's built at session
+// open and updated by the allocate and evict events, so the domain of job
+// is not counted from all its tasks for every predicate.
+type domainIndex struct {
+	// The number of tasks by job and the value of its label.
+	counts map[api.JobID]map[string]int
+
+	// The value of label of the node where each counted task is, as the
+	// task may have left the node when it's removed, e.g. unpipelined.
+	domains map[api.TaskID]string
+}
+
+func newDomainIndex() *domainIndex {
+	return &domainIndex{
+		counts:  map[api.JobID]map[string]int{},
+		domains: map[api.TaskID]string{},
+	}
+}
+
+// add counts task on node into the domain of node by label.
+func (di *domainIndex) add(task *api.TaskInfo, node *api.NodeInfo, label string) {
+	di.remove(task)
+
+	if task.Status == api.Releasing || node.Node == nil {
+		return
+	}
+	domain, found := node.Node.Labels[label]
+	if !found {
+		return
+	}
+
+	if _, found := di.counts[task.Job]; !found {
+		di.counts[task.Job] = map[string]int{}
+	}
+	di.counts[task.Job][domain]++
+	di.domains[task.UID] = domain
+}
+
+// remove drops task from the domain it was counted in.
+func (di *domainIndex) remove(task *api.TaskInfo) {
+	domain, found := di.domains[task.UID]
+	if !found {
+		return
+	}
+	delete(di.domains, task.UID)
+
+	if di.counts[task.Job][domain]--; di.counts[task.Job][domain] == 0 {
+		delete(di.counts[task.Job], domain)
+	}
+}
+
+// domain returns the domain of job: the one of the most tasks if
+// they're in several, and false if none placed.
+func (di *domainIndex) domain(job api.JobID) (string, bool) {
+	domain, max := "", 0
+	for d, c := range di.counts[job] {
+		if c > max || (c == max && d < domain) {
+			domain, max = d, c
+		}
+	}
+
+	return domain, max != 0
+}
+
+func (sp *spreadPlugin) OnSessionOpen(ssn *framework.Session) {
+	for _, job := range ssn.Jobs {
+		policy, found := job.Annotations()[arbv1.SpreadReplicasAnnotationKey]
+		if !found {
+			continue
+		}
+
+		switch policy {
+		case RequiredPolicy, PreferredPolicy:
+			sp.policies[job.UID] = policy
+		default:
+			glog.Warningf("Invalid spread policy <%v> of Job <%v:%v/%v>, ignore it.",
+				policy, job.UID, job.Namespace, job.Name)
+		}
+	}
+
+	for _, job := range ssn.Jobs {
+		label := job.Annotations()[arbv1.SameTopologyAnnotationKey]
+		if len(label) == 0 {
+			continue
+		}
+
+		sp.topologies[job.UID] = label
+		for _, task := range job.Tasks {
+			if node, found := ssn.NodeIndex[task.NodeName]; found {
+				sp.domains.add(task, node, label)
+			}
+		}
+	}
+
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		label, found := sp.topologies[task.Job]
+		if !found {
+			return nil
+		}
+
+		if node.Node == nil {
+			return fmt.Errorf("node <%v> has no label <%v>", node.Name, label)
+		}
+		value, found := node.Node.Labels[label]
+		if !found {
+			return fmt.Errorf("node <%v> has no label <%v>", node.Name, label)
+		}
+
+		if domain, found := sp.domains.domain(task.Job); found && domain != value {
+			return fmt.Errorf("node <%v> is not in <%v=%v> of Job <%v>", node.Name, label, domain, task.Job)
+		}
+
+		return nil
+	})
+
+	if len(sp.topologies) != 0 {
+		ssn.AddEventHandler(&framework.EventHandler{
+			AllocateFunc: func(event *framework.Event) {
+				label, found := sp.topologies[event.Task.Job]
+				if !found {
+					return
+				}
+				if node, found := ssn.NodeIndex[event.Task.NodeName]; found {
+					sp.domains.add(event.Task, node, label)
+				}
+			},
+			EvictFunc: func(event *framework.Event) {
+				sp.domains.remove(event.Task)
+			},
+		})
+	}
+
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		if sp.policies[task.Job] != RequiredPolicy {
+			return nil
+		}
+
+		if jobTasksOnNode(task.Job, node) != 0 {
+			return fmt.Errorf("node <%v> already has a task of Job <%v>", node.Name, task.Job)
+		}
+
+		return nil
+	})
+
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		if sp.policies[task.Job] != PreferredPolicy {
+			return 0, nil
+		}
+
+		job, found := ssn.JobIndex[task.Job]
+		if !found {
+			return 0, nil
+		}
+
+		// The fewer tasks of the job on node, the higher score.
+		return float64(len(job.Tasks) - jobTasksOnNode(task.Job, node)), nil
+	})
+}
+
+func (sp *spreadPlugin) OnSessionClose(ssn *framework.Session) {
+	sp.policies = map[api.JobID]string{}
+	sp.topologies = map[api.JobID]string{}
+	sp.domains = newDomainIndex()
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spread
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func TestSpreadReplicas(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name     string
+		policy   string
+		nodes    int
+		assigned int
+		distinct int
+	}{
+		{
+			name:     "no spread",
+			nodes:    3,
+			assigned: 3,
+			distinct: 1,
+		},
+		{
+			name:     "required spread",
+			policy:   RequiredPolicy,
+			nodes:    3,
+			assigned: 3,
+			distinct: 3,
+		},
+		{
+			name:     "preferred spread",
+			policy:   PreferredPolicy,
+			nodes:    3,
+			assigned: 3,
+			distinct: 3,
+		},
+		{
+			name:     "required spread with less nodes",
+			policy:   RequiredPolicy,
+			nodes:    2,
+			assigned: 2,
+			distinct: 2,
+		},
+		{
+			name:     "preferred spread with less nodes",
+			policy:   PreferredPolicy,
+			nodes:    2,
+			assigned: 3,
+			distinct: 2,
+		},
+	}
+
+	for i, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		}
+
+		// Each node can hold all tasks of the job.
+		for j := 0; j < test.nodes; j++ {
+			schedulerCache.AddNode(testutil.BuildNode(fmt.Sprintf("n%d", j), testutil.BuildResourceList("4", "8Gi")))
+		}
+
+		owner := testutil.BuildOwnerReference("owner1")
+		for j := 0; j < 3; j++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", j), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+		}
+
+		spec := &arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+		}
+		if len(test.policy) != 0 {
+			spec.Annotations = map[string]string{arbv1.SpreadReplicasAnnotationKey: test.policy}
+		}
+		schedulerCache.AddSchedulingSpec(spec)
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+
+		allocate.New().Execute(ssn)
+
+		assigned := 0
+		nodes := map[string]bool{}
+		for _, task := range ssn.JobIndex["owner1"].Tasks {
+			if len(task.NodeName) != 0 {
+				assigned++
+				nodes[task.NodeName] = true
+			}
+		}
+
+		if assigned != test.assigned || len(nodes) != test.distinct {
+			t.Errorf("case %d (%s): expected %d tasks on %d nodes, got %d tasks on %d nodes",
+				i, test.name, test.assigned, test.distinct, assigned, len(nodes))
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestSameTopology(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	const zoneLabel = "topology.kubernetes.io/zone"
+
+	tests := []struct {
+		name     string
+		topology string
+		// The node where the first task is running, if any.
+		node  string
+		zones int
+		// The zone of the tasks, if expected in one.
+		zone string
+	}{
+		{
+			name:  "the spread tasks are across zones",
+			zones: 2,
+		},
+		{
+			name:     "the tasks of same topology are in one zone",
+			topology: zoneLabel,
+			zones:    1,
+		},
+		{
+			name:     "the tasks of same topology are in the zone of the running one",
+			topology: zoneLabel,
+			node:     "n3",
+			zones:    1,
+			zone:     "z1",
+		},
+	}
+
+	for i, test := range tests {
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: testutil.NewFakeBinder(),
+		}
+
+		// Two zones of two nodes, each zone can hold all tasks of the job.
+		for j := 0; j < 4; j++ {
+			node := testutil.BuildNode(fmt.Sprintf("n%d", j), testutil.BuildResourceList("2", "4Gi"))
+			node.Labels = map[string]string{zoneLabel: fmt.Sprintf("z%d", j/2)}
+			schedulerCache.AddNode(node)
+		}
+
+		// The gang of 4 tasks.
+		owner := testutil.BuildOwnerReference("owner1")
+		for j := 0; j < 4; j++ {
+			node, phase := "", v1.PodPending
+			if j == 0 && len(test.node) != 0 {
+				node, phase = test.node, v1.PodRunning
+			}
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", j), node, phase, testutil.BuildResourceList("1", "1Gi"), owner))
+		}
+
+		spec := &arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+				Annotations:     map[string]string{arbv1.SpreadReplicasAnnotationKey: PreferredPolicy},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 4,
+			},
+		}
+		if len(test.topology) != 0 {
+			spec.Annotations[arbv1.SameTopologyAnnotationKey] = test.topology
+		}
+		schedulerCache.AddSchedulingSpec(spec)
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+
+		allocate.New().Execute(ssn)
+
+		assigned := 0
+		zones := map[string]bool{}
+		for _, task := range ssn.JobIndex["owner1"].Tasks {
+			if len(task.NodeName) != 0 {
+				assigned++
+				zones[ssn.NodeIndex[task.NodeName].Node.Labels[zoneLabel]] = true
+			}
+		}
+
+		if assigned != 4 || len(zones) != test.zones {
+			t.Errorf("case %d (%s): expected 4 tasks in %d zones, got %d tasks in %d zones",
+				i, test.name, test.zones, assigned, len(zones))
+		}
+		if len(test.zone) != 0 && !zones[test.zone] {
+			t.Errorf("case %d (%s): expected tasks in zone %v, got %v", i, test.name, test.zone, zones)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
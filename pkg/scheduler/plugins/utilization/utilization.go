@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utilization
+
+import (
+	"math"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "utilization"
+
+const (
+	// Threshold is the argument of the utilization in percent, e.g. "70",
+	// above which a node is hot, by the real-time metrics of any of its CPU
+	// and memory.
+	Threshold = "threshold"
+)
+
+const defaultThreshold = 70.0
+
+type utilizationPlugin struct {
+	threshold float64
+
+	// The utilization in percent of the nodes whose metrics are available
+	// in this session, by node name.
+	utilization map[string]float64
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	up := &utilizationPlugin{
+		threshold:   defaultThreshold,
+		utilization: map[string]float64{},
+	}
+
+	args.GetFloat64(&up.threshold, Threshold)
+	if up.threshold < 0 || up.threshold > 100 {
+		glog.Warningf("Invalid threshold <%v> of %v, use <%v> instead", up.threshold, PluginName, defaultThreshold)
+		up.threshold = defaultThreshold
+	}
+
+	return up
+}
+
+func (up *utilizationPlugin) Name() string {
+	return PluginName
+}
+
+// nodeUtilization returns the highest utilization in percent of CPU and
+// memory of node.
+func nodeUtilization(usage map[v1.ResourceName]float64) float64 {
+	u := math.Max(usage[v1.ResourceCPU], usage[v1.ResourceMemory])
+	return math.Min(math.Max(u, 0), 1) * 100
+}
+
+func (up *utilizationPlugin) OnSessionOpen(ssn *framework.Session) {
+	// The metrics are read once per session; the nodes without metrics are
+	// scored as usual by requests.
+	for _, node := range ssn.Nodes {
+		usage, err := ssn.NodeUtilization(node)
+		if err != nil {
+			glog.V(4).Infof("No utilization of node <%v>: %v", node.Name, err)
+			continue
+		}
+		up.utilization[node.Name] = nodeUtilization(usage)
+	}
+
+	if len(up.utilization) == 0 {
+		glog.V(3).Infof("No utilization metrics of nodes, %v is skipped", PluginName)
+		return
+	}
+
+	// The hot nodes are penalized by how far they're above threshold, even
+	// if their requests look fine; they're still used if nothing else fits.
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		u, found := up.utilization[node.Name]
+		if !found || u <= up.threshold {
+			return 0, nil
+		}
+		return up.threshold - u, nil
+	})
+}
+
+func (up *utilizationPlugin) OnSessionClose(ssn *framework.Session) {
+	up.utilization = map[string]float64{}
+}
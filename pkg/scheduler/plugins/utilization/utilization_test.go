@@ -0,0 +1,170 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utilization
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+// fakeMetricsSource returns the CPU utilization of nodes; the nodes not in
+// it have no metrics.
+type fakeMetricsSource struct {
+	cpu map[string]float64
+}
+
+func (fm *fakeMetricsSource) NodeUtilization(node *v1.Node) (map[v1.ResourceName]float64, error) {
+	u, found := fm.cpu[node.Name]
+	if !found {
+		return nil, fmt.Errorf("no metrics of node %v", node.Name)
+	}
+	return map[v1.ResourceName]float64{v1.ResourceCPU: u, v1.ResourceMemory: 0.1}, nil
+}
+
+func TestHotNode(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name    string
+		source  cache.MetricsSource
+		coolCPU string
+		// The number of tasks placed on the hot node n1.
+		hot int
+	}{
+		{
+			name:    "the hot node is deprioritized",
+			source:  &fakeMetricsSource{cpu: map[string]float64{"n1": 0.9, "n2": 0.2}},
+			coolCPU: "4",
+			hot:     0,
+		},
+		{
+			name:    "the hot node is still used if others are full",
+			source:  &fakeMetricsSource{cpu: map[string]float64{"n1": 0.9, "n2": 0.2}},
+			coolCPU: "2",
+			hot:     1,
+		},
+		{
+			name:    "the node without metrics is not penalized",
+			source:  &fakeMetricsSource{cpu: map[string]float64{"n1": 0.9}},
+			coolCPU: "4",
+			hot:     0,
+		},
+	}
+
+	for _, test := range tests {
+		owner := testutil.BuildOwnerReference("owner1")
+
+		binder := testutil.NewFakeBinder()
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:         make(map[string]*api.NodeInfo),
+			Jobs:          make(map[api.JobID]*api.JobInfo),
+			Binder:        binder,
+			MetricsSource: test.source,
+		}
+
+		// Both nodes are empty by requests, but n1 is busy by metrics.
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+		schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList(test.coolCPU, "8Gi")))
+		for i := 0; i < 3; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+		}
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+		})
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+
+		allocate.New().Execute(ssn)
+
+		hot := 0
+		for _, task := range ssn.JobIndex["owner1"].Tasks {
+			if len(task.NodeName) == 0 {
+				t.Errorf("case %s: expected task %v placed", test.name, task.Name)
+			}
+			if task.NodeName == "n1" {
+				hot++
+			}
+		}
+		if hot != test.hot {
+			t.Errorf("case %s: expected %d tasks on hot node, got %d", test.name, test.hot, hot)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestNoMetrics(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	for _, source := range []cache.MetricsSource{nil, &fakeMetricsSource{}} {
+		owner := testutil.BuildOwnerReference("owner1")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:         make(map[string]*api.NodeInfo),
+			Jobs:          make(map[api.JobID]*api.JobInfo),
+			MetricsSource: source,
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p0", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+		})
+
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName}})
+
+		// All nodes are scored as if the plugin is not enabled.
+		task := ssn.JobIndex["owner1"].Tasks["c1-p0"]
+		if score, err := ssn.NodeOrderFn(task, ssn.NodeIndex["n1"]); err != nil || score != 0 {
+			t.Errorf("source %v: expected score 0 without metrics, got %v, %v", source, score, err)
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
+
+func TestNodeUtilization(t *testing.T) {
+	for _, test := range []struct {
+		usage    map[v1.ResourceName]float64
+		expected float64
+	}{
+		{usage: map[v1.ResourceName]float64{v1.ResourceCPU: 0.5, v1.ResourceMemory: 0.8}, expected: 80},
+		{usage: map[v1.ResourceName]float64{v1.ResourceCPU: 1.5}, expected: 100},
+		{usage: map[v1.ResourceName]float64{v1.ResourceMemory: -1}, expected: 0},
+		{usage: map[v1.ResourceName]float64{}, expected: 0},
+	} {
+		if got := nodeUtilization(test.usage); got != test.expected {
+			t.Errorf("expected utilization %v of %v, got %v", test.expected, test.usage, got)
+		}
+	}
+}
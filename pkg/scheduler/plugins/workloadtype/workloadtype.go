@@ -0,0 +1,139 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloadtype
+
+import (
+	"github.com/golang/glog"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "workloadtype"
+
+const (
+	// Label is the label of pods for their workload type, e.g. "batch" or
+	// "latency-sensitive".
+	Label = "label"
+
+	// Policy is how the tasks are placed by workload type: ColocatePolicy
+	// or SegregatePolicy.
+	Policy = "policy"
+)
+
+const (
+	// ColocatePolicy prefers the nodes running the tasks of the same
+	// workload type, and avoids the ones running other types, so that
+	// latency-sensitive tasks are not disturbed by batch ones.
+	ColocatePolicy = "colocate"
+
+	// SegregatePolicy prefers the nodes running the tasks of other workload
+	// types, and avoids the ones running the same type, e.g. to keep the
+	// tasks contending for the same resource apart.
+	SegregatePolicy = "segregate"
+)
+
+const defaultLabel = arbv1.GroupName + "/workload-type"
+
+type workloadTypePlugin struct {
+	label  string
+	policy string
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	wp := &workloadTypePlugin{
+		label:  defaultLabel,
+		policy: ColocatePolicy,
+	}
+
+	if label, found := args[Label]; found && len(label) != 0 {
+		wp.label = label
+	}
+	if policy, found := args[Policy]; found {
+		switch policy {
+		case ColocatePolicy, SegregatePolicy:
+			wp.policy = policy
+		default:
+			glog.Warningf("Invalid policy <%v> of %v, use <%v> instead", policy, PluginName, ColocatePolicy)
+		}
+	}
+
+	return wp
+}
+
+func (wp *workloadTypePlugin) Name() string {
+	return PluginName
+}
+
+// workloadType returns the workload type of task by its label, or "" if
+// it's not labeled.
+func (wp *workloadTypePlugin) workloadType(task *api.TaskInfo) string {
+	if task.Pod == nil {
+		return ""
+	}
+	return task.Pod.Labels[wp.label]
+}
+
+// countTasks returns the number of tasks on node of workload type wt, and of
+// the other types; the tasks not labeled are not counted.
+func (wp *workloadTypePlugin) countTasks(node *api.NodeInfo, wt string) (same, other int) {
+	for _, task := range node.Tasks {
+		// Only the tasks that stay on node, including the ones placed in
+		// this session, are counted; the releasing ones are leaving.
+		if !api.AllocatedStatus(task.Status) && task.Status != api.Pipelined {
+			continue
+		}
+
+		t := wp.workloadType(task)
+		if len(t) == 0 {
+			continue
+		}
+		if t == wt {
+			same++
+		} else {
+			other++
+		}
+	}
+	return
+}
+
+func (wp *workloadTypePlugin) OnSessionOpen(ssn *framework.Session) {
+	// The nodes are scored in [-1, 1] by the fraction of same type in the
+	// labeled tasks on node, so a node of mixed types is between; the nodes
+	// without labeled tasks, or the tasks not labeled, get 0.
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		wt := wp.workloadType(task)
+		if len(wt) == 0 {
+			return 0, nil
+		}
+
+		same, other := wp.countTasks(node, wt)
+		if same+other == 0 {
+			return 0, nil
+		}
+
+		score := float64(same-other) / float64(same+other)
+		if wp.policy == SegregatePolicy {
+			score = -score
+		}
+		return score, nil
+	})
+}
+
+func (wp *workloadTypePlugin) OnSessionClose(ssn *framework.Session) {}
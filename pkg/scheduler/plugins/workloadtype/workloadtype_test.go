@@ -0,0 +1,183 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloadtype
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func buildPod(ns, n, nn string, p v1.PodPhase, req v1.ResourceList, owner metav1.OwnerReference, workloadType string) *v1.Pod {
+	pod := testutil.BuildPod(ns, n, nn, p, req, owner)
+	pod.Labels = map[string]string{}
+	if len(workloadType) != 0 {
+		pod.Labels[defaultLabel] = workloadType
+	}
+	return pod
+}
+
+func TestWorkloadType(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name         string
+		policy       string
+		workloadType string
+		expected     string
+	}{
+		{
+			name:         "batch task goes to the node running batch",
+			workloadType: "batch",
+			expected:     "n2",
+		},
+		{
+			name:         "latency-sensitive task goes to the node running latency-sensitive",
+			workloadType: "latency-sensitive",
+			expected:     "n1",
+		},
+		{
+			name:         "batch task goes to the node running latency-sensitive if segregated",
+			policy:       SegregatePolicy,
+			workloadType: "batch",
+			expected:     "n1",
+		},
+	}
+
+	for _, test := range tests {
+		running := testutil.BuildOwnerReference("running")
+		pending := testutil.BuildOwnerReference("pending")
+
+		binder := testutil.NewFakeBinder()
+		schedulerCache := &cache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: binder,
+		}
+
+		// The task not labeled on n1 is not counted.
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+		schedulerCache.AddNode(testutil.BuildNode("n2", testutil.BuildResourceList("4", "8Gi")))
+		schedulerCache.AddPod(buildPod("c1", "latency", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), running, "latency-sensitive"))
+		schedulerCache.AddPod(buildPod("c1", "batch", "n2", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), running, "batch"))
+		schedulerCache.AddPod(buildPod("c1", "unlabeled", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), running, ""))
+		schedulerCache.AddPod(buildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), pending, test.workloadType))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", running))
+		schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", pending))
+
+		args := framework.Arguments{}
+		if len(test.policy) != 0 {
+			args[Policy] = test.policy
+		}
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{
+			{Name: PluginName, Arguments: args},
+		})
+
+		allocate.New().Execute(ssn)
+		framework.CloseSession(ssn)
+
+		if !schedulerCache.WaitForInflight(3 * time.Second) {
+			t.Fatalf("case %s: binds are not completed in time", test.name)
+		}
+
+		binder.Lock()
+		host := binder.Binds["c1/p1"]
+		binder.Unlock()
+		if host != test.expected {
+			t.Errorf("case %s: expected task bound to %s, got %q", test.name, test.expected, host)
+		}
+	}
+}
+
+func TestNodeScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		node     []string
+		expected float64
+	}{
+		{
+			name:     "no labeled task on node",
+			node:     []string{""},
+			expected: 0,
+		},
+		{
+			name:     "only the same type",
+			node:     []string{"batch", "batch"},
+			expected: 1,
+		},
+		{
+			name:     "only other types",
+			node:     []string{"latency-sensitive", "web"},
+			expected: -1,
+		},
+		{
+			name:     "mixed types",
+			node:     []string{"batch", "batch", "batch", "latency-sensitive"},
+			expected: 0.5,
+		},
+		{
+			name:     "mixed types, segregated",
+			policy:   SegregatePolicy,
+			node:     []string{"batch", "batch", "batch", "latency-sensitive"},
+			expected: -0.5,
+		},
+	}
+
+	for _, test := range tests {
+		owner := testutil.BuildOwnerReference("owner1")
+
+		node := api.NewNodeInfo(testutil.BuildNode("n1", testutil.BuildResourceList("16", "32Gi")))
+		for i, wt := range test.node {
+			pod := buildPod("c1", fmt.Sprintf("p%d", i), "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner, wt)
+			node.AddTask(api.NewTaskInfo(pod))
+		}
+		// The releasing task is leaving, so it's not counted.
+		releasing := buildPod("c1", "releasing", "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner, "latency-sensitive")
+		now := metav1.Now()
+		releasing.DeletionTimestamp = &now
+		node.AddTask(api.NewTaskInfo(releasing))
+
+		task := api.NewTaskInfo(buildPod("c1", "pending", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner, "batch"))
+
+		args := framework.Arguments{}
+		if len(test.policy) != 0 {
+			args[Policy] = test.policy
+		}
+		ssn := &framework.Session{}
+		New(args).OnSessionOpen(ssn)
+
+		score, err := ssn.NodeOrderFn(task, node)
+		if err != nil {
+			t.Errorf("case %s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if score != test.expected {
+			t.Errorf("case %s: expected score %v, got %v", test.name, test.expected, score)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonebalance
+
+import (
+	"github.com/golang/glog"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+)
+
+// PluginName indicates name of the plugin.
+const PluginName = "zonebalance"
+
+const (
+	// Label is the label of nodes whose value is the zone of node.
+	Label = "label"
+
+	// Scope is the tasks whose allocation is balanced across zones: "job"
+	// balances the tasks of the job being scheduled, and "cluster" balances
+	// all tasks.
+	Scope = "scope"
+)
+
+// The values of Scope.
+const (
+	JobScope     = "job"
+	ClusterScope = "cluster"
+)
+
+const defaultLabel = "failure-domain.beta.kubernetes.io/zone"
+
+type zoneBalancePlugin struct {
+	label string
+	scope string
+
+	// The allocatable resource of each zone.
+	allocatable map[string]*api.Resource
+
+	// The resource allocated to the tasks in each zone, of each job and of
+	// all tasks; they're built on demand, and dropped once tasks are placed
+	// or evicted.
+	jobAllocated     map[api.JobID]map[string]*api.Resource
+	clusterAllocated map[string]*api.Resource
+}
+
+func New(args framework.Arguments) framework.Plugin {
+	zbp := &zoneBalancePlugin{
+		label:        defaultLabel,
+		scope:        JobScope,
+		allocatable:  map[string]*api.Resource{},
+		jobAllocated: map[api.JobID]map[string]*api.Resource{},
+	}
+
+	if label, found := args[Label]; found && len(label) != 0 {
+		zbp.label = label
+	}
+
+	switch scope := args[Scope]; scope {
+	case "", JobScope:
+	case ClusterScope:
+		zbp.scope = ClusterScope
+	default:
+		glog.Warningf("Unknown scope <%v> of plugin <%v>, use <%v>", scope, PluginName, JobScope)
+	}
+
+	return zbp
+}
+
+func (zbp *zoneBalancePlugin) Name() string {
+	return PluginName
+}
+
+// zone returns the zone of node; the nodes without the label are in one
+// zone of their own.
+func (zbp *zoneBalancePlugin) zone(node *api.NodeInfo) string {
+	if node == nil || node.Node == nil {
+		return ""
+	}
+	return node.Node.Labels[zbp.label]
+}
+
+// allocated returns the resource allocated to tasks in each zone, except the
+// releasing ones; only the tasks of job are counted if job is not empty.
+func (zbp *zoneBalancePlugin) allocated(ssn *framework.Session, job api.JobID) map[string]*api.Resource {
+	zones := map[string]*api.Resource{}
+	add := func(task *api.TaskInfo) {
+		if len(task.NodeName) == 0 || task.Status == api.Releasing {
+			return
+		}
+		node, found := ssn.NodeIndex[task.NodeName]
+		if !found {
+			return
+		}
+		zone := zbp.zone(node)
+		if _, found := zones[zone]; !found {
+			zones[zone] = api.EmptyResource()
+		}
+		zones[zone].Add(task.Resreq)
+	}
+
+	if len(job) != 0 {
+		if ji, found := ssn.JobIndex[job]; found {
+			for _, task := range ji.Tasks {
+				add(task)
+			}
+		}
+		return zones
+	}
+
+	for _, node := range ssn.Nodes {
+		for _, task := range node.Tasks {
+			add(task)
+		}
+	}
+	return zones
+}
+
+// load returns the dominant share of the allocatable of zone in allocated.
+func (zbp *zoneBalancePlugin) load(zone string, allocated map[string]*api.Resource) float64 {
+	used, found := allocated[zone]
+	if !found {
+		return 0
+	}
+
+	res := 0.0
+	for _, rn := range api.ResourceNames() {
+		total := zbp.allocatable[zone].Get(rn)
+		if total == 0 {
+			continue
+		}
+		if share := used.Get(rn) / total; share > res {
+			res = share
+		}
+	}
+	return res
+}
+
+func (zbp *zoneBalancePlugin) OnSessionOpen(ssn *framework.Session) {
+	for _, node := range ssn.Nodes {
+		zone := zbp.zone(node)
+		if _, found := zbp.allocatable[zone]; !found {
+			zbp.allocatable[zone] = api.EmptyResource()
+		}
+		zbp.allocatable[zone].Add(node.Allocatable)
+	}
+
+	// Nothing to balance in a single zone.
+	if len(zbp.allocatable) < 2 {
+		return
+	}
+
+	// The nodes in the less loaded zones are preferred.
+	ssn.AddNodeOrderFn(func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		var allocated map[string]*api.Resource
+		if zbp.scope == ClusterScope {
+			if zbp.clusterAllocated == nil {
+				zbp.clusterAllocated = zbp.allocated(ssn, "")
+			}
+			allocated = zbp.clusterAllocated
+		} else {
+			if _, found := zbp.jobAllocated[task.Job]; !found {
+				zbp.jobAllocated[task.Job] = zbp.allocated(ssn, task.Job)
+			}
+			allocated = zbp.jobAllocated[task.Job]
+		}
+
+		return -zbp.load(zbp.zone(node), allocated), nil
+	})
+
+	forget := func(event *framework.Event) {
+		delete(zbp.jobAllocated, event.Task.Job)
+		zbp.clusterAllocated = nil
+	}
+	ssn.AddEventHandler(&framework.EventHandler{
+		AllocateFunc: forget,
+		EvictFunc:    forget,
+	})
+}
+
+func (zbp *zoneBalancePlugin) OnSessionClose(ssn *framework.Session) {
+	zbp.allocatable = map[string]*api.Resource{}
+	zbp.jobAllocated = map[api.JobID]map[string]*api.Resource{}
+	zbp.clusterAllocated = nil
+}
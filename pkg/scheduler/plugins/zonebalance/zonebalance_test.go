@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonebalance
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+func buildNode(name, zone string, alloc v1.ResourceList) *v1.Node {
+	return testutil.BuildNodeWithLabels(name, alloc, map[string]string{defaultLabel: zone})
+}
+
+func TestZoneBalance(t *testing.T) {
+	framework.RegisterPluginBuilder(PluginName, New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name  string
+		scope string
+		zone  string
+	}{
+		{
+			name: "the zone with fewer tasks of the job is preferred",
+			zone: "z2",
+		},
+		{
+			name:  "the zone with less allocation of cluster is preferred",
+			scope: ClusterScope,
+			zone:  "z1",
+		},
+	}
+
+	for _, test := range tests {
+		owner1 := testutil.BuildOwnerReference("owner1")
+		owner2 := testutil.BuildOwnerReference("owner2")
+
+		schedulerCache := &cache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		}
+
+		for n, zone := range map[string]string{"n1": "z1", "n2": "z1", "n3": "z2", "n4": "z2"} {
+			schedulerCache.AddNode(buildNode(n, zone, testutil.BuildResourceList("8", "16Gi")))
+		}
+
+		// The job has two tasks in z1, while the cluster is more loaded in z2.
+		for i := 0; i < 2; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("r%d", i), "n1", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner1))
+		}
+		for i := 0; i < 4; i++ {
+			schedulerCache.AddPod(testutil.BuildPod("c2", fmt.Sprintf("r%d", i), "n3", v1.PodRunning, testutil.BuildResourceList("1", "1Gi"), owner2))
+		}
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner1))
+		for _, owner := range []metav1.OwnerReference{owner1, owner2} {
+			schedulerCache.AddSchedulingSpec(testutil.BuildSchedulingSpec("", "", owner))
+		}
+
+		args := framework.Arguments{}
+		if len(test.scope) != 0 {
+			args[Scope] = test.scope
+		}
+		ssn := framework.OpenSession(schedulerCache, []*framework.PluginOption{{Name: PluginName, Arguments: args}})
+
+		task := ssn.JobIndex["owner1"].TaskStatusIndex[api.Pending]["c1-p1"]
+		scores := ssn.NodeScores(task, ssn.Nodes)
+		for _, node := range ssn.Nodes {
+			preferred := node.Node.Labels[defaultLabel] == test.zone
+			if best := scores[node.Name] > 0; best != preferred {
+				t.Errorf("case %s: expected node <%v> preferred %v, got score %v",
+					test.name, node.Name, preferred, scores[node.Name])
+			}
+		}
+
+		framework.CloseSession(ssn)
+	}
+}
@@ -17,36 +17,107 @@ limitations under the License.
 package scheduler
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
 
+	"k8s.io/api/core/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
 
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/client"
 	schedcache "github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
 	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
 )
 
 type Scheduler struct {
 	cache   schedcache.Cache
 	config  *rest.Config
 	actions []framework.Action
+	plugins []*framework.PluginOption
+
+	percentageOfNodesToScore int32
+
+	// The limits of preemption in session, see Session.
+	maxPreemptees          int
+	maxPreemptions         int
+	maxPreemptionRounds    int
+	maxVictimsPerPreemptor int
+	maxVictimsPerJob       int
+
+	// sessionTimeout is the deadline of each session; the actions stop
+	// at their loop boundaries once it's passed. Zero means no deadline.
+	sessionTimeout time.Duration
+
+	// validateSession validates the session after each action, for debugging.
+	validateSession bool
+
+	// shutdownTimeout is how long the scheduler waits for the binds in
+	// flight when it's shut down.
+	shutdownTimeout time.Duration
+
+	// debugSession keeps the state of the latest session for the debug
+	// endpoint, see ServeHTTP.
+	debugSession bool
+
+	mutex       sync.Mutex
+	lastSession *sessionState
+}
+
+const defaultShutdownTimeout = 30 * time.Second
+
+// Options are the options of the Scheduler returned by NewScheduler.
+type Options struct {
+	// The pending pods of SchedulerName are scheduled.
+	SchedulerName string
+
+	// The actions and plugins of sessions, by name; the PluginArgs are
+	// parsed by buildPluginOptions.
+	Actions    []string
+	Plugins    []string
+	PluginArgs []string
+
+	PercentageOfNodesToScore int32
+
+	// The limits of preemption in session, see Session.
+	MaxPreemptees          int
+	MaxPreemptions         int
+	MaxPreemptionRounds    int
+	MaxVictimsPerPreemptor int
+	MaxVictimsPerJob       int
+
+	// The options of the cache, see schedcache.Options; the DefaultRequests
+	// are parsed by buildDefaultRequests.
+	APIQPS            float32
+	APIBurst          int
+	PodStartSLO       time.Duration
+	DefaultRequests   []string
+	NominationTimeout time.Duration
+
+	// SessionTimeout is the deadline of each session, or zero for none.
+	SessionTimeout time.Duration
+
+	// ValidateSession validates the session after each action.
+	ValidateSession bool
+
+	// DebugSession keeps the state of the latest session for ServeHTTP.
+	DebugSession bool
 }
 
-func NewScheduler(
-	config *rest.Config,
-	schedulerName string,
-	actionNames []string,
-) (*Scheduler, error) {
+// NewScheduler returns a Scheduler of the API server of config.
+func NewScheduler(config *rest.Config, opts *Options) (*Scheduler, error) {
 
 	var actions []framework.Action
 
-	for _, name := range actionNames {
+	for _, name := range opts.Actions {
 		act, found := framework.GetAction(name)
 		if found {
 			actions = append(actions, act)
@@ -55,38 +126,207 @@ func NewScheduler(
 		}
 	}
 
+	plugins, err := buildPluginOptions(opts.Plugins, opts.PluginArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := buildDefaultRequests(opts.DefaultRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheOpts := &schedcache.Options{
+		SchedulerName:     opts.SchedulerName,
+		APIQPS:            opts.APIQPS,
+		APIBurst:          opts.APIBurst,
+		StartLatencySLO:   opts.PodStartSLO,
+		DefaultRequests:   requests,
+		NominationTimeout: opts.NominationTimeout,
+	}
+
 	scheduler := &Scheduler{
 		config:  config,
-		cache:   schedcache.New(config, schedulerName),
+		cache:   schedcache.New(config, cacheOpts),
 		actions: actions,
+		plugins: plugins,
+
+		percentageOfNodesToScore: opts.PercentageOfNodesToScore,
+		maxPreemptees:            opts.MaxPreemptees,
+		maxPreemptions:           opts.MaxPreemptions,
+		maxPreemptionRounds:      opts.MaxPreemptionRounds,
+		maxVictimsPerPreemptor:   opts.MaxVictimsPerPreemptor,
+		maxVictimsPerJob:         opts.MaxVictimsPerJob,
+		sessionTimeout:           opts.SessionTimeout,
+		validateSession:          opts.ValidateSession,
+		shutdownTimeout:          defaultShutdownTimeout,
+		debugSession:             opts.DebugSession,
 	}
 
 	return scheduler, nil
 }
 
-func (pc *Scheduler) Run(stopCh <-chan struct{}) {
+// Run schedules in sessions until ctx is cancelled; then it shuts down after
+// the current session is closed, so it returns when the binds of scheduled
+// tasks are completed or timed out.
+func (pc *Scheduler) Run(ctx context.Context) {
 	createSchedulingSpecKind(pc.config)
 
 	// Start cache for policy.
-	go pc.cache.Run(stopCh)
-	pc.cache.WaitForCacheSync(stopCh)
+	go pc.cache.Run(ctx.Done())
+	pc.cache.WaitForCacheSync(ctx.Done())
 
-	go wait.Until(pc.runOnce, 1*time.Second, stopCh)
+	wait.Until(func() { pc.runOnce(ctx) }, 1*time.Second, ctx.Done())
+
+	pc.shutdown()
+}
+
+// shutdown waits for the binds and evictions in flight, up to shutdownTimeout.
+func (pc *Scheduler) shutdown() {
+	glog.V(3).Infof("Shutting down scheduler, waiting %v for binds in flight ...", pc.shutdownTimeout)
+
+	if !pc.cache.WaitForInflight(pc.shutdownTimeout) {
+		glog.Errorf("Binds in flight are not completed in %v", pc.shutdownTimeout)
+	}
 }
 
-func (pc *Scheduler) runOnce() {
+func (pc *Scheduler) runOnce(ctx context.Context) {
 	glog.V(4).Infof("Start scheduling ...")
 	defer glog.V(4).Infof("End scheduling ...")
 
-	ssn := framework.OpenSession(pc.cache)
+	sctx := ctx
+	if pc.sessionTimeout > 0 {
+		var cancel context.CancelFunc
+		sctx, cancel = context.WithTimeout(ctx, pc.sessionTimeout)
+		defer cancel()
+	}
+
+	ssn := framework.OpenSession(pc.cache, pc.plugins, func(ssn *framework.Session) {
+		ssn.Context = sctx
+		ssn.PercentageOfNodesToScore = pc.percentageOfNodesToScore
+		ssn.MaxPreemptees = pc.maxPreemptees
+		ssn.MaxPreemptions = pc.maxPreemptions
+		ssn.MaxPreemptionRounds = pc.maxPreemptionRounds
+		ssn.MaxVictimsPerPreemptor = pc.maxVictimsPerPreemptor
+		ssn.MaxVictimsPerJob = pc.maxVictimsPerJob
+	})
 	defer framework.CloseSession(ssn)
 
 	for _, action := range pc.actions {
+		// Skip the rest actions if shutting down or timed out; the decisions
+		// of executed actions are committed, as the binds of ready jobs are
+		// sent together.
+		if ctx.Err() != nil {
+			glog.V(3).Infof("Scheduler is shutting down, skip action %s", action.Name())
+			break
+		}
+		if sctx.Err() != nil {
+			glog.V(3).Infof("Session %v is timed out, skip action %s", ssn.ID, action.Name())
+			break
+		}
+
 		action.Execute(ssn)
+
+		if pc.validateSession {
+			if err := ssn.Validate(); err != nil {
+				glog.Errorf("Session is invalid after action %s: %v", action.Name(), err)
+			}
+		}
+	}
+
+	if ctx.Err() == nil && sctx.Err() != nil {
+		glog.Warningf("Session %v timed out after %v", ssn.ID, pc.sessionTimeout)
+		metrics.SessionTimeouts.Add(1)
+	}
+
+	frag := updateFragmentationMetrics(ssn.Nodes)
+
+	if pc.debugSession {
+		state := newSessionState(ssn)
+		state.Fragmentation = frag
+
+		pc.mutex.Lock()
+		pc.lastSession = state
+		pc.mutex.Unlock()
+	}
+}
+
+// buildPluginOptions builds the options of enabled plugins; the format of
+// plugin's argument is <plugin>.<key>=<value>.
+func buildPluginOptions(pluginNames []string, pluginArgs []string) ([]*framework.PluginOption, error) {
+	var plugins []*framework.PluginOption
+	pluginIndex := map[string]*framework.PluginOption{}
+
+	for _, name := range pluginNames {
+		if _, found := framework.GetPluginBuilder(name); !found {
+			return nil, fmt.Errorf("Plugin %s is not supported", name)
+		}
+
+		po := &framework.PluginOption{
+			Name:      name,
+			Arguments: framework.Arguments{},
+		}
+		plugins = append(plugins, po)
+		pluginIndex[name] = po
 	}
 
+	for _, arg := range pluginArgs {
+		kv := strings.SplitN(arg, "=", 2)
+		nk := strings.SplitN(kv[0], ".", 2)
+		if len(kv) != 2 || len(nk) != 2 {
+			return nil, fmt.Errorf("Plugin argument %s is invalid", arg)
+		}
+
+		po, found := pluginIndex[nk[0]]
+		if !found {
+			return nil, fmt.Errorf("Plugin %s of argument %s is not enabled", nk[0], arg)
+		}
+		po.Arguments[nk[1]] = kv[1]
+	}
+
+	return plugins, nil
 }
 
+// buildDefaultRequests parses the default requests of the pods that request
+// nothing, in the format of [<namespace>:]<resource>=<quantity>,...; the
+// requests without namespace are for all namespaces not listed, by "".
+func buildDefaultRequests(args []string) (map[string]v1.ResourceList, error) {
+	requests := map[string]v1.ResourceList{}
+
+	for _, arg := range args {
+		ns, list := "", arg
+		if i := strings.Index(arg, ":"); i >= 0 {
+			ns, list = arg[:i], arg[i+1:]
+			if len(ns) == 0 {
+				return nil, fmt.Errorf("Default request %s has empty namespace", arg)
+			}
+		}
+		if _, found := requests[ns]; found {
+			return nil, fmt.Errorf("Default request of namespace %q is set twice", ns)
+		}
+
+		rl := v1.ResourceList{}
+		for _, item := range strings.Split(list, ",") {
+			kv := strings.SplitN(item, "=", 2)
+			if len(kv) != 2 || len(kv[0]) == 0 {
+				return nil, fmt.Errorf("Default request %s is invalid", arg)
+			}
+			q, err := resource.ParseQuantity(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("Default request %s is invalid: %v", arg, err)
+			}
+			if q.Sign() < 0 {
+				return nil, fmt.Errorf("Default request %s must not be negative", arg)
+			}
+			rl[v1.ResourceName(kv[0])] = q
+		}
+		requests[ns] = rl
+	}
+
+	return requests, nil
+}
+
+// createSchedulingSpecKind creates the CRDs of SchedulingSpec and Reservation.
 func createSchedulingSpecKind(config *rest.Config) error {
 	extensionscs, err := apiextensionsclient.NewForConfig(config)
 	if err != nil {
@@ -96,5 +336,9 @@ func createSchedulingSpecKind(config *rest.Config) error {
 	if err != nil && !apierrors.IsAlreadyExists(err) {
 		return err
 	}
+	_, err = client.CreateReservationKind(extensionscs)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,400 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/actions/allocate"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+	schedcache "github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/cache"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/framework"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/metrics"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/plugins/gang"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/util/testutil"
+)
+
+// slowBinder takes a while to bind, so the binds are in flight when the
+// scheduler is shut down.
+type slowBinder struct {
+	sync.Mutex
+	binds []string
+}
+
+func (sb *slowBinder) Bind(p *v1.Pod, hostname string) error {
+	time.Sleep(100 * time.Millisecond)
+
+	sb.Lock()
+	defer sb.Unlock()
+	sb.binds = append(sb.binds, fmt.Sprintf("%v/%v:%v", p.Namespace, p.Name, hostname))
+	return nil
+}
+
+// cancelAction cancels the context of scheduler when it's executed.
+type cancelAction struct {
+	cancel context.CancelFunc
+}
+
+func (ca *cancelAction) Name() string                   { return "cancel" }
+func (ca *cancelAction) Initialize()                    {}
+func (ca *cancelAction) Execute(ssn *framework.Session) { ca.cancel() }
+func (ca *cancelAction) UnInitialize()                  {}
+
+func TestShutdown(t *testing.T) {
+	framework.RegisterPluginBuilder(gang.PluginName, gang.New)
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name          string
+		cancelFirst   bool
+		expectedBinds []string
+	}{
+		{
+			name:        "cancelled before allocate, nothing is bound",
+			cancelFirst: true,
+		},
+		{
+			name:          "cancelled after allocate, all binds of the gang are flushed",
+			expectedBinds: []string{"c1/p1:n1", "c1/p2:n1"},
+		},
+	}
+
+	for _, test := range tests {
+		owner := testutil.BuildOwnerReference("owner1")
+
+		binder := &slowBinder{}
+		schedulerCache := &schedcache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: binder,
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+		schedulerCache.AddPod(testutil.BuildPod("c1", "p2", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+		schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "j1",
+				Namespace:       "c1",
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+			Spec: arbv1.SchedulingSpecTemplate{
+				MinAvailable: 2,
+			},
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		actions := []framework.Action{allocate.New(), &cancelAction{cancel: cancel}}
+		if test.cancelFirst {
+			actions = []framework.Action{&cancelAction{cancel: cancel}, allocate.New()}
+		}
+
+		sched := &Scheduler{
+			cache:           schedulerCache,
+			actions:         actions,
+			plugins:         []*framework.PluginOption{{Name: gang.PluginName}},
+			shutdownTimeout: 3 * time.Second,
+		}
+
+		sched.runOnce(ctx)
+		sched.shutdown()
+
+		binder.Lock()
+		binds := binder.binds
+		binder.Unlock()
+
+		if len(binds) != len(test.expectedBinds) {
+			t.Errorf("case %s: expected binds %v, got %v", test.name, test.expectedBinds, binds)
+			continue
+		}
+		if len(binds) != 0 {
+			got := map[string]bool{}
+			for _, b := range binds {
+				got[b] = true
+			}
+			expected := map[string]bool{}
+			for _, b := range test.expectedBinds {
+				expected[b] = true
+			}
+			if !reflect.DeepEqual(expected, got) {
+				t.Errorf("case %s: expected binds %v, got %v", test.name, test.expectedBinds, binds)
+			}
+		}
+	}
+}
+
+// slowPlugin takes a while in each predicate, so the session runs over its
+// timeout.
+type slowPlugin struct {
+	delay time.Duration
+}
+
+func (sp *slowPlugin) Name() string { return "slow" }
+
+func (sp *slowPlugin) OnSessionOpen(ssn *framework.Session) {
+	ssn.AddPredicateFn(func(task *api.TaskInfo, node *api.NodeInfo) error {
+		time.Sleep(sp.delay)
+		return nil
+	})
+}
+
+func (sp *slowPlugin) OnSessionClose(ssn *framework.Session) {}
+
+func TestSessionTimeout(t *testing.T) {
+	framework.RegisterPluginBuilder(gang.PluginName, gang.New)
+	framework.RegisterPluginBuilder("slow", func(framework.Arguments) framework.Plugin {
+		return &slowPlugin{delay: 100 * time.Millisecond}
+	})
+	defer framework.CleanupPluginBuilders()
+
+	tests := []struct {
+		name             string
+		sessionTimeout   time.Duration
+		expectedBinds    int
+		expectedTimeouts int64
+	}{
+		{
+			name:          "no timeout, all jobs are allocated",
+			expectedBinds: 3,
+		},
+		{
+			name:             "timed out in allocate, the jobs allocated before are bound",
+			sessionTimeout:   150 * time.Millisecond,
+			expectedBinds:    2,
+			expectedTimeouts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		binder := &slowBinder{}
+		schedulerCache := &schedcache.SchedulerCache{
+			Nodes:  make(map[string]*api.NodeInfo),
+			Jobs:   make(map[api.JobID]*api.JobInfo),
+			Binder: binder,
+		}
+
+		schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+		for i := 1; i <= 3; i++ {
+			owner := testutil.BuildOwnerReference(fmt.Sprintf("owner%d", i))
+			schedulerCache.AddPod(testutil.BuildPod("c1", fmt.Sprintf("p%d", i), "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+			schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            fmt.Sprintf("j%d", i),
+					Namespace:       "c1",
+					OwnerReferences: []metav1.OwnerReference{owner},
+				},
+			})
+		}
+
+		sched := &Scheduler{
+			cache:           schedulerCache,
+			actions:         []framework.Action{allocate.New()},
+			plugins:         []*framework.PluginOption{{Name: gang.PluginName}, {Name: "slow"}},
+			sessionTimeout:  test.sessionTimeout,
+			shutdownTimeout: 3 * time.Second,
+		}
+
+		timeouts := metrics.SessionTimeouts.Value()
+
+		sched.runOnce(context.Background())
+		sched.shutdown()
+
+		binder.Lock()
+		binds := binder.binds
+		binder.Unlock()
+
+		if len(binds) != test.expectedBinds {
+			t.Errorf("case %s: expected %d binds, got %v", test.name, test.expectedBinds, binds)
+		}
+		if got := metrics.SessionTimeouts.Value() - timeouts; got != test.expectedTimeouts {
+			t.Errorf("case %s: expected %d session timeouts, got %d", test.name, test.expectedTimeouts, got)
+		}
+	}
+}
+
+// limitsPlugin saves the preemption limits of session at open.
+type limitsPlugin struct {
+	limits *[]int
+}
+
+func (lp *limitsPlugin) Name() string { return "limits" }
+
+func (lp *limitsPlugin) OnSessionOpen(ssn *framework.Session) {
+	*lp.limits = []int{ssn.MaxPreemptees, ssn.MaxPreemptions, ssn.MaxPreemptionRounds,
+		ssn.MaxVictimsPerPreemptor, ssn.MaxVictimsPerJob}
+}
+
+func (lp *limitsPlugin) OnSessionClose(ssn *framework.Session) {}
+
+func TestSessionLimits(t *testing.T) {
+	var limits []int
+	framework.RegisterPluginBuilder("limits", func(framework.Arguments) framework.Plugin {
+		return &limitsPlugin{limits: &limits}
+	})
+	defer framework.CleanupPluginBuilders()
+
+	sched := &Scheduler{
+		cache: &schedcache.SchedulerCache{
+			Nodes: make(map[string]*api.NodeInfo),
+			Jobs:  make(map[api.JobID]*api.JobInfo),
+		},
+		plugins:                []*framework.PluginOption{{Name: "limits"}},
+		maxPreemptees:          1,
+		maxPreemptions:         2,
+		maxPreemptionRounds:    3,
+		maxVictimsPerPreemptor: 4,
+		maxVictimsPerJob:       5,
+	}
+
+	sched.runOnce(context.Background())
+
+	// The plugins see the limits of scheduler when they're opened.
+	if expected := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(expected, limits) {
+		t.Errorf("expected limits %v at session open, got %v", expected, limits)
+	}
+}
+
+func TestDebugSession(t *testing.T) {
+	framework.RegisterPluginBuilder(gang.PluginName, gang.New)
+	defer framework.CleanupPluginBuilders()
+
+	owner := testutil.BuildOwnerReference("owner1")
+
+	schedulerCache := &schedcache.SchedulerCache{
+		Nodes:  make(map[string]*api.NodeInfo),
+		Jobs:   make(map[api.JobID]*api.JobInfo),
+		Binder: &slowBinder{},
+	}
+
+	schedulerCache.AddNode(testutil.BuildNode("n1", testutil.BuildResourceList("4", "8Gi")))
+	schedulerCache.AddPod(testutil.BuildPod("c1", "p1", "", v1.PodPending, testutil.BuildResourceList("1", "1Gi"), owner))
+	schedulerCache.AddSchedulingSpec(&arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "j1",
+			Namespace:       "c1",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	})
+
+	sched := &Scheduler{
+		cache:           schedulerCache,
+		actions:         []framework.Action{allocate.New()},
+		plugins:         []*framework.PluginOption{{Name: gang.PluginName}},
+		shutdownTimeout: 3 * time.Second,
+		debugSession:    true,
+	}
+
+	// No session yet.
+	rec := httptest.NewRecorder()
+	sched.ServeHTTP(rec, httptest.NewRequest("GET", SessionPath, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d before any session, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	sched.runOnce(context.Background())
+	sched.shutdown()
+
+	rec = httptest.NewRecorder()
+	sched.ServeHTTP(rec, httptest.NewRequest("GET", SessionPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	state := &sessionState{}
+	if err := json.Unmarshal(rec.Body.Bytes(), state); err != nil {
+		t.Fatalf("failed to decode session state: %v", err)
+	}
+
+	if len(state.Nodes) != 1 {
+		t.Fatalf("expected 1 node, got %v", state.Nodes)
+	}
+	node := state.Nodes[0]
+	if node.Name != "n1" || node.Allocatable.MilliCPU != 4000 ||
+		node.Used.MilliCPU != 1000 || node.Idle.MilliCPU != 3000 || node.Releasing.MilliCPU != 0 {
+		t.Errorf("unexpected node state: %s", rec.Body.String())
+	}
+
+	if len(state.Jobs) != 1 || len(state.Jobs[0].Tasks) != 1 {
+		t.Fatalf("expected 1 job of 1 task, got %v", state.Jobs)
+	}
+	job := state.Jobs[0]
+	if task := job.Tasks[0]; task.Name != "p1" || task.Status != api.Binding.String() || task.NodeName != "n1" {
+		t.Errorf("unexpected task state: %+v", task)
+	}
+	if job.Share != 0.25 {
+		t.Errorf("expected job share 0.25, got %v", job.Share)
+	}
+}
+
+func TestBuildDefaultRequests(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected map[string]v1.ResourceList
+		err      bool
+	}{
+		{
+			args: []string{"cpu=100m,memory=128Mi", "c1:cpu=1"},
+			expected: map[string]v1.ResourceList{
+				"":   {v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("128Mi")},
+				"c1": {v1.ResourceCPU: resource.MustParse("1")},
+			},
+		},
+		{args: []string{"cpu"}, err: true},
+		{args: []string{"cpu=x"}, err: true},
+		{args: []string{"cpu=-1"}, err: true},
+		{args: []string{":cpu=1"}, err: true},
+		{args: []string{"c1:cpu=1", "c1:memory=1Gi"}, err: true},
+	}
+
+	for i, test := range tests {
+		requests, err := buildDefaultRequests(test.args)
+		if test.err {
+			if err == nil {
+				t.Errorf("case %d: expected error of %v, got %v", i, test.args, requests)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if len(requests) != len(test.expected) {
+			t.Errorf("case %d: expected %v, got %v", i, test.expected, requests)
+			continue
+		}
+		for ns, rl := range test.expected {
+			for name, q := range rl {
+				if got := requests[ns][name]; got.Cmp(q) != 0 {
+					t.Errorf("case %d: expected %s of %q to be %v, got %v", i, name, ns, q.String(), got.String())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testutil has the helpers to build the objects of the scheduler
+// tests, and the fakes of the binder, evictor and clock of the cache.
+package testutil
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	arbv1 "github.com/kubernetes-incubator/kube-arbitrator/pkg/apis/v1alpha1"
+	"github.com/kubernetes-incubator/kube-arbitrator/pkg/scheduler/api"
+)
+
+func BuildResourceList(cpu string, memory string) v1.ResourceList {
+	return v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse(cpu),
+		v1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+func BuildResourceListWithGPU(cpu string, memory string, gpu string) v1.ResourceList {
+	return v1.ResourceList{
+		v1.ResourceCPU:      resource.MustParse(cpu),
+		v1.ResourceMemory:   resource.MustParse(memory),
+		api.GPUResourceName: resource.MustParse(gpu),
+	}
+}
+
+func BuildNode(name string, alloc v1.ResourceList) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:  types.UID(name),
+			Name: name,
+		},
+		Status: v1.NodeStatus{
+			Capacity:    alloc,
+			Allocatable: alloc,
+		},
+	}
+}
+
+// BuildNodeWithLabels returns the node of BuildNode with the labels.
+func BuildNodeWithLabels(name string, alloc v1.ResourceList, labels map[string]string) *v1.Node {
+	node := BuildNode(name, alloc)
+	node.Labels = labels
+	return node
+}
+
+// BuildNodeWithAnnotations returns the node of BuildNode with the
+// annotations.
+func BuildNodeWithAnnotations(name string, alloc v1.ResourceList, annotations map[string]string) *v1.Node {
+	node := BuildNode(name, alloc)
+	node.Annotations = annotations
+	return node
+}
+
+// BuildPod returns the pod ns/n of owner in phase p on node nn, which
+// requests req in its only container.
+func BuildPod(ns, n, nn string, p v1.PodPhase, req v1.ResourceList, owner metav1.OwnerReference) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:             types.UID(fmt.Sprintf("%v-%v", ns, n)),
+			Name:            n,
+			Namespace:       ns,
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Status: v1.PodStatus{
+			Phase: p,
+		},
+		Spec: v1.PodSpec{
+			NodeName: nn,
+			Containers: []v1.Container{
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: req,
+					},
+				},
+			},
+		},
+	}
+}
+
+func BuildOwnerReference(owner string) metav1.OwnerReference {
+	controller := true
+	return metav1.OwnerReference{
+		Controller: &controller,
+		UID:        types.UID(owner),
+	}
+}
+
+func BuildSchedulingSpec(ns, n string, owner metav1.OwnerReference) *arbv1.SchedulingSpec {
+	return &arbv1.SchedulingSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            n,
+			Namespace:       ns,
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	}
+}
+
+// FakeBinder records the node of each bound pod by "namespace/name".
+type FakeBinder struct {
+	sync.Mutex
+	Binds map[string]string
+}
+
+func NewFakeBinder() *FakeBinder {
+	return &FakeBinder{Binds: map[string]string{}}
+}
+
+func (fb *FakeBinder) Bind(p *v1.Pod, hostname string) error {
+	fb.Lock()
+	defer fb.Unlock()
+
+	fb.Binds[fmt.Sprintf("%v/%v", p.Namespace, p.Name)] = hostname
+	return nil
+}
+
+// Length returns the number of bound pods.
+func (fb *FakeBinder) Length() int {
+	fb.Lock()
+	defer fb.Unlock()
+
+	return len(fb.Binds)
+}
+
+// FakeEvictor records the evicted pods by "namespace/name".
+type FakeEvictor struct {
+	sync.Mutex
+	Evicts []string
+}
+
+func (fe *FakeEvictor) Evict(p *v1.Pod, reason string) error {
+	fe.Lock()
+	defer fe.Unlock()
+
+	fe.Evicts = append(fe.Evicts, fmt.Sprintf("%v/%v", p.Namespace, p.Name))
+	return nil
+}
+
+// Evicted returns the evicted pods in order of their names.
+func (fe *FakeEvictor) Evicted() []string {
+	fe.Lock()
+	defer fe.Unlock()
+
+	res := append([]string{}, fe.Evicts...)
+	sort.Strings(res)
+	return res
+}
+
+// FakeClock returns Time as the current time; the tests move it forward by
+// setting Time.
+type FakeClock struct {
+	Time time.Time
+}
+
+func (fc *FakeClock) Now() time.Time {
+	return fc.Time
+}